@@ -0,0 +1,33 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+)
+
+// consumerPattern matches the consumers accepted by the provider,
+// `projects/{project_number}` or `projects/{project_id}`.
+var consumerPattern = regexp.MustCompile(`^projects/(\d+|[a-z][a-z0-9-]{4,28}[a-z0-9])$`)
+
+// projectNumberConsumerPattern matches consumers in the
+// `projects/{project_number}` form used by the API.
+var projectNumberConsumerPattern = regexp.MustCompile(`^projects/\d+$`)
+
+// resolveConsumer returns the `projects/{project_number}` form of a consumer
+// given as `projects/{project_number}` or `projects/{project_id}`.
+func (p *UtilsProviderConfig) resolveConsumer(ctx context.Context, consumer string) (string, error) {
+	if projectNumberConsumerPattern.MatchString(consumer) {
+		return consumer, nil
+	}
+
+	project, err := retryTransient(ctx, func(ctx context.Context) (*cloudresourcemanager.Project, error) {
+		return p.ResourceManagerClient.Projects.Get(consumer).Context(ctx).Do()
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not resolve project number of consumer %s: %w", consumer, err)
+	}
+	return project.Name, nil
+}
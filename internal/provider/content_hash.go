@@ -0,0 +1,185 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// contentHashPrefix prefixes the hashes which are stored in place of config
+// contents when `store_contents_in_state` is false.
+const contentHashPrefix = "sha256:"
+
+// contentHash returns the hash which is stored in place of contents.
+func contentHash(contents []byte) string {
+	return contentHashPrefix + sha256Hex(contents)
+}
+
+// isContentHash reports whether s is a hash returned by contentHash.
+func isContentHash(s string) bool {
+	h, ok := strings.CutPrefix(s, contentHashPrefix)
+	if !ok || len(h) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(h)
+	return err == nil
+}
+
+// yamlContentHash returns the content hash of a config value, which may
+// already be a content hash.
+func yamlContentHash(v YAMLStringValue) string {
+	if isContentHash(v.ValueString()) {
+		return v.ValueString()
+	}
+	return contentHash([]byte(v.ValueString()))
+}
+
+// descriptorContentHash returns the content hash of a base64-encoded
+// descriptor, which may already be a content hash.
+func descriptorContentHash(v ProtoDescriptorValue) string {
+	if isContentHash(v.ValueString()) {
+		return v.ValueString()
+	}
+	descriptor, err := base64.StdEncoding.DecodeString(v.ValueString())
+	if err != nil {
+		descriptor = []byte(v.ValueString())
+	}
+	return contentHash(descriptor)
+}
+
+// storeContents reports whether config contents are stored in state, rather
+// than their hashes.
+func (data ServiceConfigResourceModel) storeContents() bool {
+	return data.StoreContentsInState.IsNull() || data.StoreContentsInState.IsUnknown() || data.StoreContentsInState.ValueBool()
+}
+
+// storedContents returns the value to store in state for contents read from
+// the API.
+func (data ServiceConfigResourceModel) storedContents(contents []byte) string {
+	if data.storeContents() {
+		return string(contents)
+	}
+	return contentHash(contents)
+}
+
+// storedDescriptor returns the value to store in state for a descriptor read
+// from the API.
+func (data ServiceConfigResourceModel) storedDescriptor(descriptor []byte) ProtoDescriptorValue {
+	if data.storeContents() {
+		return NewProtoDescriptorValue(descriptor)
+	}
+	return ProtoDescriptorValue{StringValue: types.StringValue(contentHash(descriptor))}
+}
+
+// contentHashes returns the content hashes of the config files and descriptor
+// in data, keyed by attribute, so that configs can be compared regardless of
+// whether their contents or hashes are stored.
+func (data ServiceConfigResourceModel) contentHashes(ctx context.Context, diags *diag.Diagnostics) map[string]string {
+	hashes := make(map[string]string)
+	if !data.ConfigYaml.IsNull() {
+		hashes["config_yaml"] = yamlContentHash(data.ConfigYaml)
+	}
+	if !data.ConfigJson.IsNull() {
+		hashes["config_json"] = yamlContentHash(data.ConfigJson)
+	}
+	if !data.ProtoDescriptor.IsNull() {
+		hashes["proto_descriptor"] = descriptorContentHash(data.ProtoDescriptor)
+	}
+	if !data.ConfigFiles.IsNull() {
+		var fileModels []ServiceConfigFileModel
+		diags.Append(data.ConfigFiles.ElementsAs(ctx, &fileModels, false)...)
+		for i, fileModel := range fileModels {
+			hashes[fmt.Sprintf("config_files.%d", i)] = strings.Join([]string{
+				fileModel.Path.ValueString(),
+				fileModel.Type.ValueString(),
+				yamlContentHash(fileModel.Contents),
+			}, "\x00")
+		}
+	}
+	return hashes
+}
+
+// keepContentHashes replaces the contents planned in data with the hashes in
+// state wherever they match, so that storing hashes does not produce diffs.
+func (data *ServiceConfigResourceModel) keepContentHashes(ctx context.Context, state ServiceConfigResourceModel, diags *diag.Diagnostics) {
+	keepYAML := func(planned *YAMLStringValue, stored YAMLStringValue) {
+		if planned.IsNull() || planned.IsUnknown() || !isContentHash(stored.ValueString()) {
+			return
+		}
+		if yamlContentHash(*planned) == stored.ValueString() {
+			*planned = stored
+		}
+	}
+	keepYAML(&data.ConfigYaml, state.ConfigYaml)
+	keepYAML(&data.ConfigJson, state.ConfigJson)
+
+	keepDescriptor := func(planned *ProtoDescriptorValue, stored ProtoDescriptorValue) {
+		if planned.IsNull() || planned.IsUnknown() || !isContentHash(stored.ValueString()) {
+			return
+		}
+		if descriptorContentHash(*planned) == stored.ValueString() {
+			*planned = stored
+		}
+	}
+	keepDescriptor(&data.ProtoDescriptor, state.ProtoDescriptor)
+	keepDescriptor(&data.ProtoDescriptorBase64, state.ProtoDescriptorBase64)
+
+	if data.ConfigFiles.IsNull() || data.ConfigFiles.IsUnknown() || state.ConfigFiles.IsNull() {
+		return
+	}
+	var planned, stored []ServiceConfigFileModel
+	diags.Append(data.ConfigFiles.ElementsAs(ctx, &planned, false)...)
+	diags.Append(state.ConfigFiles.ElementsAs(ctx, &stored, false)...)
+	if diags.HasError() {
+		return
+	}
+	for i := range planned {
+		if i < len(stored) && planned[i].Path.Equal(stored[i].Path) && planned[i].Type.Equal(stored[i].Type) {
+			keepYAML(&planned[i].Contents, stored[i].Contents)
+		}
+	}
+	list, listDiags := types.ListValueFrom(ctx, types.ObjectType{AttrTypes: ServiceConfigFileModel{}.AttributeTypes()}, planned)
+	diags.Append(listDiags...)
+	data.ConfigFiles = list
+}
+
+// restoreContents replaces any content hashes in data with the contents from
+// the configuration, so that the full contents can be submitted.
+func (data *ServiceConfigResourceModel) restoreContents(ctx context.Context, config tfsdk.Config, diags *diag.Diagnostics) {
+	if isContentHash(data.ConfigYaml.ValueString()) {
+		diags.Append(config.GetAttribute(ctx, path.Root("config_yaml"), &data.ConfigYaml)...)
+	}
+	if isContentHash(data.ConfigJson.ValueString()) {
+		diags.Append(config.GetAttribute(ctx, path.Root("config_json"), &data.ConfigJson)...)
+	}
+	if isContentHash(data.ProtoDescriptor.ValueString()) {
+		diags.Append(config.GetAttribute(ctx, path.Root("proto_descriptor"), &data.ProtoDescriptor)...)
+		if data.ProtoDescriptor.IsNull() {
+			diags.Append(config.GetAttribute(ctx, path.Root("proto_descriptor_base64"), &data.ProtoDescriptor)...)
+		}
+	}
+
+	if data.ConfigFiles.IsNull() || data.ConfigFiles.IsUnknown() {
+		return
+	}
+	var fileModels []ServiceConfigFileModel
+	diags.Append(data.ConfigFiles.ElementsAs(ctx, &fileModels, false)...)
+	if diags.HasError() {
+		return
+	}
+	for i := range fileModels {
+		if isContentHash(fileModels[i].Contents.ValueString()) {
+			diags.Append(config.GetAttribute(ctx, path.Root("config_files").AtListIndex(i).AtName("contents"), &fileModels[i].Contents)...)
+		}
+	}
+	list, listDiags := types.ListValueFrom(ctx, types.ObjectType{AttrTypes: ServiceConfigFileModel{}.AttributeTypes()}, fileModels)
+	diags.Append(listDiags...)
+	data.ConfigFiles = list
+}
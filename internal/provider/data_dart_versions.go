@@ -7,30 +7,82 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	constraints "github.com/Masterminds/semver/v3"
 	"github.com/coreos/go-semver/semver"
+	"github.com/hashicorp/terraform-plugin-framework-validators/datasourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"golang.org/x/sync/errgroup"
 )
 
-type DartVersionsDataSource struct{}
+// Types of SDK listed by DartVersionsDataSource.
+const (
+	sdkTypeDart    = "dart"
+	sdkTypeFlutter = "flutter"
+)
+
+// Orders in which DartVersionsDataSource lists versions.
+const (
+	sortOrderAsc  = "asc"
+	sortOrderDesc = "desc"
+)
+
+// dartArchiveURL lists the objects of the bucket of Dart SDK releases.
+var dartArchiveURL = "https://www.googleapis.com/storage/v1/b/dart-archive/o"
+
+// dartReleasesURL is the bucket of Dart SDK releases, where the `VERSION`
+// file of each release records its date.
+var dartReleasesURL = "https://storage.googleapis.com/dart-archive"
+
+// dartReleaseDateConcurrency is the maximum number of `VERSION` files fetched
+// at once when listing release dates.
+const dartReleaseDateConcurrency = 8
+
+// dartReleaseDateLayouts are the formats of the dates in `VERSION` files.
+// Recent releases use a date, and older releases a date and time.
+var dartReleaseDateLayouts = []string{"2006-01-02", "200601021504", time.RFC3339}
+
+// flutterReleasesURL is the manifest of Flutter SDK releases. The manifests
+// for other platforms list the same versions.
+var flutterReleasesURL = "https://storage.googleapis.com/flutter_infra_release/releases/releases_linux.json"
+
+var _ datasource.DataSourceWithValidateConfig = &DartVersionsDataSource{}
+var _ datasource.DataSourceWithConfigValidators = &DartVersionsDataSource{}
+
+type DartVersionsDataSource struct {
+	// client is used to list versions, or http.DefaultClient if nil.
+	client *http.Client
+}
 
 type DartVersionsDataSourceModel struct {
 	SdkType    types.String `tfsdk:"sdk_type"`
 	MinVersion types.String `tfsdk:"min_version"`
-	Channels   types.List   `tfsdk:"channels"`
+	MaxVersion types.String `tfsdk:"max_version"`
+	Constraint types.String `tfsdk:"version_constraint"`
+	Limit      types.Int64  `tfsdk:"limit"`
+	SortOrder  types.String `tfsdk:"sort_order"`
+
+	IncludeReleaseDates types.Bool `tfsdk:"include_release_dates"`
+	Channels            types.List `tfsdk:"channels"`
 
 	// Computed
-	ID       types.String `tfsdk:"id"`
-	Versions types.List   `tfsdk:"versions"`
+	ID           types.String `tfsdk:"id"`
+	Versions     types.List   `tfsdk:"versions"`
+	ReleaseDates types.Map    `tfsdk:"release_dates"`
 }
 
 func NewDartVersionsDataSource() datasource.DataSource {
@@ -45,19 +97,60 @@ func (s *DartVersionsDataSource) Metadata(ctx context.Context, req datasource.Me
 // Schema implements datasource.DataSource.
 func (s *DartVersionsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		MarkdownDescription: "A list of Dart SDK versions.",
+		MarkdownDescription: "A list of Dart or Flutter SDK versions.",
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
-				MarkdownDescription: "The ID of the config. Format: `{sdkType}/{minVersion}`.",
+				MarkdownDescription: "The ID of the config. Format: `{sdkType}/{minVersion}`, `{sdkType}/{minVersion}/{maxVersion}` when `max_version` is set, or `{sdkType}/{versionConstraint}` when `version_constraint` is set, followed by the query `?limit={limit}&sort_order={sortOrder}` of whichever of `limit` and `sort_order` are set.",
 				Computed:            true,
 			},
 			"sdk_type": schema.StringAttribute{
-				MarkdownDescription: "The type of SDK.",
+				MarkdownDescription: "The type of SDK: `dart` lists Dart SDK releases, and `flutter` lists Flutter SDK releases.",
 				Required:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(sdkTypeDart, sdkTypeFlutter),
+				},
 			},
 			"min_version": schema.StringAttribute{
-				MarkdownDescription: "The minimum version of the SDK.",
-				Required:            true,
+				MarkdownDescription: "The minimum version of the SDK, for example `3.5.0`. Exactly one of `min_version` or `version_constraint` must be set.",
+				Optional:            true,
+				Validators: []validator.String{
+					semverValidator{},
+				},
+			},
+			"max_version": schema.StringAttribute{
+				MarkdownDescription: "The version of the SDK below which versions are listed, for example `3.6.0`. Must be greater than `min_version`.",
+				Optional:            true,
+				Validators: []validator.String{
+					semverValidator{},
+				},
+			},
+			"version_constraint": schema.StringAttribute{
+				MarkdownDescription: "A constraint on the versions of the SDK, for example `>=3.4.0 <3.6.0 !=3.5.2`. " +
+					"See [Masterminds/semver](https://github.com/Masterminds/semver#checking-version-constraints) for the syntax. " +
+					"Pre-release versions are only matched by constraints which include a pre-release, for example `>=3.6.0-0`. " +
+					"Conflicts with `min_version` and `max_version`.",
+				Optional: true,
+				Validators: []validator.String{
+					versionConstraintValidator{},
+				},
+			},
+			"limit": schema.Int64Attribute{
+				MarkdownDescription: "The maximum number of versions to list. When set, only the newest `limit` versions are listed.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"sort_order": schema.StringAttribute{
+				MarkdownDescription: "The order of `versions`: `asc` (the default) lists the oldest version first, and `desc` lists the newest version first.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(sortOrderAsc, sortOrderDesc),
+				},
+			},
+			"include_release_dates": schema.BoolAttribute{
+				MarkdownDescription: "Whether to list the release date of each version in `release_dates`. Defaults to `false`, since listing Dart release dates fetches the `VERSION` file of each listed version.",
+				Optional:            true,
 			},
 			"channels": schema.ListAttribute{
 				MarkdownDescription: "The list of release channels to include.",
@@ -74,6 +167,11 @@ func (s *DartVersionsDataSource) Schema(ctx context.Context, req datasource.Sche
 				Computed:            true,
 				ElementType:         basetypes.StringType{},
 			},
+			"release_dates": schema.MapAttribute{
+				MarkdownDescription: "The release date of each version in `versions`, in RFC 3339 format, when `include_release_dates` is `true`. Versions whose release date is not known are omitted.",
+				Computed:            true,
+				ElementType:         basetypes.StringType{},
+			},
 		},
 	}
 }
@@ -81,6 +179,43 @@ func (s *DartVersionsDataSource) Schema(ctx context.Context, req datasource.Sche
 func (d *DartVersionsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
 }
 
+// ConfigValidators implements datasource.DataSourceWithConfigValidators.
+func (d *DartVersionsDataSource) ConfigValidators(ctx context.Context) []datasource.ConfigValidator {
+	return []datasource.ConfigValidator{
+		datasourcevalidator.ExactlyOneOf(path.MatchRoot("min_version"), path.MatchRoot("version_constraint")),
+		datasourcevalidator.Conflicting(path.MatchRoot("max_version"), path.MatchRoot("version_constraint")),
+	}
+}
+
+// ValidateConfig implements datasource.DataSourceWithValidateConfig.
+func (d *DartVersionsDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var model DartVersionsDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if model.MinVersion.IsNull() || model.MinVersion.IsUnknown() || model.MaxVersion.IsNull() || model.MaxVersion.IsUnknown() {
+		return
+	}
+
+	// Invalid versions are reported by the attribute validators.
+	minVersion, err := semver.NewVersion(model.MinVersion.ValueString())
+	if err != nil {
+		return
+	}
+	maxVersion, err := semver.NewVersion(model.MaxVersion.ValueString())
+	if err != nil {
+		return
+	}
+	if !minVersion.LessThan(*maxVersion) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("max_version"),
+			"Invalid version range",
+			fmt.Sprintf("`max_version` (%s) must be greater than `min_version` (%s).", maxVersion, minVersion),
+		)
+	}
+}
+
 func (d *DartVersionsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
 	model := DartVersionsDataSourceModel{}
 	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
@@ -101,59 +236,154 @@ func (d *DartVersionsDataSource) Read(ctx context.Context, req datasource.ReadRe
 		}
 	}
 
-	eg := new(errgroup.Group)
-
-	versionsChan := make(chan []string)
+	versionsSet := make(map[string]struct{})
+	// The release date of each Flutter version, and the channel of each Dart
+	// version, used to list release dates.
+	flutterReleaseDates := make(map[string]string)
+	dartChannels := make(map[string]string)
+	if model.SdkType.ValueString() == sdkTypeFlutter {
+		releaseDates, err := d.listFlutterVersions(ctx, channels)
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to list versions", err.Error())
+			return
+		}
+		for version, releaseDate := range releaseDates {
+			versionsSet[version] = struct{}{}
+			flutterReleaseDates[version] = releaseDate
+		}
+	} else {
+		// Each goroutine writes only its own channel's results, and the
+		// diagnostics are only touched once they have all finished.
+		eg, egCtx := errgroup.WithContext(ctx)
+		channelVersions := make([][]string, len(channels))
+		for i, channel := range channels {
+			eg.Go(func() error {
+				versions, err := d.listVersions(egCtx, channel)
+				if err != nil {
+					return err
+				}
+				channelVersions[i] = versions
+				return nil
+			})
+		}
+		if err := eg.Wait(); err != nil {
+			resp.Diagnostics.AddError("Failed to list versions", err.Error())
+			return
+		}
 
-	for _, channel := range channels {
-		channel := channel
-		eg.Go(func() error {
-			versions, err := d.listVersions(channel)
-			if err != nil {
-				return err
+		for i, versions := range channelVersions {
+			for _, version := range versions {
+				versionsSet[version] = struct{}{}
+				dartChannels[version] = channels[i]
 			}
-			versionsChan <- versions
-			return nil
-		})
+		}
 	}
 
-	go func() {
-		err := eg.Wait()
+	// min_version and version_constraint are validated, but may still be
+	// unknown until apply, in which case they are not applied.
+	var minVersion *semver.Version
+	if !model.MinVersion.IsNull() && !model.MinVersion.IsUnknown() {
+		var err error
+		minVersion, err = semver.NewVersion(model.MinVersion.ValueString())
 		if err != nil {
-			resp.Diagnostics.AddError("Failed to list versions", err.Error())
+			resp.Diagnostics.AddAttributeError(path.Root("min_version"), "Invalid version", err.Error())
+			return
 		}
-		close(versionsChan)
-	}()
-
-	versionsSet := make(map[string]struct{})
-	for versions := range versionsChan {
-		if versions == nil {
-			continue
+	}
+	var maxVersion *semver.Version
+	if !model.MaxVersion.IsNull() && !model.MaxVersion.IsUnknown() {
+		var err error
+		maxVersion, err = semver.NewVersion(model.MaxVersion.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("max_version"), "Invalid version", err.Error())
+			return
 		}
-		for _, version := range versions {
-			versionsSet[version] = struct{}{}
+	}
+	var constraint *constraints.Constraints
+	if !model.Constraint.IsNull() && !model.Constraint.IsUnknown() {
+		var err error
+		constraint, err = constraints.NewConstraint(model.Constraint.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("version_constraint"), "Invalid version constraint", err.Error())
+			return
 		}
 	}
-
-	minVersion := semver.New(model.MinVersion.ValueString())
 	versions := make([]*semver.Version, 0, len(versionsSet))
 	for version := range versionsSet {
-		semversion := semver.New(version)
-		if semversion.LessThan(*minVersion) {
+		semversion, err := semver.NewVersion(version)
+		if err != nil {
+			continue
+		}
+		if constraint != nil {
+			constraintVersion, err := constraints.StrictNewVersion(version)
+			if err != nil || !constraint.Check(constraintVersion) {
+				continue
+			}
+		}
+		if minVersion != nil && semversion.LessThan(*minVersion) {
+			continue
+		}
+		if maxVersion != nil && !semversion.LessThan(*maxVersion) {
 			continue
 		}
 		versions = append(versions, semversion)
 	}
 	semver.Sort(versions)
+	if !model.Limit.IsNull() && !model.Limit.IsUnknown() {
+		if limit := int(model.Limit.ValueInt64()); limit < len(versions) {
+			versions = versions[len(versions)-limit:]
+		}
+	}
+	if model.SortOrder.ValueString() == sortOrderDesc {
+		slices.Reverse(versions)
+	}
 
 	versionAttrs := make([]attr.Value, 0, len(versions))
 	for _, version := range versions {
 		versionAttrs = append(versionAttrs, types.StringValue(version.String()))
 	}
 
-	model.ID = types.StringValue(
-		fmt.Sprintf("%s/%s", model.SdkType.ValueString(), model.MinVersion.ValueString()),
-	)
+	model.ReleaseDates = types.MapNull(basetypes.StringType{})
+	if model.IncludeReleaseDates.ValueBool() {
+		var releaseDates map[string]string
+		if model.SdkType.ValueString() == sdkTypeFlutter {
+			releaseDates = make(map[string]string, len(versions))
+			for _, version := range versions {
+				if releaseDate := flutterReleaseDates[version.String()]; releaseDate != "" {
+					releaseDates[version.String()] = releaseDate
+				}
+			}
+		} else {
+			var err error
+			releaseDates, err = d.listDartReleaseDates(ctx, versions, dartChannels)
+			if err != nil {
+				resp.Diagnostics.AddError("Failed to list release dates", err.Error())
+				return
+			}
+		}
+		releaseDatesAttr, diags := types.MapValueFrom(ctx, basetypes.StringType{}, releaseDates)
+		resp.Diagnostics.Append(diags...)
+		model.ReleaseDates = releaseDatesAttr
+	}
+
+	id := fmt.Sprintf("%s/%s", model.SdkType.ValueString(), model.MinVersion.ValueString())
+	switch {
+	case !model.Constraint.IsNull():
+		id = fmt.Sprintf("%s/%s", model.SdkType.ValueString(), model.Constraint.ValueString())
+	case !model.MaxVersion.IsNull():
+		id += "/" + model.MaxVersion.ValueString()
+	}
+	query := url.Values{}
+	if !model.Limit.IsNull() {
+		query.Set("limit", strconv.FormatInt(model.Limit.ValueInt64(), 10))
+	}
+	if !model.SortOrder.IsNull() {
+		query.Set("sort_order", model.SortOrder.ValueString())
+	}
+	if len(query) > 0 {
+		id += "?" + query.Encode()
+	}
+	model.ID = types.StringValue(id)
 	versionsAttr, diags := types.ListValue(basetypes.StringType{}, versionAttrs)
 	resp.Diagnostics.Append(diags...)
 	model.Versions = versionsAttr
@@ -163,40 +393,188 @@ func (d *DartVersionsDataSource) Read(ctx context.Context, req datasource.ReadRe
 
 var versionRegex = regexp.MustCompile(`\d+\.\d+\.\d+`)
 
-func (d *DartVersionsDataSource) listVersions(channel string) ([]string, error) {
-	url, _ := url.Parse("https://www.googleapis.com/storage/v1/b/dart-archive/o")
+func (d *DartVersionsDataSource) httpClient() *http.Client {
+	if d.client != nil {
+		return d.client
+	}
+	return http.DefaultClient
+}
+
+// listVersions lists the versions of the Dart SDK released in the given
+// channel, following each page of the bucket listing.
+func (d *DartVersionsDataSource) listVersions(ctx context.Context, channel string) ([]string, error) {
+	var versions []string
+	pageToken := ""
+	for {
+		prefixes, nextPageToken, err := d.listVersionPrefixes(ctx, channel, pageToken)
+		if err != nil {
+			return nil, err
+		}
+	prefixes:
+		for _, prefix := range prefixes {
+			parts := strings.Split(prefix, "/")
+			for _, part := range parts {
+				if versionRegex.Match([]byte(part)) {
+					versions = append(versions, part)
+					continue prefixes
+				}
+			}
+		}
+		if nextPageToken == "" {
+			return versions, nil
+		}
+		pageToken = nextPageToken
+	}
+}
+
+// listVersionPrefixes lists a page of the release prefixes of the given
+// channel, returning the token of the next page if there is one.
+func (d *DartVersionsDataSource) listVersionPrefixes(ctx context.Context, channel, pageToken string) ([]string, string, error) {
+	url, _ := url.Parse(dartArchiveURL)
 	query := url.Query()
 	query.Set("prefix", fmt.Sprintf("channels/%s/release/", channel))
 	query.Set("delimiter", "/")
+	if pageToken != "" {
+		query.Set("pageToken", pageToken)
+	}
 	url.RawQuery = query.Encode()
 
-	resp, err := http.Get(url.String())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list %s versions: %w", channel, err)
+	}
+	resp, err := d.httpClient().Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list %s versions: %w", channel, err)
+		return nil, "", fmt.Errorf("failed to list %s versions: %w", channel, err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to list %s versions: %s", channel, resp.Status)
+		return nil, "", fmt.Errorf("failed to list %s versions: %s", channel, resp.Status)
 	}
 
 	var response struct {
-		Prefixes []string `json:"prefixes"`
+		Prefixes      []string `json:"prefixes"`
+		NextPageToken string   `json:"nextPageToken"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode %s response: %w", channel, err)
+		return nil, "", fmt.Errorf("failed to decode %s response: %w", channel, err)
 	}
+	return response.Prefixes, response.NextPageToken, nil
+}
 
-	versions := make([]string, 0, len(response.Prefixes))
-prefixes:
-	for _, prefix := range response.Prefixes {
-		parts := strings.Split(prefix, "/")
-		for _, part := range parts {
-			if versionRegex.Match([]byte(part)) {
-				versions = append(versions, part)
-				continue prefixes
+// listDartReleaseDates returns the release date of each of the given versions
+// of the Dart SDK, released in the channels given by `channels`.
+//
+// Versions whose `VERSION` file has no date, or a date in an unknown format,
+// are omitted.
+func (d *DartVersionsDataSource) listDartReleaseDates(ctx context.Context, versions []*semver.Version, channels map[string]string) (map[string]string, error) {
+	// As when listing versions, each goroutine writes only its own result.
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(dartReleaseDateConcurrency)
+	releaseDates := make([]string, len(versions))
+	for i, version := range versions {
+		eg.Go(func() error {
+			releaseDate, err := d.getDartReleaseDate(egCtx, channels[version.String()], version.String())
+			if err != nil {
+				return err
 			}
+			releaseDates[i] = releaseDate
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	dates := make(map[string]string, len(versions))
+	for i, version := range versions {
+		if releaseDates[i] == "" {
+			tflog.Warn(ctx, "Release date of Dart version not known", map[string]any{
+				"version": version.String(),
+			})
+			continue
+		}
+		dates[version.String()] = releaseDates[i]
+	}
+	return dates, nil
+}
+
+// getDartReleaseDate returns the release date of a version of the Dart SDK in
+// RFC 3339 format, or an empty string if its `VERSION` file has no date in a
+// known format.
+func (d *DartVersionsDataSource) getDartReleaseDate(ctx context.Context, channel, version string) (string, error) {
+	versionURL := fmt.Sprintf("%s/channels/%s/release/%s/VERSION", dartReleasesURL, channel, version)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, versionURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s release date: %w", version, err)
+	}
+	resp, err := d.httpClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s release date: %w", version, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get %s release date: %s", version, resp.Status)
+	}
+
+	var response struct {
+		Date string `json:"date"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("failed to decode %s VERSION: %w", version, err)
+	}
+	for _, layout := range dartReleaseDateLayouts {
+		if date, err := time.Parse(layout, response.Date); err == nil {
+			return date.UTC().Format(time.RFC3339), nil
 		}
 	}
+	return "", nil
+}
 
+// listFlutterVersions lists the versions of the Flutter SDK released in the
+// given channels, returning the release date of each in RFC 3339 format, or
+// an empty string if it is not known.
+//
+// Early releases have versions such as `v1.9.1+hotfix.6`, so a leading `v`
+// is removed, and versions which are not valid semantic versions are skipped.
+func (d *DartVersionsDataSource) listFlutterVersions(ctx context.Context, channels []string) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, flutterReleasesURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Flutter versions: %w", err)
+	}
+	resp, err := d.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Flutter versions: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list Flutter versions: %s", resp.Status)
+	}
+
+	var response struct {
+		Releases []struct {
+			Channel     string    `json:"channel"`
+			Version     string    `json:"version"`
+			ReleaseDate time.Time `json:"release_date"`
+		} `json:"releases"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode Flutter releases: %w", err)
+	}
+
+	versions := make(map[string]string, len(response.Releases))
+	for _, release := range response.Releases {
+		if !slices.Contains(channels, release.Channel) {
+			continue
+		}
+		version := strings.TrimPrefix(release.Version, "v")
+		if _, err := semver.NewVersion(version); err != nil {
+			continue
+		}
+		versions[version] = ""
+		if !release.ReleaseDate.IsZero() {
+			versions[version] = release.ReleaseDate.UTC().Format(time.RFC3339)
+		}
+	}
 	return versions, nil
 }
@@ -1,9 +1,21 @@
 package provider
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"path"
 	"regexp"
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
 	"github.com/hashicorp/terraform-plugin-testing/statecheck"
@@ -30,6 +42,170 @@ func TestAccDataSourceDartVersions(t *testing.T) {
 	})
 }
 
+func TestAccDataSourceDartVersionsMaxVersion(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCreateConfig(`
+				data "utils_dart_versions" "test" {
+					sdk_type = "dart"
+					min_version = "3.4.0"
+					max_version = "3.5.0"
+				}`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("data.utils_dart_versions.test", tfjsonpath.New("id"), knownvalue.StringExact("dart/3.4.0/3.5.0")),
+					statecheck.ExpectKnownValue("data.utils_dart_versions.test", tfjsonpath.New("versions"), listEquals{[]string{"3.4.0", "3.4.1", "3.4.2", "3.4.3", "3.4.4"}}),
+				},
+			},
+			{
+				Config: testAccCreateConfig(`
+				data "utils_dart_versions" "test" {
+					sdk_type = "dart"
+					min_version = "3.5.0"
+					max_version = "3.5.0"
+				}`),
+				ExpectError: regexp.MustCompile(`Invalid version range`),
+			},
+		},
+	})
+}
+
+func TestAccDataSourceDartVersionsConstraint(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCreateConfig(`
+				data "utils_dart_versions" "test" {
+					sdk_type = "dart"
+					version_constraint = ">=3.4.0 <3.6.0 !=3.5.2"
+				}`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("data.utils_dart_versions.test", tfjsonpath.New("id"), knownvalue.StringExact("dart/>=3.4.0 <3.6.0 !=3.5.2")),
+					statecheck.ExpectKnownValue("data.utils_dart_versions.test", tfjsonpath.New("versions"), listEquals{[]string{"3.4.0", "3.4.1", "3.4.2", "3.4.3", "3.4.4", "3.5.0", "3.5.1", "3.5.3"}}),
+				},
+			},
+			{
+				Config: testAccCreateConfig(`
+				data "utils_dart_versions" "test" {
+					sdk_type = "dart"
+					version_constraint = ">=3.4.0 <<3.6.0"
+				}`),
+				ExpectError: regexp.MustCompile(`Invalid version constraint`),
+			},
+			{
+				Config: testAccCreateConfig(`
+				data "utils_dart_versions" "test" {
+					sdk_type = "dart"
+					min_version = "3.4.0"
+					version_constraint = ">=3.4.0"
+				}`),
+				ExpectError: regexp.MustCompile(`Invalid Attribute Combination`),
+			},
+		},
+	})
+}
+
+func TestAccDataSourceDartVersionsLimit(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCreateConfig(`
+				data "utils_dart_versions" "test" {
+					sdk_type = "dart"
+					version_constraint = ">=3.4.0 <3.6.0"
+					limit = 2
+				}`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("data.utils_dart_versions.test", tfjsonpath.New("id"), knownvalue.StringExact("dart/>=3.4.0 <3.6.0?limit=2")),
+					statecheck.ExpectKnownValue("data.utils_dart_versions.test", tfjsonpath.New("versions"), listEquals{[]string{"3.5.2", "3.5.3"}}),
+				},
+			},
+			{
+				Config: testAccCreateConfig(`
+				data "utils_dart_versions" "test" {
+					sdk_type = "dart"
+					min_version = "3.5.0"
+					limit = 0
+				}`),
+				ExpectError: regexp.MustCompile(`must be at least 1`),
+			},
+		},
+	})
+}
+
+func TestAccDataSourceDartVersionsSortOrder(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCreateConfig(`
+				data "utils_dart_versions" "asc" {
+					sdk_type = "dart"
+					version_constraint = ">=3.5.0 <3.6.0"
+					sort_order = "asc"
+				}
+
+				data "utils_dart_versions" "desc" {
+					sdk_type = "dart"
+					version_constraint = ">=3.5.0 <3.6.0"
+					sort_order = "desc"
+				}`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("data.utils_dart_versions.asc", tfjsonpath.New("id"), knownvalue.StringExact("dart/>=3.5.0 <3.6.0?sort_order=asc")),
+					statecheck.ExpectKnownValue("data.utils_dart_versions.asc", tfjsonpath.New("versions"), listEquals{[]string{"3.5.0", "3.5.1", "3.5.2", "3.5.3"}}),
+					statecheck.ExpectKnownValue("data.utils_dart_versions.desc", tfjsonpath.New("id"), knownvalue.StringExact("dart/>=3.5.0 <3.6.0?sort_order=desc")),
+					statecheck.ExpectKnownValue("data.utils_dart_versions.desc", tfjsonpath.New("versions"), listEquals{[]string{"3.5.3", "3.5.2", "3.5.1", "3.5.0"}}),
+				},
+			},
+			{
+				Config: testAccCreateConfig(`
+				data "utils_dart_versions" "test" {
+					sdk_type = "dart"
+					min_version = "3.5.0"
+					sort_order = "newest"
+				}`),
+				ExpectError: regexp.MustCompile(`must be one of`),
+			},
+		},
+	})
+}
+
+func TestAccDataSourceDartVersionsReleaseDates(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCreateConfig(`
+				data "utils_dart_versions" "dart" {
+					sdk_type = "dart"
+					version_constraint = ">=3.5.0 <3.6.0"
+					include_release_dates = true
+				}
+
+				data "utils_dart_versions" "flutter" {
+					sdk_type = "flutter"
+					min_version = "3.24.0"
+					limit = 1
+					include_release_dates = true
+				}
+
+				data "utils_dart_versions" "excluded" {
+					sdk_type = "dart"
+					version_constraint = ">=3.5.0 <3.6.0"
+				}`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("data.utils_dart_versions.dart", tfjsonpath.New("release_dates"), knownvalue.MapSizeExact(4)),
+					statecheck.ExpectKnownValue("data.utils_dart_versions.flutter", tfjsonpath.New("release_dates"), knownvalue.MapSizeExact(1)),
+					statecheck.ExpectKnownValue("data.utils_dart_versions.excluded", tfjsonpath.New("release_dates"), knownvalue.Null()),
+				},
+			},
+		},
+	})
+}
+
 func TestAccDataSourceDartVersionsBeta(t *testing.T) {
 	resource.Test(t, resource.TestCase{
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
@@ -66,3 +242,486 @@ func TestAccDataSourceDartVersionsBad(t *testing.T) {
 		},
 	})
 }
+
+func TestAccDataSourceDartVersionsBadMinVersion(t *testing.T) {
+	for _, minVersion := range []string{"3.5", "v3.5.0", ""} {
+		t.Run(minVersion, func(t *testing.T) {
+			resource.Test(t, resource.TestCase{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: testAccCreateConfig(fmt.Sprintf(`
+						data "utils_dart_versions" "test" {
+							sdk_type = "dart"
+							min_version = %q
+						}`, minVersion)),
+						ExpectError: regexp.MustCompile(`Invalid version`),
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestAccDataSourceDartVersionsFlutter(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCreateConfig(`
+				data "utils_dart_versions" "test" {
+					sdk_type = "flutter"
+					min_version = "3.24.0"
+				}`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("data.utils_dart_versions.test", tfjsonpath.New("id"), knownvalue.StringExact("flutter/3.24.0")),
+					statecheck.ExpectKnownValue("data.utils_dart_versions.test", tfjsonpath.New("versions"), listNotEmpty{}),
+					statecheck.ExpectKnownValue("data.utils_dart_versions.test", tfjsonpath.New("versions"), listOfGreaterThan{5}),
+				},
+			},
+		},
+	})
+}
+
+func TestAccDataSourceDartVersionsFlutterBeta(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCreateConfig(`
+				data "utils_dart_versions" "test" {
+					sdk_type = "flutter"
+					min_version = "3.24.0"
+					channels = ["beta"]
+				}`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("data.utils_dart_versions.test", tfjsonpath.New("id"), knownvalue.StringExact("flutter/3.24.0")),
+					statecheck.ExpectKnownValue("data.utils_dart_versions.test", tfjsonpath.New("versions"), listNotEmpty{}),
+				},
+			},
+		},
+	})
+}
+
+func TestAccDataSourceDartVersionsBadSdkType(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCreateConfig(`
+				data "utils_dart_versions" "test" {
+					sdk_type = "swift"
+					min_version = "3.5.0"
+				}`),
+				ExpectError: regexp.MustCompile(`must be one of`),
+			},
+		},
+	})
+}
+
+// fakeDartArchiveTransport serves listings of the Dart archive, failing
+// requests for the channels in `failures`, and `VERSION` files with the dates
+// in `dates`.
+type fakeDartArchiveTransport struct {
+	failures []string
+	dates    map[string]string
+}
+
+func (t fakeDartArchiveTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.HasSuffix(req.URL.Path, "/VERSION") {
+		version := path.Base(path.Dir(req.URL.Path))
+		date, ok := t.dates[version]
+		if !ok {
+			return &http.Response{
+				StatusCode: http.StatusNotFound,
+				Status:     "404 Not Found",
+				Body:       io.NopCloser(strings.NewReader("")),
+				Request:    req,
+			}, nil
+		}
+		body := fmt.Sprintf(`{"date": %q, "version": %q}`, date, version)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	}
+
+	prefix := req.URL.Query().Get("prefix")
+	for _, channel := range t.failures {
+		if strings.HasPrefix(prefix, "channels/"+channel+"/") {
+			return nil, errors.New("fake failure")
+		}
+	}
+	body := `{"prefixes": ["` + prefix + `3.5.0/", "` + prefix + `3.5.1/", "` + prefix + `3.6.0/", "` + prefix + `latest/"]}`
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// testDartVersionsConfig returns the config of a utils_dart_versions data
+// source listing stable Dart versions, with `attrs` overriding its attributes.
+func testDartVersionsConfig(t *testing.T, attrs map[string]tftypes.Value) tfsdk.Config {
+	t.Helper()
+	ctx := context.Background()
+
+	schemaResp := &datasource.SchemaResponse{}
+	(&DartVersionsDataSource{}).Schema(ctx, datasource.SchemaRequest{}, schemaResp)
+
+	values := map[string]tftypes.Value{
+		"id":                    tftypes.NewValue(tftypes.String, nil),
+		"sdk_type":              tftypes.NewValue(tftypes.String, "dart"),
+		"min_version":           tftypes.NewValue(tftypes.String, "3.5.0"),
+		"max_version":           tftypes.NewValue(tftypes.String, nil),
+		"version_constraint":    tftypes.NewValue(tftypes.String, nil),
+		"include_release_dates": tftypes.NewValue(tftypes.Bool, nil),
+		"release_dates":         tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
+		"limit":                 tftypes.NewValue(tftypes.Number, nil),
+		"sort_order":            tftypes.NewValue(tftypes.String, nil),
+		"channels":              testDartVersionsChannels("stable"),
+		"versions":              tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+	}
+	for name, value := range attrs {
+		values[name] = value
+	}
+	return tfsdk.Config{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), values),
+	}
+}
+
+func testDartVersionsChannels(channels ...string) tftypes.Value {
+	values := make([]tftypes.Value, 0, len(channels))
+	for _, channel := range channels {
+		values = append(values, tftypes.NewValue(tftypes.String, channel))
+	}
+	return tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, values)
+}
+
+func testReadDartVersions(t *testing.T, transport http.RoundTripper, attrs map[string]tftypes.Value) *datasource.ReadResponse {
+	t.Helper()
+	ctx := context.Background()
+	d := &DartVersionsDataSource{client: &http.Client{Transport: transport}}
+
+	config := testDartVersionsConfig(t, attrs)
+	resp := &datasource.ReadResponse{
+		State: tfsdk.State{
+			Schema: config.Schema,
+			Raw:    tftypes.NewValue(config.Schema.Type().TerraformType(ctx), nil),
+		},
+	}
+	d.Read(ctx, datasource.ReadRequest{Config: config}, resp)
+	return resp
+}
+
+// testDartVersionsState returns the ID and versions listed by a successful
+// read.
+func testDartVersionsState(t *testing.T, resp *datasource.ReadResponse) (string, []string) {
+	t.Helper()
+	model := testDartVersionsModel(t, resp)
+	var versions []string
+	if diags := model.Versions.ElementsAs(context.Background(), &versions, false); diags.HasError() {
+		t.Fatalf("failed to get versions: %v", diags)
+	}
+	return model.ID.ValueString(), versions
+}
+
+func testDartVersionsModel(t *testing.T, resp *datasource.ReadResponse) DartVersionsDataSourceModel {
+	t.Helper()
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected errors: %v", resp.Diagnostics)
+	}
+
+	var model DartVersionsDataSourceModel
+	if diags := resp.State.Get(context.Background(), &model); diags.HasError() {
+		t.Fatalf("failed to get state: %v", diags)
+	}
+	return model
+}
+
+func TestDataSourceDartVersionsRead(t *testing.T) {
+	tests := []struct {
+		name         string
+		attrs        map[string]tftypes.Value
+		wantID       string
+		wantVersions string
+	}{
+		{
+			name:         "channels",
+			attrs:        map[string]tftypes.Value{"channels": testDartVersionsChannels("stable", "beta", "dev")},
+			wantID:       "dart/3.5.0",
+			wantVersions: "3.5.0,3.5.1,3.6.0",
+		},
+		{
+			name:         "max_version",
+			attrs:        map[string]tftypes.Value{"max_version": tftypes.NewValue(tftypes.String, "3.6.0")},
+			wantID:       "dart/3.5.0/3.6.0",
+			wantVersions: "3.5.0,3.5.1",
+		},
+		{
+			name: "version_constraint",
+			attrs: map[string]tftypes.Value{
+				"min_version":        tftypes.NewValue(tftypes.String, nil),
+				"version_constraint": tftypes.NewValue(tftypes.String, ">=3.5.0 <3.7.0 !=3.5.1"),
+			},
+			wantID:       "dart/>=3.5.0 <3.7.0 !=3.5.1",
+			wantVersions: "3.5.0,3.6.0",
+		},
+		{
+			name: "version_constraint alternatives",
+			attrs: map[string]tftypes.Value{
+				"min_version":        tftypes.NewValue(tftypes.String, nil),
+				"version_constraint": tftypes.NewValue(tftypes.String, "~3.5.1 || ^3.6"),
+			},
+			wantID:       "dart/~3.5.1 || ^3.6",
+			wantVersions: "3.5.1,3.6.0",
+		},
+		{
+			name: "unknown version_constraint",
+			attrs: map[string]tftypes.Value{
+				"min_version":        tftypes.NewValue(tftypes.String, nil),
+				"version_constraint": tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+			},
+			wantID:       "dart/",
+			wantVersions: "3.5.0,3.5.1,3.6.0",
+		},
+		{
+			name: "limit",
+			attrs: map[string]tftypes.Value{
+				"channels": testDartVersionsChannels("stable", "beta", "dev"),
+				"limit":    tftypes.NewValue(tftypes.Number, 2),
+			},
+			wantID:       "dart/3.5.0?limit=2",
+			wantVersions: "3.5.1,3.6.0",
+		},
+		{
+			name: "limit above count",
+			attrs: map[string]tftypes.Value{
+				"limit": tftypes.NewValue(tftypes.Number, 10),
+			},
+			wantID:       "dart/3.5.0?limit=10",
+			wantVersions: "3.5.0,3.5.1,3.6.0",
+		},
+		{
+			name: "limit with max_version",
+			attrs: map[string]tftypes.Value{
+				"max_version": tftypes.NewValue(tftypes.String, "3.6.0"),
+				"limit":       tftypes.NewValue(tftypes.Number, 1),
+			},
+			wantID:       "dart/3.5.0/3.6.0?limit=1",
+			wantVersions: "3.5.1",
+		},
+		{
+			name: "sort_order asc",
+			attrs: map[string]tftypes.Value{
+				"sort_order": tftypes.NewValue(tftypes.String, "asc"),
+			},
+			wantID:       "dart/3.5.0?sort_order=asc",
+			wantVersions: "3.5.0,3.5.1,3.6.0",
+		},
+		{
+			name: "sort_order desc",
+			attrs: map[string]tftypes.Value{
+				"sort_order": tftypes.NewValue(tftypes.String, "desc"),
+			},
+			wantID:       "dart/3.5.0?sort_order=desc",
+			wantVersions: "3.6.0,3.5.1,3.5.0",
+		},
+		{
+			name: "sort_order desc with limit",
+			attrs: map[string]tftypes.Value{
+				"sort_order": tftypes.NewValue(tftypes.String, "desc"),
+				"limit":      tftypes.NewValue(tftypes.Number, 2),
+			},
+			wantID:       "dart/3.5.0?limit=2&sort_order=desc",
+			wantVersions: "3.6.0,3.5.1",
+		},
+		{
+			name:         "unknown min_version",
+			attrs:        map[string]tftypes.Value{"min_version": tftypes.NewValue(tftypes.String, tftypes.UnknownValue)},
+			wantID:       "dart/",
+			wantVersions: "3.5.0,3.5.1,3.6.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, versions := testDartVersionsState(t, testReadDartVersions(t, fakeDartArchiveTransport{}, tt.attrs))
+			if id != tt.wantID {
+				t.Errorf("got id %s, want %s", id, tt.wantID)
+			}
+			if got := strings.Join(versions, ","); got != tt.wantVersions {
+				t.Errorf("got versions %s, want %s", got, tt.wantVersions)
+			}
+		})
+	}
+
+	// Run with -race to check that failures are reported without racing on
+	// the response.
+	t.Run("channel failure", func(t *testing.T) {
+		resp := testReadDartVersions(t, fakeDartArchiveTransport{failures: []string{"beta"}}, map[string]tftypes.Value{
+			"channels": testDartVersionsChannels("stable", "beta", "dev"),
+		})
+		if !resp.Diagnostics.HasError() {
+			t.Fatal("expected an error")
+		}
+		if got := resp.Diagnostics.Errors()[0].Summary(); got != "Failed to list versions" {
+			t.Errorf("got error %q, want %q", got, "Failed to list versions")
+		}
+		if !resp.State.Raw.IsNull() {
+			t.Errorf("got state %v, want null", resp.State.Raw)
+		}
+	})
+
+	t.Run("invalid min_version", func(t *testing.T) {
+		resp := testReadDartVersions(t, fakeDartArchiveTransport{}, map[string]tftypes.Value{
+			"min_version": tftypes.NewValue(tftypes.String, "3.5"),
+		})
+		if !resp.Diagnostics.HasError() {
+			t.Fatal("expected an error")
+		}
+		if got := resp.Diagnostics.Errors()[0].Summary(); got != "Invalid version" {
+			t.Errorf("got error %q, want %q", got, "Invalid version")
+		}
+	})
+}
+
+func TestDataSourceDartVersionsValidateConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxVersion tftypes.Value
+		wantError  bool
+	}{
+		{name: "no max_version", maxVersion: tftypes.NewValue(tftypes.String, nil)},
+		{name: "unknown max_version", maxVersion: tftypes.NewValue(tftypes.String, tftypes.UnknownValue)},
+		{name: "greater", maxVersion: tftypes.NewValue(tftypes.String, "3.6.0")},
+		{name: "invalid", maxVersion: tftypes.NewValue(tftypes.String, "3.6")},
+		{name: "equal", maxVersion: tftypes.NewValue(tftypes.String, "3.5.0"), wantError: true},
+		{name: "less", maxVersion: tftypes.NewValue(tftypes.String, "3.4.4"), wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := datasource.ValidateConfigRequest{
+				Config: testDartVersionsConfig(t, map[string]tftypes.Value{"max_version": tt.maxVersion}),
+			}
+			var resp datasource.ValidateConfigResponse
+			(&DartVersionsDataSource{}).ValidateConfig(context.Background(), req, &resp)
+
+			if got := resp.Diagnostics.HasError(); got != tt.wantError {
+				t.Errorf("got error %v, want %v: %v", got, tt.wantError, resp.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestDataSourceDartVersionsListVersionsPages(t *testing.T) {
+	var pageTokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Query().Get("prefix"), "channels/stable/release/"; got != want {
+			t.Errorf("got prefix %q, want %q", got, want)
+		}
+		pageToken := r.URL.Query().Get("pageToken")
+		pageTokens = append(pageTokens, pageToken)
+		switch pageToken {
+		case "":
+			fmt.Fprint(w, `{"prefixes": ["channels/stable/release/3.4.0/", "channels/stable/release/3.5.0/"], "nextPageToken": "page-2"}`)
+		case "page-2":
+			fmt.Fprint(w, `{"prefixes": ["channels/stable/release/3.6.0/", "channels/stable/release/latest/"]}`)
+		default:
+			http.Error(w, "unexpected page token", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	defer func(url string) { dartArchiveURL = url }(dartArchiveURL)
+	dartArchiveURL = server.URL
+
+	d := &DartVersionsDataSource{client: server.Client()}
+	versions, err := d.listVersions(context.Background(), "stable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(versions, ","), "3.4.0,3.5.0,3.6.0"; got != want {
+		t.Errorf("got versions %s, want %s", got, want)
+	}
+	if got, want := strings.Join(pageTokens, ","), ",page-2"; got != want {
+		t.Errorf("got page tokens %q, want %q", got, want)
+	}
+}
+
+func TestDataSourceDartVersionsReleaseDates(t *testing.T) {
+	transport := fakeDartArchiveTransport{
+		dates: map[string]string{
+			"3.5.0": "2024-08-06",
+			"3.5.1": "202408201200",
+			"3.6.0": "not a date",
+		},
+	}
+
+	t.Run("excluded", func(t *testing.T) {
+		model := testDartVersionsModel(t, testReadDartVersions(t, transport, nil))
+		if !model.ReleaseDates.IsNull() {
+			t.Errorf("got release dates %v, want null", model.ReleaseDates)
+		}
+	})
+
+	t.Run("included", func(t *testing.T) {
+		model := testDartVersionsModel(t, testReadDartVersions(t, transport, map[string]tftypes.Value{
+			"include_release_dates": tftypes.NewValue(tftypes.Bool, true),
+		}))
+		var releaseDates map[string]string
+		if diags := model.ReleaseDates.ElementsAs(context.Background(), &releaseDates, false); diags.HasError() {
+			t.Fatalf("failed to get release dates: %v", diags)
+		}
+		want := map[string]string{
+			"3.5.0": "2024-08-06T00:00:00Z",
+			"3.5.1": "2024-08-20T12:00:00Z",
+		}
+		if !maps.Equal(releaseDates, want) {
+			t.Errorf("got release dates %v, want %v", releaseDates, want)
+		}
+	})
+
+	t.Run("missing VERSION", func(t *testing.T) {
+		resp := testReadDartVersions(t, fakeDartArchiveTransport{}, map[string]tftypes.Value{
+			"include_release_dates": tftypes.NewValue(tftypes.Bool, true),
+		})
+		if !resp.Diagnostics.HasError() {
+			t.Fatal("expected an error")
+		}
+		if got := resp.Diagnostics.Errors()[0].Summary(); got != "Failed to list release dates" {
+			t.Errorf("got error %q, want %q", got, "Failed to list release dates")
+		}
+	})
+}
+
+func TestDataSourceDartVersionsListFlutterVersions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"releases": [
+			{"channel": "stable", "version": "3.24.0", "release_date": "2024-08-06T18:41:29.948758Z"},
+			{"channel": "beta", "version": "3.25.0-0.1.pre", "release_date": "2024-08-07T10:00:00Z"},
+			{"channel": "stable", "version": "v1.9.1+hotfix.6", "release_date": "2019-10-23T22:33:51.318371Z"},
+			{"channel": "stable", "version": "1.0.0"},
+			{"channel": "stable", "version": "not-a-version"}
+		]}`)
+	}))
+	defer server.Close()
+	defer func(url string) { flutterReleasesURL = url }(flutterReleasesURL)
+	flutterReleasesURL = server.URL
+
+	d := &DartVersionsDataSource{client: server.Client()}
+	releaseDates, err := d.listFlutterVersions(context.Background(), []string{"stable"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"3.24.0":         "2024-08-06T18:41:29Z",
+		"1.9.1+hotfix.6": "2019-10-23T22:33:51Z",
+		"1.0.0":          "",
+	}
+	if !maps.Equal(releaseDates, want) {
+		t.Errorf("got release dates %v, want %v", releaseDates, want)
+	}
+}
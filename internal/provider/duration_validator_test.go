@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestDurationValidator(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     types.String
+		wantError bool
+	}{
+		{name: "minutes", value: types.StringValue("10m")},
+		{name: "compound", value: types.StringValue("1h30m")},
+		{name: "zero", value: types.StringValue("0s")},
+		{name: "unknown", value: types.StringUnknown()},
+		{name: "null", value: types.StringNull()},
+		{name: "no unit", value: types.StringValue("10"), wantError: true},
+		{name: "invalid", value: types.StringValue("ten minutes"), wantError: true},
+		{name: "negative", value: types.StringValue("-5m"), wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := validator.StringRequest{
+				Path:        path.Root("wait"),
+				ConfigValue: tt.value,
+			}
+			var resp validator.StringResponse
+			durationValidator{}.ValidateString(context.Background(), req, &resp)
+
+			if got := resp.Diagnostics.HasError(); got != tt.wantError {
+				t.Errorf("got error %v, want %v: %v", got, tt.wantError, resp.Diagnostics)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/cloudbilling/v1"
+	"google.golang.org/api/option"
+)
+
+// fakeCloudBilling is an in-memory implementation of the parts of the
+// Cloud Billing REST API used by the provider.
+type fakeCloudBilling struct {
+	mu sync.Mutex
+	// accounts maps the names of the billing accounts to whether they are
+	// open.
+	accounts map[string]bool
+	// forbidden makes every call fail with PermissionDenied.
+	forbidden bool
+	// getCalls counts the calls to GetBillingAccount.
+	getCalls int
+}
+
+func newFakeCloudBilling() *fakeCloudBilling {
+	return &fakeCloudBilling{
+		accounts: make(map[string]bool),
+	}
+}
+
+// newFakeCloudBillingClient serves f over HTTP and returns a client for it.
+func newFakeCloudBillingClient(t *testing.T, f *fakeCloudBilling) *cloudbilling.APIService {
+	t.Helper()
+
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	client, err := cloudbilling.NewService(
+		context.Background(),
+		option.WithEndpoint(server.URL),
+		option.WithHTTPClient(server.Client()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func (f *fakeCloudBilling) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name, ok := strings.CutPrefix(req.URL.Path, "/v1/")
+	if req.Method != http.MethodGet || !ok || !strings.HasPrefix(name, "billingAccounts/") {
+		writeFakeTenantError(w, http.StatusNotImplemented, fmt.Sprintf("%s %s is not implemented", req.Method, req.URL.Path))
+		return
+	}
+
+	f.getCalls++
+	if f.forbidden {
+		writeFakeTenantError(w, http.StatusForbidden, fmt.Sprintf("The caller does not have permission billing.accounts.get on %s", name))
+		return
+	}
+	open, ok := f.accounts[name]
+	if !ok {
+		writeFakeTenantError(w, http.StatusNotFound, fmt.Sprintf("Billing account %s not found", name))
+		return
+	}
+	writeFakeTenantResponse(w, &cloudbilling.BillingAccount{
+		Name: name,
+		Open: open,
+	})
+}
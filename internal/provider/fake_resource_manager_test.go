@@ -0,0 +1,148 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	"google.golang.org/api/option"
+)
+
+// fakeResourceManager is an in-memory implementation of the parts of the
+// Cloud Resource Manager REST API used by the provider.
+type fakeResourceManager struct {
+	mu sync.Mutex
+	// projectNumbers maps project IDs to project numbers.
+	projectNumbers map[string]string
+	// tenantProjects makes every other `projects/{project_number}` exist,
+	// with the project ID `tenant-{project_number}`, like the tenant projects
+	// created by fakeTenantServer.
+	tenantProjects bool
+	// projects are returned as is, keyed by name.
+	projects map[string]*cloudresourcemanager.Project
+	// missingProjects are the project IDs for which GetProject fails with
+	// NotFound. Other unknown projects fail with PermissionDenied.
+	missingProjects map[string]bool
+	// getCalls counts the calls to GetProject.
+	getCalls int
+	// folders are returned as is, keyed by name.
+	folders map[string]*cloudresourcemanager.Folder
+	// iamPolicies are the IAM policies of projects and folders, keyed by
+	// name. Others have an empty policy.
+	iamPolicies map[string]*cloudresourcemanager.Policy
+	// denyGetIamPolicy makes GetIamPolicy fail with PermissionDenied.
+	denyGetIamPolicy bool
+}
+
+func newFakeResourceManager() *fakeResourceManager {
+	return &fakeResourceManager{
+		projectNumbers:  make(map[string]string),
+		projects:        make(map[string]*cloudresourcemanager.Project),
+		missingProjects: make(map[string]bool),
+		folders:         make(map[string]*cloudresourcemanager.Folder),
+		iamPolicies:     make(map[string]*cloudresourcemanager.Policy),
+	}
+}
+
+// newFakeResourceManagerClient serves f over HTTP and returns a client for it.
+func newFakeResourceManagerClient(t *testing.T, f *fakeResourceManager) *cloudresourcemanager.Service {
+	t.Helper()
+
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	client, err := cloudresourcemanager.NewService(
+		context.Background(),
+		option.WithEndpoint(server.URL),
+		option.WithHTTPClient(server.Client()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func (f *fakeResourceManager) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if folderId, ok := strings.CutPrefix(req.URL.Path, "/v3/folders/"); ok {
+		f.serveFolder(w, req, "folders/"+folderId)
+		return
+	}
+
+	projectId, ok := strings.CutPrefix(req.URL.Path, "/v3/projects/")
+	if projectId, ok := strings.CutSuffix(projectId, ":getIamPolicy"); ok && req.Method == http.MethodPost {
+		f.getIamPolicy(w, "projects/"+projectId)
+		return
+	}
+	if req.Method != http.MethodGet || !ok {
+		writeFakeTenantError(w, http.StatusNotImplemented, fmt.Sprintf("%s %s is not implemented", req.Method, req.URL.Path))
+		return
+	}
+
+	f.getCalls++
+	if project, ok := f.projects["projects/"+projectId]; ok {
+		writeFakeTenantResponse(w, project)
+		return
+	}
+	for id, number := range f.projectNumbers {
+		if projectId == id || projectId == number {
+			writeFakeTenantResponse(w, &cloudresourcemanager.Project{
+				Name:      "projects/" + number,
+				ProjectId: id,
+				State:     "ACTIVE",
+			})
+			return
+		}
+	}
+	if _, err := strconv.ParseUint(projectId, 10, 64); err == nil && f.tenantProjects {
+		writeFakeTenantResponse(w, &cloudresourcemanager.Project{
+			Name:      "projects/" + projectId,
+			ProjectId: "tenant-" + projectId,
+			State:     "ACTIVE",
+		})
+		return
+	}
+	if f.missingProjects[projectId] {
+		writeFakeTenantError(w, http.StatusNotFound, fmt.Sprintf("Project %s not found", projectId))
+		return
+	}
+	writeFakeTenantError(w, http.StatusForbidden, fmt.Sprintf("Project %s not found or permission denied", projectId))
+}
+
+func (f *fakeResourceManager) getIamPolicy(w http.ResponseWriter, name string) {
+	if f.denyGetIamPolicy {
+		writeFakeTenantError(w, http.StatusForbidden, fmt.Sprintf("The caller does not have permission resourcemanager.projects.getIamPolicy on %s", name))
+		return
+	}
+	policy, ok := f.iamPolicies[name]
+	if !ok {
+		policy = &cloudresourcemanager.Policy{}
+	}
+	writeFakeTenantResponse(w, policy)
+}
+
+func (f *fakeResourceManager) serveFolder(w http.ResponseWriter, req *http.Request, name string) {
+	if name, ok := strings.CutSuffix(name, ":getIamPolicy"); ok && req.Method == http.MethodPost {
+		f.getIamPolicy(w, name)
+		return
+	}
+	if req.Method != http.MethodGet {
+		writeFakeTenantError(w, http.StatusNotImplemented, fmt.Sprintf("%s %s is not implemented", req.Method, req.URL.Path))
+		return
+	}
+	folder, ok := f.folders[name]
+	if !ok {
+		writeFakeTenantError(w, http.StatusForbidden, fmt.Sprintf("Folder %s not found or permission denied", name))
+		return
+	}
+	writeFakeTenantResponse(w, folder)
+}
@@ -0,0 +1,505 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/longrunning/autogen/longrunningpb"
+	servicemanagement "cloud.google.com/go/servicemanagement/apiv1"
+	"cloud.google.com/go/servicemanagement/apiv1/servicemanagementpb"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeServiceManager is an in-memory implementation of the Service Management
+// API for unit tests.
+type fakeServiceManager struct {
+	servicemanagementpb.UnimplementedServiceManagerServer
+
+	mu       sync.Mutex
+	services map[string]*servicemanagementpb.ManagedService
+	// configs are keyed by `{serviceName}/{configId}`.
+	configs map[string]*serviceconfig.Service
+	// submitted holds the requests accepted by SubmitConfigSource.
+	submitted []*servicemanagementpb.SubmitConfigSourceRequest
+	// submitCalls counts the calls to SubmitConfigSource.
+	submitCalls int
+	// submitFailures are returned, in order, by the first calls to
+	// SubmitConfigSource.
+	submitFailures []codes.Code
+	// operationFailures are reported, in order, by the operations of the
+	// next calls to SubmitConfigSource.
+	operationFailures []codes.Code
+	// reportDiagnostics are returned by GenerateConfigReport.
+	reportDiagnostics []*servicemanagementpb.Diagnostic
+	// rollouts are keyed by `{serviceName}/{rolloutId}`.
+	rollouts map[string]*servicemanagementpb.Rollout
+	// listRolloutsFailures are returned, in order, by the first calls to
+	// ListServiceRollouts.
+	listRolloutsFailures []codes.Code
+	// listRolloutsCalls counts the calls to ListServiceRollouts.
+	listRolloutsCalls int
+	// rolloutConflicts is the number of calls to CreateServiceRollout which
+	// fail because another rollout is in progress.
+	rolloutConflicts int
+	// createdRollouts holds the requests received by CreateServiceRollout.
+	createdRollouts []*servicemanagementpb.CreateServiceRolloutRequest
+	// rolloutStatuses are reported, in order, by the next rollouts created.
+	// Later rollouts succeed.
+	rolloutStatuses []servicemanagementpb.Rollout_RolloutStatus
+	// rolloutStatusUpdates are applied, in order, to the rollouts returned by
+	// the next calls to GetServiceRollout.
+	rolloutStatusUpdates []servicemanagementpb.Rollout_RolloutStatus
+	// rolloutsPending makes the operations of new rollouts incomplete, with
+	// the rollouts IN_PROGRESS.
+	rolloutsPending bool
+}
+
+// fakeOperationStartTime is the start time reported for all operations.
+var fakeOperationStartTime = time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+
+func newFakeServiceManager() *fakeServiceManager {
+	return &fakeServiceManager{
+		services: make(map[string]*servicemanagementpb.ManagedService),
+		configs:  make(map[string]*serviceconfig.Service),
+		rollouts: make(map[string]*servicemanagementpb.Rollout),
+	}
+}
+
+func (f *fakeServiceManager) GetService(ctx context.Context, req *servicemanagementpb.GetServiceRequest) (*servicemanagementpb.ManagedService, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	service, ok := f.services[req.ServiceName]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "service %s not found", req.ServiceName)
+	}
+	return service, nil
+}
+
+func (f *fakeServiceManager) CreateService(ctx context.Context, req *servicemanagementpb.CreateServiceRequest) (*longrunningpb.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	service := req.GetService()
+	if _, ok := f.services[service.GetServiceName()]; ok {
+		return nil, status.Errorf(codes.AlreadyExists, "service %s already exists", service.GetServiceName())
+	}
+	f.services[service.GetServiceName()] = service
+
+	response, err := anypb.New(service)
+	if err != nil {
+		return nil, err
+	}
+	return &longrunningpb.Operation{
+		Name:   "operations/create-" + service.GetServiceName(),
+		Done:   true,
+		Result: &longrunningpb.Operation_Response{Response: response},
+	}, nil
+}
+
+func (f *fakeServiceManager) GetServiceConfig(ctx context.Context, req *servicemanagementpb.GetServiceConfigRequest) (*serviceconfig.Service, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Like the real API, report missing services as PermissionDenied.
+	if _, ok := f.services[req.ServiceName]; !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "The service %s was not found or permission denied.", req.ServiceName)
+	}
+	config, ok := f.configs[req.ServiceName+"/"+req.ConfigId]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "config %s not found", req.ConfigId)
+	}
+	return config, nil
+}
+
+func (f *fakeServiceManager) SubmitConfigSource(ctx context.Context, req *servicemanagementpb.SubmitConfigSourceRequest) (*longrunningpb.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.services[req.ServiceName]; !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "The service %s was not found or permission denied.", req.ServiceName)
+	}
+	f.submitCalls++
+	if len(f.submitFailures) > 0 {
+		code := f.submitFailures[0]
+		f.submitFailures = f.submitFailures[1:]
+		return nil, status.Error(code, "fake failure")
+	}
+	if len(f.operationFailures) > 0 {
+		code := f.operationFailures[0]
+		f.operationFailures = f.operationFailures[1:]
+		return &longrunningpb.Operation{
+			Name:   fmt.Sprintf("operations/failed%d", f.submitCalls),
+			Done:   true,
+			Result: &longrunningpb.Operation_Error{Error: status.New(code, "fake operation failure").Proto()},
+		}, nil
+	}
+	f.submitted = append(f.submitted, req)
+
+	sourceFiles := make([]*anypb.Any, 0, len(req.GetConfigSource().GetFiles()))
+	for _, file := range req.GetConfigSource().GetFiles() {
+		sourceFile, err := anypb.New(file)
+		if err != nil {
+			return nil, err
+		}
+		sourceFiles = append(sourceFiles, sourceFile)
+	}
+	config := &serviceconfig.Service{
+		Name:       req.ServiceName,
+		Id:         fmt.Sprintf("config%d", len(f.submitted)),
+		SourceInfo: &serviceconfig.SourceInfo{SourceFiles: sourceFiles},
+	}
+	if !req.ValidateOnly {
+		f.configs[req.ServiceName+"/"+config.Id] = config
+	}
+
+	response, err := anypb.New(&servicemanagementpb.SubmitConfigSourceResponse{ServiceConfig: config})
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := anypb.New(&servicemanagementpb.OperationMetadata{
+		StartTime: timestamppb.New(fakeOperationStartTime),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &longrunningpb.Operation{
+		Name:     "operations/" + config.Id,
+		Metadata: metadata,
+		Done:     true,
+		Result:   &longrunningpb.Operation_Response{Response: response},
+	}, nil
+}
+
+func (f *fakeServiceManager) GenerateConfigReport(ctx context.Context, req *servicemanagementpb.GenerateConfigReportRequest) (*servicemanagementpb.GenerateConfigReportResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return &servicemanagementpb.GenerateConfigReportResponse{Diagnostics: f.reportDiagnostics}, nil
+}
+
+// ListServiceRollouts returns the rollouts of the service, latest first. Only
+// the "status=SUCCESS" filter is supported.
+func (f *fakeServiceManager) ListServiceRollouts(ctx context.Context, req *servicemanagementpb.ListServiceRolloutsRequest) (*servicemanagementpb.ListServiceRolloutsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.listRolloutsCalls++
+	if len(f.listRolloutsFailures) > 0 {
+		code := f.listRolloutsFailures[0]
+		f.listRolloutsFailures = f.listRolloutsFailures[1:]
+		return nil, status.Errorf(code, "fake failure")
+	}
+
+	var rollouts []*servicemanagementpb.Rollout
+	for _, rollout := range f.rollouts {
+		if rollout.ServiceName != req.ServiceName {
+			continue
+		}
+		if req.Filter == "status=SUCCESS" && rollout.Status != servicemanagementpb.Rollout_SUCCESS {
+			continue
+		}
+		rollouts = append(rollouts, rollout)
+	}
+	slices.SortFunc(rollouts, func(a, b *servicemanagementpb.Rollout) int {
+		return strings.Compare(b.RolloutId, a.RolloutId)
+	})
+	return &servicemanagementpb.ListServiceRolloutsResponse{Rollouts: rollouts}, nil
+}
+
+func (f *fakeServiceManager) GetServiceRollout(ctx context.Context, req *servicemanagementpb.GetServiceRolloutRequest) (*servicemanagementpb.Rollout, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	rollout, ok := f.rollouts[req.ServiceName+"/"+req.RolloutId]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "rollout %s not found", req.RolloutId)
+	}
+	if len(f.rolloutStatusUpdates) > 0 {
+		rollout.Status, f.rolloutStatusUpdates = f.rolloutStatusUpdates[0], f.rolloutStatusUpdates[1:]
+	}
+	return rollout, nil
+}
+
+func (f *fakeServiceManager) CreateServiceRollout(ctx context.Context, req *servicemanagementpb.CreateServiceRolloutRequest) (*longrunningpb.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.services[req.ServiceName]; !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "The service %s was not found or permission denied.", req.ServiceName)
+	}
+	if f.rolloutConflicts > 0 {
+		f.rolloutConflicts--
+		return nil, status.Errorf(codes.FailedPrecondition, "Cannot create a rollout for service %s while another rollout is in progress.", req.ServiceName)
+	}
+	f.createdRollouts = append(f.createdRollouts, req)
+
+	rollout := proto.Clone(req.GetRollout()).(*servicemanagementpb.Rollout)
+	rollout.RolloutId = fmt.Sprintf("rollout%d", len(f.createdRollouts))
+	rollout.ServiceName = req.ServiceName
+	rollout.Status = servicemanagementpb.Rollout_SUCCESS
+	if len(f.rolloutStatuses) > 0 {
+		rollout.Status, f.rolloutStatuses = f.rolloutStatuses[0], f.rolloutStatuses[1:]
+	}
+	rollout.CreateTime = timestamppb.New(fakeOperationStartTime)
+	rollout.CreatedBy = "user@example.com"
+	// Like the API, percentages are normalized to two decimal places.
+	for configId, percentage := range rollout.GetTrafficPercentStrategy().GetPercentages() {
+		rollout.GetTrafficPercentStrategy().Percentages[configId] = math.Round(percentage*100) / 100
+	}
+	f.rollouts[req.ServiceName+"/"+rollout.RolloutId] = rollout
+
+	metadata, err := anypb.New(&servicemanagementpb.OperationMetadata{
+		ResourceNames: []string{"services/" + req.ServiceName + "/rollouts/" + rollout.RolloutId},
+		StartTime:     timestamppb.New(fakeOperationStartTime),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if f.rolloutsPending {
+		rollout.Status = servicemanagementpb.Rollout_IN_PROGRESS
+		return &longrunningpb.Operation{
+			Name:     "operations/" + rollout.RolloutId,
+			Metadata: metadata,
+		}, nil
+	}
+	response, err := anypb.New(rollout)
+	if err != nil {
+		return nil, err
+	}
+	return &longrunningpb.Operation{
+		Name:     "operations/" + rollout.RolloutId,
+		Metadata: metadata,
+		Done:     true,
+		Result:   &longrunningpb.Operation_Response{Response: response},
+	}, nil
+}
+
+// newFakeProviderConfig returns a provider configuration whose Service
+// Management client is connected to srv using the given dial options.
+func newFakeProviderConfig(t *testing.T, srv servicemanagementpb.ServiceManagerServer, opts ...grpc.DialOption) *UtilsProviderConfig {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	// Like the real API, accept messages larger than the gRPC default.
+	server := grpc.NewServer(grpc.MaxRecvMsgSize(math.MaxInt32))
+	servicemanagementpb.RegisterServiceManagerServer(server, srv)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		append([]grpc.DialOption{
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		}, opts...)...,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	client, err := servicemanagement.NewServiceManagerClient(context.Background(), option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &UtilsProviderConfig{
+		ServiceManagerClient: client,
+	}
+}
+
+// fakeProvider is a UtilsProvider which is configured with fixed clients.
+type fakeProvider struct {
+	UtilsProvider
+	config *UtilsProviderConfig
+}
+
+func (p *fakeProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	resp.ResourceData = p.config
+	resp.DataSourceData = p.config
+}
+
+// newFakeProviderServer returns a configured provider server which uses the
+// given clients, along with its schemas.
+func newFakeProviderServer(t *testing.T, config *UtilsProviderConfig) (tfprotov6.ProviderServer, *tfprotov6.GetProviderSchemaResponse) {
+	t.Helper()
+	ctx := context.Background()
+
+	server := providerserver.NewProtocol6(&fakeProvider{config: config})()
+	schemas, err := server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := server.ConfigureProvider(ctx, &tfprotov6.ConfigureProviderRequest{
+		Config: testDynamicValue(t, schemas.Provider.ValueType(), nil),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireNoErrors(t, resp.Diagnostics)
+
+	return server, schemas
+}
+
+// testDynamicValue returns an object value of the given type, using null for
+// any attribute not present in values.
+func testDynamicValue(t *testing.T, typ tftypes.Type, values map[string]tftypes.Value) *tfprotov6.DynamicValue {
+	t.Helper()
+
+	objectType := typ.(tftypes.Object)
+	attrs := make(map[string]tftypes.Value, len(objectType.AttributeTypes))
+	for name, attrType := range objectType.AttributeTypes {
+		if value, ok := values[name]; ok {
+			attrs[name] = value
+		} else {
+			attrs[name] = tftypes.NewValue(attrType, nil)
+		}
+	}
+
+	value, err := tfprotov6.NewDynamicValue(typ, tftypes.NewValue(typ, attrs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &value
+}
+
+func requireNoErrors(t *testing.T, diags []*tfprotov6.Diagnostic) {
+	t.Helper()
+
+	for _, d := range diags {
+		if d.Severity == tfprotov6.DiagnosticSeverityError {
+			t.Fatalf("unexpected error: %s: %s", d.Summary, d.Detail)
+		}
+	}
+}
+
+// testPlanResource plans config for the resource, starting from priorState,
+// which may be nil for new resources.
+func testPlanResource(t *testing.T, server tfprotov6.ProviderServer, typeName string, typ tftypes.Type, priorState *tfprotov6.DynamicValue, priorPrivate []byte, config *tfprotov6.DynamicValue) *tfprotov6.PlanResourceChangeResponse {
+	t.Helper()
+	ctx := context.Background()
+
+	if priorState == nil {
+		priorState = testNullDynamicValue(t, typ)
+	}
+
+	validateResp, err := server.ValidateResourceConfig(ctx, &tfprotov6.ValidateResourceConfigRequest{
+		TypeName: typeName,
+		ClientCapabilities: &tfprotov6.ValidateResourceConfigClientCapabilities{
+			WriteOnlyAttributesAllowed: true,
+		},
+		Config: config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireNoErrors(t, validateResp.Diagnostics)
+
+	planResp, err := server.PlanResourceChange(ctx, &tfprotov6.PlanResourceChangeRequest{
+		TypeName:         typeName,
+		PriorState:       priorState,
+		PriorPrivate:     priorPrivate,
+		ProposedNewState: config,
+		Config:           config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireNoErrors(t, planResp.Diagnostics)
+
+	return planResp
+}
+
+// testApplyResource plans and applies config for the resource, starting from
+// priorState, which may be nil for new resources. It returns the new state.
+func testApplyResource(t *testing.T, server tfprotov6.ProviderServer, typeName string, typ tftypes.Type, priorState, config *tfprotov6.DynamicValue) *tfprotov6.DynamicValue {
+	t.Helper()
+	ctx := context.Background()
+
+	if priorState == nil {
+		priorState = testNullDynamicValue(t, typ)
+	}
+	planResp := testPlanResource(t, server, typeName, typ, priorState, nil, config)
+
+	applyResp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:     typeName,
+		PriorState:   priorState,
+		PlannedState: planResp.PlannedState,
+		Config:       config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireNoErrors(t, applyResp.Diagnostics)
+
+	return applyResp.NewState
+}
+
+// testReadResource refreshes state for the resource and returns the new
+// state.
+func testReadResource(t *testing.T, server tfprotov6.ProviderServer, typeName string, state *tfprotov6.DynamicValue) *tfprotov6.DynamicValue {
+	t.Helper()
+
+	resp, err := server.ReadResource(context.Background(), &tfprotov6.ReadResourceRequest{
+		TypeName:     typeName,
+		CurrentState: state,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireNoErrors(t, resp.Diagnostics)
+
+	return resp.NewState
+}
+
+// testNullDynamicValue returns a null value of the given type.
+func testNullDynamicValue(t *testing.T, typ tftypes.Type) *tfprotov6.DynamicValue {
+	t.Helper()
+
+	value, err := tfprotov6.NewDynamicValue(typ, tftypes.NewValue(typ, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &value
+}
+
+// testStateAttributes returns the attributes of a state value.
+func testStateAttributes(t *testing.T, typ tftypes.Type, state *tfprotov6.DynamicValue) map[string]tftypes.Value {
+	t.Helper()
+
+	value, err := state.Unmarshal(typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var attrs map[string]tftypes.Value
+	if err := value.As(&attrs); err != nil {
+		t.Fatal(err)
+	}
+	return attrs
+}
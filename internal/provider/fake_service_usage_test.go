@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/serviceusage/v1"
+)
+
+// fakeServiceUsage is an in-memory implementation of the parts of the
+// Service Usage REST API used by the provider.
+type fakeServiceUsage struct {
+	mu sync.Mutex
+	// enabledServices are the names of the enabled services, in the
+	// `projects/{project_number}/services/{service}` form.
+	enabledServices map[string]bool
+
+	// operationPolls is the number of times an enable or disable operation
+	// is polled before it is done, or never if negative.
+	operationPolls int
+	// operationError, if set, is the error of enable and disable
+	// operations.
+	operationError *serviceusage.Status
+	// operations are the enable and disable operations by name, with the
+	// number of times each has been polled.
+	operations map[string]int
+}
+
+func newFakeServiceUsage() *fakeServiceUsage {
+	return &fakeServiceUsage{
+		enabledServices: make(map[string]bool),
+		operations:      make(map[string]int),
+	}
+}
+
+// newFakeServiceUsageClient serves f over HTTP and returns a client for it.
+func newFakeServiceUsageClient(t *testing.T, f *fakeServiceUsage) *serviceusage.Service {
+	t.Helper()
+
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	client, err := serviceusage.NewService(
+		context.Background(),
+		option.WithEndpoint(server.URL),
+		option.WithHTTPClient(server.Client()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func (f *fakeServiceUsage) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name, ok := strings.CutPrefix(req.URL.Path, "/v1/")
+	if ok && req.Method == http.MethodPost && strings.Contains(name, "/services/") {
+		f.mutateService(w, name)
+		return
+	}
+	if ok && req.Method == http.MethodGet && strings.HasPrefix(name, "operations/") {
+		f.getOperation(w, name)
+		return
+	}
+	if req.Method != http.MethodGet || !ok || !strings.Contains(name, "/services/") {
+		writeFakeTenantError(w, http.StatusNotImplemented, fmt.Sprintf("%s %s is not implemented", req.Method, req.URL.Path))
+		return
+	}
+
+	state := "DISABLED"
+	if f.enabledServices[name] {
+		state = "ENABLED"
+	}
+	writeFakeTenantResponse(w, &serviceusage.GoogleApiServiceusageV1Service{
+		Name:  name,
+		State: state,
+	})
+}
+
+// mutateService enables or disables a service, returning an operation which
+// completes according to operationPolls and operationError.
+func (f *fakeServiceUsage) mutateService(w http.ResponseWriter, name string) {
+	service, method, ok := strings.Cut(name, ":")
+	if !ok || (method != "enable" && method != "disable") {
+		writeFakeTenantError(w, http.StatusNotImplemented, fmt.Sprintf("POST %s is not implemented", name))
+		return
+	}
+	if f.operationError == nil {
+		f.enabledServices[service] = method == "enable"
+	}
+
+	opName := fmt.Sprintf("operations/%s-%d", method, len(f.operations))
+	f.operations[opName] = 0
+	writeFakeTenantResponse(w, f.operation(opName))
+}
+
+func (f *fakeServiceUsage) getOperation(w http.ResponseWriter, name string) {
+	if _, ok := f.operations[name]; !ok {
+		writeFakeTenantError(w, http.StatusNotFound, fmt.Sprintf("operation %s not found", name))
+		return
+	}
+	f.operations[name]++
+	writeFakeTenantResponse(w, f.operation(name))
+}
+
+func (f *fakeServiceUsage) operation(name string) *serviceusage.Operation {
+	op := &serviceusage.Operation{
+		Name: name,
+		Done: f.operationPolls >= 0 && f.operations[name] >= f.operationPolls,
+	}
+	if op.Done {
+		op.Error = f.operationError
+	}
+	return op
+}
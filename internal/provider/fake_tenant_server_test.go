@@ -0,0 +1,470 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/serviceconsumermanagement/v1"
+)
+
+// fakeTenantServer is an in-memory implementation of the parts of the
+// Service Consumer Management REST API used by the provider.
+type fakeTenantServer struct {
+	mu sync.Mutex
+	// tenancyUnits are keyed by name.
+	tenancyUnits map[string]*serviceconsumermanagement.TenancyUnit
+	// listCalls counts the calls to ListTenancyUnits, including rejected
+	// ones.
+	listCalls int
+	// listPageSize is the maximum number of tenancy units returned by each
+	// call to ListTenancyUnits.
+	listPageSize int
+	// hiddenListCalls is the number of upcoming calls to ListTenancyUnits
+	// which return no tenancy units, as if they were not visible yet.
+	hiddenListCalls int
+	// rejectFilter makes ListTenancyUnits reject any filter as invalid.
+	rejectFilter bool
+	// operations are the pending operations, keyed by name. They complete
+	// the first time they are polled.
+	operations map[string]*serviceconsumermanagement.Operation
+	// operationError, if set, is the error with which pending operations
+	// complete.
+	operationError *serviceconsumermanagement.Status
+	// removedTags are the tags of the tenant resources removed by
+	// RemoveProject, in order.
+	removedTags []string
+	// deletedTags are the tags of the tenant resources deleted by
+	// DeleteProject, in order.
+	deletedTags []string
+	// undeletedTags are the tags of the tenant resources restored by
+	// UndeleteProject, in order.
+	undeletedTags []string
+	// projectConfigs are the configs of the tenant projects added by
+	// AddProject or updated by ApplyProjectConfig, keyed by tag.
+	projectConfigs map[string]*serviceconsumermanagement.TenantProjectConfig
+	// appliedTags are the tags of the tenant projects updated by
+	// ApplyProjectConfig, in order.
+	appliedTags []string
+	// attachedTags are the tags of the tenant resources attached by
+	// AttachProject, in order.
+	attachedTags []string
+	// reservedProjects are the projects reserved in the tenancy unit of the
+	// service producer project, keyed by tag.
+	reservedProjects map[string]string
+	// projectStatus is the status of the tenant projects added by AddProject.
+	projectStatus string
+	// settledStatus, if set, is the status which PENDING_CREATE tenant
+	// resources settle in once pendingListCalls calls to ListTenancyUnits
+	// have listed them as pending.
+	settledStatus    string
+	pendingListCalls int
+	// projectNumber is the number of the last project added by AddProject.
+	projectNumber int
+	// mutationFailures are the errors with which the upcoming calls to
+	// AddProject and ApplyProjectConfig fail, in order.
+	mutationFailures []fakeTenantError
+	// mutationCalls counts the calls to AddProject and ApplyProjectConfig,
+	// including failed ones.
+	mutationCalls int
+}
+
+// fakeTenantError is an error returned by the fake, with its HTTP code and
+// canonical status.
+type fakeTenantError struct {
+	code   int
+	status string
+}
+
+func newFakeTenantServer() *fakeTenantServer {
+	return &fakeTenantServer{
+		tenancyUnits:     make(map[string]*serviceconsumermanagement.TenancyUnit),
+		listPageSize:     100,
+		operations:       make(map[string]*serviceconsumermanagement.Operation),
+		projectConfigs:   make(map[string]*serviceconsumermanagement.TenantProjectConfig),
+		reservedProjects: make(map[string]string),
+		projectStatus:    "ACTIVE",
+		projectNumber:    1000,
+	}
+}
+
+// newFakeTenantClient serves f over HTTP and returns a client for it.
+func newFakeTenantClient(t *testing.T, f *fakeTenantServer) *serviceconsumermanagement.APIService {
+	t.Helper()
+
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	client, err := serviceconsumermanagement.NewService(
+		context.Background(),
+		option.WithEndpoint(server.URL),
+		option.WithHTTPClient(server.Client()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// fakeTenancyUnitFilter matches the filters accepted by the fake.
+var fakeTenancyUnitFilter = regexp.MustCompile(`^name:"([^"]+)"$`)
+
+func (f *fakeTenantServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := strings.TrimPrefix(req.URL.Path, "/v1/")
+	switch {
+	case req.Method == http.MethodGet && strings.HasSuffix(name, "/tenancyUnits"):
+		f.listTenancyUnits(w, req, strings.TrimSuffix(name, "/tenancyUnits"))
+	case req.Method == http.MethodPost && strings.HasSuffix(name, "/tenancyUnits"):
+		f.createTenancyUnit(w, req, strings.TrimSuffix(name, "/tenancyUnits"))
+	case req.Method == http.MethodPost && strings.HasSuffix(name, ":addProject"):
+		f.addProject(w, req, strings.TrimSuffix(name, ":addProject"))
+	case req.Method == http.MethodPost && strings.HasSuffix(name, ":attachProject"):
+		f.attachProject(w, req, strings.TrimSuffix(name, ":attachProject"))
+	case req.Method == http.MethodPost && strings.HasSuffix(name, ":applyProjectConfig"):
+		f.applyProjectConfig(w, req, strings.TrimSuffix(name, ":applyProjectConfig"))
+	case req.Method == http.MethodPost && strings.HasSuffix(name, ":removeProject"):
+		f.removeProject(w, req, strings.TrimSuffix(name, ":removeProject"))
+	case req.Method == http.MethodPost && strings.HasSuffix(name, ":undeleteProject"):
+		f.undeleteProject(w, req, strings.TrimSuffix(name, ":undeleteProject"))
+	case req.Method == http.MethodPost && strings.HasSuffix(name, ":deleteProject"):
+		f.deleteProject(w, req, strings.TrimSuffix(name, ":deleteProject"))
+	case req.Method == http.MethodDelete && strings.Contains(name, "/tenancyUnits/"):
+		f.deleteTenancyUnit(w, name)
+	case req.Method == http.MethodGet && strings.HasPrefix(name, "operations/"):
+		f.getOperation(w, name)
+	default:
+		writeFakeTenantError(w, http.StatusNotImplemented, fmt.Sprintf("%s %s is not implemented", req.Method, req.URL.Path))
+	}
+}
+
+func (f *fakeTenantServer) listTenancyUnits(w http.ResponseWriter, req *http.Request, parent string) {
+	f.listCalls++
+
+	var unitId string
+	if filter := req.URL.Query().Get("filter"); filter != "" {
+		match := fakeTenancyUnitFilter.FindStringSubmatch(filter)
+		if f.rejectFilter || match == nil {
+			writeFakeTenantError(w, http.StatusBadRequest, fmt.Sprintf("Invalid filter %q", filter))
+			return
+		}
+		unitId = match[1]
+	}
+
+	if f.hiddenListCalls > 0 {
+		f.hiddenListCalls--
+		writeFakeTenantResponse(w, &serviceconsumermanagement.ListTenancyUnitsResponse{})
+		return
+	}
+
+	if f.settledStatus != "" {
+		if f.pendingListCalls > 0 {
+			f.pendingListCalls--
+		} else {
+			for _, tenancyUnit := range f.tenancyUnits {
+				for _, r := range tenancyUnit.TenantResources {
+					if r.Status == "PENDING_CREATE" {
+						r.Status = f.settledStatus
+					}
+				}
+			}
+		}
+	}
+
+	var names []string
+	for name := range f.tenancyUnits {
+		if strings.HasPrefix(name, parent+"/tenancyUnits/") && (unitId == "" || strings.Contains(name, unitId)) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	start := 0
+	if token := req.URL.Query().Get("pageToken"); token != "" {
+		start, _ = strconv.Atoi(token)
+	}
+	end := min(start+f.listPageSize, len(names))
+	resp := &serviceconsumermanagement.ListTenancyUnitsResponse{}
+	for _, name := range names[start:end] {
+		resp.TenancyUnits = append(resp.TenancyUnits, f.tenancyUnits[name])
+	}
+	if end < len(names) {
+		resp.NextPageToken = strconv.Itoa(end)
+	}
+	writeFakeTenantResponse(w, resp)
+}
+
+func (f *fakeTenantServer) createTenancyUnit(w http.ResponseWriter, req *http.Request, parent string) {
+	var body serviceconsumermanagement.CreateTenancyUnitRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeFakeTenantError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	unitId := body.TenancyUnitId
+	if unitId == "" {
+		unitId = fmt.Sprintf("unit%d", len(f.tenancyUnits)+1)
+	}
+	name := parent + "/tenancyUnits/" + unitId
+	if _, ok := f.tenancyUnits[name]; ok {
+		writeFakeTenantError(w, http.StatusConflict, fmt.Sprintf("Tenancy unit %s already exists", name))
+		return
+	}
+
+	// The parent is `services/{service}/{collection_id}/{resource_id}`.
+	parts := strings.SplitN(parent, "/", 3)
+	tenancyUnit := &serviceconsumermanagement.TenancyUnit{
+		Name:       name,
+		Service:    parts[1],
+		Consumer:   parts[2],
+		CreateTime: time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	f.tenancyUnits[name] = tenancyUnit
+	writeFakeTenantResponse(w, tenancyUnit)
+}
+
+func (f *fakeTenantServer) deleteTenancyUnit(w http.ResponseWriter, name string) {
+	tenancyUnit, ok := f.tenancyUnits[name]
+	if !ok {
+		writeFakeTenantError(w, http.StatusNotFound, fmt.Sprintf("Tenancy unit %s not found", name))
+		return
+	}
+	for _, r := range tenancyUnit.TenantResources {
+		if r.Status != "DELETED" {
+			writeFakeTenantError(w, http.StatusBadRequest, fmt.Sprintf("Tenancy unit %s has tenant resources", name))
+			return
+		}
+	}
+	delete(f.tenancyUnits, name)
+	writeFakeTenantResponse(w, &serviceconsumermanagement.Operation{
+		Name: "operations/delete-" + strings.ReplaceAll(name, "/", "-"),
+		Done: true,
+	})
+}
+
+func (f *fakeTenantServer) addProject(w http.ResponseWriter, req *http.Request, name string) {
+	var body serviceconsumermanagement.AddTenantProjectRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeFakeTenantError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if f.failMutation(w) {
+		return
+	}
+
+	tenancyUnit, ok := f.tenancyUnits[name]
+	if !ok {
+		writeFakeTenantError(w, http.StatusNotFound, fmt.Sprintf("Tenancy unit %s not found", name))
+		return
+	}
+	for _, r := range tenancyUnit.TenantResources {
+		if r.Tag == body.Tag {
+			writeFakeTenantError(w, http.StatusConflict, fmt.Sprintf("Tenant resource with tag %q already exists", body.Tag))
+			return
+		}
+	}
+	f.projectNumber++
+	tenancyUnit.TenantResources = append(tenancyUnit.TenantResources, &serviceconsumermanagement.TenantResource{
+		Tag:      body.Tag,
+		Resource: fmt.Sprintf("projects/%d", f.projectNumber),
+		Status:   f.projectStatus,
+	})
+	f.projectConfigs[body.Tag] = body.ProjectConfig
+	f.writePendingOperation(w, fmt.Sprintf("operations/add-project-%d", f.projectNumber))
+}
+
+func (f *fakeTenantServer) attachProject(w http.ResponseWriter, req *http.Request, name string) {
+	var body serviceconsumermanagement.AttachTenantProjectRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeFakeTenantError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tenancyUnit, ok := f.tenancyUnits[name]
+	if !ok {
+		writeFakeTenantError(w, http.StatusNotFound, fmt.Sprintf("Tenancy unit %s not found", name))
+		return
+	}
+	for _, r := range tenancyUnit.TenantResources {
+		if r.Tag == body.Tag {
+			writeFakeTenantError(w, http.StatusConflict, fmt.Sprintf("Tenant resource with tag %q already exists", body.Tag))
+			return
+		}
+	}
+	resource := body.ExternalResource
+	if body.ReservedResource != "" {
+		if resource = f.reservedProjects[body.ReservedResource]; resource == "" {
+			writeFakeTenantError(w, http.StatusNotFound, fmt.Sprintf("Reserved resource with tag %q not found", body.ReservedResource))
+			return
+		}
+		delete(f.reservedProjects, body.ReservedResource)
+	}
+	tenancyUnit.TenantResources = append(tenancyUnit.TenantResources, &serviceconsumermanagement.TenantResource{
+		Tag:      body.Tag,
+		Resource: resource,
+		Status:   "ACTIVE",
+	})
+	f.attachedTags = append(f.attachedTags, body.Tag)
+	f.writePendingOperation(w, fmt.Sprintf("operations/attach-project-%d", len(f.attachedTags)))
+}
+
+func (f *fakeTenantServer) applyProjectConfig(w http.ResponseWriter, req *http.Request, name string) {
+	var body serviceconsumermanagement.ApplyTenantProjectConfigRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeFakeTenantError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if f.failMutation(w) {
+		return
+	}
+
+	if _, _, ok := f.findTenantResource(w, name, body.Tag); !ok {
+		return
+	}
+	f.projectConfigs[body.Tag] = body.ProjectConfig
+	f.appliedTags = append(f.appliedTags, body.Tag)
+	f.writePendingOperation(w, "operations/apply-project-config-"+body.Tag)
+}
+
+func (f *fakeTenantServer) removeProject(w http.ResponseWriter, req *http.Request, name string) {
+	var body serviceconsumermanagement.RemoveTenantProjectRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeFakeTenantError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tenancyUnit, i, ok := f.findTenantResource(w, name, body.Tag)
+	if !ok {
+		return
+	}
+	if tenancyUnit.TenantResources[i].Status == "FAILED" {
+		writeFakeTenantError(w, http.StatusBadRequest, fmt.Sprintf("Tenant resource with tag %q is FAILED and cannot be removed", body.Tag))
+		return
+	}
+	tenancyUnit.TenantResources = slices.Delete(tenancyUnit.TenantResources, i, i+1)
+	f.removedTags = append(f.removedTags, body.Tag)
+	f.writePendingOperation(w, fmt.Sprintf("operations/remove-%d", len(f.removedTags)))
+}
+
+func (f *fakeTenantServer) deleteProject(w http.ResponseWriter, req *http.Request, name string) {
+	var body serviceconsumermanagement.DeleteTenantProjectRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeFakeTenantError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tenancyUnit, i, ok := f.findTenantResource(w, name, body.Tag)
+	if !ok {
+		return
+	}
+	tenancyUnit.TenantResources[i].Status = "DELETED"
+	f.deletedTags = append(f.deletedTags, body.Tag)
+	f.writePendingOperation(w, fmt.Sprintf("operations/delete-project-%d", len(f.deletedTags)))
+}
+
+func (f *fakeTenantServer) undeleteProject(w http.ResponseWriter, req *http.Request, name string) {
+	var body serviceconsumermanagement.UndeleteTenantProjectRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeFakeTenantError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tenancyUnit, i, ok := f.findTenantResource(w, name, body.Tag)
+	if !ok {
+		return
+	}
+	if status := tenancyUnit.TenantResources[i].Status; status != "PENDING_DELETE" && status != "DELETED" {
+		writeFakeTenantError(w, http.StatusBadRequest, fmt.Sprintf("Tenant resource with tag %q is %s and cannot be undeleted", body.Tag, status))
+		return
+	}
+	tenancyUnit.TenantResources[i].Status = "ACTIVE"
+	f.undeletedTags = append(f.undeletedTags, body.Tag)
+	f.writePendingOperation(w, fmt.Sprintf("operations/undelete-project-%d", len(f.undeletedTags)))
+}
+
+// findTenantResource returns the tenancy unit named name and the index of its
+// tenant resource with the given tag. If there is none, it writes an error
+// and returns false.
+func (f *fakeTenantServer) findTenantResource(w http.ResponseWriter, name, tag string) (*serviceconsumermanagement.TenancyUnit, int, bool) {
+	tenancyUnit, ok := f.tenancyUnits[name]
+	if !ok {
+		writeFakeTenantError(w, http.StatusNotFound, fmt.Sprintf("Tenancy unit %s not found", name))
+		return nil, 0, false
+	}
+	i := slices.IndexFunc(tenancyUnit.TenantResources, func(r *serviceconsumermanagement.TenantResource) bool {
+		return r.Tag == tag
+	})
+	if i < 0 {
+		writeFakeTenantError(w, http.StatusNotFound, fmt.Sprintf("Tenant resource with tag %q not found", tag))
+		return nil, 0, false
+	}
+	return tenancyUnit, i, true
+}
+
+// writePendingOperation writes an operation which completes the first time
+// it is polled.
+// failMutation counts a call to AddProject or ApplyProjectConfig, and fails it
+// with the next of mutationFailures, if any.
+func (f *fakeTenantServer) failMutation(w http.ResponseWriter) bool {
+	f.mutationCalls++
+	if len(f.mutationFailures) == 0 {
+		return false
+	}
+	failure := f.mutationFailures[0]
+	f.mutationFailures = f.mutationFailures[1:]
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(failure.code)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{
+			"code":    failure.code,
+			"message": "fake failure",
+			"status":  failure.status,
+		},
+	})
+	return true
+}
+
+func (f *fakeTenantServer) writePendingOperation(w http.ResponseWriter, name string) {
+	op := &serviceconsumermanagement.Operation{Name: name}
+	f.operations[op.Name] = op
+	writeFakeTenantResponse(w, op)
+}
+
+func (f *fakeTenantServer) getOperation(w http.ResponseWriter, name string) {
+	op, ok := f.operations[name]
+	if !ok {
+		writeFakeTenantError(w, http.StatusNotFound, fmt.Sprintf("Operation %s not found", name))
+		return
+	}
+	delete(f.operations, name)
+	writeFakeTenantResponse(w, &serviceconsumermanagement.Operation{Name: op.Name, Done: true, Error: f.operationError})
+}
+
+func writeFakeTenantResponse(w http.ResponseWriter, resp any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func writeFakeTenantError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var _ basetypes.Float64Typable = PercentageType{}
+var _ basetypes.Float64ValuableWithSemanticEquals = PercentageValue{}
+var _ validator.Float64 = percentageValidator{}
+
+// percentageTolerance is the largest difference between two traffic
+// percentages which are considered equal. The API normalizes percentages, so
+// values computed in HCL, for example `100 / 3`, are not returned exactly.
+const percentageTolerance = 0.01
+
+// PercentageType is a float64 type for traffic percentages. Values which
+// differ by less than percentageTolerance are semantically equal.
+type PercentageType struct {
+	basetypes.Float64Type
+}
+
+func (t PercentageType) Equal(o attr.Type) bool {
+	other, ok := o.(PercentageType)
+	if !ok {
+		return false
+	}
+	return t.Float64Type.Equal(other.Float64Type)
+}
+
+func (t PercentageType) String() string {
+	return "PercentageType"
+}
+
+func (t PercentageType) ValueFromFloat64(ctx context.Context, in basetypes.Float64Value) (basetypes.Float64Valuable, diag.Diagnostics) {
+	return PercentageValue{Float64Value: in}, nil
+}
+
+func (t PercentageType) ValueFromTerraform(ctx context.Context, in tftypes.Value) (attr.Value, error) {
+	attrValue, err := t.Float64Type.ValueFromTerraform(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	floatValue, ok := attrValue.(basetypes.Float64Value)
+	if !ok {
+		return nil, fmt.Errorf("unexpected value type of %T", attrValue)
+	}
+
+	return PercentageValue{Float64Value: floatValue}, nil
+}
+
+func (t PercentageType) ValueType(ctx context.Context) attr.Value {
+	return PercentageValue{}
+}
+
+// PercentageValue is a value of PercentageType.
+type PercentageValue struct {
+	basetypes.Float64Value
+}
+
+func (v PercentageValue) Equal(o attr.Value) bool {
+	other, ok := o.(PercentageValue)
+	if !ok {
+		return false
+	}
+	return v.Float64Value.Equal(other.Float64Value)
+}
+
+func (v PercentageValue) Type(ctx context.Context) attr.Type {
+	return PercentageType{}
+}
+
+// Float64SemanticEquals reports whether both values are within
+// percentageTolerance of each other.
+func (v PercentageValue) Float64SemanticEquals(ctx context.Context, newValuable basetypes.Float64Valuable) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	newValue, ok := newValuable.(PercentageValue)
+	if !ok {
+		diags.AddError(
+			"Semantic Equality Check Error",
+			fmt.Sprintf("Expected value type %T but got value type %T. Please report this to the provider developers.", v, newValuable),
+		)
+		return false, diags
+	}
+
+	return percentageEqual(v.ValueFloat64(), newValue.ValueFloat64()), nil
+}
+
+// percentageEqual reports whether a and b are within percentageTolerance of
+// each other.
+func percentageEqual(a, b float64) bool {
+	return math.Abs(a-b) < percentageTolerance
+}
+
+// percentagesEqual reports whether a and b have the same keys and their
+// percentages are within percentageTolerance of each other.
+func percentagesEqual(a, b map[string]float64) bool {
+	return maps.EqualFunc(a, b, percentageEqual)
+}
+
+// percentageValidator validates that a traffic percentage is greater than 0
+// and at most 100.
+type percentageValidator struct{}
+
+func (v percentageValidator) Description(ctx context.Context) string {
+	return "value must be greater than 0 and at most 100"
+}
+
+func (v percentageValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v percentageValidator) ValidateFloat64(ctx context.Context, req validator.Float64Request, resp *validator.Float64Response) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	if percent := req.ConfigValue.ValueFloat64(); percent <= 0 || percent > 100 {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid traffic percentage", fmt.Sprintf("The percentage must be greater than 0 and at most 100, got %v.", percent))
+	}
+}
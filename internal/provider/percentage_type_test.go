@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+func TestPercentageSemanticEquals(t *testing.T) {
+	tests := []struct {
+		name     string
+		oldValue float64
+		newValue float64
+		want     bool
+	}{
+		{name: "identical", oldValue: 50, newValue: 50, want: true},
+		{name: "normalized third", oldValue: 100.0 / 3, newValue: 33.33, want: true},
+		{name: "normalized two thirds", oldValue: 200.0 / 3, newValue: 66.67, want: true},
+		{name: "different", oldValue: 33.33, newValue: 33.34, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldValue := PercentageValue{Float64Value: basetypes.NewFloat64Value(tt.oldValue)}
+			newValue := PercentageValue{Float64Value: basetypes.NewFloat64Value(tt.newValue)}
+			got, diags := oldValue.Float64SemanticEquals(context.Background(), newValue)
+			if diags.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentageValidator(t *testing.T) {
+	tests := []struct {
+		value   types.Float64
+		wantErr bool
+	}{
+		{value: types.Float64Null()},
+		{value: types.Float64Unknown()},
+		{value: types.Float64Value(0.01)},
+		{value: types.Float64Value(100.0 / 3)},
+		{value: types.Float64Value(100)},
+		{value: types.Float64Value(0), wantErr: true},
+		{value: types.Float64Value(-10), wantErr: true},
+		{value: types.Float64Value(100.5), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value.String(), func(t *testing.T) {
+			resp := &validator.Float64Response{}
+			percentageValidator{}.ValidateFloat64(context.Background(), validator.Float64Request{
+				Path:        path.Root("percentage"),
+				ConfigValue: tt.value,
+			}, resp)
+			if got := resp.Diagnostics.HasError(); got != tt.wantErr {
+				t.Errorf("got error %v, want %v: %v", got, tt.wantErr, resp.Diagnostics)
+			}
+		})
+	}
+}
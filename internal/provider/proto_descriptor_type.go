@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var _ basetypes.StringTypable = ProtoDescriptorType{}
+var _ basetypes.StringValuableWithSemanticEquals = ProtoDescriptorValue{}
+
+// ProtoDescriptorType is a string type for base64-encoded proto descriptors.
+// Values are rendered by their hash and size rather than their contents, and
+// values which decode to the same descriptor are semantically equal.
+type ProtoDescriptorType struct {
+	basetypes.StringType
+}
+
+func (t ProtoDescriptorType) Equal(o attr.Type) bool {
+	other, ok := o.(ProtoDescriptorType)
+	if !ok {
+		return false
+	}
+	return t.StringType.Equal(other.StringType)
+}
+
+func (t ProtoDescriptorType) String() string {
+	return "ProtoDescriptorType"
+}
+
+func (t ProtoDescriptorType) ValueFromString(ctx context.Context, in basetypes.StringValue) (basetypes.StringValuable, diag.Diagnostics) {
+	return ProtoDescriptorValue{StringValue: in}, nil
+}
+
+func (t ProtoDescriptorType) ValueFromTerraform(ctx context.Context, in tftypes.Value) (attr.Value, error) {
+	attrValue, err := t.StringType.ValueFromTerraform(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	stringValue, ok := attrValue.(basetypes.StringValue)
+	if !ok {
+		return nil, fmt.Errorf("unexpected value type of %T", attrValue)
+	}
+
+	return ProtoDescriptorValue{StringValue: stringValue}, nil
+}
+
+func (t ProtoDescriptorType) ValueType(ctx context.Context) attr.Value {
+	return ProtoDescriptorValue{}
+}
+
+// ProtoDescriptorValue is a value of ProtoDescriptorType.
+type ProtoDescriptorValue struct {
+	basetypes.StringValue
+}
+
+// NewProtoDescriptorValue returns a known ProtoDescriptorValue holding the
+// base64 encoding of descriptor.
+func NewProtoDescriptorValue(descriptor []byte) ProtoDescriptorValue {
+	return ProtoDescriptorValue{StringValue: basetypes.NewStringValue(base64.StdEncoding.EncodeToString(descriptor))}
+}
+
+func (v ProtoDescriptorValue) Equal(o attr.Value) bool {
+	other, ok := o.(ProtoDescriptorValue)
+	if !ok {
+		return false
+	}
+	return v.StringValue.Equal(other.StringValue)
+}
+
+func (v ProtoDescriptorValue) Type(ctx context.Context) attr.Type {
+	return ProtoDescriptorType{}
+}
+
+// String renders the value by its hash and size, for example
+// `sha256:0123456789ab… (2.3 MB)`, so that descriptors are not dumped into
+// logs.
+func (v ProtoDescriptorValue) String() string {
+	if v.IsNull() || v.IsUnknown() {
+		return v.StringValue.String()
+	}
+	descriptor, err := base64.StdEncoding.DecodeString(v.ValueString())
+	if err != nil {
+		descriptor = []byte(v.ValueString())
+	}
+	return describeProtoDescriptor(descriptor)
+}
+
+// StringSemanticEquals reports whether both values decode to the same
+// descriptor, ignoring differences such as line wrapping.
+func (v ProtoDescriptorValue) StringSemanticEquals(ctx context.Context, newValuable basetypes.StringValuable) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	newValue, ok := newValuable.(ProtoDescriptorValue)
+	if !ok {
+		diags.AddError(
+			"Semantic Equality Check Error",
+			fmt.Sprintf("Expected value type %T but got value type %T. Please report this to the provider developers.", v, newValuable),
+		)
+		return false, diags
+	}
+
+	oldDescriptor, err := base64.StdEncoding.DecodeString(v.ValueString())
+	if err != nil {
+		return false, nil
+	}
+	newDescriptor, err := base64.StdEncoding.DecodeString(newValue.ValueString())
+	if err != nil {
+		return false, nil
+	}
+
+	return bytes.Equal(oldDescriptor, newDescriptor), nil
+}
+
+// describeProtoDescriptor returns a short description of a descriptor in the
+// form `sha256:0123456789ab… (2.3 MB)`.
+func describeProtoDescriptor(descriptor []byte) string {
+	return fmt.Sprintf("sha256:%s… (%s)", sha256Hex(descriptor)[:12], formatByteSize(len(descriptor)))
+}
+
+// formatByteSize formats n bytes using SI units.
+func formatByteSize(n int) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	size := float64(n) / unit
+	for _, suffix := range []string{"kB", "MB"} {
+		if size < unit {
+			return fmt.Sprintf("%.1f %s", size, suffix)
+		}
+		size /= unit
+	}
+	return fmt.Sprintf("%.1f GB", size)
+}
@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestProtoDescriptorValueString(t *testing.T) {
+	descriptor := make([]byte, 2_345_678)
+	value := NewProtoDescriptorValue(descriptor)
+
+	want := "sha256:" + sha256Hex(descriptor)[:12] + "… (2.3 MB)"
+	if got := value.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFormatByteSize(t *testing.T) {
+	for _, tt := range []struct {
+		n    int
+		want string
+	}{
+		{n: 0, want: "0 B"},
+		{n: 999, want: "999 B"},
+		{n: 1_500, want: "1.5 kB"},
+		{n: 2_345_678, want: "2.3 MB"},
+		{n: 4_200_000_000, want: "4.2 GB"},
+	} {
+		if got := formatByteSize(tt.n); got != tt.want {
+			t.Errorf("formatByteSize(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestProtoDescriptorSemanticEquals(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("descriptor", 20)))
+
+	var wrapped strings.Builder
+	for i := 0; i < len(encoded); i += 76 {
+		wrapped.WriteString(encoded[i:min(i+76, len(encoded))])
+		wrapped.WriteString("\n")
+	}
+
+	tests := []struct {
+		name     string
+		oldValue string
+		newValue string
+		want     bool
+	}{
+		{name: "identical", oldValue: encoded, newValue: encoded, want: true},
+		{name: "line wrapped", oldValue: encoded, newValue: wrapped.String(), want: true},
+		{name: "different", oldValue: encoded, newValue: base64.StdEncoding.EncodeToString([]byte("other")), want: false},
+		{name: "invalid", oldValue: encoded, newValue: "not base64!", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldValue := ProtoDescriptorValue{StringValue: types.StringValue(tt.oldValue)}
+			newValue := ProtoDescriptorValue{StringValue: types.StringValue(tt.newValue)}
+
+			got, diags := oldValue.StringSemanticEquals(context.Background(), newValue)
+			if diags.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			if got != tt.want {
+				t.Errorf("StringSemanticEquals() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
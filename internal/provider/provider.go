@@ -2,20 +2,32 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"slices"
 
 	lrauto "cloud.google.com/go/longrunning/autogen"
 	servicemanagement "cloud.google.com/go/servicemanagement/apiv1"
+	"github.com/googleapis/gax-go/v2/callctx"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"golang.org/x/oauth2"
 	googleoauth "golang.org/x/oauth2/google"
+	"google.golang.org/api/cloudbilling/v1"
+	"google.golang.org/api/cloudresourcemanager/v3"
 	"google.golang.org/api/option"
 	"google.golang.org/api/serviceconsumermanagement/v1"
+	"google.golang.org/api/serviceusage/v1"
+	htransport "google.golang.org/api/transport/http"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/oauth"
+	"google.golang.org/grpc/metadata"
 )
 
 // Ensure UtilsProvider satisfies various provider interfaces.
@@ -28,6 +40,15 @@ var scopes = []string{
 	"https://www.googleapis.com/auth/service.management",
 }
 
+// defaultMaxMessageSizeMB is the default maximum size of gRPC messages sent
+// to and received from the Service Management API. Configs which include
+// large descriptors easily exceed the gRPC default of 4 MB.
+const defaultMaxMessageSizeMB = 64
+
+// quotaProjectHeader is the header used by Google APIs to determine the
+// project which is billed for a request.
+const quotaProjectHeader = "x-goog-user-project"
+
 // UtilsProvider defines the provider implementation.
 type UtilsProvider struct {
 	// version is set to the provider version on release, "dev" when the
@@ -46,6 +67,24 @@ type UtilsProviderConfig struct {
 
 	// OperationsClient is the authenticated operations client for `servicemanagement.googleapis.com`.
 	OperationsClient *lrauto.OperationsClient
+
+	// ResourceManagerClient is the authenticated client for `cloudresourcemanager.googleapis.com`.
+	ResourceManagerClient *cloudresourcemanager.Service
+
+	// ServiceUsageClient is the authenticated client for `serviceusage.googleapis.com`.
+	ServiceUsageClient *serviceusage.Service
+
+	// BillingClient is the authenticated client for `cloudbilling.googleapis.com`.
+	BillingClient *cloudbilling.APIService
+
+	// SubmitRetryAttempts is the maximum number of attempts made to submit a
+	// service config. Zero means defaultSubmitRetryAttempts.
+	SubmitRetryAttempts int
+
+	// TenantProjectRetryAttempts is the maximum number of attempts made to
+	// add or configure a tenant project. Zero means
+	// defaultTenantProjectRetryAttempts.
+	TenantProjectRetryAttempts int
 }
 
 // UtilsProviderModel describes the provider data model.
@@ -55,6 +94,28 @@ type UtilsProviderModel struct {
 
 	// Optional. AccessToken is the optional GCP access token.
 	AccessToken types.String `tfsdk:"access_token"`
+
+	// Optional. SubmitRetryAttempts is the maximum number of attempts made to
+	// submit a service config.
+	SubmitRetryAttempts types.Int64 `tfsdk:"submit_retry_attempts"`
+
+	// Optional. TenantProjectRetryAttempts is the maximum number of attempts
+	// made to add or configure a tenant project.
+	TenantProjectRetryAttempts types.Int64 `tfsdk:"tenant_project_retry_attempts"`
+
+	// Optional. MaxMessageSizeMB is the maximum size of gRPC messages in
+	// megabytes.
+	MaxMessageSizeMB types.Int64 `tfsdk:"max_message_size_mb"`
+}
+
+// withQuotaProject returns a context which bills API calls made with it to
+// the given project. If project is null or unknown, the provider-level
+// quota project is used.
+func (p *UtilsProviderConfig) withQuotaProject(ctx context.Context, project types.String) context.Context {
+	if project.IsUnknown() || project.IsNull() || project.ValueString() == "" {
+		return ctx
+	}
+	return callctx.SetHeaders(ctx, quotaProjectHeader, project.ValueString())
 }
 
 func (p *UtilsProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -73,6 +134,27 @@ func (p *UtilsProvider) Schema(ctx context.Context, req provider.SchemaRequest,
 				MarkdownDescription: "Optional. GCP access token",
 				Optional:            true,
 			},
+			"max_message_size_mb": schema.Int64Attribute{
+				MarkdownDescription: fmt.Sprintf("Optional. Maximum size in megabytes of messages sent to and received from the Service Management API, which must fit service configs including their proto descriptors. Defaults to %d.", defaultMaxMessageSizeMB),
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"submit_retry_attempts": schema.Int64Attribute{
+				MarkdownDescription: fmt.Sprintf("Optional. Maximum number of attempts made to submit a service config when quota is exhausted, the API is unavailable or another operation on the service is in progress. Defaults to %d.", defaultSubmitRetryAttempts),
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"tenant_project_retry_attempts": schema.Int64Attribute{
+				MarkdownDescription: fmt.Sprintf("Optional. Maximum number of attempts made to add or configure a tenant project when project creation quota is exhausted or the change is aborted by a concurrent one. Defaults to %d.", defaultTenantProjectRetryAttempts),
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
 		},
 	}
 }
@@ -94,9 +176,6 @@ func (p *UtilsProvider) Configure(ctx context.Context, req provider.ConfigureReq
 	persistentCtx := context.Background()
 
 	dialOpts := []option.ClientOption{}
-	if !data.ProjectID.IsUnknown() && !data.ProjectID.IsNull() {
-		dialOpts = append(dialOpts, option.WithQuotaProject(data.ProjectID.ValueString()))
-	}
 
 	var foundGoogleCreds bool
 	switch {
@@ -126,31 +205,108 @@ func (p *UtilsProvider) Configure(ctx context.Context, req provider.ConfigureReq
 		return
 	}
 
-	client, err := servicemanagement.NewServiceManagerClient(persistentCtx, dialOpts...)
+	// The provider-level quota project is only set on calls which do not set
+	// one with withQuotaProject, which option.WithQuotaProject would replace
+	// or duplicate.
+	grpcOpts, restOpts := dialOpts, dialOpts
+	if quotaProject := data.ProjectID.ValueString(); quotaProject != "" {
+		grpcOpts = append(slices.Clip(dialOpts), option.WithGRPCDialOption(grpc.WithChainUnaryInterceptor(quotaProjectInterceptor(quotaProject))))
+
+		transport, err := htransport.NewTransport(persistentCtx, http.DefaultTransport, dialOpts...)
+		if err != nil {
+			resp.Diagnostics.AddError("Could not create HTTP transport", err.Error())
+			return
+		}
+		restOpts = append(slices.Clip(dialOpts), option.WithHTTPClient(&http.Client{
+			Transport: &quotaProjectTransport{base: transport, project: quotaProject},
+		}))
+	}
+
+	maxMessageSizeMB := int64(defaultMaxMessageSizeMB)
+	if !data.MaxMessageSizeMB.IsUnknown() && !data.MaxMessageSizeMB.IsNull() {
+		maxMessageSizeMB = data.MaxMessageSizeMB.ValueInt64()
+	}
+	serviceManagerOpts := append(slices.Clip(grpcOpts), option.WithGRPCDialOption(maxMessageSizeDialOption(int(maxMessageSizeMB)<<20)))
+
+	client, err := servicemanagement.NewServiceManagerClient(persistentCtx, serviceManagerOpts...)
 	if err != nil {
 		resp.Diagnostics.AddError("Could not create service manager client", err.Error())
 		return
 	}
-	tenantClient, err := serviceconsumermanagement.NewService(persistentCtx, dialOpts...)
+	tenantClient, err := serviceconsumermanagement.NewService(persistentCtx, restOpts...)
 	if err != nil {
 		resp.Diagnostics.AddError("Could not create tenant client", err.Error())
 		return
 	}
-	operations, err := lrauto.NewOperationsClient(persistentCtx, dialOpts...)
+	operations, err := lrauto.NewOperationsClient(persistentCtx, grpcOpts...)
 	if err != nil {
 		resp.Diagnostics.AddError("Could not create operations client", err.Error())
 		return
 	}
 
+	resourceManager, err := cloudresourcemanager.NewService(persistentCtx, restOpts...)
+	if err != nil {
+		resp.Diagnostics.AddError("Could not create resource manager client", err.Error())
+		return
+	}
+	serviceUsage, err := serviceusage.NewService(persistentCtx, restOpts...)
+	if err != nil {
+		resp.Diagnostics.AddError("Could not create service usage client", err.Error())
+		return
+	}
+	billing, err := cloudbilling.NewService(persistentCtx, restOpts...)
+	if err != nil {
+		resp.Diagnostics.AddError("Could not create billing client", err.Error())
+		return
+	}
+
 	config := &UtilsProviderConfig{
-		ServiceManagerClient: client,
-		TenantClient:         tenantClient,
-		OperationsClient:     operations,
+		ServiceManagerClient:  client,
+		TenantClient:          tenantClient,
+		OperationsClient:      operations,
+		ResourceManagerClient: resourceManager,
+		ServiceUsageClient:    serviceUsage,
+		BillingClient:         billing,
+		SubmitRetryAttempts:   int(data.SubmitRetryAttempts.ValueInt64()),
+
+		TenantProjectRetryAttempts: int(data.TenantProjectRetryAttempts.ValueInt64()),
 	}
 	resp.ResourceData = config
 	resp.DataSourceData = config
 }
 
+// quotaProjectTransport sets the quota project header to project on requests
+// which do not set it already.
+type quotaProjectTransport struct {
+	base    http.RoundTripper
+	project string
+}
+
+func (t *quotaProjectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get(quotaProjectHeader) == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set(quotaProjectHeader, t.project)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// quotaProjectInterceptor returns a gRPC interceptor which sets the quota
+// project header to project on calls which do not set it already.
+func quotaProjectInterceptor(project string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if md, _ := metadata.FromOutgoingContext(ctx); len(md.Get(quotaProjectHeader)) == 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, quotaProjectHeader, project)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// maxMessageSizeDialOption returns a dial option which limits gRPC messages
+// to maxBytes in both directions.
+func maxMessageSizeDialOption(maxBytes int) grpc.DialOption {
+	return grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxBytes), grpc.MaxCallSendMsgSize(maxBytes))
+}
+
 func (p *UtilsProvider) Resources(ctx context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewServiceResource,
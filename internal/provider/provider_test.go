@@ -1,8 +1,21 @@
 package provider
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"testing"
+
+	"cloud.google.com/go/servicemanagement/apiv1/servicemanagementpb"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"google.golang.org/api/option"
+	"google.golang.org/api/serviceusage/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 // testAccProtoV6ProviderFactories are used to instantiate a provider during
@@ -18,3 +31,105 @@ func testAccCreateConfig(config string) string {
 provider "utils" {}
 	` + config
 }
+
+// testAccProducerProject returns the producer project used by acceptance
+// tests which create Service Management resources, skipping the test if it
+// is not configured.
+func testAccProducerProject(t *testing.T) string {
+	project := os.Getenv("UTILS_TEST_PRODUCER_PROJECT")
+	if project == "" {
+		t.Skip("UTILS_TEST_PRODUCER_PROJECT must be set for this acceptance test")
+	}
+	return project
+}
+
+// testAccConsumerProject returns the number of the consumer project used by
+// acceptance tests which create tenancy units, skipping the test if it is not
+// configured.
+func testAccConsumerProject(t *testing.T) string {
+	project := os.Getenv("UTILS_TEST_CONSUMER_PROJECT_NUMBER")
+	if project == "" {
+		t.Skip("UTILS_TEST_CONSUMER_PROJECT_NUMBER must be set for this acceptance test")
+	}
+	return project
+}
+
+// testAccTenantProjectConfig returns the folder, billing account and owner
+// used by acceptance tests which create tenant projects, skipping the test if
+// they are not configured.
+func testAccTenantProjectConfig(t *testing.T) (folder, billingAccount, owner string) {
+	folder = os.Getenv("UTILS_TEST_TENANT_FOLDER")
+	billingAccount = os.Getenv("UTILS_TEST_BILLING_ACCOUNT")
+	owner = os.Getenv("UTILS_TEST_TENANT_OWNER")
+	if folder == "" || billingAccount == "" || owner == "" {
+		t.Skip("UTILS_TEST_TENANT_FOLDER, UTILS_TEST_BILLING_ACCOUNT and UTILS_TEST_TENANT_OWNER must be set for this acceptance test")
+	}
+	return folder, billingAccount, owner
+}
+
+// quotaProjectRecorder records the quota project header of GetService calls.
+type quotaProjectRecorder struct {
+	*fakeServiceManager
+	got []string
+}
+
+func (r *quotaProjectRecorder) GetService(ctx context.Context, req *servicemanagementpb.GetServiceRequest) (*servicemanagementpb.ManagedService, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	r.got = md.Get(quotaProjectHeader)
+	return r.fakeServiceManager.GetService(ctx, req)
+}
+
+func TestQuotaProject(t *testing.T) {
+	for _, tt := range []struct {
+		name            string
+		providerProject string
+		project         types.String
+		want            []string
+	}{
+		{name: "none", project: types.StringNull()},
+		{name: "provider", providerProject: "provider", project: types.StringNull(), want: []string{"provider"}},
+		{name: "resource", project: types.StringValue("resource"), want: []string{"resource"}},
+		{name: "resource override", providerProject: "provider", project: types.StringValue("resource"), want: []string{"resource"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var dialOpts []grpc.DialOption
+			if tt.providerProject != "" {
+				dialOpts = append(dialOpts, grpc.WithChainUnaryInterceptor(quotaProjectInterceptor(tt.providerProject)))
+			}
+			recorder := &quotaProjectRecorder{fakeServiceManager: newFakeServiceManager()}
+			config := newFakeProviderConfig(t, recorder, dialOpts...)
+
+			var restGot []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				restGot = req.Header.Values(quotaProjectHeader)
+				writeFakeTenantResponse(w, &serviceusage.GoogleApiServiceusageV1Service{State: "ENABLED"})
+			}))
+			t.Cleanup(server.Close)
+			httpClient := server.Client()
+			if tt.providerProject != "" {
+				httpClient = &http.Client{Transport: &quotaProjectTransport{base: httpClient.Transport, project: tt.providerProject}}
+			}
+			serviceUsage, err := serviceusage.NewService(
+				context.Background(),
+				option.WithEndpoint(server.URL),
+				option.WithHTTPClient(httpClient),
+				option.WithoutAuthentication(),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := config.withQuotaProject(context.Background(), tt.project)
+			_, _ = config.ServiceManagerClient.GetService(ctx, &servicemanagementpb.GetServiceRequest{ServiceName: "test.endpoints.example.cloud.goog"})
+			if !slices.Equal(recorder.got, tt.want) {
+				t.Errorf("got gRPC quota project %q, want %q", recorder.got, tt.want)
+			}
+			if _, err := serviceUsage.Services.Get("projects/consumer/services/test.endpoints.example.cloud.goog").Context(ctx).Do(); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(restGot, tt.want) {
+				t.Errorf("got REST quota project %q, want %q", restGot, tt.want)
+			}
+		})
+	}
+}
@@ -3,15 +3,25 @@ package provider
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
+	"time"
 
 	"cloud.google.com/go/servicemanagement/apiv1/servicemanagementpb"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"google.golang.org/api/cloudresourcemanager/v3"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/serviceusage/v1"
+	"google.golang.org/genproto/googleapis/api/serviceconfig"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -20,6 +30,12 @@ import (
 var _ resource.Resource = &ServiceResource{}
 var _ resource.ResourceWithImportState = &ServiceResource{}
 
+// serviceEnablePendingKey is the private state key which is set when the
+// service could not be enabled on the producer project after it was created.
+// Read then records the service as not enabled, so that the next apply
+// retries enabling it.
+const serviceEnablePendingKey = "enable_pending"
+
 func NewServiceResource() resource.Resource {
 	return &ServiceResource{}
 }
@@ -31,9 +47,16 @@ type ServiceResource struct {
 
 // ServiceResource Model describes the resource data model.
 type ServiceResourceModel struct {
-	ServiceName        types.String `tfsdk:"service_name"`
-	ProducerProjectId  types.String `tfsdk:"producer_project_id"`
-	DefaultTenancyUnit types.String `tfsdk:"default_tenancy_unit"`
+	ServiceName             types.String `tfsdk:"service_name"`
+	ProducerProjectId       types.String `tfsdk:"producer_project_id"`
+	Project                 types.String `tfsdk:"project"`
+	ValidateProducerProject types.Bool   `tfsdk:"validate_producer_project"`
+	EnableOnProducerProject types.Bool   `tfsdk:"enable_on_producer_project"`
+	DefaultTenancyUnit      types.String `tfsdk:"default_tenancy_unit"`
+	CreateOperation         types.String `tfsdk:"create_operation"`
+	DeleteOperation         types.String `tfsdk:"delete_operation"`
+	Endpoints               types.List   `tfsdk:"endpoints"`
+	Apis                    types.List   `tfsdk:"apis"`
 }
 
 func (r *ServiceResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -60,10 +83,52 @@ func (r *ServiceResource) Schema(ctx context.Context, req resource.SchemaRequest
 					stringplanmodifier.RequiresReplaceIfConfigured(),
 				},
 			},
+			"project": schema.StringAttribute{
+				MarkdownDescription: "The project to bill for API calls made for this service. Defaults to the provider `project_id`.",
+				Optional:            true,
+			},
+			"validate_producer_project": schema.BoolAttribute{
+				MarkdownDescription: "Whether to check that the producer project exists and has `servicemanagement.googleapis.com` enabled before creating the service. The check is skipped if the caller lacks the `resourcemanager.projects.get` permission on the producer project.",
+				Optional:            true,
+			},
+			"enable_on_producer_project": schema.BoolAttribute{
+				MarkdownDescription: "Whether to enable the service on the producer project after it is created. If enabling fails, the service is kept with a warning and the next apply retries enabling it. The service is disabled again before it is deleted.",
+				Optional:            true,
+			},
 			"default_tenancy_unit": schema.StringAttribute{
 				MarkdownDescription: "The tenancy unit assigned to the producer project which holds consumer projects/resources not yet assigned to Celest users.",
 				Computed:            true,
 			},
+			"create_operation": schema.StringAttribute{
+				MarkdownDescription: "The name of the long-running operation which created the service.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"delete_operation": schema.StringAttribute{
+				MarkdownDescription: "The name of the long-running operation which deleted the service, if a deletion was started but did not complete.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"endpoints": schema.ListAttribute{
+				MarkdownDescription: "The names of the endpoints in the currently rolled-out service config. Empty if the service has not been rolled out.",
+				Computed:            true,
+				ElementType:         types.StringType,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"apis": schema.ListAttribute{
+				MarkdownDescription: "The fully-qualified names of the APIs in the currently rolled-out service config. Empty if the service has not been rolled out.",
+				Computed:            true,
+				ElementType:         types.StringType,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
+			},
 		},
 	}
 }
@@ -87,6 +152,8 @@ func (r *ServiceResource) Configure(ctx context.Context, req resource.ConfigureR
 
 	r.ServiceManagerClient = clients.ServiceManagerClient
 	r.OperationsClient = clients.OperationsClient
+	r.ResourceManagerClient = clients.ResourceManagerClient
+	r.ServiceUsageClient = clients.ServiceUsageClient
 }
 
 func (r *ServiceResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -99,6 +166,15 @@ func (r *ServiceResource) Create(ctx context.Context, req resource.CreateRequest
 		return
 	}
 
+	ctx = r.withQuotaProject(ctx, data.Project)
+
+	if data.ValidateProducerProject.ValueBool() {
+		resp.Diagnostics.Append(r.validateProducerProject(ctx, data.ProducerProjectId.ValueString())...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
 	_, err := r.ServiceManagerClient.GetService(ctx, &servicemanagementpb.GetServiceRequest{
 		ServiceName: data.ServiceName.ValueString(),
 	})
@@ -123,9 +199,19 @@ func (r *ServiceResource) Create(ctx context.Context, req resource.CreateRequest
 		return
 	}
 
+	// Nothing populates the default tenancy unit or a delete operation yet.
+	data.DefaultTenancyUnit = types.StringNull()
+	data.DeleteOperation = types.StringNull()
+	// A new service has no rollouts.
+	data.Endpoints = types.ListValueMust(types.StringType, []attr.Value{})
+	data.Apis = types.ListValueMust(types.StringType, []attr.Value{})
+	data.CreateOperation = types.StringValue(serviceOp.Name())
+
 	service, err := serviceOp.Wait(ctx)
 	if err != nil {
 		resp.Diagnostics.AddError("Error creating service", err.Error())
+		// Save partial state so the in-flight operation can be tracked down.
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 		return
 	}
 
@@ -133,6 +219,19 @@ func (r *ServiceResource) Create(ctx context.Context, req resource.CreateRequest
 	data.ProducerProjectId = types.StringValue(service.ProducerProjectId)
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+
+	// The service has been created, so failing to enable it must not taint
+	// it, since a deleted service name cannot be reused for 30 days.
+	if data.EnableOnProducerProject.ValueBool() {
+		if err := r.enableService(ctx, data.ProducerProjectId.ValueString(), data.ServiceName.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeWarning(
+				path.Root("enable_on_producer_project"),
+				"Could not enable service on producer project",
+				fmt.Sprintf("Service %s was created, but could not be enabled on producer project %s. The next apply retries enabling it: %s", data.ServiceName.ValueString(), data.ProducerProjectId.ValueString(), err),
+			)
+			resp.Diagnostics.Append(resp.Private.SetKey(ctx, serviceEnablePendingKey, []byte("true"))...)
+		}
+	}
 }
 
 func (r *ServiceResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
@@ -145,8 +244,12 @@ func (r *ServiceResource) Read(ctx context.Context, req resource.ReadRequest, re
 		return
 	}
 
-	service, err := r.ServiceManagerClient.GetService(ctx, &servicemanagementpb.GetServiceRequest{
-		ServiceName: data.ServiceName.ValueString(),
+	ctx = r.withQuotaProject(ctx, data.Project)
+
+	service, err := retryTransient(ctx, func(ctx context.Context) (*servicemanagementpb.ManagedService, error) {
+		return r.ServiceManagerClient.GetService(ctx, &servicemanagementpb.GetServiceRequest{
+			ServiceName: data.ServiceName.ValueString(),
+		})
 	})
 
 	if err != nil {
@@ -158,14 +261,98 @@ func (r *ServiceResource) Read(ctx context.Context, req resource.ReadRequest, re
 	}
 
 	data.ServiceName = types.StringValue(service.ServiceName)
+
+	enablePending, diags := req.Private.GetKey(ctx, serviceEnablePendingKey)
+	resp.Diagnostics.Append(diags...)
+	if len(enablePending) > 0 {
+		data.EnableOnProducerProject = types.BoolValue(false)
+	}
+
+	// Record the producer project reported by the API, so that a service
+	// which has been recreated under a different project differs from the
+	// configuration and is replaced, rather than adopted.
+	if actual := service.ProducerProjectId; !data.ProducerProjectId.IsNull() && actual != data.ProducerProjectId.ValueString() {
+		resp.Diagnostics.AddAttributeWarning(
+			path.Root("producer_project_id"),
+			"Producer project has changed",
+			fmt.Sprintf("Service %s belongs to producer project %q, but %q was recorded. The service will be replaced unless the configuration is updated to match.",
+				service.ServiceName, actual, data.ProducerProjectId.ValueString()),
+		)
+	}
 	data.ProducerProjectId = types.StringValue(service.ProducerProjectId)
 
+	// The endpoints and APIs are informational, so a failure to look them
+	// up keeps their previous values rather than blocking the refresh.
+	config, err := r.getActiveServiceConfig(ctx, service.ServiceName)
+	if err != nil {
+		resp.Diagnostics.AddWarning(
+			"Could not retrieve active service config",
+			fmt.Sprintf("The endpoints and APIs of service %s were not refreshed: %s", service.ServiceName, err),
+		)
+	} else {
+		// Leave the lists empty rather than null if there is no active config.
+		endpoints := make([]string, 0, len(config.GetEndpoints()))
+		for _, endpoint := range config.GetEndpoints() {
+			endpoints = append(endpoints, endpoint.GetName())
+		}
+		apis := make([]string, 0, len(config.GetApis()))
+		for _, api := range config.GetApis() {
+			apis = append(apis, api.GetName())
+		}
+
+		var diags diag.Diagnostics
+		data.Endpoints, diags = types.ListValueFrom(ctx, types.StringType, endpoints)
+		resp.Diagnostics.Append(diags...)
+		data.Apis, diags = types.ListValueFrom(ctx, types.StringType, apis)
+		resp.Diagnostics.Append(diags...)
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *ServiceResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	panic("Updating a service is not supported")
+	var data ServiceResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state ServiceResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// All other attributes require replacement, so only the attributes which
+	// are not stored on the service itself can change here.
+	data.DefaultTenancyUnit = state.DefaultTenancyUnit
+
+	ctx = r.withQuotaProject(ctx, data.Project)
+
+	switch enable := data.EnableOnProducerProject.ValueBool(); {
+	case enable && !state.EnableOnProducerProject.ValueBool():
+		if err := r.enableService(ctx, data.ProducerProjectId.ValueString(), data.ServiceName.ValueString()); err != nil {
+			resp.Diagnostics.AddError("Error enabling service on producer project", err.Error())
+			return
+		}
+	case !enable && state.EnableOnProducerProject.ValueBool():
+		if err := r.disableService(ctx, data.ProducerProjectId.ValueString(), data.ServiceName.ValueString()); err != nil {
+			resp.Diagnostics.AddError("Error disabling service on producer project", err.Error())
+			return
+		}
+	}
+
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, serviceEnablePendingKey, nil)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *ServiceResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
@@ -178,6 +365,15 @@ func (r *ServiceResource) Delete(ctx context.Context, req resource.DeleteRequest
 		return
 	}
 
+	ctx = r.withQuotaProject(ctx, data.Project)
+
+	if data.EnableOnProducerProject.ValueBool() {
+		if err := r.disableService(ctx, data.ProducerProjectId.ValueString(), data.ServiceName.ValueString()); err != nil {
+			resp.Diagnostics.AddError("Error disabling service on producer project", err.Error())
+			return
+		}
+	}
+
 	op, err := r.ServiceManagerClient.DeleteService(ctx, &servicemanagementpb.DeleteServiceRequest{
 		ServiceName: data.ServiceName.ValueString(),
 	})
@@ -187,8 +383,12 @@ func (r *ServiceResource) Delete(ctx context.Context, req resource.DeleteRequest
 		return
 	}
 
+	data.DeleteOperation = types.StringValue(op.Name())
+
 	if err := op.Wait(ctx); err != nil {
 		resp.Diagnostics.AddError("Error deleting service", err.Error())
+		// Keep the resource in state so the in-flight operation can be tracked down.
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 		return
 	}
 
@@ -198,3 +398,156 @@ func (r *ServiceResource) Delete(ctx context.Context, req resource.DeleteRequest
 func (r *ServiceResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
 	resource.ImportStatePassthroughID(ctx, path.Root("service_name"), req, resp)
 }
+
+// validateProducerProject checks that the producer project exists and has the
+// Service Management API enabled, so that misconfigurations are reported
+// against `producer_project_id` instead of as an opaque error from CreateService.
+func (p *UtilsProviderConfig) validateProducerProject(ctx context.Context, projectID string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	attrPath := path.Root("producer_project_id")
+
+	project, err := retryTransient(ctx, func(ctx context.Context) (*cloudresourcemanager.Project, error) {
+		return p.ResourceManagerClient.Projects.Get("projects/" + projectID).Context(ctx).Do()
+	})
+	switch {
+	case isGoogleAPIErrorCode(err, http.StatusForbidden):
+		tflog.Warn(ctx, "Skipping producer project validation, since the caller cannot get the project", map[string]interface{}{
+			"producer_project_id": projectID,
+			"error":               err.Error(),
+		})
+		return diags
+	case isGoogleAPIErrorCode(err, http.StatusNotFound):
+		diags.AddAttributeError(
+			attrPath,
+			"Producer project not found",
+			fmt.Sprintf("Project %q does not exist. Set `validate_producer_project = false` to skip this check.", projectID),
+		)
+		return diags
+	case err != nil:
+		diags.AddAttributeError(attrPath, "Error getting producer project", err.Error())
+		return diags
+	}
+	if project.State != "ACTIVE" {
+		diags.AddAttributeError(
+			attrPath,
+			"Producer project is not active",
+			fmt.Sprintf("Project %q is in state %s.", projectID, project.State),
+		)
+		return diags
+	}
+
+	service, err := p.ServiceUsageClient.Services.Get(fmt.Sprintf("projects/%s/services/%s", projectID, serviceManagementAPI)).Context(ctx).Do()
+	if err != nil {
+		diags.AddAttributeError(attrPath, "Error checking producer project services", err.Error())
+		return diags
+	}
+	if service.State != "ENABLED" {
+		diags.AddAttributeError(
+			attrPath,
+			"Service Management API not enabled",
+			fmt.Sprintf("%s must be enabled on project %q before creating services in it.", serviceManagementAPI, projectID),
+		)
+	}
+
+	return diags
+}
+
+// enableService enables serviceName on the given project and waits for the
+// operation to complete.
+func (p *UtilsProviderConfig) enableService(ctx context.Context, projectID, serviceName string) error {
+	name := fmt.Sprintf("projects/%s/services/%s", projectID, serviceName)
+	op, err := p.ServiceUsageClient.Services.Enable(name, &serviceusage.EnableServiceRequest{}).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	return p.waitServiceUsageOperation(ctx, op)
+}
+
+// disableService disables serviceName on the given project and waits for the
+// operation to complete.
+func (p *UtilsProviderConfig) disableService(ctx context.Context, projectID, serviceName string) error {
+	name := fmt.Sprintf("projects/%s/services/%s", projectID, serviceName)
+	op, err := p.ServiceUsageClient.Services.Disable(name, &serviceusage.DisableServiceRequest{}).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	return p.waitServiceUsageOperation(ctx, op)
+}
+
+// serviceUsageOperationTimeout is how long to wait for a Service Usage
+// operation to complete.
+var serviceUsageOperationTimeout = 30 * time.Minute
+
+// waitServiceUsageOperation waits for a Service Usage operation to complete
+// and returns its error, if any.
+func (p *UtilsProviderConfig) waitServiceUsageOperation(ctx context.Context, op *serviceusage.Operation) error {
+	name := op.Name
+	done := func(op *serviceusage.Operation) bool { return op.Done }
+	op, err := pollOperation(ctx, serviceUsageOperationTimeout, op, done, func(ctx context.Context) (*serviceusage.Operation, error) {
+		return p.ServiceUsageClient.Operations.Get(name).Context(ctx).Do()
+	})
+	if err != nil {
+		return err
+	}
+	if op.Error != nil {
+		return fmt.Errorf("operation %s failed: %s", op.Name, op.Error.Message)
+	}
+	return nil
+}
+
+// getLatestSuccessfulRollout returns the latest successful rollout of the
+// service, or nil if the service has not been rolled out.
+func (p *UtilsProviderConfig) getLatestSuccessfulRollout(ctx context.Context, serviceName string) (*servicemanagementpb.Rollout, error) {
+	rollout, err := retryTransient(ctx, func(ctx context.Context) (*servicemanagementpb.Rollout, error) {
+		return p.ServiceManagerClient.ListServiceRollouts(ctx, &servicemanagementpb.ListServiceRolloutsRequest{
+			ServiceName: serviceName,
+			Filter:      "status=SUCCESS",
+			PageSize:    1,
+		}).Next()
+	})
+	if err == iterator.Done {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rollout, nil
+}
+
+// dominantConfigId returns the ID of the config which is serving the most
+// traffic, preferring the latest config ID on ties, or "" if percentages is
+// empty.
+func dominantConfigId(percentages map[string]float64) string {
+	var configId string
+	var maxPercentage float64
+	for id, percentage := range percentages {
+		if configId == "" || percentage > maxPercentage || (percentage == maxPercentage && id > configId) {
+			configId = id
+			maxPercentage = percentage
+		}
+	}
+	return configId
+}
+
+// getActiveServiceConfig returns the config which is serving the most traffic
+// in the latest successful rollout of the service, or nil if the service has
+// not been rolled out.
+func (p *UtilsProviderConfig) getActiveServiceConfig(ctx context.Context, serviceName string) (*serviceconfig.Service, error) {
+	rollout, err := p.getLatestSuccessfulRollout(ctx, serviceName)
+	if rollout == nil || err != nil {
+		return nil, err
+	}
+
+	configId := dominantConfigId(rollout.GetTrafficPercentStrategy().GetPercentages())
+	if configId == "" {
+		return nil, nil
+	}
+
+	return retryTransient(ctx, func(ctx context.Context) (*serviceconfig.Service, error) {
+		return p.ServiceManagerClient.GetServiceConfig(ctx, &servicemanagementpb.GetServiceConfigRequest{
+			ServiceName: serviceName,
+			ConfigId:    configId,
+			View:        servicemanagementpb.GetServiceConfigRequest_BASIC,
+		})
+	})
+}
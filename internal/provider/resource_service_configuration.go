@@ -2,22 +2,60 @@ package provider
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"time"
 
 	"cloud.google.com/go/servicemanagement/apiv1/servicemanagementpb"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/resourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"gopkg.in/yaml.v3"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &ServiceConfigResource{}
 var _ resource.ResourceWithImportState = &ServiceConfigResource{}
+var _ resource.ResourceWithUpgradeState = &ServiceConfigResource{}
+var _ resource.ResourceWithConfigValidators = &ServiceConfigResource{}
+var _ resource.ResourceWithModifyPlan = &ServiceConfigResource{}
+
+// importedKey is the private state key which is set on imported configs until
+// they are first updated.
+const importedKey = "imported"
+
+// The default paths of the submitted config and descriptor files.
+const (
+	defaultConfigYamlPath          = "service.yaml"
+	defaultProtoDescriptorPathName = "descriptor.pb"
+)
 
 func NewServiceConfigResource() resource.Resource {
 	return &ServiceConfigResource{}
@@ -29,12 +67,62 @@ type ServiceConfigResource struct {
 }
 
 type ServiceConfigResourceModel struct {
+	Id                      types.String         `tfsdk:"id"`
+	ServiceName             types.String         `tfsdk:"service_name"`
+	ConfigYaml              YAMLStringValue      `tfsdk:"config_yaml"`
+	ConfigJson              YAMLStringValue      `tfsdk:"config_json"`
+	ConfigFiles             types.List           `tfsdk:"config_files"`
+	ConfigYamlPath          types.String         `tfsdk:"config_yaml_path"`
+	UseExistingConfigId     types.String         `tfsdk:"use_existing_config_id"`
+	ProtoDescriptor         ProtoDescriptorValue `tfsdk:"proto_descriptor"`
+	ProtoDescriptorBase64   ProtoDescriptorValue `tfsdk:"proto_descriptor_base64"`
+	ProtoDescriptorBase64Wo types.String         `tfsdk:"proto_descriptor_base64_wo"`
+	ProtoDescriptorHash     types.String         `tfsdk:"proto_descriptor_hash"`
+	ProtoDescriptorPath     types.String         `tfsdk:"proto_descriptor_path"`
+	ProtoDescriptorsBase64  types.List           `tfsdk:"proto_descriptors_base64"`
+	ProtoDescriptorSha256   types.String         `tfsdk:"proto_descriptor_sha256"`
+	ProtoDescriptorPathName types.String         `tfsdk:"proto_descriptor_path_name"`
+	ValidateDuringPlan      types.Bool           `tfsdk:"validate_during_plan"`
+	ConfigId                types.String         `tfsdk:"config_id"`
+	Name                    types.String         `tfsdk:"name"`
+	Title                   types.String         `tfsdk:"title"`
+	CreateTime              types.String         `tfsdk:"create_time"`
+	PreviousConfigId        types.String         `tfsdk:"previous_config_id"`
+	Triggers                types.Map            `tfsdk:"triggers"`
+	StoreContentsInState    types.Bool           `tfsdk:"store_contents_in_state"`
+}
+
+// ServiceConfigResourceModelV0 describes the resource data model at schema version 0.
+type ServiceConfigResourceModelV0 struct {
 	Id                    types.String `tfsdk:"id"`
 	ServiceName           types.String `tfsdk:"service_name"`
 	ConfigYaml            types.String `tfsdk:"config_yaml"`
 	ProtoDescriptorBase64 types.String `tfsdk:"proto_descriptor_base64"`
 }
 
+// ServiceConfigFileModel describes a single config file submitted as part of
+// the config source.
+type ServiceConfigFileModel struct {
+	Path     types.String    `tfsdk:"path"`
+	Contents YAMLStringValue `tfsdk:"contents"`
+	Type     types.String    `tfsdk:"type"`
+}
+
+func (ServiceConfigFileModel) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"path":     types.StringType,
+		"contents": YAMLStringType{},
+		"type":     types.StringType,
+	}
+}
+
+// configFileTypes are the values accepted for the `type` of a config file.
+var configFileTypes = []string{
+	servicemanagementpb.ConfigFile_SERVICE_CONFIG_YAML.String(),
+	servicemanagementpb.ConfigFile_OPEN_API_YAML.String(),
+	servicemanagementpb.ConfigFile_OPEN_API_JSON.String(),
+}
+
 func (r *ServiceConfigResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
 	resp.TypeName = req.ProviderTypeName + "_service_config"
 }
@@ -43,6 +131,7 @@ func (r *ServiceConfigResource) Schema(ctx context.Context, req resource.SchemaR
 	resp.Schema = schema.Schema{
 		// This description is used by the documentation generator and the language server.
 		MarkdownDescription: "A service manager service.",
+		Version:             3,
 
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
@@ -57,18 +146,401 @@ func (r *ServiceConfigResource) Schema(ctx context.Context, req resource.SchemaR
 				},
 			},
 			"config_yaml": schema.StringAttribute{
-				MarkdownDescription: "The service config in YAML format.",
-				Required:            true,
+				MarkdownDescription: "The service config in YAML format. Only one of `config_yaml`, `config_json` or `config_files` can be specified. Configs which were not created from source files, such as those pushed by gcloud, are read into `config_yaml` from the normalized config.",
+				CustomType:          YAMLStringType{},
+				Optional:            true,
+				DeprecationMessage:  "Use `config_files` instead.",
+				Validators: []validator.String{
+					serviceConfigYAMLValidator{},
+				},
+			},
+			"config_json": schema.StringAttribute{
+				MarkdownDescription: "The service config in JSON format. The API has no JSON file type, so the config is submitted as the YAML file `service.json`, which works because JSON is valid YAML. Only one of `config_yaml`, `config_json` or `config_files` can be specified.",
+				// JSON is valid YAML, so YAML semantic equality applies.
+				CustomType: YAMLStringType{},
+				Optional:   true,
+			},
+			"config_yaml_path": schema.StringAttribute{
+				MarkdownDescription: fmt.Sprintf("The path under which `config_yaml` is submitted, for example to match the paths referenced by the config. Defaults to `%s`.", defaultConfigYamlPath),
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(defaultConfigYamlPath),
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+			"config_files": schema.ListNestedAttribute{
+				MarkdownDescription: "The service config files. Only one of `config_yaml`, `config_json` or `config_files` can be specified.",
+				Optional:            true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"path": schema.StringAttribute{
+							MarkdownDescription: "The file path, for example `service.yaml`. Must be unique within the config.",
+							Required:            true,
+						},
+						"contents": schema.StringAttribute{
+							MarkdownDescription: "The contents of the file.",
+							CustomType:          YAMLStringType{},
+							Required:            true,
+						},
+						"type": schema.StringAttribute{
+							MarkdownDescription: "The type of the file. One of `SERVICE_CONFIG_YAML`, `OPEN_API_YAML` or `OPEN_API_JSON`. Defaults to `SERVICE_CONFIG_YAML`.",
+							Optional:            true,
+							Computed:            true,
+							Default:             stringdefault.StaticString(servicemanagementpb.ConfigFile_SERVICE_CONFIG_YAML.String()),
+							Validators: []validator.String{
+								stringvalidator.OneOf(configFileTypes...),
+							},
+						},
+					},
+				},
+			},
+			"use_existing_config_id": schema.StringAttribute{
+				MarkdownDescription: "The ID of an existing config to track instead of submitting one, for example `2024-08-01r0`. The config must exist. Cannot be specified together with `config_yaml`, `config_json`, `config_files` or any of the proto descriptor attributes. Changing the ID adopts the new config, and unsetting it submits a config from the contents instead.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplaceIfConfigured(),
+				},
+			},
+			"proto_descriptor": schema.StringAttribute{
+				MarkdownDescription: "The base64-encoded proto descriptor. The descriptor is stored in state, so prefer `proto_descriptor_base64_wo` or `proto_descriptor_path` for large descriptors. Only one of `proto_descriptor`, `proto_descriptor_base64_wo`, `proto_descriptor_path` or `proto_descriptors_base64` can be specified.",
+				CustomType:          ProtoDescriptorType{},
+				Optional:            true,
+				// Computed so that the deprecated `proto_descriptor_base64`
+				// can be aliased to it.
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					protoDescriptorAliasModifier{},
+				},
 			},
 			"proto_descriptor_base64": schema.StringAttribute{
-				MarkdownDescription: "The base64-encoded proto descriptor.",
-				Required:            true,
-				Sensitive:           true, // Not sensitive but suppress from output
+				MarkdownDescription: "Deprecated alias of `proto_descriptor`.",
+				CustomType:          ProtoDescriptorType{},
+				Optional:            true,
+				DeprecationMessage:  "Use `proto_descriptor` instead.",
+			},
+			"proto_descriptor_base64_wo": schema.StringAttribute{
+				MarkdownDescription: "The base64-encoded proto descriptor. This value is write-only: it is sent to the API but never stored in state, and only its hash is tracked in `proto_descriptor_sha256`. Requires Terraform 1.11 or later and must be set together with `proto_descriptor_hash`. Only one of `proto_descriptor`, `proto_descriptor_base64_wo`, `proto_descriptor_path` or `proto_descriptors_base64` can be specified.",
+				Optional:            true,
+				WriteOnly:           true,
+			},
+			"proto_descriptor_hash": schema.StringAttribute{
+				MarkdownDescription: "A value which identifies the descriptor in `proto_descriptor_base64_wo`, typically its hash, for example `sha256(var.descriptor)`. A new config is submitted whenever it changes. Must be set together with `proto_descriptor_base64_wo`.",
+				Optional:            true,
+			},
+			"proto_descriptor_path": schema.StringAttribute{
+				MarkdownDescription: "The path to a file containing the proto descriptor set. The file is read when the config is submitted and only its hash is stored in state. Only one of `proto_descriptor`, `proto_descriptor_base64_wo`, `proto_descriptor_path` or `proto_descriptors_base64` can be specified.",
+				Optional:            true,
+			},
+			"proto_descriptor_path_name": schema.StringAttribute{
+				MarkdownDescription: fmt.Sprintf("The path under which the proto descriptor is submitted. Defaults to `%s`.", defaultProtoDescriptorPathName),
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(defaultProtoDescriptorPathName),
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+			"proto_descriptors_base64": schema.ListAttribute{
+				MarkdownDescription: "A list of base64-encoded proto descriptor sets, for example one per proto package, which are merged into a single descriptor set when the config is submitted. Files which appear in several sets must be identical. Only the hash of the merged set is tracked in `proto_descriptor_sha256`. Only one of `proto_descriptor`, `proto_descriptor_base64_wo`, `proto_descriptor_path` or `proto_descriptors_base64` can be specified.",
+				ElementType:         types.StringType,
+				Optional:            true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+			},
+			"proto_descriptor_sha256": schema.StringAttribute{
+				MarkdownDescription: "The hex-encoded SHA-256 hash of the proto descriptor.",
+				Computed:            true,
+			},
+			"config_id": schema.StringAttribute{
+				MarkdownDescription: "The ID of the config, without the service name.",
+				Computed:            true,
+			},
+			"name": schema.StringAttribute{
+				MarkdownDescription: "The service name recorded in the config.",
+				Computed:            true,
+			},
+			"title": schema.StringAttribute{
+				MarkdownDescription: "The title of the service recorded in the config.",
+				Computed:            true,
+			},
+			"create_time": schema.StringAttribute{
+				MarkdownDescription: "The time the config was submitted, in RFC 3339 format. The API does not return the submission time of configs, so it is not available for imported or adopted configs.",
+				Computed:            true,
+			},
+			"previous_config_id": schema.StringAttribute{
+				MarkdownDescription: "The ID of the config which this config replaced, without the service name, for example to roll back with `utils_service_rollout`. Known during plan whenever a new config is submitted, and null for the first config.",
+				Computed:            true,
+			},
+			"triggers": schema.MapAttribute{
+				MarkdownDescription: "Arbitrary values which, when changed, cause a new config to be submitted, like the `triggers` of `null_resource`. Use this to resubmit the config when something it references indirectly changes. Setting `triggers` on an imported config does not submit a new one.",
+				ElementType:         types.StringType,
+				Optional:            true,
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplaceIf(
+						triggersRequireReplace,
+						"Changing the triggers submits a new config, unless the config was imported.",
+						"Changing the triggers submits a new config, unless the config was imported.",
+					),
+				},
+			},
+			"store_contents_in_state": schema.BoolAttribute{
+				MarkdownDescription: "Whether to store the contents of `config_yaml`, `config_json`, `config_files` and `proto_descriptor` in state. If `false`, only their SHA-256 hashes are stored and changes are detected by comparing hashes. Terraform always stores configured values when a config is submitted, so the hashes replace them the next time the resource is refreshed. Defaults to `true`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+			},
+			"validate_during_plan": schema.BoolAttribute{
+				MarkdownDescription: "Whether to validate the config with the API when planning changes, so that invalid configs are reported by `terraform plan` rather than `terraform apply`. Validation is skipped while any of the config's values are unknown. Since Terraform plans again during apply, a config may be validated more than once.",
+				Optional:            true,
+			},
+		},
+	}
+}
+
+// ConfigValidators implements resource.ResourceWithConfigValidators.
+func (r *ServiceConfigResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{
+		// Adopted configs have neither contents nor a descriptor.
+		resourcevalidator.ExactlyOneOf(path.MatchRoot("config_yaml"), path.MatchRoot("config_json"), path.MatchRoot("config_files"), path.MatchRoot("use_existing_config_id")),
+		resourcevalidator.ExactlyOneOf(path.MatchRoot("proto_descriptor"), path.MatchRoot("proto_descriptor_base64"), path.MatchRoot("proto_descriptor_base64_wo"), path.MatchRoot("proto_descriptor_path"), path.MatchRoot("proto_descriptors_base64"), path.MatchRoot("use_existing_config_id")),
+		resourcevalidator.RequiredTogether(path.MatchRoot("proto_descriptor_base64_wo"), path.MatchRoot("proto_descriptor_hash")),
+	}
+}
+
+// ModifyPlan implements resource.ResourceWithModifyPlan.
+func (r *ServiceConfigResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to do on destroy.
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var data ServiceConfigResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	// Write-only values are only available in the config.
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("proto_descriptor_base64_wo"), &data.ProtoDescriptorBase64Wo)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Hash the descriptor at plan time so that changes to a descriptor file
+	// produce a diff, even though only the hash is stored in state.
+	var descriptor []byte
+	hash := types.StringUnknown()
+	switch {
+	case !data.UseExistingConfigId.IsNull():
+		// Adopted configs have no descriptor to hash.
+		hash = types.StringNull()
+	case data.ProtoDescriptor.IsUnknown() || data.ProtoDescriptorBase64Wo.IsUnknown() || data.ProtoDescriptorPath.IsUnknown() || data.ProtoDescriptorsBase64.IsUnknown():
+	case !data.ProtoDescriptorPath.IsNull():
+		var err error
+		descriptor, err = readProtoDescriptor(data.ProtoDescriptorPath.ValueString())
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			// The file may be created by another resource during apply.
+		case err != nil:
+			resp.Diagnostics.AddAttributeError(path.Root("proto_descriptor_path"), "Invalid proto descriptor", err.Error())
+			return
+		default:
+			hash = types.StringValue(sha256Hex(descriptor))
+		}
+	case !data.ProtoDescriptorsBase64.IsNull():
+		descriptorSets, known := data.protoDescriptorSets(ctx, &resp.Diagnostics)
+		if resp.Diagnostics.HasError() || !known {
+			break
+		}
+		var err error
+		descriptor, err = mergeProtoDescriptors(descriptorSets)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("proto_descriptors_base64"), "Invalid proto descriptor", err.Error())
+			return
+		}
+		hash = types.StringValue(sha256Hex(descriptor))
+	default:
+		var err error
+		descriptor, err = base64.StdEncoding.DecodeString(data.protoDescriptorBase64())
+		if err == nil {
+			hash = types.StringValue(sha256Hex(descriptor))
+		}
+	}
+
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("proto_descriptor_sha256"), hash)...)
+
+	if !req.State.Raw.IsNull() {
+		var state ServiceConfigResourceModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		if !data.storeContents() {
+			planned := data
+			planned.keepContentHashes(ctx, state, &resp.Diagnostics)
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("config_yaml"), planned.ConfigYaml)...)
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("config_json"), planned.ConfigJson)...)
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("config_files"), planned.ConfigFiles)...)
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("proto_descriptor"), planned.ProtoDescriptor)...)
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("proto_descriptor_base64"), planned.ProtoDescriptorBase64)...)
+		}
+
+		// Configs are only resubmitted when their contents change, so keep
+		// the attributes of the existing config otherwise.
+		data.ProtoDescriptorSha256 = hash
+		if len(resp.RequiresReplace) == 0 && !data.configChanged(ctx, state, &resp.Diagnostics) {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("id"), state.Id)...)
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("config_id"), state.ConfigId)...)
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("name"), state.Name)...)
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("title"), state.Title)...)
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("create_time"), state.CreateTime)...)
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("previous_config_id"), state.PreviousConfigId)...)
+		} else {
+			// The current config, including when the resource is replaced.
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("previous_config_id"), state.ConfigId)...)
+		}
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	} else {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("previous_config_id"), types.StringNull())...)
+	}
+
+	// Only validate configs which are about to be submitted.
+	unchanged := resp.Plan.Raw.Equal(req.State.Raw)
+	if unchanged || !data.ValidateDuringPlan.ValueBool() || hash.IsUnknown() || !data.UseExistingConfigId.IsNull() ||
+		data.ServiceName.IsUnknown() || data.ConfigYaml.IsUnknown() || data.ConfigJson.IsUnknown() || data.ConfigFiles.IsUnknown() || r.ServiceManagerClient == nil {
+		return
+	}
+
+	files := data.configFiles(ctx, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() || files == nil {
+		// Individual config files may still be unknown.
+		return
+	}
+
+	_, _, err := r.createConfig(ctx, data.ServiceName.ValueString(), data.protoDescriptorPathName(), descriptor, files, true)
+	if err != nil {
+		attrPath := path.Root("config_files")
+		switch {
+		case !data.ConfigYaml.IsNull():
+			attrPath = path.Root("config_yaml")
+		case !data.ConfigJson.IsNull():
+			attrPath = path.Root("config_json")
+		}
+		resp.Diagnostics.AddAttributeError(attrPath, "Invalid service config", err.Error())
+	}
+}
+
+// protoDescriptorAliasModifier plans `proto_descriptor` from the deprecated
+// `proto_descriptor_base64` when only the latter is configured, and as null
+// when neither is, since the descriptor is then given by another attribute.
+type protoDescriptorAliasModifier struct{}
+
+func (m protoDescriptorAliasModifier) Description(ctx context.Context) string {
+	return "Defaults to the value of `proto_descriptor_base64`."
+}
+
+func (m protoDescriptorAliasModifier) MarkdownDescription(ctx context.Context) string {
+	return m.Description(ctx)
+}
+
+func (m protoDescriptorAliasModifier) PlanModifyString(ctx context.Context, req planmodifier.StringRequest, resp *planmodifier.StringResponse) {
+	if !req.ConfigValue.IsNull() {
+		return
+	}
+	var alias ProtoDescriptorValue
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("proto_descriptor_base64"), &alias)...)
+	resp.PlanValue = alias.StringValue
+}
+
+// triggersRequireReplace requires replacement when the triggers change, except
+// when they are first set on an imported config.
+func triggersRequireReplace(ctx context.Context, req planmodifier.MapRequest, resp *mapplanmodifier.RequiresReplaceIfFuncResponse) {
+	if req.StateValue.IsNull() {
+		imported, diags := req.Private.GetKey(ctx, importedKey)
+		resp.Diagnostics.Append(diags...)
+		if len(imported) > 0 {
+			return
+		}
+	}
+	resp.RequiresReplace = true
+}
+
+// UpgradeState implements resource.ResourceWithUpgradeState.
+func (r *ServiceConfigResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		// Version 1 added `config_files`. Existing resources keep using `config_yaml`.
+		0: {
+			PriorSchema: &schema.Schema{
+				Attributes: map[string]schema.Attribute{
+					"id":                      schema.StringAttribute{Computed: true},
+					"service_name":            schema.StringAttribute{Required: true},
+					"config_yaml":             schema.StringAttribute{Required: true},
+					"proto_descriptor_base64": schema.StringAttribute{Required: true},
+				},
 			},
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				var prior ServiceConfigResourceModelV0
+				resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+				if resp.Diagnostics.HasError() {
+					return
+				}
+
+				upgraded := ServiceConfigResourceModel{
+					Id:                      prior.Id,
+					ServiceName:             prior.ServiceName,
+					ConfigYaml:              YAMLStringValue{StringValue: prior.ConfigYaml},
+					ConfigFiles:             types.ListNull(types.ObjectType{AttrTypes: ServiceConfigFileModel{}.AttributeTypes()}),
+					ProtoDescriptor:         ProtoDescriptorValue{StringValue: prior.ProtoDescriptorBase64},
+					ProtoDescriptorBase64:   ProtoDescriptorValue{StringValue: prior.ProtoDescriptorBase64},
+					ProtoDescriptorsBase64:  types.ListNull(types.StringType),
+					Triggers:                types.MapNull(types.StringType),
+					StoreContentsInState:    types.BoolValue(true),
+					ConfigYamlPath:          types.StringValue(defaultConfigYamlPath),
+					ProtoDescriptorPathName: types.StringValue(defaultProtoDescriptorPathName),
+				}
+				resp.Diagnostics.Append(resp.State.Set(ctx, &upgraded)...)
+			},
+		},
+		// Version 2 removed `Sensitive` from `proto_descriptor_base64` in
+		// favour of a type which renders descriptors by their hash. The stored
+		// values are unchanged.
+		1: {
+			StateUpgrader: upgradeProtoDescriptorState,
+		},
+		// Version 3 renamed `proto_descriptor_base64` to `proto_descriptor`.
+		2: {
+			StateUpgrader: upgradeProtoDescriptorState,
 		},
 	}
 }
 
+// upgradeProtoDescriptorState copies `proto_descriptor_base64` to
+// `proto_descriptor` in raw state. The deprecated attribute is kept so that
+// configurations which still use it have no changes.
+func upgradeProtoDescriptorState(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal(req.RawState.JSON, &state); err != nil {
+		resp.Diagnostics.AddError("Could not upgrade state", err.Error())
+		return
+	}
+	if descriptor, ok := state["proto_descriptor_base64"]; ok {
+		state["proto_descriptor"] = descriptor
+	}
+	upgraded, err := json.Marshal(state)
+	if err != nil {
+		resp.Diagnostics.AddError("Could not upgrade state", err.Error())
+		return
+	}
+	resp.DynamicValue = &tfprotov6.DynamicValue{JSON: upgraded}
+}
+
 func (r *ServiceConfigResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
 	if req.ProviderData == nil {
 		return
@@ -85,6 +557,7 @@ func (r *ServiceConfigResource) Configure(ctx context.Context, req resource.Conf
 
 	r.ServiceManagerClient = config.ServiceManagerClient
 	r.OperationsClient = config.OperationsClient
+	r.SubmitRetryAttempts = config.SubmitRetryAttempts
 }
 
 // Create implements resource.Resource.
@@ -93,21 +566,41 @@ func (r *ServiceConfigResource) Create(ctx context.Context, req resource.CreateR
 
 	// This will populate the data struct with the values from the plan.
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("proto_descriptor_base64_wo"), &data.ProtoDescriptorBase64Wo)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !data.UseExistingConfigId.IsNull() {
+		r.adoptConfig(ctx, &data, &resp.Diagnostics)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
 
+	files := data.configFiles(ctx, &resp.Diagnostics)
+	descriptor := data.protoDescriptor(ctx, &resp.Diagnostics)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	output, err := r.createConfig(ctx, data.ServiceName.ValueString(), data.ProtoDescriptorBase64.ValueString(), data.ConfigYaml.ValueString())
+	config, createTime, err := r.createConfig(ctx, data.ServiceName.ValueString(), data.protoDescriptorPathName(), descriptor, files, false)
 	if err != nil {
 		resp.Diagnostics.AddError("Could not submit configuration source", err.Error())
 		return
 	}
 
-	data.Id = newConfigId(output.ServiceConfig.GetName(), output.ServiceConfig.GetId())
+	data.setConfig(config)
+	data.CreateTime = types.StringValue(createTime.Format(time.RFC3339))
 
-	// Save created data into Terraform state
+	// Save created data into Terraform state before reporting the
+	// diagnostics of the config, so that it is tracked even if they fail the
+	// apply.
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	resp.Diagnostics.Append(r.configDiagnostics(ctx, config)...)
 }
 
 // Read implements resource.Resource.
@@ -129,23 +622,80 @@ func (r *ServiceConfigResource) Read(ctx context.Context, req resource.ReadReque
 		"service_name": serviceName,
 		"config_id":    configId,
 	})
-	config, err := r.ServiceManagerClient.GetServiceConfig(ctx, &servicemanagementpb.GetServiceConfigRequest{
-		ServiceName: serviceName,
-		ConfigId:    configId,
-		View:        servicemanagementpb.GetServiceConfigRequest_FULL,
+	config, err := retryTransient(ctx, func(ctx context.Context) (*serviceconfig.Service, error) {
+		return r.ServiceManagerClient.GetServiceConfig(ctx, &servicemanagementpb.GetServiceConfigRequest{
+			ServiceName: serviceName,
+			ConfigId:    configId,
+			View:        servicemanagementpb.GetServiceConfigRequest_FULL,
+		})
 	})
 
 	if err != nil {
+		if isNotFound(err) {
+			tflog.Info(ctx, "Service config not found, removing from state", map[string]interface{}{
+				"service_name": serviceName,
+				"config_id":    configId,
+			})
+			resp.State.RemoveResource(ctx)
+			return
+		}
 		resp.Diagnostics.AddError("Could not retrieve configuration for service", err.Error())
 		return
 	}
 
 	tflog.Debug(ctx, "Retrieved service config")
 
-	data.Id = newConfigId(config.Name, config.Id)
+	data.setConfig(config)
 	data.ServiceName = types.StringValue(config.Name)
 
+	// Adopted configs are only tracked by their ID.
+	if !data.UseExistingConfigId.IsNull() {
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+
+	// Imported resources have none of these attributes set, so prefer
+	// `config_files`.
+	useConfigFiles := data.ConfigYaml.IsNull() && data.ConfigJson.IsNull()
+	var configFiles []*servicemanagementpb.ConfigFile
+
 	sourceFiles := config.GetSourceInfo().GetSourceFiles()
+	if len(sourceFiles) == 0 {
+		// Configs pushed by gcloud or directly through the API, e.g. with
+		// PushServiceConfig, have no source files, so fall back to the
+		// normalized config.
+		tflog.Debug(ctx, "Service config has no source files, serializing config")
+		contents, err := serviceConfigYAML(config)
+		if err != nil {
+			resp.Diagnostics.AddError("Could not serialize service config", err.Error())
+			return
+		}
+		if data.ConfigJson.IsNull() {
+			data.ConfigYaml = NewYAMLStringValue(data.storedContents(contents))
+		} else {
+			data.ConfigJson = NewYAMLStringValue(data.storedContents(contents))
+		}
+		data.ConfigFiles = types.ListNull(types.ObjectType{AttrTypes: ServiceConfigFileModel{}.AttributeTypes()})
+		data.ProtoDescriptor = ProtoDescriptorValue{StringValue: types.StringNull()}
+		data.ProtoDescriptorBase64 = ProtoDescriptorValue{StringValue: types.StringNull()}
+		data.ProtoDescriptorSha256 = types.StringNull()
+		resp.Diagnostics.AddWarning(
+			"Service config has no source files",
+			fmt.Sprintf("Service config %s was not created from source files, for example because it was pushed by gcloud or "+
+				"directly through the API, so `config_yaml` was populated from the normalized config and the proto descriptor "+
+				"could not be recovered. The next apply will submit a new config from the Terraform configuration.", data.Id.ValueString()),
+		)
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+
+	// Files are matched by type and then, if there are several of the same
+	// type, by the path they were submitted under.
+	configYamlPath := data.configYamlPath()
+	if !data.ConfigJson.IsNull() {
+		configYamlPath = "service.json"
+	}
+	var configYamlFile, descriptorFile *servicemanagementpb.ConfigFile
 	for _, sourceFile := range sourceFiles {
 		// SourceFiles are of type google.api.servicemanagement.v1.ConfigFile
 		// https://cloud.google.com/service-infrastructure/docs/service-management/reference/rest/v1/ConfigView
@@ -160,16 +710,50 @@ func (r *ServiceConfigResource) Read(ctx context.Context, req resource.ReadReque
 			"file_type": file.GetFileType(),
 		})
 
-		switch file.FileType {
-		case servicemanagementpb.ConfigFile_FILE_DESCRIPTOR_SET_PROTO:
-			data.ProtoDescriptorBase64 = types.StringValue(base64.StdEncoding.EncodeToString(file.GetFileContents()))
-		case servicemanagementpb.ConfigFile_SERVICE_CONFIG_YAML:
-			data.ConfigYaml = types.StringValue(string(file.GetFileContents()))
+		switch {
+		case file.FileType == servicemanagementpb.ConfigFile_FILE_DESCRIPTOR_SET_PROTO:
+			if descriptorFile == nil || file.GetFilePath() == data.protoDescriptorPathName() {
+				descriptorFile = &file
+			}
+		case useConfigFiles && slices.Contains(configFileTypes, file.FileType.String()):
+			configFiles = append(configFiles, &file)
+		case !useConfigFiles && file.FileType == servicemanagementpb.ConfigFile_SERVICE_CONFIG_YAML:
+			if configYamlFile == nil || file.GetFilePath() == configYamlPath {
+				configYamlFile = &file
+			}
 		default:
 			resp.Diagnostics.AddError("Unknown file type", fmt.Sprintf("Unknown file type: %v", file.FileType))
 		}
 	}
 
+	if descriptorFile != nil {
+		data.ProtoDescriptorSha256 = types.StringValue(sha256Hex(descriptorFile.GetFileContents()))
+		data.ProtoDescriptorPathName = types.StringValue(descriptorFile.GetFilePath())
+		// Descriptors read from a file, given as a write-only value or
+		// merged from several sets are only tracked by their hash.
+		if data.ProtoDescriptorPath.IsNull() && data.ProtoDescriptorHash.IsNull() && data.ProtoDescriptorsBase64.IsNull() {
+			data.ProtoDescriptor = data.storedDescriptor(descriptorFile.GetFileContents())
+			if !data.ProtoDescriptorBase64.IsNull() {
+				data.ProtoDescriptorBase64 = data.ProtoDescriptor
+			}
+		}
+	}
+	if configYamlFile != nil {
+		if data.ConfigJson.IsNull() {
+			data.ConfigYaml = NewYAMLStringValue(data.storedContents(configYamlFile.GetFileContents()))
+			data.ConfigYamlPath = types.StringValue(configYamlFile.GetFilePath())
+		} else {
+			data.ConfigJson = NewYAMLStringValue(data.storedContents(configYamlFile.GetFileContents()))
+		}
+	}
+
+	if useConfigFiles {
+		data.ConfigFiles = data.mergeConfigFiles(ctx, configFiles, &resp.Diagnostics)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
@@ -178,20 +762,67 @@ func (r *ServiceConfigResource) Read(ctx context.Context, req resource.ReadReque
 func (r *ServiceConfigResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
 	var data ServiceConfigResourceModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("proto_descriptor_base64_wo"), &data.ProtoDescriptorBase64Wo)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state ServiceConfigResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	output, err := r.createConfig(ctx, data.ServiceName.ValueString(), data.ProtoDescriptorBase64.ValueString(), data.ConfigYaml.ValueString())
+	// Adopted configs are replaced when their ID changes, so only settings
+	// such as `triggers` can have changed.
+	if !data.UseExistingConfigId.IsNull() {
+		data.keepConfig(state)
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		resp.Diagnostics.Append(resp.Private.SetKey(ctx, importedKey, nil)...)
+		return
+	}
+
+	// Record the current config before it is replaced by the new one.
+	data.PreviousConfigId = state.ConfigId
+
+	// Unchanged contents may only be planned as hashes, so submit the
+	// contents from the configuration.
+	submitted := data
+	submitted.restoreContents(ctx, req.Config, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	files := submitted.configFiles(ctx, &resp.Diagnostics)
+	descriptor := submitted.protoDescriptor(ctx, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.ProtoDescriptorSha256 = submitted.ProtoDescriptorSha256
+
+	// Only settings such as `validate_during_plan` changed, so keep the
+	// existing config.
+	if !data.configChanged(ctx, state, &resp.Diagnostics) {
+		data.keepConfig(state)
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		resp.Diagnostics.Append(resp.Private.SetKey(ctx, importedKey, nil)...)
+		return
+	}
+
+	config, createTime, err := r.createConfig(ctx, data.ServiceName.ValueString(), data.protoDescriptorPathName(), descriptor, files, false)
 	if err != nil {
 		resp.Diagnostics.AddError("Could not submit configuration source", err.Error())
 		return
 	}
 
-	data.Id = newConfigId(output.ServiceConfig.GetName(), output.ServiceConfig.GetId())
+	data.setConfig(config)
+	data.CreateTime = types.StringValue(createTime.Format(time.RFC3339))
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, importedKey, nil)...)
+	resp.Diagnostics.Append(r.configDiagnostics(ctx, config)...)
 }
 
 // Delete implements resource.Resource.
@@ -201,40 +832,436 @@ func (r *ServiceConfigResource) Delete(ctx context.Context, req resource.DeleteR
 
 // ImportState implements resource.ResourceWithImportState.
 func (r *ServiceConfigResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	serviceName, configId, err := parseConfigId(req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid config ID", err.Error())
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), newConfigId(serviceName, configId))...)
+	// Defaults are not applied to imported state.
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("config_yaml_path"), defaultConfigYamlPath)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("proto_descriptor_path_name"), defaultProtoDescriptorPathName)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("store_contents_in_state"), true)...)
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, importedKey, []byte("true"))...)
 }
 
-func (r *ServiceConfigResource) createConfig(ctx context.Context, serviceName, protoDescriptor, configYaml string) (*servicemanagementpb.SubmitConfigSourceResponse, error) {
-	proto, err := base64.StdEncoding.DecodeString(protoDescriptor)
+// adoptConfig records the existing config given by `use_existing_config_id`
+// in data without submitting anything.
+func (r *ServiceConfigResource) adoptConfig(ctx context.Context, data *ServiceConfigResourceModel, diags *diag.Diagnostics) {
+	serviceName := data.ServiceName.ValueString()
+	configId := data.UseExistingConfigId.ValueString()
+	tflog.Debug(ctx, "Adopting existing service config", map[string]interface{}{
+		"service_name": serviceName,
+		"config_id":    configId,
+	})
+	config, err := retryTransient(ctx, func(ctx context.Context) (*serviceconfig.Service, error) {
+		return r.ServiceManagerClient.GetServiceConfig(ctx, &servicemanagementpb.GetServiceConfigRequest{
+			ServiceName: serviceName,
+			ConfigId:    configId,
+		})
+	})
 	if err != nil {
-		return nil, fmt.Errorf("could not decode proto descriptor: %w", err)
-	}
-	configOp, err := r.ServiceManagerClient.SubmitConfigSource(ctx, &servicemanagementpb.SubmitConfigSourceRequest{
-		ServiceName: serviceName,
-		ConfigSource: &servicemanagementpb.ConfigSource{
-			Files: []*servicemanagementpb.ConfigFile{
-				{
-					FileContents: []byte(configYaml),
-					FilePath:     "service.yaml",
-					FileType:     servicemanagementpb.ConfigFile_SERVICE_CONFIG_YAML,
-				},
-				{
-					FileContents: proto,
-					FilePath:     "descriptor.pb",
+		if isNotFound(err) {
+			diags.AddAttributeError(
+				path.Root("use_existing_config_id"),
+				"Service config not found",
+				fmt.Sprintf("Config %q of service %q does not exist.", configId, serviceName),
+			)
+			return
+		}
+		diags.AddError("Could not retrieve configuration for service", err.Error())
+		return
+	}
+
+	data.setConfig(config)
+	// The submission time of existing configs is unknown.
+	data.CreateTime = types.StringNull()
+	data.ProtoDescriptorSha256 = types.StringNull()
+}
+
+// createConfig submits a config source for the service and returns the
+// created config along with the time it was submitted. If validateOnly is
+// set, the config is only validated and no config is created.
+func (r *ServiceConfigResource) createConfig(ctx context.Context, serviceName string, descriptorPath string, descriptor []byte, files []*servicemanagementpb.ConfigFile, validateOnly bool) (*serviceconfig.Service, time.Time, error) {
+	tflog.Debug(ctx, "Submitting service config", map[string]interface{}{
+		"service_name":     serviceName,
+		"proto_descriptor": describeProtoDescriptor(descriptor),
+		"validate_only":    validateOnly,
+	})
+	var metadata *servicemanagementpb.OperationMetadata
+	output, err := retrySubmit(ctx, r.SubmitRetryAttempts, func(ctx context.Context) (*servicemanagementpb.SubmitConfigSourceResponse, error) {
+		configOp, err := r.ServiceManagerClient.SubmitConfigSource(ctx, &servicemanagementpb.SubmitConfigSourceRequest{
+			ServiceName:  serviceName,
+			ValidateOnly: validateOnly,
+			ConfigSource: &servicemanagementpb.ConfigSource{
+				Files: append(slices.Clip(files), &servicemanagementpb.ConfigFile{
+					FileContents: descriptor,
+					FilePath:     descriptorPath,
 					FileType:     servicemanagementpb.ConfigFile_FILE_DESCRIPTOR_SET_PROTO,
-				},
+				}),
 			},
-		},
+		})
+		if err != nil {
+			return nil, err
+		}
+		output, err := configOp.Wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+		metadata, _ = configOp.Metadata()
+		return output, nil
 	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	createTime := time.Now().UTC()
+	if metadata.GetStartTime() != nil {
+		createTime = metadata.GetStartTime().AsTime()
+	}
+
+	return output.GetServiceConfig(), createTime, nil
+}
+
+// configChanged reports whether the config in data needs to be submitted,
+// given the config in state.
+func (data ServiceConfigResourceModel) configChanged(ctx context.Context, state ServiceConfigResourceModel, diags *diag.Diagnostics) bool {
+	return !data.UseExistingConfigId.Equal(state.UseExistingConfigId) ||
+		!maps.Equal(data.contentHashes(ctx, diags), state.contentHashes(ctx, diags)) ||
+		!data.ProtoDescriptorHash.Equal(state.ProtoDescriptorHash) ||
+		!data.ProtoDescriptorSha256.Equal(state.ProtoDescriptorSha256) ||
+		(!data.ConfigYaml.IsNull() && data.configYamlPath() != state.configYamlPath()) ||
+		data.protoDescriptorPathName() != state.protoDescriptorPathName()
+}
+
+// configYamlPath returns the path under which `config_yaml` is submitted.
+// State written before `config_yaml_path` was added has no path.
+func (data ServiceConfigResourceModel) configYamlPath() string {
+	if data.ConfigYamlPath.IsNull() || data.ConfigYamlPath.IsUnknown() {
+		return defaultConfigYamlPath
+	}
+	return data.ConfigYamlPath.ValueString()
+}
+
+// protoDescriptorPathName returns the path under which the descriptor is
+// submitted.
+func (data ServiceConfigResourceModel) protoDescriptorPathName() string {
+	if data.ProtoDescriptorPathName.IsNull() || data.ProtoDescriptorPathName.IsUnknown() {
+		return defaultProtoDescriptorPathName
+	}
+	return data.ProtoDescriptorPathName.ValueString()
+}
+
+// keepConfig records the identifying fields of the config in state in data.
+func (data *ServiceConfigResourceModel) keepConfig(state ServiceConfigResourceModel) {
+	data.Id = state.Id
+	data.ConfigId = state.ConfigId
+	data.Name = state.Name
+	data.Title = state.Title
+	data.CreateTime = state.CreateTime
+	data.PreviousConfigId = state.PreviousConfigId
+}
+
+// setConfig records the identifying fields of config in data.
+func (data *ServiceConfigResourceModel) setConfig(config *serviceconfig.Service) {
+	data.Id = newConfigId(config.GetName(), config.GetId())
+	data.ConfigId = types.StringValue(config.GetId())
+	data.Name = types.StringValue(config.GetName())
+	data.Title = types.StringValue(config.GetTitle())
+}
+
+// configFiles returns the config files to submit, excluding the proto
+// descriptor. It returns nil if any of the files are unknown.
+func (data ServiceConfigResourceModel) configFiles(ctx context.Context, diags *diag.Diagnostics) []*servicemanagementpb.ConfigFile {
+	if !data.ConfigYaml.IsNull() {
+		return []*servicemanagementpb.ConfigFile{
+			{
+				FileContents: []byte(data.ConfigYaml.ValueString()),
+				FilePath:     data.configYamlPath(),
+				FileType:     servicemanagementpb.ConfigFile_SERVICE_CONFIG_YAML,
+			},
+		}
+	}
+	if !data.ConfigJson.IsNull() {
+		return []*servicemanagementpb.ConfigFile{
+			{
+				FileContents: []byte(data.ConfigJson.ValueString()),
+				FilePath:     "service.json",
+				FileType:     servicemanagementpb.ConfigFile_SERVICE_CONFIG_YAML,
+			},
+		}
+	}
+
+	var fileModels []ServiceConfigFileModel
+	diags.Append(data.ConfigFiles.ElementsAs(ctx, &fileModels, false)...)
+	if diags.HasError() {
+		return nil
+	}
+
+	files := make([]*servicemanagementpb.ConfigFile, 0, len(fileModels))
+	paths := make(map[string]bool, len(fileModels))
+	for i, fileModel := range fileModels {
+		if fileModel.Path.IsUnknown() || fileModel.Contents.IsUnknown() || fileModel.Type.IsUnknown() {
+			return nil
+		}
+		filePath := fileModel.Path.ValueString()
+		if paths[filePath] {
+			diags.AddAttributeError(
+				path.Root("config_files").AtListIndex(i).AtName("path"),
+				"Duplicate config file path",
+				fmt.Sprintf("Config file path %q is used more than once.", filePath),
+			)
+			return nil
+		}
+		paths[filePath] = true
+
+		files = append(files, &servicemanagementpb.ConfigFile{
+			FileContents: []byte(fileModel.Contents.ValueString()),
+			FilePath:     filePath,
+			FileType:     servicemanagementpb.ConfigFile_FileType(servicemanagementpb.ConfigFile_FileType_value[fileModel.Type.ValueString()]),
+		})
+	}
+	return files
+}
+
+// mergeConfigFiles maps the config files returned by the API back onto the
+// `config_files` in state by path, preserving the order of the existing
+// elements. Files which are not in state are appended, and elements whose
+// path is no longer returned are dropped.
+func (data ServiceConfigResourceModel) mergeConfigFiles(ctx context.Context, files []*servicemanagementpb.ConfigFile, diags *diag.Diagnostics) types.List {
+	elemType := types.ObjectType{AttrTypes: ServiceConfigFileModel{}.AttributeTypes()}
+
+	var existing []ServiceConfigFileModel
+	if !data.ConfigFiles.IsNull() && !data.ConfigFiles.IsUnknown() {
+		diags.Append(data.ConfigFiles.ElementsAs(ctx, &existing, false)...)
+		if diags.HasError() {
+			return types.ListNull(elemType)
+		}
+	}
+
+	byPath := make(map[string]*servicemanagementpb.ConfigFile, len(files))
+	for _, file := range files {
+		byPath[file.GetFilePath()] = file
+	}
+
+	toModel := func(file *servicemanagementpb.ConfigFile) ServiceConfigFileModel {
+		return ServiceConfigFileModel{
+			Path:     types.StringValue(file.GetFilePath()),
+			Contents: NewYAMLStringValue(data.storedContents(file.GetFileContents())),
+			Type:     types.StringValue(file.GetFileType().String()),
+		}
+	}
+
+	merged := make([]ServiceConfigFileModel, 0, len(files))
+	for _, fileModel := range existing {
+		file, ok := byPath[fileModel.Path.ValueString()]
+		if !ok {
+			continue
+		}
+		merged = append(merged, toModel(file))
+		delete(byPath, fileModel.Path.ValueString())
+	}
+	for _, file := range files {
+		if _, ok := byPath[file.GetFilePath()]; ok {
+			merged = append(merged, toModel(file))
+		}
+	}
+
+	list, listDiags := types.ListValueFrom(ctx, elemType, merged)
+	diags.Append(listDiags...)
+	return list
+}
+
+// protoDescriptor returns the proto descriptor to submit and records its hash
+// in data.
+func (data *ServiceConfigResourceModel) protoDescriptor(ctx context.Context, diags *diag.Diagnostics) []byte {
+	var descriptor []byte
+	if !data.ProtoDescriptorPath.IsNull() {
+		var err error
+		descriptor, err = readProtoDescriptor(data.ProtoDescriptorPath.ValueString())
+		if err != nil {
+			diags.AddAttributeError(path.Root("proto_descriptor_path"), "Invalid proto descriptor", err.Error())
+			return nil
+		}
+	} else if !data.ProtoDescriptorsBase64.IsNull() {
+		descriptorSets, _ := data.protoDescriptorSets(ctx, diags)
+		if diags.HasError() {
+			return nil
+		}
+		var err error
+		descriptor, err = mergeProtoDescriptors(descriptorSets)
+		if err != nil {
+			diags.AddAttributeError(path.Root("proto_descriptors_base64"), "Invalid proto descriptor", err.Error())
+			return nil
+		}
+	} else {
+		attrPath := path.Root("proto_descriptor")
+		if !data.ProtoDescriptorBase64.IsNull() {
+			attrPath = path.Root("proto_descriptor_base64")
+		}
+		if !data.ProtoDescriptorBase64Wo.IsNull() {
+			attrPath = path.Root("proto_descriptor_base64_wo")
+		}
+		var err error
+		descriptor, err = base64.StdEncoding.DecodeString(data.protoDescriptorBase64())
+		if err != nil {
+			diags.AddAttributeError(attrPath, "Invalid proto descriptor", fmt.Sprintf("could not decode proto descriptor: %s", err))
+			return nil
+		}
+	}
+
+	hash := sha256Hex(descriptor)
+	if planned := data.ProtoDescriptorSha256; !planned.IsUnknown() && !planned.IsNull() && planned.ValueString() != hash {
+		diags.AddAttributeError(
+			path.Root("proto_descriptor_path"),
+			"Proto descriptor changed",
+			"The proto descriptor changed after the plan was created. Run `terraform plan` again.",
+		)
+		return nil
+	}
+	data.ProtoDescriptorSha256 = types.StringValue(hash)
+
+	return descriptor
+}
 
+// protoDescriptorBase64 returns the base64-encoded descriptor from either
+// `proto_descriptor` or `proto_descriptor_base64_wo`.
+func (data ServiceConfigResourceModel) protoDescriptorBase64() string {
+	if !data.ProtoDescriptorBase64Wo.IsNull() {
+		return data.ProtoDescriptorBase64Wo.ValueString()
+	}
+	return data.ProtoDescriptor.ValueString()
+}
+
+// protoDescriptorSets returns the decoded `proto_descriptors_base64`. It
+// returns false if any of them are unknown.
+func (data ServiceConfigResourceModel) protoDescriptorSets(ctx context.Context, diags *diag.Diagnostics) ([][]byte, bool) {
+	var encoded []types.String
+	diags.Append(data.ProtoDescriptorsBase64.ElementsAs(ctx, &encoded, false)...)
+	if diags.HasError() {
+		return nil, false
+	}
+
+	descriptorSets := make([][]byte, 0, len(encoded))
+	for i, value := range encoded {
+		if value.IsUnknown() {
+			return nil, false
+		}
+		descriptorSet, err := base64.StdEncoding.DecodeString(value.ValueString())
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root("proto_descriptors_base64").AtListIndex(i),
+				"Invalid proto descriptor",
+				fmt.Sprintf("could not decode proto descriptor: %s", err),
+			)
+			return nil, false
+		}
+		descriptorSets = append(descriptorSets, descriptorSet)
+	}
+	return descriptorSets, true
+}
+
+// mergeProtoDescriptors merges serialized FileDescriptorSets into one. Files
+// are kept in the order they are first seen, and files which appear in more
+// than one set must be identical.
+func mergeProtoDescriptors(descriptorSets [][]byte) ([]byte, error) {
+	var merged descriptorpb.FileDescriptorSet
+	seen := make(map[string]*descriptorpb.FileDescriptorProto)
+	for i, descriptorSet := range descriptorSets {
+		var set descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(descriptorSet, &set); err != nil {
+			return nil, fmt.Errorf("descriptor set %d is not a valid FileDescriptorSet: %w", i, err)
+		}
+		for _, file := range set.GetFile() {
+			existing, ok := seen[file.GetName()]
+			if !ok {
+				seen[file.GetName()] = file
+				merged.File = append(merged.File, file)
+				continue
+			}
+			if !proto.Equal(existing, file) {
+				return nil, fmt.Errorf("descriptor set %d contains a definition of %s which conflicts with an earlier descriptor set", i, file.GetName())
+			}
+		}
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(&merged)
+}
+
+// serviceConfigYAML serializes a normalized service config to YAML which
+// can be submitted as a SERVICE_CONFIG_YAML file.
+func serviceConfigYAML(config *serviceconfig.Service) ([]byte, error) {
+	contents, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(config)
 	if err != nil {
 		return nil, err
 	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(contents, &doc); err != nil {
+		return nil, err
+	}
+	doc["type"] = "google.api.Service"
+	return yaml.Marshal(doc)
+}
 
-	config, err := configOp.Wait(ctx)
+// readProtoDescriptor reads the file at filePath and verifies that it contains
+// a FileDescriptorSet.
+func readProtoDescriptor(filePath string) ([]byte, error) {
+	descriptor, err := os.ReadFile(filePath)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("could not read proto descriptor: %w", err)
 	}
+	if err := proto.Unmarshal(descriptor, &descriptorpb.FileDescriptorSet{}); err != nil {
+		return nil, fmt.Errorf("%s is not a valid FileDescriptorSet: %w", filePath, err)
+	}
+	return descriptor, nil
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// configDiagnostics returns the diagnostics reported by the API for a
+// submitted config, such as unreferenced types or conflicting HTTP rules.
+// Callers save the config to state first, since errors fail the apply.
+func (r *ServiceConfigResource) configDiagnostics(ctx context.Context, config *serviceconfig.Service) diag.Diagnostics {
+	var diags diag.Diagnostics
 
-	return config, nil
+	newConfig, err := anypb.New(&servicemanagementpb.ConfigRef{
+		Name: fmt.Sprintf("services/%s/configs/%s", config.GetName(), config.GetId()),
+	})
+	if err != nil {
+		diags.AddWarning("Could not retrieve service config diagnostics", err.Error())
+		return diags
+	}
+
+	report, err := r.ServiceManagerClient.GenerateConfigReport(ctx, &servicemanagementpb.GenerateConfigReportRequest{
+		NewConfig: newConfig,
+	})
+	if err != nil {
+		diags.AddWarning("Could not retrieve service config diagnostics", err.Error())
+		return diags
+	}
+
+	diags.Append(convertConfigDiagnostics(report.GetDiagnostics())...)
+	return diags
+}
+
+// convertConfigDiagnostics converts Service Management diagnostics into
+// Terraform diagnostics.
+func convertConfigDiagnostics(configDiags []*servicemanagementpb.Diagnostic) diag.Diagnostics {
+	var diags diag.Diagnostics
+	for _, d := range configDiags {
+		detail := d.GetMessage()
+		if location := d.GetLocation(); location != "" {
+			detail = location + ": " + detail
+		}
+		switch d.GetKind() {
+		case servicemanagementpb.Diagnostic_ERROR:
+			diags.AddError("Service config error", detail)
+		default:
+			diags.AddWarning("Service config warning", detail)
+		}
+	}
+	return diags
 }
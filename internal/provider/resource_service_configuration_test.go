@@ -0,0 +1,1455 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/servicemanagement/apiv1/servicemanagementpb"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+	"google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestAccResourceServiceConfig(t *testing.T) {
+	project := testAccProducerProject(t)
+	serviceName := fmt.Sprintf("tf-test-%s.endpoints.%s.cloud.goog", acctest.RandString(8), project)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCreateConfig(fmt.Sprintf(`
+				resource "utils_service" "test" {
+					service_name = %[1]q
+					producer_project_id = %[2]q
+				}
+
+				resource "utils_service_config" "test" {
+					service_name = utils_service.test.service_name
+					config_files = [{
+						path = "service.yaml"
+						contents = <<-EOT
+							type: google.api.Service
+							config_version: 3
+							name: %[1]s
+							title: Terraform acceptance test
+						EOT
+					}]
+					proto_descriptor = ""
+				}`, serviceName, project)),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("utils_service_config.test", tfjsonpath.New("name"), knownvalue.StringExact(serviceName)),
+					statecheck.ExpectKnownValue("utils_service_config.test", tfjsonpath.New("title"), knownvalue.StringExact("Terraform acceptance test")),
+				},
+			},
+			{
+				ResourceName: "utils_service_config.test",
+				ImportState:  true,
+				// Import using the resource name format emitted by gcloud.
+				ImportStateIdFunc: func(s *terraform.State) (string, error) {
+					rs := s.RootModule().Resources["utils_service_config.test"]
+					return fmt.Sprintf("services/%s/configs/%s", rs.Primary.Attributes["service_name"], rs.Primary.Attributes["config_id"]), nil
+				},
+				ImportStateVerify: true,
+				// The submission time is not returned by the API.
+				ImportStateVerifyIgnore: []string{"create_time"},
+			},
+		},
+	})
+}
+
+func TestServiceConfigMergeConfigFiles(t *testing.T) {
+	ctx := context.Background()
+	elemType := types.ObjectType{AttrTypes: ServiceConfigFileModel{}.AttributeTypes()}
+
+	existing, diags := types.ListValueFrom(ctx, elemType, []ServiceConfigFileModel{
+		{Path: types.StringValue("metrics.yaml"), Contents: NewYAMLStringValue("old"), Type: types.StringValue("SERVICE_CONFIG_YAML")},
+		{Path: types.StringValue("removed.yaml"), Contents: NewYAMLStringValue("old"), Type: types.StringValue("SERVICE_CONFIG_YAML")},
+		{Path: types.StringValue("service.yaml"), Contents: NewYAMLStringValue("old"), Type: types.StringValue("SERVICE_CONFIG_YAML")},
+	})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	data := ServiceConfigResourceModel{ConfigFiles: existing}
+	merged := data.mergeConfigFiles(ctx, []*servicemanagementpb.ConfigFile{
+		{FilePath: "service.yaml", FileContents: []byte("service"), FileType: servicemanagementpb.ConfigFile_SERVICE_CONFIG_YAML},
+		{FilePath: "iam.yaml", FileContents: []byte("iam"), FileType: servicemanagementpb.ConfigFile_SERVICE_CONFIG_YAML},
+		{FilePath: "metrics.yaml", FileContents: []byte("metrics"), FileType: servicemanagementpb.ConfigFile_SERVICE_CONFIG_YAML},
+	}, &diags)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	var files []ServiceConfigFileModel
+	if diags := merged.ElementsAs(ctx, &files, false); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	want := [][2]string{
+		{"metrics.yaml", "metrics"},
+		{"service.yaml", "service"},
+		{"iam.yaml", "iam"},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("got %d files, want %d", len(files), len(want))
+	}
+	for i, file := range files {
+		if file.Path.ValueString() != want[i][0] || file.Contents.ValueString() != want[i][1] {
+			t.Errorf("file %d: got %s=%q, want %s=%q", i, file.Path.ValueString(), file.Contents.ValueString(), want[i][0], want[i][1])
+		}
+	}
+}
+
+func TestReadProtoDescriptor(t *testing.T) {
+	dir := t.TempDir()
+
+	descriptor, err := proto.Marshal(&descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{{Name: proto.String("test.proto")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	validPath := filepath.Join(dir, "valid.pb")
+	if err := os.WriteFile(validPath, descriptor, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	invalidPath := filepath.Join(dir, "invalid.pb")
+	if err := os.WriteFile(invalidPath, []byte("not a descriptor"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readProtoDescriptor(validPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sha256Hex(got) != sha256Hex(descriptor) {
+		t.Errorf("got contents with hash %s, want %s", sha256Hex(got), sha256Hex(descriptor))
+	}
+
+	if _, err := readProtoDescriptor(filepath.Join(dir, "missing.pb")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got error %v for missing file, want fs.ErrNotExist", err)
+	}
+
+	if _, err := readProtoDescriptor(invalidPath); err == nil {
+		t.Error("expected error for invalid descriptor")
+	}
+}
+
+func TestResourceServiceConfigReadNotFound(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	for _, tt := range []struct {
+		name          string
+		serviceExists bool
+		configExists  bool
+		wantRemoved   bool
+	}{
+		{name: "exists", serviceExists: true, configExists: true, wantRemoved: false},
+		{name: "config deleted", serviceExists: true, configExists: false, wantRemoved: true},
+		{name: "service deleted", serviceExists: false, configExists: false, wantRemoved: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeServiceManager()
+			if tt.serviceExists {
+				fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+			}
+			if tt.configExists {
+				fake.configs[serviceName+"/config1"] = &serviceconfig.Service{Name: serviceName, Id: "config1"}
+			}
+			server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+			typ := schemas.ResourceSchemas["utils_service_config"].ValueType()
+
+			resp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+				TypeName: "utils_service_config",
+				CurrentState: testDynamicValue(t, typ, map[string]tftypes.Value{
+					"id":           tftypes.NewValue(tftypes.String, serviceName+"/config1"),
+					"service_name": tftypes.NewValue(tftypes.String, serviceName),
+				}),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			requireNoErrors(t, resp.Diagnostics)
+
+			newState, err := resp.NewState.Unmarshal(typ)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if removed := newState.IsNull(); removed != tt.wantRemoved {
+				t.Errorf("got removed %v, want %v", removed, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestResourceServiceConfigReadSourceInfo(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+	configYaml := "type: google.api.Service\nname: " + serviceName + "\ntitle: Example API\n"
+	descriptor, err := proto.Marshal(&descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{{Name: proto.String("test.proto")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sourceInfo := &serviceconfig.SourceInfo{}
+	for _, file := range []*servicemanagementpb.ConfigFile{
+		{FilePath: "service.yaml", FileContents: []byte(configYaml), FileType: servicemanagementpb.ConfigFile_SERVICE_CONFIG_YAML},
+		{FilePath: "service.pb", FileContents: descriptor, FileType: servicemanagementpb.ConfigFile_FILE_DESCRIPTOR_SET_PROTO},
+	} {
+		sourceFile, err := anypb.New(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sourceInfo.SourceFiles = append(sourceInfo.SourceFiles, sourceFile)
+	}
+
+	for _, tt := range []struct {
+		name           string
+		sourceInfo     *serviceconfig.SourceInfo
+		wantDescriptor bool
+		wantConfigYaml bool
+		wantWarning    bool
+	}{
+		{name: "source info", sourceInfo: sourceInfo, wantDescriptor: true},
+		{name: "no source info", wantConfigYaml: true, wantWarning: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeServiceManager()
+			fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+			fake.configs[serviceName+"/config1"] = &serviceconfig.Service{
+				Name:       serviceName,
+				Title:      "Example API",
+				Id:         "config1",
+				SourceInfo: tt.sourceInfo,
+			}
+			server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+			typ := schemas.ResourceSchemas["utils_service_config"].ValueType()
+
+			// Imported state
+			resp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+				TypeName: "utils_service_config",
+				CurrentState: testDynamicValue(t, typ, map[string]tftypes.Value{
+					"id":                      tftypes.NewValue(tftypes.String, serviceName+"/config1"),
+					"store_contents_in_state": tftypes.NewValue(tftypes.Bool, true),
+				}),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			requireNoErrors(t, resp.Diagnostics)
+			if gotWarning := len(resp.Diagnostics) > 0; gotWarning != tt.wantWarning {
+				t.Errorf("got warnings %v, want warning %v", resp.Diagnostics, tt.wantWarning)
+			}
+
+			attrs := testStateAttributes(t, typ, resp.NewState)
+			if got := attrs["proto_descriptor"].IsNull(); got == tt.wantDescriptor {
+				t.Errorf("got null proto_descriptor %v, want %v", got, !tt.wantDescriptor)
+			}
+
+			// Source files are read into `config_files` on import, whereas
+			// the normalized config is read into `config_yaml`.
+			if got := attrs["config_files"].IsNull(); got != tt.wantConfigYaml {
+				t.Errorf("got null config_files %v, want %v", got, tt.wantConfigYaml)
+			}
+			if tt.wantConfigYaml {
+				var contents string
+				if err := attrs["config_yaml"].As(&contents); err != nil {
+					t.Fatal(err)
+				}
+				want := configYaml + "id: config1\n"
+				if equal, _ := NewYAMLStringValue(contents).StringSemanticEquals(ctx, NewYAMLStringValue(want)); !equal {
+					t.Errorf("got config_yaml %q, want %q", contents, want)
+				}
+			}
+		})
+	}
+}
+
+func TestResourceServiceConfigSubmitRetry(t *testing.T) {
+	delay := submitRetryBaseDelay
+	t.Cleanup(func() { submitRetryBaseDelay = delay })
+	submitRetryBaseDelay = 0
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	for _, tt := range []struct {
+		name              string
+		maxAttempts       int
+		submitFailures    []codes.Code
+		operationFailures []codes.Code
+		wantCalls         int
+		wantError         string
+	}{
+		{name: "success", wantCalls: 1},
+		{name: "resource exhausted", submitFailures: []codes.Code{codes.ResourceExhausted, codes.ResourceExhausted}, wantCalls: 3},
+		{name: "unavailable", submitFailures: []codes.Code{codes.Unavailable}, wantCalls: 2},
+		{name: "operation aborted", operationFailures: []codes.Code{codes.Aborted}, wantCalls: 2},
+		{name: "invalid argument", submitFailures: []codes.Code{codes.InvalidArgument}, wantCalls: 1, wantError: "fake failure"},
+		{name: "operation invalid argument", operationFailures: []codes.Code{codes.InvalidArgument}, wantCalls: 1, wantError: "fake operation failure"},
+		{
+			name:           "attempts exhausted",
+			maxAttempts:    2,
+			submitFailures: []codes.Code{codes.ResourceExhausted, codes.ResourceExhausted, codes.ResourceExhausted},
+			wantCalls:      2,
+			wantError:      "failed after 2 attempts",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			fake := newFakeServiceManager()
+			fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+			fake.submitFailures = tt.submitFailures
+			fake.operationFailures = tt.operationFailures
+			providerConfig := newFakeProviderConfig(t, fake)
+			providerConfig.SubmitRetryAttempts = tt.maxAttempts
+			server, schemas := newFakeProviderServer(t, providerConfig)
+			typ := schemas.ResourceSchemas["utils_service_config"].ValueType()
+
+			config := testDynamicValue(t, typ, map[string]tftypes.Value{
+				"service_name":     tftypes.NewValue(tftypes.String, serviceName),
+				"config_yaml":      tftypes.NewValue(tftypes.String, "type: google.api.Service\nname: "+serviceName+"\n"),
+				"proto_descriptor": tftypes.NewValue(tftypes.String, base64.StdEncoding.EncodeToString([]byte("descriptor"))),
+			})
+			priorState := testNullDynamicValue(t, typ)
+			planResp := testPlanResource(t, server, "utils_service_config", typ, priorState, nil, config)
+			resp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+				TypeName:     "utils_service_config",
+				PriorState:   priorState,
+				PlannedState: planResp.PlannedState,
+				Config:       config,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantError == "" {
+				requireNoErrors(t, resp.Diagnostics)
+			} else if len(resp.Diagnostics) == 0 || !strings.Contains(resp.Diagnostics[0].Detail, tt.wantError) {
+				t.Errorf("got diagnostics %v, want error containing %q", resp.Diagnostics, tt.wantError)
+			}
+			if fake.submitCalls != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", fake.submitCalls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestConvertConfigDiagnostics(t *testing.T) {
+	report := &servicemanagementpb.GenerateConfigReportResponse{
+		Diagnostics: []*servicemanagementpb.Diagnostic{
+			{Location: "service.yaml:12", Kind: servicemanagementpb.Diagnostic_WARNING, Message: "Type 'foo.Bar' is not referenced."},
+			{Location: "", Kind: servicemanagementpb.Diagnostic_WARNING, Message: "Field 'baz' is deprecated."},
+			{Location: "http.yaml:3", Kind: servicemanagementpb.Diagnostic_ERROR, Message: "Conflicting HTTP rule."},
+		},
+	}
+
+	diags := convertConfigDiagnostics(report.GetDiagnostics())
+
+	if got := len(diags.Warnings()); got != 2 {
+		t.Errorf("got %d warnings, want 2", got)
+	}
+	if got := len(diags.Errors()); got != 1 {
+		t.Fatalf("got %d errors, want 1", got)
+	}
+	if got, want := diags[0].Detail(), "service.yaml:12: Type 'foo.Bar' is not referenced."; got != want {
+		t.Errorf("got detail %q, want %q", got, want)
+	}
+	if got, want := diags[1].Detail(), "Field 'baz' is deprecated."; got != want {
+		t.Errorf("got detail %q, want %q", got, want)
+	}
+	if got, want := diags.Errors()[0].Detail(), "http.yaml:3: Conflicting HTTP rule."; got != want {
+		t.Errorf("got detail %q, want %q", got, want)
+	}
+}
+
+func TestResourceServiceConfigReportErrors(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	fake := newFakeServiceManager()
+	fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+	fake.reportDiagnostics = []*servicemanagementpb.Diagnostic{
+		{Location: "http.yaml:3", Kind: servicemanagementpb.Diagnostic_ERROR, Message: "Conflicting HTTP rule."},
+	}
+	server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+	typ := schemas.ResourceSchemas["utils_service_config"].ValueType()
+
+	config := testDynamicValue(t, typ, map[string]tftypes.Value{
+		"service_name":     tftypes.NewValue(tftypes.String, serviceName),
+		"config_yaml":      tftypes.NewValue(tftypes.String, "type: google.api.Service\nname: "+serviceName+"\n"),
+		"proto_descriptor": tftypes.NewValue(tftypes.String, base64.StdEncoding.EncodeToString([]byte("descriptor"))),
+	})
+	priorState := testNullDynamicValue(t, typ)
+	planResp := testPlanResource(t, server, "utils_service_config", typ, priorState, nil, config)
+	resp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:     "utils_service_config",
+		PriorState:   priorState,
+		PlannedState: planResp.PlannedState,
+		Config:       config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The report error fails the apply, but the created config is tracked.
+	if len(resp.Diagnostics) != 1 || resp.Diagnostics[0].Severity != tfprotov6.DiagnosticSeverityError ||
+		resp.Diagnostics[0].Detail != "http.yaml:3: Conflicting HTTP rule." {
+		t.Errorf("got diagnostics %v, want the report error", resp.Diagnostics)
+	}
+	var configID string
+	if err := testStateAttributes(t, typ, resp.NewState)["config_id"].As(&configID); err != nil {
+		t.Fatal(err)
+	}
+	if configID == "" {
+		t.Error("got no config_id in state")
+	}
+}
+
+func TestResourceServiceConfigWriteOnlyDescriptor(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	descriptor, err := proto.Marshal(&descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{{Name: proto.String("test.proto")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	descriptorBase64 := base64.StdEncoding.EncodeToString(descriptor)
+
+	fake := newFakeServiceManager()
+	fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+	server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+	typ := schemas.ResourceSchemas["utils_service_config"].ValueType()
+
+	config := testDynamicValue(t, typ, map[string]tftypes.Value{
+		"service_name":               tftypes.NewValue(tftypes.String, serviceName),
+		"config_yaml":                tftypes.NewValue(tftypes.String, "type: google.api.Service\nname: "+serviceName+"\n"),
+		"proto_descriptor_base64_wo": tftypes.NewValue(tftypes.String, descriptorBase64),
+		"proto_descriptor_hash":      tftypes.NewValue(tftypes.String, sha256Hex(descriptor)),
+	})
+
+	newState := testApplyResource(t, server, "utils_service_config", typ, nil, config)
+
+	if len(fake.submitted) != 1 {
+		t.Fatalf("got %d submitted configs, want 1", len(fake.submitted))
+	}
+	var submittedDescriptor []byte
+	for _, file := range fake.submitted[0].GetConfigSource().GetFiles() {
+		if file.GetFileType() == servicemanagementpb.ConfigFile_FILE_DESCRIPTOR_SET_PROTO {
+			submittedDescriptor = file.GetFileContents()
+		}
+	}
+	if !bytes.Equal(submittedDescriptor, descriptor) {
+		t.Errorf("got submitted descriptor %x, want %x", submittedDescriptor, descriptor)
+	}
+
+	// Neither the new state nor a refresh of it may contain the descriptor.
+	readResp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+		TypeName:     "utils_service_config",
+		CurrentState: newState,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireNoErrors(t, readResp.Diagnostics)
+
+	for name, state := range map[string]*tfprotov6.DynamicValue{"apply": newState, "read": readResp.NewState} {
+		attrs := testStateAttributes(t, typ, state)
+		for _, attrName := range []string{"proto_descriptor", "proto_descriptor_base64_wo"} {
+			if !attrs[attrName].IsNull() {
+				t.Errorf("%s: got %s %v, want null", name, attrName, attrs[attrName])
+			}
+		}
+		var sha256 string
+		if err := attrs["proto_descriptor_sha256"].As(&sha256); err != nil {
+			t.Fatal(err)
+		}
+		if want := sha256Hex(descriptor); sha256 != want {
+			t.Errorf("%s: got proto_descriptor_sha256 %q, want %q", name, sha256, want)
+		}
+	}
+}
+
+func TestResourceServiceConfigMetadata(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	descriptor, err := proto.Marshal(&descriptorpb.FileDescriptorSet{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := newFakeServiceManager()
+	fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+	server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+	typ := schemas.ResourceSchemas["utils_service_config"].ValueType()
+
+	newState := testApplyResource(t, server, "utils_service_config", typ, nil, testDynamicValue(t, typ, map[string]tftypes.Value{
+		"service_name":     tftypes.NewValue(tftypes.String, serviceName),
+		"config_yaml":      tftypes.NewValue(tftypes.String, "type: google.api.Service\nname: "+serviceName+"\n"),
+		"proto_descriptor": tftypes.NewValue(tftypes.String, base64.StdEncoding.EncodeToString(descriptor)),
+	}))
+
+	readResp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+		TypeName:     "utils_service_config",
+		CurrentState: newState,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireNoErrors(t, readResp.Diagnostics)
+
+	want := map[string]string{
+		"id":          serviceName + "/config1",
+		"config_id":   "config1",
+		"name":        serviceName,
+		"create_time": "2024-08-01T12:00:00Z",
+	}
+	for name, state := range map[string]*tfprotov6.DynamicValue{"apply": newState, "read": readResp.NewState} {
+		attrs := testStateAttributes(t, typ, state)
+		for attrName, wantValue := range want {
+			var got string
+			if err := attrs[attrName].As(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got != wantValue {
+				t.Errorf("%s: got %s %q, want %q", name, attrName, got, wantValue)
+			}
+		}
+	}
+}
+
+func TestResourceServiceConfigUpgradeState(t *testing.T) {
+	ctx := context.Background()
+	server, schemas := newFakeProviderServer(t, &UtilsProviderConfig{})
+	typ := schemas.ResourceSchemas["utils_service_config"].ValueType()
+
+	for _, tt := range []struct {
+		name    string
+		version int64
+		state   string
+	}{
+		{
+			name:    "v0",
+			version: 0,
+			state:   `{"id":"svc/config1","service_name":"svc","config_yaml":"name: svc\n","proto_descriptor_base64":"ZGVzY3JpcHRvcg=="}`,
+		},
+		{
+			name:    "v1",
+			version: 1,
+			state:   `{"id":"svc/config1","service_name":"svc","config_yaml":"name: svc\n","config_files":null,"proto_descriptor_base64":"ZGVzY3JpcHRvcg==","proto_descriptor_path":null,"proto_descriptor_sha256":"` + sha256Hex([]byte("descriptor")) + `","validate_during_plan":null}`,
+		},
+		{
+			name:    "v2",
+			version: 2,
+			state:   `{"config_files":null,"config_id":"config1","config_json":null,"config_yaml":"name: svc\n","config_yaml_path":"service.yaml","create_time":"2024-08-01T12:00:00Z","id":"svc/config1","name":"svc","proto_descriptor_base64":"ZGVzY3JpcHRvcg==","proto_descriptor_base64_wo":null,"proto_descriptor_hash":null,"proto_descriptor_path":null,"proto_descriptor_path_name":"descriptor.pb","proto_descriptor_sha256":"` + sha256Hex([]byte("descriptor")) + `","proto_descriptors_base64":null,"service_name":"svc","store_contents_in_state":true,"title":"","triggers":null,"validate_during_plan":null}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := server.UpgradeResourceState(ctx, &tfprotov6.UpgradeResourceStateRequest{
+				TypeName: "utils_service_config",
+				Version:  tt.version,
+				RawState: &tfprotov6.RawState{JSON: []byte(tt.state)},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			requireNoErrors(t, resp.Diagnostics)
+
+			attrs := testStateAttributes(t, typ, resp.UpgradedState)
+			for name, want := range map[string]string{
+				"id":                      "svc/config1",
+				"config_yaml":             "name: svc\n",
+				"proto_descriptor":        "ZGVzY3JpcHRvcg==",
+				"proto_descriptor_base64": "ZGVzY3JpcHRvcg==",
+			} {
+				var got string
+				if err := attrs[name].As(&got); err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("got %s %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestResourceServiceConfigDeprecatedProtoDescriptor(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+	descriptorBase64 := base64.StdEncoding.EncodeToString([]byte("descriptor"))
+
+	fake := newFakeServiceManager()
+	fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+	server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+	typ := schemas.ResourceSchemas["utils_service_config"].ValueType()
+
+	config := func(attr string) *tfprotov6.DynamicValue {
+		return testDynamicValue(t, typ, map[string]tftypes.Value{
+			"service_name": tftypes.NewValue(tftypes.String, serviceName),
+			"config_yaml":  tftypes.NewValue(tftypes.String, "type: google.api.Service\nname: "+serviceName+"\n"),
+			attr:           tftypes.NewValue(tftypes.String, descriptorBase64),
+		})
+	}
+	requireNoChanges := func(t *testing.T, state, config *tfprotov6.DynamicValue) {
+		t.Helper()
+		planned, err := testPlanResource(t, server, "utils_service_config", typ, state, nil, config).PlannedState.Unmarshal(typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prior, err := state.Unmarshal(typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diffs, err := prior.Diff(planned); err != nil || len(diffs) > 0 {
+			t.Errorf("expected no changes, got %v (%v)", diffs, err)
+		}
+	}
+
+	// The deprecated attribute is aliased to `proto_descriptor`.
+	state := testApplyResource(t, server, "utils_service_config", typ, nil, config("proto_descriptor_base64"))
+	if got := fake.submitted[0].GetConfigSource().GetFiles()[1].GetFileContents(); string(got) != "descriptor" {
+		t.Errorf("got submitted descriptor %q, want %q", got, "descriptor")
+	}
+	state = testReadResource(t, server, "utils_service_config", state)
+	attrs := testStateAttributes(t, typ, state)
+	for _, name := range []string{"proto_descriptor", "proto_descriptor_base64"} {
+		if want := tftypes.NewValue(tftypes.String, descriptorBase64); !attrs[name].Equal(want) {
+			t.Errorf("got %s %v, want %v", name, attrs[name], want)
+		}
+	}
+	requireNoChanges(t, state, config("proto_descriptor_base64"))
+
+	// State upgraded from version 2 has no changes until the configuration
+	// is migrated.
+	v2State, err := json.Marshal(map[string]any{
+		"id":                         serviceName + "/config1",
+		"service_name":               serviceName,
+		"config_yaml":                "type: google.api.Service\nname: " + serviceName + "\n",
+		"config_yaml_path":           "service.yaml",
+		"config_id":                  "config1",
+		"name":                       serviceName,
+		"title":                      "",
+		"create_time":                fakeOperationStartTime.Format(time.RFC3339),
+		"proto_descriptor_base64":    descriptorBase64,
+		"proto_descriptor_sha256":    sha256Hex([]byte("descriptor")),
+		"proto_descriptor_path_name": "descriptor.pb",
+		"store_contents_in_state":    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	upgradeResp, err := server.UpgradeResourceState(ctx, &tfprotov6.UpgradeResourceStateRequest{
+		TypeName: "utils_service_config",
+		Version:  2,
+		RawState: &tfprotov6.RawState{JSON: v2State},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireNoErrors(t, upgradeResp.Diagnostics)
+	requireNoChanges(t, upgradeResp.UpgradedState, config("proto_descriptor_base64"))
+
+	// Migrating the configuration only drops the deprecated attribute.
+	planned := testPlanResource(t, server, "utils_service_config", typ, upgradeResp.UpgradedState, nil, config("proto_descriptor")).PlannedState
+	plannedAttrs := testStateAttributes(t, typ, planned)
+	if !plannedAttrs["proto_descriptor_base64"].IsNull() {
+		t.Errorf("got planned proto_descriptor_base64 %v, want null", plannedAttrs["proto_descriptor_base64"])
+	}
+	if want := tftypes.NewValue(tftypes.String, serviceName+"/config1"); !plannedAttrs["id"].Equal(want) {
+		t.Errorf("got planned id %v, want %v", plannedAttrs["id"], want)
+	}
+}
+
+func TestResourceServiceConfigUseExistingConfigId(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+	configYaml := "type: google.api.Service\nname: " + serviceName + "\n"
+
+	fake := newFakeServiceManager()
+	fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+	fake.configs[serviceName+"/existing"] = &serviceconfig.Service{Name: serviceName, Id: "existing", Title: "Existing"}
+	server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+	typ := schemas.ResourceSchemas["utils_service_config"].ValueType()
+
+	adoptConfig := func(configId string) *tfprotov6.DynamicValue {
+		return testDynamicValue(t, typ, map[string]tftypes.Value{
+			"service_name":           tftypes.NewValue(tftypes.String, serviceName),
+			"use_existing_config_id": tftypes.NewValue(tftypes.String, configId),
+		})
+	}
+	contentConfig := testDynamicValue(t, typ, map[string]tftypes.Value{
+		"service_name":     tftypes.NewValue(tftypes.String, serviceName),
+		"config_yaml":      tftypes.NewValue(tftypes.String, configYaml),
+		"proto_descriptor": tftypes.NewValue(tftypes.String, ""),
+	})
+
+	state := testApplyResource(t, server, "utils_service_config", typ, nil, adoptConfig("existing"))
+	if len(fake.submitted) != 0 {
+		t.Errorf("got %d submitted configs, want 0", len(fake.submitted))
+	}
+	state = testReadResource(t, server, "utils_service_config", state)
+	attrs := testStateAttributes(t, typ, state)
+	for name, want := range map[string]string{
+		"id":        serviceName + "/existing",
+		"config_id": "existing",
+		"title":     "Existing",
+	} {
+		if want := tftypes.NewValue(tftypes.String, want); !attrs[name].Equal(want) {
+			t.Errorf("got %s %v, want %v", name, attrs[name], want)
+		}
+	}
+	if !attrs["config_files"].IsNull() {
+		t.Errorf("got config_files %v, want null", attrs["config_files"])
+	}
+
+	planned := testPlanResource(t, server, "utils_service_config", typ, state, nil, adoptConfig("existing")).PlannedState
+	if got := testStateAttributes(t, typ, planned)["id"]; !got.Equal(attrs["id"]) {
+		t.Errorf("got planned id %v, want %v", got, attrs["id"])
+	}
+
+	t.Run("content mode", func(t *testing.T) {
+		planned := testPlanResource(t, server, "utils_service_config", typ, state, nil, contentConfig).PlannedState
+		if got := testStateAttributes(t, typ, planned)["id"]; got.IsKnown() {
+			t.Errorf("switching to content mode should submit a new config, got planned id %v", got)
+		}
+		testApplyResource(t, server, "utils_service_config", typ, state, contentConfig)
+		if len(fake.submitted) != 1 {
+			t.Errorf("got %d submitted configs, want 1", len(fake.submitted))
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		priorState := testNullDynamicValue(t, typ)
+		planResp := testPlanResource(t, server, "utils_service_config", typ, priorState, nil, adoptConfig("missing"))
+		resp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+			TypeName:     "utils_service_config",
+			PriorState:   priorState,
+			PlannedState: planResp.PlannedState,
+			Config:       adoptConfig("missing"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Diagnostics) == 0 || resp.Diagnostics[0].Summary != "Service config not found" {
+			t.Errorf("got diagnostics %v, want a not found error", resp.Diagnostics)
+		}
+	})
+
+	t.Run("conflicting contents", func(t *testing.T) {
+		resp, err := server.ValidateResourceConfig(ctx, &tfprotov6.ValidateResourceConfigRequest{
+			TypeName: "utils_service_config",
+			Config: testDynamicValue(t, typ, map[string]tftypes.Value{
+				"service_name":           tftypes.NewValue(tftypes.String, serviceName),
+				"config_yaml":            tftypes.NewValue(tftypes.String, configYaml),
+				"use_existing_config_id": tftypes.NewValue(tftypes.String, "existing"),
+			}),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Diagnostics) == 0 {
+			t.Error("expected an error when both config_yaml and use_existing_config_id are set")
+		}
+	})
+}
+
+func TestResourceServiceConfigValidateDuringPlan(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	for _, tt := range []struct {
+		name               string
+		validateDuringPlan bool
+		submitFailures     []codes.Code
+		wantSubmitted      int
+		wantError          bool
+	}{
+		{name: "disabled", validateDuringPlan: false, wantSubmitted: 0},
+		{name: "valid", validateDuringPlan: true, wantSubmitted: 2},
+		{name: "invalid", validateDuringPlan: true, submitFailures: []codes.Code{codes.InvalidArgument, codes.InvalidArgument}, wantError: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeServiceManager()
+			fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+			fake.submitFailures = tt.submitFailures
+			server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+			typ := schemas.ResourceSchemas["utils_service_config"].ValueType()
+
+			config := testDynamicValue(t, typ, map[string]tftypes.Value{
+				"service_name":         tftypes.NewValue(tftypes.String, serviceName),
+				"config_yaml":          tftypes.NewValue(tftypes.String, "type: google.api.Service\nname: "+serviceName+"\n"),
+				"proto_descriptor":     tftypes.NewValue(tftypes.String, ""),
+				"validate_during_plan": tftypes.NewValue(tftypes.Bool, tt.validateDuringPlan),
+			})
+
+			// Each plan validates the config, as nothing is cached between
+			// them.
+			for range 2 {
+				resp, err := server.PlanResourceChange(ctx, &tfprotov6.PlanResourceChangeRequest{
+					TypeName:         "utils_service_config",
+					PriorState:       testNullDynamicValue(t, typ),
+					ProposedNewState: config,
+					Config:           config,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !tt.wantError {
+					requireNoErrors(t, resp.Diagnostics)
+					continue
+				}
+				if len(resp.Diagnostics) != 1 || resp.Diagnostics[0].Summary != "Invalid service config" {
+					t.Fatalf("got diagnostics %v, want an invalid config error", resp.Diagnostics)
+				}
+				if got, want := resp.Diagnostics[0].Attribute, tftypes.NewAttributePath().WithAttributeName("config_yaml"); !got.Equal(want) {
+					t.Errorf("got error on %v, want %v", got, want)
+				}
+			}
+
+			if len(fake.submitted) != tt.wantSubmitted {
+				t.Fatalf("got %d submissions, want %d", len(fake.submitted), tt.wantSubmitted)
+			}
+			for _, req := range fake.submitted {
+				if !req.ValidateOnly {
+					t.Error("got a submission which was not validate only")
+				}
+			}
+			if len(fake.configs) != 0 {
+				t.Errorf("got %d configs created during plan, want none", len(fake.configs))
+			}
+		})
+	}
+}
+
+func TestResourceServiceConfigPreviousConfigId(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	fake := newFakeServiceManager()
+	fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+	server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+	typ := schemas.ResourceSchemas["utils_service_config"].ValueType()
+
+	config := func(title string, validateDuringPlan bool) *tfprotov6.DynamicValue {
+		return testDynamicValue(t, typ, map[string]tftypes.Value{
+			"service_name":         tftypes.NewValue(tftypes.String, serviceName),
+			"config_yaml":          tftypes.NewValue(tftypes.String, "type: google.api.Service\nname: "+serviceName+"\ntitle: "+title+"\n"),
+			"proto_descriptor":     tftypes.NewValue(tftypes.String, ""),
+			"validate_during_plan": tftypes.NewValue(tftypes.Bool, validateDuringPlan),
+		})
+	}
+	requirePreviousConfigId := func(t *testing.T, state *tfprotov6.DynamicValue, want any) {
+		t.Helper()
+		got := testStateAttributes(t, typ, state)["previous_config_id"]
+		if want := tftypes.NewValue(tftypes.String, want); !got.Equal(want) {
+			t.Errorf("got previous_config_id %v, want %v", got, want)
+		}
+	}
+
+	state := testApplyResource(t, server, "utils_service_config", typ, nil, config("One", false))
+	requirePreviousConfigId(t, state, nil)
+
+	// The previous config is known during plan.
+	planned := testPlanResource(t, server, "utils_service_config", typ, state, nil, config("Two", false)).PlannedState
+	requirePreviousConfigId(t, planned, "config1")
+
+	state = testApplyResource(t, server, "utils_service_config", typ, state, config("Two", false))
+	requirePreviousConfigId(t, state, "config1")
+	state = testReadResource(t, server, "utils_service_config", state)
+	requirePreviousConfigId(t, state, "config1")
+
+	// Changing settings keeps the config, and so the previous config.
+	state = testApplyResource(t, server, "utils_service_config", typ, state, config("Two", true))
+	requirePreviousConfigId(t, state, "config1")
+
+	state = testApplyResource(t, server, "utils_service_config", typ, state, config("Three", true))
+	requirePreviousConfigId(t, state, "config2")
+	state = testReadResource(t, server, "utils_service_config", state)
+	requirePreviousConfigId(t, state, "config2")
+}
+
+func TestResourceServiceConfigTriggers(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	fake := newFakeServiceManager()
+	fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+	server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+	typ := schemas.ResourceSchemas["utils_service_config"].ValueType()
+
+	triggersType := tftypes.Map{ElementType: tftypes.String}
+	config := func(triggers map[string]tftypes.Value) *tfprotov6.DynamicValue {
+		values := map[string]tftypes.Value{
+			"service_name":     tftypes.NewValue(tftypes.String, serviceName),
+			"config_yaml":      tftypes.NewValue(tftypes.String, "type: google.api.Service\nname: "+serviceName+"\n"),
+			"proto_descriptor": tftypes.NewValue(tftypes.String, ""),
+		}
+		if triggers != nil {
+			values["triggers"] = tftypes.NewValue(triggersType, triggers)
+		}
+		return testDynamicValue(t, typ, values)
+	}
+	requiresReplace := func(t *testing.T, priorState *tfprotov6.DynamicValue, priorPrivate []byte, config *tfprotov6.DynamicValue) bool {
+		t.Helper()
+
+		resp := testPlanResource(t, server, "utils_service_config", typ, priorState, priorPrivate, config)
+		for _, attrPath := range resp.RequiresReplace {
+			if attrPath.Equal(tftypes.NewAttributePath().WithAttributeName("triggers")) {
+				return true
+			}
+		}
+		return false
+	}
+
+	withTriggers := config(map[string]tftypes.Value{"image": tftypes.NewValue(tftypes.String, "v1")})
+	changedTriggers := config(map[string]tftypes.Value{"image": tftypes.NewValue(tftypes.String, "v2")})
+
+	t.Run("changed", func(t *testing.T) {
+		state := testApplyResource(t, server, "utils_service_config", typ, nil, withTriggers)
+		if !requiresReplace(t, state, nil, changedTriggers) {
+			t.Error("changing the triggers should require replacement")
+		}
+		if requiresReplace(t, state, nil, withTriggers) {
+			t.Error("unchanged triggers should not require replacement")
+		}
+	})
+
+	t.Run("added", func(t *testing.T) {
+		state := testApplyResource(t, server, "utils_service_config", typ, nil, config(nil))
+		if !requiresReplace(t, state, nil, withTriggers) {
+			t.Error("adding triggers should require replacement")
+		}
+	})
+
+	t.Run("imported", func(t *testing.T) {
+		state := testApplyResource(t, server, "utils_service_config", typ, nil, config(nil))
+		id := testStateAttributes(t, typ, state)["id"]
+		var importID string
+		if err := id.As(&importID); err != nil {
+			t.Fatal(err)
+		}
+
+		importResp, err := server.ImportResourceState(ctx, &tfprotov6.ImportResourceStateRequest{
+			TypeName: "utils_service_config",
+			ID:       importID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		requireNoErrors(t, importResp.Diagnostics)
+		imported := importResp.ImportedResources[0]
+
+		readResp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+			TypeName:     "utils_service_config",
+			CurrentState: imported.State,
+			Private:      imported.Private,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		requireNoErrors(t, readResp.Diagnostics)
+
+		if requiresReplace(t, readResp.NewState, readResp.Private, withTriggers) {
+			t.Error("setting triggers on an imported config should not require replacement")
+		}
+	})
+}
+
+func TestResourceServiceConfigFilePaths(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+	configYaml := "type: google.api.Service\nname: " + serviceName + "\n"
+
+	fake := newFakeServiceManager()
+	fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+	server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+	typ := schemas.ResourceSchemas["utils_service_config"].ValueType()
+
+	config := func(configYamlPath, descriptorPathName string) *tfprotov6.DynamicValue {
+		values := map[string]tftypes.Value{
+			"service_name":     tftypes.NewValue(tftypes.String, serviceName),
+			"config_yaml":      tftypes.NewValue(tftypes.String, configYaml),
+			"proto_descriptor": tftypes.NewValue(tftypes.String, base64.StdEncoding.EncodeToString([]byte("descriptor"))),
+		}
+		if configYamlPath != "" {
+			values["config_yaml_path"] = tftypes.NewValue(tftypes.String, configYamlPath)
+		}
+		if descriptorPathName != "" {
+			values["proto_descriptor_path_name"] = tftypes.NewValue(tftypes.String, descriptorPathName)
+		}
+		return testDynamicValue(t, typ, values)
+	}
+	submittedPaths := func() []string {
+		var paths []string
+		for _, file := range fake.submitted[len(fake.submitted)-1].GetConfigSource().GetFiles() {
+			paths = append(paths, file.GetFilePath())
+		}
+		return paths
+	}
+	plannedId := func(t *testing.T, state, config *tfprotov6.DynamicValue) tftypes.Value {
+		t.Helper()
+		planned := testPlanResource(t, server, "utils_service_config", typ, state, nil, config).PlannedState
+		return testStateAttributes(t, typ, planned)["id"]
+	}
+
+	t.Run("default", func(t *testing.T) {
+		testApplyResource(t, server, "utils_service_config", typ, nil, config("", ""))
+		if got, want := submittedPaths(), []string{"service.yaml", "descriptor.pb"}; !slices.Equal(got, want) {
+			t.Errorf("got submitted paths %v, want %v", got, want)
+		}
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		state := testApplyResource(t, server, "utils_service_config", typ, nil, config("api/service.yaml", "api/descriptor.pb"))
+		if got, want := submittedPaths(), []string{"api/service.yaml", "api/descriptor.pb"}; !slices.Equal(got, want) {
+			t.Errorf("got submitted paths %v, want %v", got, want)
+		}
+
+		state = testReadResource(t, server, "utils_service_config", state)
+		id := testStateAttributes(t, typ, state)["id"]
+		if got := plannedId(t, state, config("api/service.yaml", "api/descriptor.pb")); !got.Equal(id) {
+			t.Errorf("got planned id %v, want %v", got, id)
+		}
+		if got := plannedId(t, state, config("service.yaml", "api/descriptor.pb")); got.IsKnown() {
+			t.Errorf("renaming config_yaml_path should submit a new config, got planned id %v", got)
+		}
+		if got := plannedId(t, state, config("api/service.yaml", "")); got.IsKnown() {
+			t.Errorf("renaming proto_descriptor_path_name should submit a new config, got planned id %v", got)
+		}
+	})
+
+	// State written before the paths were configurable has no paths, which
+	// must not submit a new config.
+	t.Run("upgraded", func(t *testing.T) {
+		state := testApplyResource(t, server, "utils_service_config", typ, nil, config("", ""))
+		attrs := testStateAttributes(t, typ, state)
+		attrs["config_yaml_path"] = tftypes.NewValue(tftypes.String, nil)
+		attrs["proto_descriptor_path_name"] = tftypes.NewValue(tftypes.String, nil)
+		state = testDynamicValue(t, typ, attrs)
+
+		if got := plannedId(t, state, config("", "")); !got.Equal(attrs["id"]) {
+			t.Errorf("got planned id %v, want %v", got, attrs["id"])
+		}
+	})
+
+	// Configs with several files of the same type are matched by path.
+	t.Run("matched by path", func(t *testing.T) {
+		var sourceFiles []*anypb.Any
+		for _, file := range []*servicemanagementpb.ConfigFile{
+			{FilePath: "other.yaml", FileContents: []byte("type: google.api.Service\ntitle: Other\n"), FileType: servicemanagementpb.ConfigFile_SERVICE_CONFIG_YAML},
+			{FilePath: "api/service.yaml", FileContents: []byte(configYaml), FileType: servicemanagementpb.ConfigFile_SERVICE_CONFIG_YAML},
+			{FilePath: "api/descriptor.pb", FileContents: []byte("descriptor"), FileType: servicemanagementpb.ConfigFile_FILE_DESCRIPTOR_SET_PROTO},
+		} {
+			sourceFile, err := anypb.New(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sourceFiles = append(sourceFiles, sourceFile)
+		}
+		fake.mu.Lock()
+		fake.configs[serviceName+"/multiple"] = &serviceconfig.Service{
+			Name:       serviceName,
+			Id:         "multiple",
+			SourceInfo: &serviceconfig.SourceInfo{SourceFiles: sourceFiles},
+		}
+		fake.mu.Unlock()
+
+		state := testDynamicValue(t, typ, map[string]tftypes.Value{
+			"id":                         tftypes.NewValue(tftypes.String, serviceName+"/multiple"),
+			"service_name":               tftypes.NewValue(tftypes.String, serviceName),
+			"config_yaml":                tftypes.NewValue(tftypes.String, configYaml),
+			"config_yaml_path":           tftypes.NewValue(tftypes.String, "api/service.yaml"),
+			"proto_descriptor":           tftypes.NewValue(tftypes.String, base64.StdEncoding.EncodeToString([]byte("descriptor"))),
+			"proto_descriptor_path_name": tftypes.NewValue(tftypes.String, "api/descriptor.pb"),
+		})
+		attrs := testStateAttributes(t, typ, testReadResource(t, server, "utils_service_config", state))
+		if want := tftypes.NewValue(tftypes.String, configYaml); !attrs["config_yaml"].Equal(want) {
+			t.Errorf("got config_yaml %v, want %v", attrs["config_yaml"], want)
+		}
+		if want := tftypes.NewValue(tftypes.String, "api/service.yaml"); !attrs["config_yaml_path"].Equal(want) {
+			t.Errorf("got config_yaml_path %v, want %v", attrs["config_yaml_path"], want)
+		}
+	})
+}
+
+func TestResourceServiceConfigLargeDescriptor(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	// Descriptors with source info for many protos easily exceed the gRPC
+	// default limit of 4 MB.
+	descriptor, err := proto.Marshal(&descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{{
+			Name: proto.String("large.proto"),
+			SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+				Location: []*descriptorpb.SourceCodeInfo_Location{{
+					LeadingComments: proto.String(strings.Repeat("x", 8<<20)),
+				}},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newServer := func(t *testing.T, opts ...grpc.DialOption) (tfprotov6.ProviderServer, tftypes.Type) {
+		fake := newFakeServiceManager()
+		fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+		server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake, opts...))
+		return server, schemas.ResourceSchemas["utils_service_config"].ValueType()
+	}
+	config := func(t *testing.T, typ tftypes.Type) *tfprotov6.DynamicValue {
+		return testDynamicValue(t, typ, map[string]tftypes.Value{
+			"service_name":     tftypes.NewValue(tftypes.String, serviceName),
+			"config_yaml":      tftypes.NewValue(tftypes.String, "type: google.api.Service\nname: "+serviceName+"\n"),
+			"proto_descriptor": tftypes.NewValue(tftypes.String, base64.StdEncoding.EncodeToString(descriptor)),
+		})
+	}
+
+	t.Run("default limit", func(t *testing.T) {
+		server, typ := newServer(t)
+		priorState := testNullDynamicValue(t, typ)
+		planResp := testPlanResource(t, server, "utils_service_config", typ, priorState, nil, config(t, typ))
+		resp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+			TypeName:     "utils_service_config",
+			PriorState:   priorState,
+			PlannedState: planResp.PlannedState,
+			Config:       config(t, typ),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Diagnostics) == 0 || !strings.Contains(resp.Diagnostics[0].Detail, "larger than max") {
+			t.Errorf("expected the message size limit to be exceeded, got %v", resp.Diagnostics)
+		}
+	})
+
+	t.Run("raised limit", func(t *testing.T) {
+		server, typ := newServer(t, maxMessageSizeDialOption(defaultMaxMessageSizeMB<<20))
+		state := testApplyResource(t, server, "utils_service_config", typ, nil, config(t, typ))
+		state = testReadResource(t, server, "utils_service_config", state)
+
+		var got string
+		if err := testStateAttributes(t, typ, state)["proto_descriptor"].As(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != base64.StdEncoding.EncodeToString(descriptor) {
+			t.Errorf("got proto_descriptor of %d bytes, want %d bytes", len(got), base64.StdEncoding.EncodedLen(len(descriptor)))
+		}
+	})
+}
+
+func TestResourceServiceConfigJSON(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+	configJSON := `{"type": "google.api.Service", "name": "` + serviceName + `", "config_version": 3}`
+
+	fake := newFakeServiceManager()
+	fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+	server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+	typ := schemas.ResourceSchemas["utils_service_config"].ValueType()
+
+	t.Run("both json and yaml", func(t *testing.T) {
+		resp, err := server.ValidateResourceConfig(ctx, &tfprotov6.ValidateResourceConfigRequest{
+			TypeName: "utils_service_config",
+			Config: testDynamicValue(t, typ, map[string]tftypes.Value{
+				"service_name":     tftypes.NewValue(tftypes.String, serviceName),
+				"config_yaml":      tftypes.NewValue(tftypes.String, "name: "+serviceName+"\n"),
+				"config_json":      tftypes.NewValue(tftypes.String, configJSON),
+				"proto_descriptor": tftypes.NewValue(tftypes.String, ""),
+			}),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Diagnostics) == 0 || resp.Diagnostics[0].Severity != tfprotov6.DiagnosticSeverityError {
+			t.Errorf("expected an error when both config_yaml and config_json are set, got %v", resp.Diagnostics)
+		}
+	})
+
+	t.Run("submit and read", func(t *testing.T) {
+		newState := testApplyResource(t, server, "utils_service_config", typ, nil, testDynamicValue(t, typ, map[string]tftypes.Value{
+			"service_name":     tftypes.NewValue(tftypes.String, serviceName),
+			"config_json":      tftypes.NewValue(tftypes.String, configJSON),
+			"proto_descriptor": tftypes.NewValue(tftypes.String, ""),
+		}))
+
+		files := fake.submitted[len(fake.submitted)-1].GetConfigSource().GetFiles()
+		if got := files[0]; got.GetFilePath() != "service.json" || got.GetFileType() != servicemanagementpb.ConfigFile_SERVICE_CONFIG_YAML || string(got.GetFileContents()) != configJSON {
+			t.Errorf("got submitted file %v", got)
+		}
+
+		readResp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+			TypeName:     "utils_service_config",
+			CurrentState: newState,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		requireNoErrors(t, readResp.Diagnostics)
+
+		attrs := testStateAttributes(t, typ, readResp.NewState)
+		var got string
+		if err := attrs["config_json"].As(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != configJSON {
+			t.Errorf("got config_json %q, want %q", got, configJSON)
+		}
+		if !attrs["config_yaml"].IsNull() || !attrs["config_files"].IsNull() {
+			t.Errorf("got config_yaml %v and config_files %v, want null", attrs["config_yaml"], attrs["config_files"])
+		}
+	})
+}
+
+func TestMergeProtoDescriptors(t *testing.T) {
+	file := func(name string, messages ...string) *descriptorpb.FileDescriptorProto {
+		f := &descriptorpb.FileDescriptorProto{Name: proto.String(name)}
+		for _, message := range messages {
+			f.MessageType = append(f.MessageType, &descriptorpb.DescriptorProto{Name: proto.String(message)})
+		}
+		return f
+	}
+	set := func(t *testing.T, files ...*descriptorpb.FileDescriptorProto) []byte {
+		t.Helper()
+		b, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: files})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	fileNames := func(t *testing.T, merged []byte) []string {
+		t.Helper()
+		var set descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(merged, &set); err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, f := range set.GetFile() {
+			names = append(names, f.GetName())
+		}
+		return names
+	}
+
+	t.Run("dedup", func(t *testing.T) {
+		merged, err := mergeProtoDescriptors([][]byte{
+			set(t, file("google/api/http.proto", "Http"), file("a/v1/a.proto", "A")),
+			set(t, file("google/api/http.proto", "Http"), file("b/v1/b.proto", "B")),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"google/api/http.proto", "a/v1/a.proto", "b/v1/b.proto"}
+		if got := fileNames(t, merged); !slices.Equal(got, want) {
+			t.Errorf("got files %v, want %v", got, want)
+		}
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		_, err := mergeProtoDescriptors([][]byte{
+			set(t, file("common/v1/common.proto", "Common")),
+			set(t, file("common/v1/common.proto", "Common", "Extra")),
+		})
+		if err == nil || !strings.Contains(err.Error(), "common/v1/common.proto") {
+			t.Errorf("got error %v, want a conflict for common/v1/common.proto", err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := mergeProtoDescriptors([][]byte{[]byte("not a descriptor")}); err == nil {
+			t.Error("expected an error for an invalid descriptor set")
+		}
+	})
+
+	t.Run("deterministic", func(t *testing.T) {
+		sets := [][]byte{
+			set(t, file("b/v1/b.proto", "B"), file("a/v1/a.proto", "A")),
+			set(t, file("c/v1/c.proto", "C")),
+		}
+		first, err := mergeProtoDescriptors(sets)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 10; i++ {
+			merged, err := mergeProtoDescriptors(sets)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(merged, first) {
+				t.Fatal("merged descriptor is not deterministic")
+			}
+		}
+		want := []string{"b/v1/b.proto", "a/v1/a.proto", "c/v1/c.proto"}
+		if got := fileNames(t, first); !slices.Equal(got, want) {
+			t.Errorf("got files %v, want %v", got, want)
+		}
+	})
+}
+
+func TestResourceServiceConfigStoreContentsInState(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+	yaml1 := "type: google.api.Service\nname: " + serviceName + "\ntitle: One\n"
+	yaml2 := "type: google.api.Service\nname: " + serviceName + "\ntitle: Two\n"
+	descriptor, err := proto.Marshal(&descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{{Name: proto.String("test.proto")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	descriptorBase64 := base64.StdEncoding.EncodeToString(descriptor)
+
+	fake := newFakeServiceManager()
+	fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+	server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+	typ := schemas.ResourceSchemas["utils_service_config"].ValueType()
+
+	config := func(configYaml string, storeContents bool) *tfprotov6.DynamicValue {
+		return testDynamicValue(t, typ, map[string]tftypes.Value{
+			"service_name":            tftypes.NewValue(tftypes.String, serviceName),
+			"config_yaml":             tftypes.NewValue(tftypes.String, configYaml),
+			"proto_descriptor":        tftypes.NewValue(tftypes.String, descriptorBase64),
+			"store_contents_in_state": tftypes.NewValue(tftypes.Bool, storeContents),
+		})
+	}
+	stringAttr := func(t *testing.T, state *tfprotov6.DynamicValue, name string) string {
+		t.Helper()
+		var s string
+		if err := testStateAttributes(t, typ, state)[name].As(&s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	requireNoChanges := func(t *testing.T, state, config *tfprotov6.DynamicValue) {
+		t.Helper()
+		planned, err := testPlanResource(t, server, "utils_service_config", typ, state, nil, config).PlannedState.Unmarshal(typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prior, err := state.Unmarshal(typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diffs, err := prior.Diff(planned); err != nil || len(diffs) > 0 {
+			t.Errorf("expected no changes, got %v (%v)", diffs, err)
+		}
+	}
+
+	// Only hashes are stored once the config has been refreshed.
+	state := testApplyResource(t, server, "utils_service_config", typ, nil, config(yaml1, false))
+	state = testReadResource(t, server, "utils_service_config", state)
+	if got, want := stringAttr(t, state, "config_yaml"), contentHash([]byte(yaml1)); got != want {
+		t.Errorf("got config_yaml %q, want %q", got, want)
+	}
+	if got, want := stringAttr(t, state, "proto_descriptor"), contentHash(descriptor); got != want {
+		t.Errorf("got proto_descriptor %q, want %q", got, want)
+	}
+	requireNoChanges(t, state, config(yaml1, false))
+
+	// Changing the config submits the full contents, including those which
+	// are only planned as hashes.
+	state = testApplyResource(t, server, "utils_service_config", typ, state, config(yaml2, false))
+	if got := len(fake.submitted); got != 2 {
+		t.Fatalf("got %d submitted configs, want 2", got)
+	}
+	for _, file := range fake.submitted[1].GetConfigSource().GetFiles() {
+		var want []byte
+		switch file.GetFileType() {
+		case servicemanagementpb.ConfigFile_SERVICE_CONFIG_YAML:
+			want = []byte(yaml2)
+		case servicemanagementpb.ConfigFile_FILE_DESCRIPTOR_SET_PROTO:
+			want = descriptor
+		}
+		if !bytes.Equal(file.GetFileContents(), want) {
+			t.Errorf("got submitted %s %q, want %q", file.GetFilePath(), file.GetFileContents(), want)
+		}
+	}
+	state = testReadResource(t, server, "utils_service_config", state)
+	requireNoChanges(t, state, config(yaml2, false))
+
+	t.Run("drift", func(t *testing.T) {
+		fake.mu.Lock()
+		remote := proto.Clone(fake.configs[serviceName+"/config2"]).(*serviceconfig.Service)
+		drifted, err := anypb.New(&servicemanagementpb.ConfigFile{
+			FilePath:     "service.yaml",
+			FileContents: []byte(yaml1),
+			FileType:     servicemanagementpb.ConfigFile_SERVICE_CONFIG_YAML,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		remote.SourceInfo.SourceFiles[0] = drifted
+		fake.configs[serviceName+"/drifted"] = remote
+		fake.mu.Unlock()
+
+		driftedState := testDynamicValue(t, typ, map[string]tftypes.Value{
+			"id":                      tftypes.NewValue(tftypes.String, serviceName+"/drifted"),
+			"service_name":            tftypes.NewValue(tftypes.String, serviceName),
+			"config_yaml":             tftypes.NewValue(tftypes.String, contentHash([]byte(yaml2))),
+			"proto_descriptor":        tftypes.NewValue(tftypes.String, contentHash(descriptor)),
+			"store_contents_in_state": tftypes.NewValue(tftypes.Bool, false),
+		})
+		driftedState = testReadResource(t, server, "utils_service_config", driftedState)
+
+		planned := testPlanResource(t, server, "utils_service_config", typ, driftedState, nil, config(yaml2, false)).PlannedState
+		if got := stringAttr(t, planned, "config_yaml"); got != yaml2 {
+			t.Errorf("got planned config_yaml %q, want %q", got, yaml2)
+		}
+	})
+
+	t.Run("migration", func(t *testing.T) {
+		submitted := len(fake.submitted)
+
+		// Storing contents again replaces the hashes without resubmitting.
+		contentsState := testApplyResource(t, server, "utils_service_config", typ, state, config(yaml2, true))
+		contentsState = testReadResource(t, server, "utils_service_config", contentsState)
+		if got := stringAttr(t, contentsState, "config_yaml"); got != yaml2 {
+			t.Errorf("got config_yaml %q, want %q", got, yaml2)
+		}
+		if got := stringAttr(t, contentsState, "proto_descriptor"); got != descriptorBase64 {
+			t.Errorf("got proto_descriptor %q, want %q", got, descriptorBase64)
+		}
+		requireNoChanges(t, contentsState, config(yaml2, true))
+
+		// And the other way around.
+		hashState := testApplyResource(t, server, "utils_service_config", typ, contentsState, config(yaml2, false))
+		hashState = testReadResource(t, server, "utils_service_config", hashState)
+		if got, want := stringAttr(t, hashState, "config_yaml"), contentHash([]byte(yaml2)); got != want {
+			t.Errorf("got config_yaml %q, want %q", got, want)
+		}
+		requireNoChanges(t, hashState, config(yaml2, false))
+
+		if got := len(fake.submitted); got != submitted {
+			t.Errorf("got %d new submitted configs, want 0", got-submitted)
+		}
+		if got, want := stringAttr(t, hashState, "id"), stringAttr(t, state, "id"); got != want {
+			t.Errorf("got id %q, want %q", got, want)
+		}
+	})
+}
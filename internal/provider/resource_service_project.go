@@ -1,31 +1,87 @@
 package provider
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"net/http"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
+	"cloud.google.com/go/servicemanagement/apiv1/servicemanagementpb"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/objectplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"google.golang.org/api/cloudbilling/v1"
+	"google.golang.org/api/cloudresourcemanager/v3"
 	"google.golang.org/api/serviceconsumermanagement/v1"
+	"google.golang.org/api/serviceusage/v1"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &ServiceProjectResource{}
+var _ resource.ResourceWithImportState = &ServiceProjectResource{}
+var _ resource.ResourceWithValidateConfig = &ServiceProjectResource{}
+var _ resource.ResourceWithModifyPlan = &ServiceProjectResource{}
+var _ resource.ResourceWithUpgradeState = &ServiceProjectResource{}
+
+// Deletion policies of tenant projects.
+const (
+	// deletionPolicyRemove removes the project from the tenancy unit, which
+	// schedules it for deletion.
+	deletionPolicyRemove = "REMOVE"
+	// deletionPolicyDelete deletes the project, keeping its tag in the
+	// tenancy unit with status DELETED.
+	deletionPolicyDelete = "DELETE"
+	// deletionPolicyAbandon only removes the project from state.
+	deletionPolicyAbandon = "ABANDON"
+)
+
+// Folder change behaviors of tenant projects.
+const (
+	// folderChangeBehaviorMove applies the new folder to the project, which
+	// moves it.
+	folderChangeBehaviorMove = "MOVE"
+	// folderChangeBehaviorRecreate replaces the project with a new one in the
+	// new folder.
+	folderChangeBehaviorRecreate = "RECREATE"
+)
+
+// tenantProjectOperationKey is the private state key which holds the name of
+// the operation started by Create when `wait_for_completion` is false, until
+// it is found to be complete.
+const tenantProjectOperationKey = "operation"
+
+// waitForStatusNone disables waiting for the status of tenant projects.
+const waitForStatusNone = "NONE"
+
+// defaultTenantProjectTimeout is the default create and update timeout, which
+// bounds waiting for the tenant project to reach `wait_for_status`.
+const defaultTenantProjectTimeout = 30 * time.Minute
+
+// tenantProjectVisibleTimeout is how long Create and Update wait for the
+// tenant project to be listed after the operation completes.
+var tenantProjectVisibleTimeout = time.Minute
 
 func NewServiceProjectResource() resource.Resource {
 	return &ServiceProjectResource{}
@@ -43,15 +99,38 @@ type ServiceProjectResourceModel struct {
 	Tag           types.String `tfsdk:"tag"`
 	ProjectConfig types.Object `tfsdk:"project_config"`
 
+	DeleteOnFailure types.Bool   `tfsdk:"delete_on_failure"`
+	DeletionPolicy  types.String `tfsdk:"deletion_policy"`
+
+	FolderChangeBehavior types.String `tfsdk:"folder_change_behavior"`
+
+	UndeleteIfPendingDelete types.Bool   `tfsdk:"undelete_if_pending_delete"`
+	AttachExisting          types.Object `tfsdk:"attach_existing"`
+	ValidateBillingAccount  types.Bool   `tfsdk:"validate_billing_account"`
+	ValidateFolder          types.Bool   `tfsdk:"validate_folder"`
+	WaitForStatus           types.String `tfsdk:"wait_for_status"`
+	WaitForCompletion       types.Bool   `tfsdk:"wait_for_completion"`
+
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
+
 	// Computed
-	Status types.String `tfsdk:"status"`
+	Status              types.String `tfsdk:"status"`
+	ServiceAccountEmail types.String `tfsdk:"service_account_email"`
+	EffectivePolicyJSON types.String `tfsdk:"effective_policy_json"`
+	Resource            types.String `tfsdk:"resource"`
+	LastOperation       types.String `tfsdk:"last_operation"`
+}
+
+type ServiceProjectAttachExistingModel struct {
+	ExternalResource types.String `tfsdk:"external_resource"`
+	ReservedResource types.String `tfsdk:"reserved_resource"`
 }
 
 type ServiceProjectConfigModel struct {
 	Folder               types.String `tfsdk:"folder"`
 	TenantProjectPolicy  types.Object `tfsdk:"tenant_project_policy"`
 	Labels               types.Map    `tfsdk:"labels"`
-	Services             types.List   `tfsdk:"services"`
+	Services             types.Set    `tfsdk:"services"`
 	BillingConfig        types.Object `tfsdk:"billing_config"`
 	ServiceAccountConfig types.Object `tfsdk:"service_account_config"`
 }
@@ -63,7 +142,7 @@ func (ServiceProjectConfigModel) AttributeTypes() map[string]attr.Type {
 			AttrTypes: ServiceProjectConfigTenantProjectPolicyModel{}.AttributeTypes(),
 		},
 		"labels":   types.MapType{ElemType: types.StringType},
-		"services": types.ListType{ElemType: types.StringType},
+		"services": types.SetType{ElemType: types.StringType},
 		"billing_config": types.ObjectType{
 			AttrTypes: ServiceProjectConfigBillingConfigModel{}.AttributeTypes(),
 		},
@@ -74,12 +153,12 @@ func (ServiceProjectConfigModel) AttributeTypes() map[string]attr.Type {
 }
 
 type ServiceProjectConfigTenantProjectPolicyModel struct {
-	PolicyBindings types.List `tfsdk:"policy_bindings"`
+	PolicyBindings types.Set `tfsdk:"policy_bindings"`
 }
 
 func (ServiceProjectConfigTenantProjectPolicyModel) AttributeTypes() map[string]attr.Type {
 	return map[string]attr.Type{
-		"policy_bindings": types.ListType{
+		"policy_bindings": types.SetType{
 			ElemType: types.ObjectType{AttrTypes: PolicyBinding{}.AttributeTypes()},
 		},
 	}
@@ -87,13 +166,13 @@ func (ServiceProjectConfigTenantProjectPolicyModel) AttributeTypes() map[string]
 
 type PolicyBinding struct {
 	Role    types.String `tfsdk:"role"`
-	Members types.List   `tfsdk:"members"`
+	Members types.Set    `tfsdk:"members"`
 }
 
 func (PolicyBinding) AttributeTypes() map[string]attr.Type {
 	return map[string]attr.Type{
 		"role":    types.StringType,
-		"members": types.ListType{ElemType: types.StringType},
+		"members": types.SetType{ElemType: types.StringType},
 	}
 }
 
@@ -109,13 +188,13 @@ func (ServiceProjectConfigBillingConfigModel) AttributeTypes() map[string]attr.T
 
 type ServiceProjectConfigServiceAccountConfigModel struct {
 	AccountID          types.String `tfsdk:"account_id"`
-	TenantProjectRoles types.List   `tfsdk:"tenant_project_roles"`
+	TenantProjectRoles types.Set    `tfsdk:"tenant_project_roles"`
 }
 
 func (ServiceProjectConfigServiceAccountConfigModel) AttributeTypes() map[string]attr.Type {
 	return map[string]attr.Type{
 		"account_id":           types.StringType,
-		"tenant_project_roles": types.ListType{ElemType: types.StringType},
+		"tenant_project_roles": types.SetType{ElemType: types.StringType},
 	}
 }
 
@@ -127,6 +206,7 @@ func (r *ServiceProjectResource) Schema(ctx context.Context, req resource.Schema
 	resp.Schema = schema.Schema{
 		// This description is used by the documentation generator and the language server.
 		MarkdownDescription: "A service manager service.",
+		Version:             2,
 
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
@@ -144,8 +224,11 @@ func (r *ServiceProjectResource) Schema(ctx context.Context, req resource.Schema
 				},
 			},
 			"tag": schema.StringAttribute{
-				MarkdownDescription: "The tag to apply to the project.",
+				MarkdownDescription: "The tag to apply to the project. It must be at most 128 characters long, and cannot contain slashes.",
 				Required:            true,
+				Validators: []validator.String{
+					tenantTagValidator{},
+				},
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.RequiresReplaceIfConfigured(),
 				},
@@ -155,24 +238,27 @@ func (r *ServiceProjectResource) Schema(ctx context.Context, req resource.Schema
 				Required:            true,
 				Attributes: map[string]schema.Attribute{
 					"folder": schema.StringAttribute{
-						MarkdownDescription: "Folder where project in this tenancy unit must be located This folder must have been previously created with the required permissions for the caller to create and configure a project in it. Valid folder resource names have the format folders/{folder_number} (for example, folders/123456).",
-						Required:            true,
+						MarkdownDescription: "Folder where project in this tenancy unit must be located This folder must have been previously created with the required permissions for the caller to create and configure a project in it. Valid folder resource names have the format folders/{folder_number} (for example, folders/123456). Required unless `attach_existing` is set, in which case it cannot be set, since an attached project is not moved. Changes made outside of Terraform are detected.",
+						Optional:            true,
+						Validators: []validator.String{
+							stringvalidator.RegexMatches(regexp.MustCompile("^folders/[0-9]+$"), "The folder must be in the format `folders/{folder_number}`."),
+						},
 					},
 					"tenant_project_policy": schema.SingleNestedAttribute{
 						MarkdownDescription: "Describes ownership and policies for the new tenant project. Required.",
 						Required:            true,
 						Attributes: map[string]schema.Attribute{
-							"policy_bindings": schema.ListNestedAttribute{
-								MarkdownDescription: "Policy bindings to be applied to the tenant project, in addition to the 'roles/owner' role granted to the Service Consumer Management service account. At least one binding must have the role roles/owner. Among the list of members for roles/owner, at least one of them must be either the user or group type.",
+							"policy_bindings": schema.SetNestedAttribute{
+								MarkdownDescription: "Policy bindings to be applied to the tenant project, in addition to the 'roles/owner' role granted to the Service Consumer Management service account. At least one binding must have the role roles/owner. Among the list of members for roles/owner, at least one of them must be either the user or group type. Changes made outside of Terraform are not detected.",
 								Required:            true,
 								NestedObject: schema.NestedAttributeObject{
 									Attributes: map[string]schema.Attribute{
 										"role": schema.StringAttribute{
-											MarkdownDescription: "The role to which members will be added.",
+											MarkdownDescription: "The role to which members will be added, in the format `roles/{role}` or `projects/{project}/roles/{role}`.",
 											Required:            true,
 										},
-										"members": schema.ListAttribute{
-											MarkdownDescription: "The members to add to the role.",
+										"members": schema.SetAttribute{
+											MarkdownDescription: "The members to add to the role, each starting with `user:`, `group:`, `serviceAccount:` or `domain:`.",
 											Required:            true,
 											ElementType:         types.StringType,
 										},
@@ -182,27 +268,30 @@ func (r *ServiceProjectResource) Schema(ctx context.Context, req resource.Schema
 						},
 					},
 					"labels": schema.MapAttribute{
-						MarkdownDescription: "Labels to apply to the project.",
+						MarkdownDescription: "Labels to apply to the project. Changes made outside of Terraform are detected.",
 						Optional:            true,
 						ElementType:         types.StringType,
 					},
-					"services": schema.ListAttribute{
-						MarkdownDescription: "Google Cloud API names of services that are activated on this project during provisioning. If any of these services can't be activated, the request fails. For example: 'compute.googleapis.com','cloudfunctions.googleapis.com'",
+					"services": schema.SetAttribute{
+						MarkdownDescription: "Google Cloud API names of services that are activated on this project during provisioning. If any of these services can't be activated, the request fails. For example: 'compute.googleapis.com','cloudfunctions.googleapis.com'. Configured services which are disabled outside of Terraform are detected; other enabled services are ignored.",
 						Optional:            true,
 						ElementType:         types.StringType,
 					},
 					"billing_config": schema.SingleNestedAttribute{
-						MarkdownDescription: "Billing account properties. The billing account must be specified.",
+						MarkdownDescription: "Billing account properties. The billing account must be specified. Changes made outside of Terraform are not detected.",
 						Required:            true,
 						Attributes: map[string]schema.Attribute{
 							"billing_account": schema.StringAttribute{
 								MarkdownDescription: "Name of the billing account. For example billingAccounts/012345-567890-ABCDEF.",
 								Required:            true,
+								Validators: []validator.String{
+									stringvalidator.RegexMatches(regexp.MustCompile("^billingAccounts/[0-9A-F]{6}-[0-9A-F]{6}-[0-9A-F]{6}$"), "The billing account must be in the format `billingAccounts/{billing_account_id}`, for example `billingAccounts/012345-567890-ABCDEF`."),
+								},
 							},
 						},
 					},
 					"service_account_config": schema.SingleNestedAttribute{
-						MarkdownDescription: "Configuration for the IAM service account on the tenant project.",
+						MarkdownDescription: "Configuration for the IAM service account on the tenant project. Changes made outside of Terraform are not detected.",
 						Required:            true,
 						Attributes: map[string]schema.Attribute{
 							"account_id": schema.StringAttribute{
@@ -212,7 +301,7 @@ func (r *ServiceProjectResource) Schema(ctx context.Context, req resource.Schema
 									stringvalidator.RegexMatches(regexp.MustCompile("^[a-z]([-a-z0-9]*[a-z0-9])$"), "The account ID must be 6-30 characters long and match the regular expression [a-z]([-a-z0-9]*[a-z0-9])."),
 								},
 							},
-							"tenant_project_roles": schema.ListAttribute{
+							"tenant_project_roles": schema.SetAttribute{
 								MarkdownDescription: "Roles for the associated service account for the tenant project.",
 								Required:            true,
 								ElementType:         types.StringType,
@@ -221,6 +310,83 @@ func (r *ServiceProjectResource) Schema(ctx context.Context, req resource.Schema
 					},
 				},
 			},
+			"delete_on_failure": schema.BoolAttribute{
+				MarkdownDescription: "Whether to delete the tenant project if it is created with status `FAILED`, so that the next apply creates it again. Defaults to `false`, which keeps the failed project in state.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"deletion_policy": schema.StringAttribute{
+				MarkdownDescription: "What to do with the tenant project when the resource is destroyed. `REMOVE` removes it from the tenancy unit, which schedules it for deletion. `DELETE` deletes it, keeping its tag in the tenancy unit with status `DELETED`. `ABANDON` leaves the project as is and only removes it from state. Defaults to `REMOVE`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(deletionPolicyRemove),
+				Validators: []validator.String{
+					stringvalidator.OneOf(deletionPolicyRemove, deletionPolicyDelete, deletionPolicyAbandon),
+				},
+			},
+			"folder_change_behavior": schema.StringAttribute{
+				MarkdownDescription: "What to do when `project_config.folder` changes. `MOVE` moves the tenant project to the new folder. `RECREATE` replaces it with a new project in the new folder, applying `deletion_policy` to the old one. Defaults to `MOVE`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(folderChangeBehaviorMove),
+				Validators: []validator.String{
+					stringvalidator.OneOf(folderChangeBehaviorMove, folderChangeBehaviorRecreate),
+				},
+			},
+			"undelete_if_pending_delete": schema.BoolAttribute{
+				MarkdownDescription: "Whether to restore a tenant project with the same tag which was deleted recently, and apply `project_config` to it, rather than adding a new project. A deleted project keeps its tag until it is removed, so adding a project with the same tag fails. Defaults to `false`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"attach_existing": schema.SingleNestedAttribute{
+				MarkdownDescription: "An existing project to attach to the tenancy unit with the tag, rather than creating a new one. `project_config` is applied to the project once it is attached. Exactly one of `external_resource` or `reserved_resource` must be set. Destroying the resource applies `deletion_policy` to the attached project like any other; use `ABANDON` to keep it.",
+				Optional:            true,
+				Attributes: map[string]schema.Attribute{
+					"external_resource": schema.StringAttribute{
+						MarkdownDescription: "A project created outside of the tenancy unit, in the format `projects/{project_number}`.",
+						Optional:            true,
+						Validators: []validator.String{
+							stringvalidator.RegexMatches(regexp.MustCompile("^projects/[0-9]+$"), "The external resource must be in the format `projects/{project_number}`."),
+							stringvalidator.ExactlyOneOf(path.MatchRelative().AtParent().AtName("reserved_resource")),
+						},
+					},
+					"reserved_resource": schema.StringAttribute{
+						MarkdownDescription: "The tag of an active project reserved in the tenancy unit of the service producer project.",
+						Optional:            true,
+						Validators: []validator.String{
+							tenantTagValidator{},
+						},
+					},
+				},
+				PlanModifiers: []planmodifier.Object{
+					objectplanmodifier.RequiresReplace(),
+				},
+			},
+			"validate_billing_account": schema.BoolAttribute{
+				MarkdownDescription: "Whether to check that `project_config.billing_config.billing_account` exists and is open when it is planned to change. The check is skipped if the caller lacks the `billing.accounts.get` permission on the billing account.",
+				Optional:            true,
+			},
+			"validate_folder": schema.BoolAttribute{
+				MarkdownDescription: "Whether to check that `project_config.folder` exists when it is planned to change, and warn if the Service Consumer Management service agent of the producer project is not granted a role to create projects on it. Requires the `resourcemanager.folders.get` and `resourcemanager.folders.getIamPolicy` permissions on the folder.",
+				Optional:            true,
+			},
+			"wait_for_status": schema.StringAttribute{
+				MarkdownDescription: "The status to wait for the tenant project to reach after it is created or configured, since it can remain `PENDING_CREATE` after the operation completes. `NONE` disables waiting. Defaults to `ACTIVE`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString("ACTIVE"),
+				Validators: []validator.String{
+					stringvalidator.OneOf("ACTIVE", waitForStatusNone),
+				},
+			},
+			"wait_for_completion": schema.BoolAttribute{
+				MarkdownDescription: "Whether Create waits for the operation which creates the tenant project to complete. If `false`, Create returns as soon as the operation is started, with `status` set to `PENDING_CREATE`, and the next refresh or apply waits for it to complete. Defaults to `true`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+			},
 			"status": schema.StringAttribute{
 				MarkdownDescription: `
 Status: Status of tenant resource.
@@ -234,8 +400,150 @@ Possible values:
   "DELETED" - Tenant resource has been deleted.`,
 				Computed: true,
 			},
+			"service_account_email": schema.StringAttribute{
+				MarkdownDescription: "The email of the IAM service account created in the tenant project for `service_account_config`. It is built from the ID of the tenant project, which is looked up with the `resourcemanager.projects.get` permission.",
+				Computed:            true,
+			},
+			"effective_policy_json": schema.StringAttribute{
+				MarkdownDescription: "The IAM policy of the tenant project as JSON, including the bindings added by Service Consumer Management, with bindings sorted by role and members sorted. It is read when the resource is refreshed, and is null if the caller lacks the `resourcemanager.projects.getIamPolicy` permission on the project.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"resource": schema.StringAttribute{
+				MarkdownDescription: "The full resource name of the tenant project, in the format `projects/{project_number}`. Null while the project is not listed by the tenancy unit.",
+				Computed:            true,
+			},
+			"last_operation": schema.StringAttribute{
+				MarkdownDescription: "The name of the last operation started on the tenant project by Terraform, in the format `operations/{operation_id}`. It is recorded before waiting for the operation, so it is kept in state if waiting fails.",
+				Computed:            true,
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create:            true,
+				Update:            true,
+				CreateDescription: "How long to wait for the tenant project to reach `wait_for_status` once it is created. Defaults to `30m`.",
+				UpdateDescription: "How long to wait for the tenant project to reach `wait_for_status` once it is configured. Defaults to `30m`.",
+			}),
+		},
+	}
+}
+
+// UpgradeState implements resource.ResourceWithUpgradeState.
+func (r *ServiceProjectResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		// Version 1 changed `project_config.services` from a list to a set.
+		0: {
+			StateUpgrader: upgradeServiceProjectSetsState,
 		},
+		// Version 2 changed `policy_bindings`, their `members` and
+		// `tenant_project_roles` from lists to sets.
+		1: {
+			StateUpgrader: upgradeServiceProjectSetsState,
+		},
+	}
+}
+
+// upgradeServiceProjectSetsState removes duplicates from the attributes of
+// `project_config` which changed from lists to sets, since both are otherwise
+// stored the same in raw state.
+func upgradeServiceProjectSetsState(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal(req.RawState.JSON, &state); err != nil {
+		resp.Diagnostics.AddError("Could not upgrade state", err.Error())
+		return
+	}
+	err := uniqueRawObject(state, "project_config", func(projectConfig map[string]json.RawMessage) error {
+		if err := uniqueRawSet(projectConfig, "services", nil); err != nil {
+			return err
+		}
+		err := uniqueRawObject(projectConfig, "tenant_project_policy", func(policy map[string]json.RawMessage) error {
+			return uniqueRawSet(policy, "policy_bindings", func(binding map[string]json.RawMessage) error {
+				return uniqueRawSet(binding, "members", nil)
+			})
+		})
+		if err != nil {
+			return err
+		}
+		return uniqueRawObject(projectConfig, "service_account_config", func(serviceAccountConfig map[string]json.RawMessage) error {
+			return uniqueRawSet(serviceAccountConfig, "tenant_project_roles", nil)
+		})
+	})
+	if err != nil {
+		resp.Diagnostics.AddError("Could not upgrade state", err.Error())
+		return
+	}
+	upgraded, err := json.Marshal(state)
+	if err != nil {
+		resp.Diagnostics.AddError("Could not upgrade state", err.Error())
+		return
+	}
+	resp.DynamicValue = &tfprotov6.DynamicValue{JSON: upgraded}
+}
+
+// uniqueRawObject calls upgrade with the raw object at key in parent, if it is
+// set, and stores the result.
+func uniqueRawObject(parent map[string]json.RawMessage, key string, upgrade func(map[string]json.RawMessage) error) error {
+	var object map[string]json.RawMessage
+	if raw, ok := parent[key]; ok {
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return err
+		}
+	}
+	if object == nil {
+		return nil
+	}
+	if err := upgrade(object); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	parent[key] = raw
+	return nil
+}
+
+// uniqueRawSet removes duplicate elements from the raw list at key in parent,
+// if it is set. If upgrade is set, the elements are objects which are upgraded
+// before being compared.
+func uniqueRawSet(parent map[string]json.RawMessage, key string, upgrade func(map[string]json.RawMessage) error) error {
+	var elements []json.RawMessage
+	if raw, ok := parent[key]; ok {
+		if err := json.Unmarshal(raw, &elements); err != nil {
+			return err
+		}
+	}
+	if elements == nil {
+		return nil
+	}
+	unique := make([]json.RawMessage, 0, len(elements))
+	for _, element := range elements {
+		if upgrade != nil {
+			var object map[string]json.RawMessage
+			if err := json.Unmarshal(element, &object); err != nil {
+				return err
+			}
+			if err := upgrade(object); err != nil {
+				return err
+			}
+			var err error
+			if element, err = json.Marshal(object); err != nil {
+				return err
+			}
+		}
+		if !slices.ContainsFunc(unique, func(u json.RawMessage) bool { return bytes.Equal(u, element) }) {
+			unique = append(unique, element)
+		}
+	}
+	raw, err := json.Marshal(unique)
+	if err != nil {
+		return err
 	}
+	parent[key] = raw
+	return nil
 }
 
 func (r *ServiceProjectResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
@@ -258,6 +566,314 @@ func (r *ServiceProjectResource) Configure(ctx context.Context, req resource.Con
 	r.ServiceManagerClient = clients.ServiceManagerClient
 	r.TenantClient = clients.TenantClient
 	r.OperationsClient = clients.OperationsClient
+	r.ResourceManagerClient = clients.ResourceManagerClient
+	r.ServiceUsageClient = clients.ServiceUsageClient
+	r.BillingClient = clients.BillingClient
+	r.TenantProjectRetryAttempts = clients.TenantProjectRetryAttempts
+}
+
+// ValidateConfig implements resource.ResourceWithValidateConfig.
+func (r *ServiceProjectResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data ServiceProjectResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() || data.ProjectConfig.IsNull() || data.ProjectConfig.IsUnknown() {
+		return
+	}
+
+	var projectConfigModel ServiceProjectConfigModel
+	resp.Diagnostics.Append(data.ProjectConfig.As(ctx, &projectConfigModel, basetypes.ObjectAsOptions{})...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !data.AttachExisting.IsUnknown() && !projectConfigModel.Folder.IsUnknown() {
+		folder := path.Root("project_config").AtName("folder")
+		switch {
+		case !data.AttachExisting.IsNull() && !projectConfigModel.Folder.IsNull():
+			resp.Diagnostics.AddAttributeError(folder, "Invalid project configuration", "`project_config.folder` cannot be set with `attach_existing`, since an attached project is not moved.")
+		case data.AttachExisting.IsNull() && projectConfigModel.Folder.IsNull():
+			resp.Diagnostics.AddAttributeError(folder, "Invalid project configuration", "`project_config.folder` is required unless `attach_existing` is set.")
+		}
+	}
+
+	resp.Diagnostics.Append(projectConfigModel.validatePolicyBindings(ctx)...)
+}
+
+// ModifyPlan implements resource.ResourceWithModifyPlan.
+func (r *ServiceProjectResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to do on destroy.
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan, state ServiceProjectResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if !req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !req.State.Raw.IsNull() && plan.FolderChangeBehavior.ValueString() == folderChangeBehaviorRecreate {
+		if !folderOf(ctx, plan.ProjectConfig, &resp.Diagnostics).Equal(folderOf(ctx, state.ProjectConfig, &resp.Diagnostics)) {
+			resp.RequiresReplace = append(resp.RequiresReplace, path.Root("project_config").AtName("folder"))
+		}
+	}
+
+	// Only values which are planned to change are checked.
+	if plan.ValidateBillingAccount.ValueBool() {
+		billingAccount := billingAccountOf(ctx, plan.ProjectConfig, &resp.Diagnostics)
+		if !billingAccount.IsUnknown() && !billingAccount.IsNull() && !billingAccountOf(ctx, state.ProjectConfig, &resp.Diagnostics).Equal(billingAccount) {
+			resp.Diagnostics.Append(r.validateBillingAccount(ctx, billingAccount.ValueString())...)
+		}
+	}
+	if plan.ValidateFolder.ValueBool() {
+		folder := folderOf(ctx, plan.ProjectConfig, &resp.Diagnostics)
+		if !folder.IsUnknown() && !folder.IsNull() && !plan.TenancyUnit.IsUnknown() && !folderOf(ctx, state.ProjectConfig, &resp.Diagnostics).Equal(folder) {
+			resp.Diagnostics.Append(r.validateFolder(ctx, folder.ValueString(), plan.TenancyUnit.ValueString())...)
+		}
+	}
+}
+
+// folderOf returns the folder of the project config, which is unknown if the
+// project config is unknown.
+func folderOf(ctx context.Context, projectConfig types.Object, diags *diag.Diagnostics) types.String {
+	if projectConfig.IsUnknown() {
+		return types.StringUnknown()
+	}
+	if projectConfig.IsNull() {
+		return types.StringNull()
+	}
+	var projectConfigModel ServiceProjectConfigModel
+	diags.Append(projectConfig.As(ctx, &projectConfigModel, basetypes.ObjectAsOptions{})...)
+	if diags.HasError() {
+		return types.StringUnknown()
+	}
+	return projectConfigModel.Folder
+}
+
+// projectCreatorRoles are the predefined roles which grant
+// `resourcemanager.projects.create` on a folder.
+var projectCreatorRoles = []string{
+	"roles/owner",
+	"roles/resourcemanager.folderAdmin",
+	"roles/resourcemanager.projectCreator",
+}
+
+// validateFolder checks that the folder exists, and warns if the Service
+// Consumer Management service agent of the tenancy unit's service is not
+// granted a role to create projects in it, since the operation which creates
+// the project only fails much later.
+//
+// Only bindings on the folder itself can be checked, since inherited
+// bindings and custom roles are not visible in its policy, so a missing
+// binding is a warning.
+func (p *UtilsProviderConfig) validateFolder(ctx context.Context, folder, tenancyUnit string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	attrPath := path.Root("project_config").AtName("folder")
+
+	crmFolder, err := retryTransient(ctx, func(ctx context.Context) (*cloudresourcemanager.Folder, error) {
+		return p.ResourceManagerClient.Folders.Get(folder).Context(ctx).Do()
+	})
+	if err != nil {
+		if isGoogleAPIErrorCode(err, http.StatusNotFound, http.StatusForbidden) {
+			diags.AddAttributeError(
+				attrPath,
+				"Folder not found",
+				fmt.Sprintf("Folder %q does not exist or the caller lacks the resourcemanager.folders.get permission on it. "+
+					"Set `validate_folder = false` to skip this check.", folder),
+			)
+			return diags
+		}
+		diags.AddAttributeError(attrPath, "Error getting folder", err.Error())
+		return diags
+	}
+	if crmFolder.State != "ACTIVE" {
+		diags.AddAttributeError(attrPath, "Folder is not active", fmt.Sprintf("Folder %q is in state %s.", folder, crmFolder.State))
+		return diags
+	}
+
+	serviceAgent, err := p.tenantServiceAgent(ctx, tenancyUnit)
+	if err != nil {
+		diags.AddAttributeWarning(attrPath, "Could not check folder permissions", fmt.Sprintf("The service agent of tenancy unit %s could not be determined: %v", tenancyUnit, err))
+		return diags
+	}
+	policy, err := retryTransient(ctx, func(ctx context.Context) (*cloudresourcemanager.Policy, error) {
+		return p.ResourceManagerClient.Folders.GetIamPolicy(folder, &cloudresourcemanager.GetIamPolicyRequest{}).Context(ctx).Do()
+	})
+	if err != nil {
+		diags.AddAttributeWarning(attrPath, "Could not check folder permissions", fmt.Sprintf("The IAM policy of folder %q could not be read: %v", folder, err))
+		return diags
+	}
+	for _, binding := range policy.Bindings {
+		if slices.Contains(projectCreatorRoles, binding.Role) && slices.Contains(binding.Members, serviceAgent) {
+			return diags
+		}
+	}
+	diags.AddAttributeWarning(
+		attrPath,
+		"Service agent may not be able to create projects",
+		fmt.Sprintf("%s is not granted any of %s on folder %q, so creating the tenant project may fail. "+
+			"This can be ignored if it is granted on a parent of the folder, or with a custom role.", strings.TrimPrefix(serviceAgent, "serviceAccount:"), strings.Join(projectCreatorRoles, ", "), folder),
+	)
+	return diags
+}
+
+// tenantServiceAgent returns the IAM member of the Service Consumer Management
+// service agent of the producer project of the tenancy unit's service, which
+// creates its tenant projects.
+func (p *UtilsProviderConfig) tenantServiceAgent(ctx context.Context, tenancyUnit string) (string, error) {
+	serviceName, _, _, err := parseTenancyUnitName(tenancyUnit)
+	if err != nil {
+		return "", err
+	}
+	service, err := retryTransient(ctx, func(ctx context.Context) (*servicemanagementpb.ManagedService, error) {
+		return p.ServiceManagerClient.GetService(ctx, &servicemanagementpb.GetServiceRequest{ServiceName: serviceName})
+	})
+	if err != nil {
+		return "", err
+	}
+	project, err := retryTransient(ctx, func(ctx context.Context) (*cloudresourcemanager.Project, error) {
+		return p.ResourceManagerClient.Projects.Get("projects/" + service.ProducerProjectId).Context(ctx).Do()
+	})
+	if err != nil {
+		return "", err
+	}
+	projectNumber := strings.TrimPrefix(project.Name, "projects/")
+	return fmt.Sprintf("serviceAccount:service-%s@service-consumer-management.iam.gserviceaccount.com", projectNumber), nil
+}
+
+// billingAccountOf returns the billing account of the project config, which
+// is unknown if the project config or its billing config is unknown.
+func billingAccountOf(ctx context.Context, projectConfig types.Object, diags *diag.Diagnostics) types.String {
+	if projectConfig.IsUnknown() {
+		return types.StringUnknown()
+	}
+	if projectConfig.IsNull() {
+		return types.StringNull()
+	}
+	var projectConfigModel ServiceProjectConfigModel
+	diags.Append(projectConfig.As(ctx, &projectConfigModel, basetypes.ObjectAsOptions{})...)
+	if diags.HasError() || projectConfigModel.BillingConfig.IsUnknown() {
+		return types.StringUnknown()
+	}
+	if projectConfigModel.BillingConfig.IsNull() {
+		return types.StringNull()
+	}
+	var billingConfigModel ServiceProjectConfigBillingConfigModel
+	diags.Append(projectConfigModel.BillingConfig.As(ctx, &billingConfigModel, basetypes.ObjectAsOptions{})...)
+	if diags.HasError() {
+		return types.StringUnknown()
+	}
+	return billingConfigModel.BillingAccount
+}
+
+// validateBillingAccount checks that the billing account exists and is open,
+// so that it is reported against `billing_account` instead of as a failed
+// operation once the project is created.
+func (p *UtilsProviderConfig) validateBillingAccount(ctx context.Context, name string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	attrPath := path.Root("project_config").AtName("billing_config").AtName("billing_account")
+
+	account, err := retryTransient(ctx, func(ctx context.Context) (*cloudbilling.BillingAccount, error) {
+		return p.BillingClient.BillingAccounts.Get(name).Context(ctx).Do()
+	})
+	switch {
+	case isGoogleAPIErrorCode(err, http.StatusForbidden):
+		tflog.Warn(ctx, "Skipping billing account validation, since the caller cannot get the billing account", map[string]interface{}{
+			"billing_account": name,
+			"error":           err.Error(),
+		})
+		return diags
+	case isGoogleAPIErrorCode(err, http.StatusNotFound):
+		diags.AddAttributeError(
+			attrPath,
+			"Billing account not found",
+			fmt.Sprintf("Billing account %q does not exist. Set `validate_billing_account = false` to skip this check.", name),
+		)
+		return diags
+	case err != nil:
+		diags.AddAttributeError(attrPath, "Error getting billing account", err.Error())
+		return diags
+	}
+	if !account.Open {
+		diags.AddAttributeError(
+			attrPath,
+			"Billing account is closed",
+			fmt.Sprintf("Billing account %q is closed, so projects cannot be linked to it.", name),
+		)
+	}
+	return diags
+}
+
+var (
+	// policyRolePattern matches predefined roles and custom project roles.
+	policyRolePattern = regexp.MustCompile("^(roles/[^/]+|projects/[^/]+/roles/[^/]+)$")
+	// policyMemberPrefixes are the principal types accepted in policy
+	// bindings of tenant projects.
+	policyMemberPrefixes = []string{"user:", "group:", "serviceAccount:", "domain:"}
+)
+
+// validatePolicyBindings checks the policy bindings of the tenant project
+// policy, which are otherwise only checked once the operation fails. There
+// must be a `roles/owner` binding with a user or group member, which cannot
+// be checked if any binding is unknown.
+func (projectConfigModel ServiceProjectConfigModel) validatePolicyBindings(ctx context.Context) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if projectConfigModel.TenantProjectPolicy.IsNull() || projectConfigModel.TenantProjectPolicy.IsUnknown() {
+		return diags
+	}
+	var tenantProjectPolicyModel ServiceProjectConfigTenantProjectPolicyModel
+	diags.Append(projectConfigModel.TenantProjectPolicy.As(ctx, &tenantProjectPolicyModel, basetypes.ObjectAsOptions{})...)
+	if diags.HasError() || tenantProjectPolicyModel.PolicyBindings.IsNull() || tenantProjectPolicyModel.PolicyBindings.IsUnknown() {
+		return diags
+	}
+
+	bindingsPath := path.Root("project_config").AtName("tenant_project_policy").AtName("policy_bindings")
+	var owner, unknown bool
+	for _, element := range tenantProjectPolicyModel.PolicyBindings.Elements() {
+		bindingPath := bindingsPath.AtSetValue(element)
+		object, ok := element.(types.Object)
+		if !ok || object.IsUnknown() {
+			unknown = true
+			continue
+		}
+		var policyBinding PolicyBinding
+		diags.Append(object.As(ctx, &policyBinding, basetypes.ObjectAsOptions{})...)
+		if diags.HasError() {
+			return diags
+		}
+
+		role := policyBinding.Role.ValueString()
+		if policyBinding.Role.IsUnknown() {
+			unknown = true
+		} else if !policyRolePattern.MatchString(role) {
+			diags.AddAttributeError(bindingPath.AtName("role"), "Invalid policy binding", fmt.Sprintf("Role %q must be in the format `roles/{role}` or `projects/{project}/roles/{role}`.", role))
+		}
+
+		if policyBinding.Members.IsUnknown() {
+			unknown = true
+			continue
+		}
+		for _, element := range policyBinding.Members.Elements() {
+			member, ok := element.(types.String)
+			if !ok || member.IsUnknown() {
+				unknown = true
+				continue
+			}
+			if !slices.ContainsFunc(policyMemberPrefixes, func(prefix string) bool { return strings.HasPrefix(member.ValueString(), prefix) }) {
+				diags.AddAttributeError(bindingPath.AtName("members").AtSetValue(member), "Invalid policy binding", fmt.Sprintf("Member %q must start with one of `%s`.", member.ValueString(), strings.Join(policyMemberPrefixes, "`, `")))
+			}
+			if role == "roles/owner" && (strings.HasPrefix(member.ValueString(), "user:") || strings.HasPrefix(member.ValueString(), "group:")) {
+				owner = true
+			}
+		}
+	}
+	if !owner && !unknown {
+		diags.AddAttributeError(bindingsPath, "Invalid policy binding", "At least one policy binding must have the role `roles/owner` with a `user:` or `group:` member.")
+	}
+	return diags
 }
 
 func (r *ServiceProjectResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -270,54 +886,381 @@ func (r *ServiceProjectResource) Create(ctx context.Context, req resource.Create
 		return
 	}
 
+	// The policy is read on the next refresh.
+	data.EffectivePolicyJSON = types.StringNull()
+	// The remaining computed attributes are set once the project is listed,
+	// but state is written before waiting for each operation, so that the
+	// operation is recorded even if waiting fails.
+	data.ID = types.StringNull()
+	data.Status = types.StringNull()
+	data.ServiceAccountEmail = types.StringNull()
+	data.Resource = types.StringNull()
+	data.LastOperation = types.StringNull()
+	started := func(ctx context.Context, op *serviceconsumermanagement.Operation) {
+		data.LastOperation = types.StringValue(op.Name)
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	}
+
 	var projectConfigModel ServiceProjectConfigModel
 	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("project_config"), &projectConfigModel)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	projectConfig := projectConfigModel.toProjectConfig(ctx, resp.Diagnostics)
+	projectConfig := projectConfigModel.toProjectConfig(ctx, &resp.Diagnostics)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
 	parent := data.TenancyUnit.ValueString()
-	op, err := r.TenantClient.Services.TenancyUnits.AddProject(parent, &serviceconsumermanagement.AddTenantProjectRequest{
-		Tag:           data.Tag.ValueString(),
-		ProjectConfig: projectConfig,
-	}).Context(ctx).Do()
+	tag := data.Tag.ValueString()
 
-	if err != nil {
-		resp.Diagnostics.AddError("Error adding project", err.Error())
-		return
+	// A project which was deleted recently keeps its tag, so it cannot be
+	// added again, but it can be restored.
+	var deleted bool
+	if data.UndeleteIfPendingDelete.ValueBool() {
+		existing, err := r.getTenantProject(ctx, parent, tag)
+		if err != nil {
+			resp.Diagnostics.AddError("Error getting project", err.Error())
+			return
+		}
+		deleted = existing != nil && (existing.Status == "PENDING_DELETE" || existing.Status == "DELETED")
 	}
 
-	for !op.Done {
-		time.Sleep(5 * time.Second)
-
-		op, err = r.TenantClient.Operations.Get(op.Name).Context(ctx).Do()
+	wait := data.WaitForCompletion.ValueBool()
+	var op *serviceconsumermanagement.Operation
+	var err error
+	if !data.AttachExisting.IsNull() {
+		var attachExistingModel ServiceProjectAttachExistingModel
+		resp.Diagnostics.Append(data.AttachExisting.As(ctx, &attachExistingModel, basetypes.ObjectAsOptions{})...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		op, err = r.attachTenantProject(ctx, parent, tag, attachExistingModel, projectConfig, wait, started)
 		if err != nil {
-			resp.Diagnostics.AddError("Error getting operation", err.Error())
+			resp.Diagnostics.AddError("Error attaching project", err.Error())
+			return
+		}
+	} else if deleted {
+		op, err = r.undeleteTenantProject(ctx, parent, tag, projectConfig, wait, started)
+		if err != nil {
+			resp.Diagnostics.AddError("Error undeleting project", err.Error())
+			return
+		}
+	} else {
+		op, err = r.addTenantProject(ctx, parent, tag, projectConfig, wait, started)
+		if err != nil {
+			resp.Diagnostics.AddError("Error adding project", err.Error())
 			return
 		}
 	}
 
-	project, err := r.getTenantProject(ctx, data.TenancyUnit.ValueString(), data.Tag.ValueString())
+	// The operation is left to the next refresh or apply, which resumes
+	// waiting for it.
+	if !wait && !op.Done {
+		tflog.Info(ctx, "Not waiting for tenant project operation", map[string]interface{}{
+			"tenancy_unit": parent,
+			"tag":          tag,
+			"operation":    op.Name,
+		})
+		opName, _ := json.Marshal(op.Name)
+		resp.Diagnostics.Append(resp.Private.SetKey(ctx, tenantProjectOperationKey, opName)...)
+		data.Status = types.StringValue("PENDING_CREATE")
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+
+	project, err := r.getCreatedTenantProject(ctx, data.TenancyUnit.ValueString(), data.Tag.ValueString(), tenantProjectVisibleTimeout)
 	if err != nil {
 		resp.Diagnostics.AddError("Error getting project", err.Error())
 		return
 	}
 	if project == nil {
-		panic("project not found")
+		// The project was added, so keep it in state to be found by a later
+		// refresh, rather than orphaning it.
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		resp.Diagnostics.AddError("Error getting project", tenantProjectNotFound(data))
+		return
 	}
 
+	createTimeout, diags := data.Timeouts.Create(ctx, defaultTenantProjectTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	project, err = r.waitTenantProjectStatus(ctx, data.TenancyUnit.ValueString(), project, data.WaitForStatus.ValueString(), createTimeout)
+	data.setProject(project)
+	r.setServiceAccountEmail(ctx, &data, project, &resp.Diagnostics)
+	if err != nil {
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		resp.Diagnostics.AddError("Error waiting for project", err.Error())
+		return
+	}
+
+	if project.Status == "FAILED" {
+		detail := fmt.Sprintf("Tenant project %s with tag %q in tenancy unit %s has status FAILED after operation %s completed.", project.Resource, project.Tag, data.TenancyUnit.ValueString(), op.Name)
+		if data.DeleteOnFailure.ValueBool() {
+			err := r.deleteFailedTenantProject(ctx, data.TenancyUnit.ValueString(), project.Tag, started)
+			if err == nil {
+				resp.State.RemoveResource(ctx)
+				resp.Diagnostics.AddError("Tenant project creation failed", detail+" It was deleted, so that the next apply creates it again.")
+				return
+			}
+			detail += fmt.Sprintf(" It could not be deleted: %v", err)
+		} else {
+			detail += " Set delete_on_failure to delete it automatically, or delete it manually."
+		}
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		resp.Diagnostics.AddError("Tenant project creation failed", detail)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// tenantOperationStarted is called with each operation started on a tenant
+// project before it is waited for, so that it can be recorded in state.
+type tenantOperationStarted func(ctx context.Context, op *serviceconsumermanagement.Operation)
+
+// addTenantProject adds a tenant project with the given tag and config to the
+// tenancy unit, and returns the operation, which is completed unless wait is
+// false.
+//
+// If a previous apply was interrupted after adding the project, the tag
+// already exists. An active project with the tag is adopted by applying the
+// config to it, and a failed one is deleted before adding the project again.
+func (r *ServiceProjectResource) addTenantProject(ctx context.Context, tenancyUnit, tag string, projectConfig *serviceconsumermanagement.TenantProjectConfig, wait bool, started tenantOperationStarted) (*serviceconsumermanagement.Operation, error) {
+	add := func() (*serviceconsumermanagement.Operation, error) {
+		op, err := retryTenantProjectMutation(ctx, r.TenantProjectRetryAttempts, func(ctx context.Context) (*serviceconsumermanagement.Operation, error) {
+			return r.TenantClient.Services.TenancyUnits.AddProject(tenancyUnit, &serviceconsumermanagement.AddTenantProjectRequest{
+				Tag:           tag,
+				ProjectConfig: projectConfig,
+			}).Context(ctx).Do()
+		})
+		if err != nil {
+			return nil, err
+		}
+		started(ctx, op)
+		if !wait {
+			return op, nil
+		}
+		return op, r.waitTenantOperation(ctx, op)
+	}
+
+	op, err := add()
+	// The tag is reported as ALREADY_EXISTS or FAILED_PRECONDITION.
+	if !isGoogleAPIErrorCode(err, http.StatusConflict, http.StatusBadRequest) {
+		return op, err
+	}
+	existing, getErr := r.getTenantProject(ctx, tenancyUnit, tag)
+	if getErr != nil || existing == nil {
+		return nil, err
+	}
+	logFields := map[string]interface{}{
+		"tenancy_unit": tenancyUnit,
+		"tag":          tag,
+		"resource":     existing.Resource,
+	}
+	switch existing.Status {
+	case "ACTIVE":
+		tflog.Info(ctx, "Adopting existing tenant project", logFields)
+		op, err := r.applyTenantProjectConfig(ctx, tenancyUnit, tag, projectConfig, wait, started)
+		if err != nil {
+			return nil, fmt.Errorf("could not apply config to existing project: %w", err)
+		}
+		return op, nil
+	case "FAILED":
+		tflog.Info(ctx, "Deleting failed tenant project before adding it again", logFields)
+		if err := r.deleteFailedTenantProject(ctx, tenancyUnit, tag, started); err != nil {
+			return nil, fmt.Errorf("could not delete failed project with the same tag: %w", err)
+		}
+		return add()
+	}
+	return nil, err
+}
+
+// applyTenantProjectConfig applies the config to the tenant project with the
+// given tag, and returns the operation, which is completed unless wait is
+// false.
+func (r *ServiceProjectResource) applyTenantProjectConfig(ctx context.Context, tenancyUnit, tag string, projectConfig *serviceconsumermanagement.TenantProjectConfig, wait bool, started tenantOperationStarted) (*serviceconsumermanagement.Operation, error) {
+	op, err := retryTenantProjectMutation(ctx, r.TenantProjectRetryAttempts, func(ctx context.Context) (*serviceconsumermanagement.Operation, error) {
+		return r.TenantClient.Services.TenancyUnits.ApplyProjectConfig(tenancyUnit, &serviceconsumermanagement.ApplyTenantProjectConfigRequest{
+			Tag:           tag,
+			ProjectConfig: projectConfig,
+		}).Context(ctx).Do()
+	})
+	if err != nil {
+		return nil, err
+	}
+	started(ctx, op)
+	if !wait {
+		return op, nil
+	}
+	return op, r.waitTenantOperation(ctx, op)
+}
+
+// undeleteTenantProject restores the deleted tenant project with the given
+// tag and applies the config to it, and returns the operation applying the
+// config, which is completed unless wait is false.
+func (r *ServiceProjectResource) undeleteTenantProject(ctx context.Context, tenancyUnit, tag string, projectConfig *serviceconsumermanagement.TenantProjectConfig, wait bool, started tenantOperationStarted) (*serviceconsumermanagement.Operation, error) {
+	op, err := r.TenantClient.Services.TenancyUnits.UndeleteProject(tenancyUnit, &serviceconsumermanagement.UndeleteTenantProjectRequest{
+		Tag: tag,
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	started(ctx, op)
+	if err := r.waitTenantOperation(ctx, op); err != nil {
+		return nil, err
+	}
+
+	op, err = r.applyTenantProjectConfig(ctx, tenancyUnit, tag, projectConfig, wait, started)
+	if err != nil {
+		return nil, fmt.Errorf("could not apply config to undeleted project: %w", err)
+	}
+	return op, nil
+}
+
+// attachTenantProject attaches the existing project to the tenancy unit with
+// the given tag and applies the config to it, and returns the operation
+// applying the config, which is completed unless wait is false.
+func (r *ServiceProjectResource) attachTenantProject(ctx context.Context, tenancyUnit, tag string, attachExisting ServiceProjectAttachExistingModel, projectConfig *serviceconsumermanagement.TenantProjectConfig, wait bool, started tenantOperationStarted) (*serviceconsumermanagement.Operation, error) {
+	op, err := r.TenantClient.Services.TenancyUnits.AttachProject(tenancyUnit, &serviceconsumermanagement.AttachTenantProjectRequest{
+		Tag:              tag,
+		ExternalResource: attachExisting.ExternalResource.ValueString(),
+		ReservedResource: attachExisting.ReservedResource.ValueString(),
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	started(ctx, op)
+	if err := r.waitTenantOperation(ctx, op); err != nil {
+		return nil, err
+	}
+
+	op, err = r.applyTenantProjectConfig(ctx, tenancyUnit, tag, projectConfig, wait, started)
+	if err != nil {
+		return nil, fmt.Errorf("could not apply config to attached project: %w", err)
+	}
+	return op, nil
+}
+
+// deleteFailedTenantProject deletes the failed tenant project with the given
+// tag, and removes its tag from the tenancy unit so that it can be added
+// again.
+func (r *ServiceProjectResource) deleteFailedTenantProject(ctx context.Context, tenancyUnit, tag string, started tenantOperationStarted) error {
+	op, err := r.TenantClient.Services.TenancyUnits.DeleteProject(tenancyUnit, &serviceconsumermanagement.DeleteTenantProjectRequest{
+		Tag: tag,
+	}).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	started(ctx, op)
+	if err := r.waitTenantOperation(ctx, op); err != nil {
+		return err
+	}
+
+	op, err = r.TenantClient.Services.TenancyUnits.RemoveProject(tenancyUnit, &serviceconsumermanagement.RemoveTenantProjectRequest{
+		Tag: tag,
+	}).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	started(ctx, op)
+	return r.waitTenantOperation(ctx, op)
+}
+
+// waitTenantProjectStatus polls the tenant project until it has the given
+// status, or has failed, and returns it. It returns the project as last seen
+// if it does not reach the status within timeout, or if the status is
+// waitForStatusNone.
+func (r *ServiceProjectResource) waitTenantProjectStatus(ctx context.Context, tenancyUnit string, project *TenantResource, status string, timeout time.Duration) (*TenantResource, error) {
+	if status == waitForStatusNone {
+		return project, nil
+	}
+	done := func(project *TenantResource) bool {
+		return project.Status == status || project.Status == "FAILED"
+	}
+	last, err := pollOperation(ctx, timeout, project, done, func(ctx context.Context) (*TenantResource, error) {
+		next, err := r.getTenantProject(ctx, tenancyUnit, project.Tag)
+		if err == nil && next == nil {
+			return nil, fmt.Errorf("tenant project with tag %q in %s is no longer listed", project.Tag, tenancyUnit)
+		}
+		return next, err
+	})
+	if err != nil {
+		return last, fmt.Errorf("tenant project %s did not reach status %s: %w", project.Resource, status, err)
+	}
+	return last, nil
+}
+
+// setProject sets the computed attributes of the model from the tenant
+// project.
+func (data *ServiceProjectResourceModel) setProject(project *TenantResource) {
 	data.ID = types.StringValue(project.Resource)
 	data.Status = types.StringValue(project.Status)
+	data.Resource = types.StringNull()
+	if project.Resource != "" {
+		data.Resource = types.StringValue(project.Resource)
+	}
+}
 
-	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+// serviceAccountId returns the account ID of `service_account_config`, or ""
+// if it is not known, for example after import.
+func (data *ServiceProjectResourceModel) serviceAccountId(ctx context.Context, diags *diag.Diagnostics) string {
+	if data.ProjectConfig.IsNull() || data.ProjectConfig.IsUnknown() {
+		return ""
+	}
+	var projectConfigModel ServiceProjectConfigModel
+	diags.Append(data.ProjectConfig.As(ctx, &projectConfigModel, basetypes.ObjectAsOptions{})...)
+	if diags.HasError() || projectConfigModel.ServiceAccountConfig.IsNull() || projectConfigModel.ServiceAccountConfig.IsUnknown() {
+		return ""
+	}
+	var serviceAccountConfigModel ServiceProjectConfigServiceAccountConfigModel
+	diags.Append(projectConfigModel.ServiceAccountConfig.As(ctx, &serviceAccountConfigModel, basetypes.ObjectAsOptions{})...)
+	if diags.HasError() {
+		return ""
+	}
+	return serviceAccountConfigModel.AccountID.ValueString()
 }
 
-func (projectConfigModel ServiceProjectConfigModel) toProjectConfig(ctx context.Context, diags diag.Diagnostics) *serviceconsumermanagement.TenantProjectConfig {
+// setServiceAccountEmail sets `service_account_email` from the tenant
+// project. Tenant resources name projects by number, but service account
+// emails use the project ID, so the project is looked up. If it cannot be,
+// the prior email is kept with a warning.
+func (r *ServiceProjectResource) setServiceAccountEmail(ctx context.Context, data *ServiceProjectResourceModel, project *TenantResource, diags *diag.Diagnostics) {
+	prior := data.ServiceAccountEmail
+	data.ServiceAccountEmail = types.StringNull()
+
+	// Failed tenant resources may have no project.
+	if project.Status == "FAILED" || project.Resource == "" {
+		return
+	}
+	accountId := data.serviceAccountId(ctx, diags)
+	if accountId == "" {
+		return
+	}
+	crmProject, err := retryTransient(ctx, func(ctx context.Context) (*cloudresourcemanager.Project, error) {
+		return r.ResourceManagerClient.Projects.Get(project.Resource).Context(ctx).Do()
+	})
+	if err != nil {
+		if !prior.IsUnknown() {
+			data.ServiceAccountEmail = prior
+		}
+		diags.AddAttributeWarning(path.Root("service_account_email"), "Could not read tenant project", fmt.Sprintf("The service account email of tenant project %s could not be determined: %v", project.Resource, err))
+		return
+	}
+	email, err := project.ServiceAccountEmail(accountId, crmProject.ProjectId)
+	if err != nil {
+		diags.AddAttributeWarning(path.Root("service_account_email"), "Unexpected tenant resource", fmt.Sprintf("The service account email of tenant resource with tag %q is unknown: %v", project.Tag, err))
+		return
+	}
+	data.ServiceAccountEmail = types.StringValue(email)
+}
+
+// toProjectConfig converts the model to the API representation. If it cannot
+// be converted, it adds errors to diags and returns nil.
+func (projectConfigModel ServiceProjectConfigModel) toProjectConfig(ctx context.Context, diags *diag.Diagnostics) *serviceconsumermanagement.TenantProjectConfig {
 	var tenantProjectPolicy serviceconsumermanagement.TenantProjectPolicy
 	if !projectConfigModel.TenantProjectPolicy.IsUnknown() && !projectConfigModel.TenantProjectPolicy.IsNull() {
 		var tenantProjectPolicyModel ServiceProjectConfigTenantProjectPolicyModel
@@ -325,9 +1268,9 @@ func (projectConfigModel ServiceProjectConfigModel) toProjectConfig(ctx context.
 		if diags.HasError() {
 			return nil
 		}
-		policyBindingsValue, diags := tenantProjectPolicyModel.PolicyBindings.ToListValue(ctx)
+		policyBindingsValue, d := tenantProjectPolicyModel.PolicyBindings.ToSetValue(ctx)
+		diags.Append(d...)
 		if diags.HasError() {
-			diags.Append(diags...)
 			return nil
 		}
 		policyBindings := make([]PolicyBinding, len(policyBindingsValue.Elements()))
@@ -379,9 +1322,9 @@ func (projectConfigModel ServiceProjectConfigModel) toProjectConfig(ctx context.
 			return nil
 		}
 		serviceAccountConfig.AccountId = serviceAccountConfigModel.AccountID.ValueString()
-		tenantProjectRolesValue, diags := serviceAccountConfigModel.TenantProjectRoles.ToListValue(ctx)
+		tenantProjectRolesValue, d := serviceAccountConfigModel.TenantProjectRoles.ToSetValue(ctx)
+		diags.Append(d...)
 		if diags.HasError() {
-			diags.Append(diags...)
 			return nil
 		}
 		tenantProjectRoles := make([]string, len(tenantProjectRolesValue.Elements()))
@@ -412,21 +1355,211 @@ func (r *ServiceProjectResource) Read(ctx context.Context, req resource.ReadRequ
 		return
 	}
 
+	opName, diags := req.Private.GetKey(ctx, tenantProjectOperationKey)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if len(opName) > 0 {
+		resp.Diagnostics.Append(r.resumeTenantOperation(ctx, opName)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		resp.Diagnostics.Append(resp.Private.SetKey(ctx, tenantProjectOperationKey, nil)...)
+	}
+
 	project, err := r.getTenantProject(ctx, data.TenancyUnit.ValueString(), data.Tag.ValueString())
 	if err != nil {
 		resp.Diagnostics.AddError("Error getting project", err.Error())
 		return
 	}
-	if project == nil {
+	// A deleted project keeps its tag until it is removed, but cannot be
+	// used or configured any more.
+	if project == nil || project.Status == "DELETED" {
+		tflog.Info(ctx, "Tenant project not found, removing from state", map[string]interface{}{
+			"tenancy_unit": data.TenancyUnit.ValueString(),
+			"tag":          data.Tag.ValueString(),
+		})
+		resp.State.RemoveResource(ctx)
 		return
 	}
+	if project.Status == "FAILED" {
+		resp.Diagnostics.AddWarning(
+			"Tenant project has failed",
+			fmt.Sprintf("Tenant project %s with tag %q in tenancy unit %s has status FAILED.", project.Resource, project.Tag, data.TenancyUnit.ValueString()),
+		)
+	}
 
-	data.ID = types.StringValue(project.Resource)
-	data.Status = types.StringValue(project.Status)
+	data.setProject(project)
+	r.setServiceAccountEmail(ctx, &data, project, &resp.Diagnostics)
+
+	// Refresh the parts of the project config which can be read back, so that
+	// changes made outside of Terraform are planned.
+	if project.Status == "ACTIVE" && !data.ProjectConfig.IsNull() {
+		data.ProjectConfig = r.readProjectConfig(ctx, project.Resource, data.ProjectConfig, &resp.Diagnostics)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	if project.Status == "ACTIVE" {
+		data.EffectivePolicyJSON = r.readEffectivePolicy(ctx, project.Resource, &resp.Diagnostics)
+	}
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
+// resumeTenantOperation waits for the operation left pending by Create, whose
+// name is held in private state as JSON. A failed operation is reported as a
+// warning, since refreshing the tenant project then shows its outcome.
+func (r *ServiceProjectResource) resumeTenantOperation(ctx context.Context, opName []byte) diag.Diagnostics {
+	var diags diag.Diagnostics
+	var name string
+	if err := json.Unmarshal(opName, &name); err != nil {
+		diags.AddError("Invalid private state", fmt.Sprintf("Could not read the pending operation of the tenant project: %v", err))
+		return diags
+	}
+	op, err := r.pollTenantOperation(ctx, &serviceconsumermanagement.Operation{Name: name})
+	if err != nil {
+		diags.AddError("Error waiting for operation", fmt.Sprintf("Could not wait for operation %s started by an earlier apply: %v", name, err))
+		return diags
+	}
+	if err := tenantOperationError(op); err != nil {
+		diags.AddWarning("Tenant project operation failed", fmt.Sprintf("The operation started by an earlier apply failed: %v", err))
+	}
+	return diags
+}
+
+// readProjectConfig returns prior with the configured folder, labels and services of the
+// tenant project named project, as currently reported by the APIs.
+//
+// Tenant projects have services enabled by default, so only the configured
+// services are checked, and services which have been disabled are removed.
+// The policy bindings, billing config and service account config cannot be
+// read back and are kept as is. If the project cannot be read, prior is
+// returned with a warning.
+func (r *ServiceProjectResource) readProjectConfig(ctx context.Context, project string, prior types.Object, diags *diag.Diagnostics) types.Object {
+	var model ServiceProjectConfigModel
+	diags.Append(prior.As(ctx, &model, basetypes.ObjectAsOptions{})...)
+	if diags.HasError() {
+		return prior
+	}
+
+	warn := func(err error) types.Object {
+		diags.AddWarning(
+			"Could not read tenant project",
+			fmt.Sprintf("Changes to the configuration of tenant project %s made outside of Terraform cannot be detected: %v", project, err),
+		)
+		return prior
+	}
+
+	crmProject, err := retryTransient(ctx, func(ctx context.Context) (*cloudresourcemanager.Project, error) {
+		return r.ResourceManagerClient.Projects.Get(project).Context(ctx).Do()
+	})
+	if err != nil {
+		return warn(err)
+	}
+	// The folder of an attached project is not configured.
+	if !model.Folder.IsNull() && strings.HasPrefix(crmProject.Parent, "folders/") {
+		model.Folder = types.StringValue(crmProject.Parent)
+	}
+	if len(crmProject.Labels) > 0 || !model.Labels.IsNull() {
+		var d diag.Diagnostics
+		model.Labels, d = types.MapValueFrom(ctx, types.StringType, crmProject.Labels)
+		diags.Append(d...)
+	}
+
+	var services []string
+	diags.Append(model.Services.ElementsAs(ctx, &services, false)...)
+	if diags.HasError() {
+		return prior
+	}
+	enabled := make([]string, 0, len(services))
+	for _, service := range services {
+		s, err := retryTransient(ctx, func(ctx context.Context) (*serviceusage.GoogleApiServiceusageV1Service, error) {
+			return r.ServiceUsageClient.Services.Get(project + "/services/" + service).Context(ctx).Do()
+		})
+		if err != nil {
+			return warn(err)
+		}
+		if s.State == "ENABLED" {
+			enabled = append(enabled, service)
+		}
+	}
+	if len(enabled) != len(services) {
+		var d diag.Diagnostics
+		model.Services, d = types.SetValueFrom(ctx, types.StringType, enabled)
+		diags.Append(d...)
+	}
+
+	config, d := types.ObjectValueFrom(ctx, ServiceProjectConfigModel{}.AttributeTypes(), model)
+	diags.Append(d...)
+	return config
+}
+
+// effectivePolicy is the JSON representation of `effective_policy_json`.
+type effectivePolicy struct {
+	Bindings []effectivePolicyBinding `json:"bindings"`
+}
+
+type effectivePolicyBinding struct {
+	Role      string                     `json:"role"`
+	Members   []string                   `json:"members"`
+	Condition *cloudresourcemanager.Expr `json:"condition,omitempty"`
+}
+
+// readEffectivePolicy returns the IAM policy of the tenant project named
+// project as JSON, sorted so that it is stable between refreshes. The etag
+// and version are omitted, since they do not affect the bindings. If the
+// caller cannot get the policy, it returns null with a warning.
+func (r *ServiceProjectResource) readEffectivePolicy(ctx context.Context, project string, diags *diag.Diagnostics) types.String {
+	policy, err := retryTransient(ctx, func(ctx context.Context) (*cloudresourcemanager.Policy, error) {
+		return r.ResourceManagerClient.Projects.GetIamPolicy(project, &cloudresourcemanager.GetIamPolicyRequest{
+			Options: &cloudresourcemanager.GetPolicyOptions{RequestedPolicyVersion: 3},
+		}).Context(ctx).Do()
+	})
+	if err != nil {
+		summary := "Could not read tenant project policy"
+		if isGoogleAPIErrorCode(err, http.StatusForbidden) {
+			summary = "Permission denied reading tenant project policy"
+		}
+		diags.AddAttributeWarning(path.Root("effective_policy_json"), summary, fmt.Sprintf("The IAM policy of tenant project %s could not be read: %v", project, err))
+		return types.StringNull()
+	}
+
+	effective := effectivePolicy{Bindings: make([]effectivePolicyBinding, 0, len(policy.Bindings))}
+	for _, binding := range policy.Bindings {
+		members := slices.Clone(binding.Members)
+		slices.Sort(members)
+		effective.Bindings = append(effective.Bindings, effectivePolicyBinding{
+			Role:      binding.Role,
+			Members:   members,
+			Condition: binding.Condition,
+		})
+	}
+	slices.SortStableFunc(effective.Bindings, func(a, b effectivePolicyBinding) int {
+		if c := strings.Compare(a.Role, b.Role); c != 0 {
+			return c
+		}
+		// Unconditional bindings come first.
+		switch {
+		case a.Condition == nil && b.Condition == nil:
+			return 0
+		case a.Condition == nil:
+			return -1
+		case b.Condition == nil:
+			return 1
+		}
+		return strings.Compare(a.Condition.Expression, b.Condition.Expression)
+	})
+
+	policyJSON, err := json.Marshal(effective)
+	if err != nil {
+		diags.AddError("Error encoding tenant project policy", err.Error())
+		return types.StringNull()
+	}
+	return types.StringValue(string(policyJSON))
+}
+
 func (r *ServiceProjectResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
 	var data ServiceProjectResourceModel
 
@@ -437,51 +1570,109 @@ func (r *ServiceProjectResource) Update(ctx context.Context, req resource.Update
 		return
 	}
 
-	var projectConfigModel ServiceProjectConfigModel
-	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("project_config"), &projectConfigModel)...)
+	var state ServiceProjectResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	projectConfig := projectConfigModel.toProjectConfig(ctx, resp.Diagnostics)
+	// Keep the previously known project until it is listed again, so that
+	// state can be written before waiting for the operation.
+	data.ID = state.ID
+	data.Status = state.Status
+	data.ServiceAccountEmail = state.ServiceAccountEmail
+	data.Resource = state.Resource
+	data.LastOperation = state.LastOperation
+
+	// Without a refresh, the operation left pending by Create may not have
+	// been waited for yet.
+	opName, diags := req.Private.GetKey(ctx, tenantProjectOperationKey)
+	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
+	if len(opName) > 0 {
+		resp.Diagnostics.Append(r.resumeTenantOperation(ctx, opName)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		resp.Diagnostics.Append(resp.Private.SetKey(ctx, tenantProjectOperationKey, nil)...)
+	}
 
-	op, err := r.TenantClient.Services.TenancyUnits.ApplyProjectConfig(data.TenancyUnit.ValueString(), &serviceconsumermanagement.ApplyTenantProjectConfigRequest{
-		Tag:           data.Tag.ValueString(),
-		ProjectConfig: projectConfig,
-	}).Context(ctx).Do()
+	// Applying the project config takes minutes, so only do it if the config
+	// changed and not for changes to other attributes.
+	var op *serviceconsumermanagement.Operation
+	if data.ProjectConfig.Equal(state.ProjectConfig) {
+		tflog.Info(ctx, "Project config unchanged, skipping apply", map[string]interface{}{
+			"tenancy_unit": data.TenancyUnit.ValueString(),
+			"tag":          data.Tag.ValueString(),
+		})
+	} else {
+		var projectConfigModel ServiceProjectConfigModel
+		resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("project_config"), &projectConfigModel)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
 
-	if err != nil {
-		resp.Diagnostics.AddError("Error updating project", err.Error())
-		return
-	}
+		projectConfig := projectConfigModel.toProjectConfig(ctx, &resp.Diagnostics)
+		if resp.Diagnostics.HasError() {
+			return
+		}
 
-	for !op.Done {
-		time.Sleep(5 * time.Second)
+		var err error
+		op, err = retryTenantProjectMutation(ctx, r.TenantProjectRetryAttempts, func(ctx context.Context) (*serviceconsumermanagement.Operation, error) {
+			return r.TenantClient.Services.TenancyUnits.ApplyProjectConfig(data.TenancyUnit.ValueString(), &serviceconsumermanagement.ApplyTenantProjectConfigRequest{
+				Tag:           data.Tag.ValueString(),
+				ProjectConfig: projectConfig,
+			}).Context(ctx).Do()
+		})
 
-		op, err = r.TenantClient.Operations.Get(op.Name).Context(ctx).Do()
 		if err != nil {
-			resp.Diagnostics.AddError("Error getting operation", err.Error())
+			resp.Diagnostics.AddError("Error updating project", err.Error())
+			return
+		}
+
+		data.LastOperation = types.StringValue(op.Name)
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		if err := r.waitTenantOperation(ctx, op); err != nil {
+			resp.Diagnostics.AddError("Error updating project", err.Error())
 			return
 		}
 	}
 
-	project, err := r.getTenantProject(ctx, data.TenancyUnit.ValueString(), data.Tag.ValueString())
+	project, err := r.getCreatedTenantProject(ctx, data.TenancyUnit.ValueString(), data.Tag.ValueString(), tenantProjectVisibleTimeout)
 	if err != nil {
 		resp.Diagnostics.AddError("Error getting project", err.Error())
 		return
 	}
 	if project == nil {
-		panic("project not found")
+		// The config was applied, so keep it in state along with the
+		// previously known project.
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		resp.Diagnostics.AddError("Error getting project", tenantProjectNotFound(data))
+		return
 	}
 
-	data.ID = types.StringValue(project.Resource)
-	data.Status = types.StringValue(project.Status)
-
+	updateTimeout, diags := data.Timeouts.Update(ctx, defaultTenantProjectTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	project, err = r.waitTenantProjectStatus(ctx, data.TenancyUnit.ValueString(), project, data.WaitForStatus.ValueString(), updateTimeout)
+	data.setProject(project)
+	r.setServiceAccountEmail(ctx, &data, project, &resp.Diagnostics)
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
-
+	if err != nil {
+		resp.Diagnostics.AddError("Error waiting for project", err.Error())
+		return
+	}
+	if project.Status == "FAILED" {
+		detail := fmt.Sprintf("Tenant project %s with tag %q in tenancy unit %s has status FAILED.", project.Resource, project.Tag, data.TenancyUnit.ValueString())
+		if op != nil {
+			detail = fmt.Sprintf("Tenant project %s with tag %q in tenancy unit %s has status FAILED after operation %s completed.", project.Resource, project.Tag, data.TenancyUnit.ValueString(), op.Name)
+		}
+		resp.Diagnostics.AddError("Tenant project configuration failed", detail)
+	}
 }
 
 func (r *ServiceProjectResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
@@ -494,34 +1685,131 @@ func (r *ServiceProjectResource) Delete(ctx context.Context, req resource.Delete
 		return
 	}
 
-	op, err := r.TenantClient.Services.TenancyUnits.RemoveProject(data.TenancyUnit.ValueString(), &serviceconsumermanagement.RemoveTenantProjectRequest{
-		Tag: data.Tag.ValueString(),
-	}).Context(ctx).Do()
+	logFields := map[string]interface{}{
+		"tenancy_unit": data.TenancyUnit.ValueString(),
+		"tag":          data.Tag.ValueString(),
+	}
+	if data.DeletionPolicy.ValueString() == deletionPolicyAbandon {
+		tflog.Info(ctx, "Abandoning tenant project", logFields)
+		return
+	}
 
+	// A project, or tenancy unit, which was already removed outside of
+	// Terraform would otherwise fail to be deleted and stay in state. If the
+	// lookup fails, deleting the project reports whether it exists.
+	project, err := r.getTenantProject(ctx, data.TenancyUnit.ValueString(), data.Tag.ValueString())
 	if err != nil {
-		resp.Diagnostics.AddError("Error removing project", err.Error())
+		tflog.Debug(ctx, "Could not get tenant project before deleting it", map[string]interface{}{
+			"error": err.Error(),
+		})
+	} else if project == nil || project.Status == "DELETED" && data.DeletionPolicy.ValueString() == deletionPolicyDelete {
+		tflog.Info(ctx, "Tenant project already removed", logFields)
 		return
 	}
 
-	for !op.Done {
-		time.Sleep(5 * time.Second)
+	var op *serviceconsumermanagement.Operation
+	summary := "Error removing project"
+	if data.DeletionPolicy.ValueString() == deletionPolicyDelete {
+		summary = "Error deleting project"
+		op, err = r.TenantClient.Services.TenancyUnits.DeleteProject(data.TenancyUnit.ValueString(), &serviceconsumermanagement.DeleteTenantProjectRequest{
+			Tag: data.Tag.ValueString(),
+		}).Context(ctx).Do()
+	} else {
+		op, err = r.TenantClient.Services.TenancyUnits.RemoveProject(data.TenancyUnit.ValueString(), &serviceconsumermanagement.RemoveTenantProjectRequest{
+			Tag: data.Tag.ValueString(),
+		}).Context(ctx).Do()
+	}
+	if isNotFound(err) {
+		tflog.Info(ctx, "Tenant project already removed", logFields)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(summary, err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("last_operation"), op.Name)...)
+	if err := r.waitTenantOperation(ctx, op); err != nil {
+		resp.Diagnostics.AddError(summary, err.Error())
+		return
+	}
+}
 
-		op, err = r.TenantClient.Operations.Get(op.Name).Context(ctx).Do()
-		if err != nil {
-			resp.Diagnostics.AddError("Error getting operation", err.Error())
-			return
-		}
+func (r *ServiceProjectResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	tenancyUnit, tag, ok := strings.Cut(req.ID, "|")
+	if !ok || !tenancyUnitNamePattern.MatchString(tenancyUnit) {
+		resp.Diagnostics.AddError(
+			"Invalid tenant project ID",
+			fmt.Sprintf("Tenant project ID %q must be in the format `{tenancy_unit}|{tag}`, where `{tenancy_unit}` is `services/{service}/{collection}/{consumer_id}/tenancyUnits/{tenancy_unit_id}`.", req.ID),
+		)
+		return
 	}
+	if err := validateTenantTag(tag); err != nil {
+		resp.Diagnostics.AddError("Invalid tenant project ID", fmt.Sprintf("Tenant project ID %q has an invalid tag: %v.", req.ID, err))
+		return
+	}
+
+	project, err := r.getTenantProject(ctx, tenancyUnit, tag)
+	if err != nil {
+		resp.Diagnostics.AddError("Error getting project", err.Error())
+		return
+	}
+	if project == nil || project.Status == "DELETED" {
+		resp.Diagnostics.AddError("Tenant project not found", fmt.Sprintf("Tenancy unit %s has no tenant project with tag %q.", tenancyUnit, tag))
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("tenancy_unit"), tenancyUnit)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("tag"), tag)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), project.Resource)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("resource"), project.Resource)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("status"), project.Status)...)
+	// Defaults are not applied to imported state.
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("delete_on_failure"), false)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("deletion_policy"), deletionPolicyRemove)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("folder_change_behavior"), folderChangeBehaviorMove)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("undelete_if_pending_delete"), false)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("wait_for_status"), "ACTIVE")...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("wait_for_completion"), true)...)
+	resp.Diagnostics.AddWarning(
+		"Tenant project config not imported",
+		fmt.Sprintf("The project_config of tenant project %s cannot be read back, so it must be set in configuration. The next apply applies it to the project, so it should match the current configuration of the project.", project.Resource),
+	)
 }
 
 type TenantResource serviceconsumermanagement.TenantResource
 
-func (r TenantResource) ServiceAccountEmail() string {
-	resourceParts := strings.Split(r.Resource, "/") // projects/{project_id}
-	if resourceParts[0] != "projects" {
-		log.Panicf("unexpected resource type: %q", r.Resource)
+// ServiceAccountEmail returns the email of the service account with the given
+// account ID in the tenant project, whose project ID is projectId. It returns
+// an error if the tenant resource is not a project.
+func (r TenantResource) ServiceAccountEmail(accountId, projectId string) (string, error) {
+	projectNumber, ok := strings.CutPrefix(r.Resource, "projects/") // projects/{project_number}
+	if !ok || projectNumber == "" || strings.Contains(projectNumber, "/") || projectId == "" {
+		return "", fmt.Errorf("unexpected resource type: %q", r.Resource)
 	}
-	return fmt.Sprintf("%s@%s.iam.gserviceaccount.com", r.Tag, resourceParts[1])
+	return fmt.Sprintf("%s@%s.iam.gserviceaccount.com", accountId, projectId), nil
+}
+
+// getCreatedTenantProject returns the tenant project with the given tag,
+// waiting up to timeout for it to be listed. It returns nil if the project is
+// still not listed.
+func (r *UtilsProviderConfig) getCreatedTenantProject(ctx context.Context, tenancyUnitID, tag string, timeout time.Duration) (*TenantResource, error) {
+	project, err := retryUntilVisible(ctx, timeout, func(ctx context.Context) (*TenantResource, error) {
+		project, err := r.getTenantProject(ctx, tenancyUnitID, tag)
+		if err == nil && project == nil {
+			return nil, fmt.Errorf("tenant project with tag %q in %s: %w", tag, tenancyUnitID, errNotVisible)
+		}
+		return project, err
+	})
+	if errors.Is(err, errNotVisible) {
+		return nil, nil
+	}
+	return project, err
+}
+
+// tenantProjectNotFound describes a tenant project which is not listed after
+// it was added or configured.
+func tenantProjectNotFound(data ServiceProjectResourceModel) string {
+	return fmt.Sprintf("Tenant project with tag %q in tenancy unit %s was not found after the operation completed. It may not be listed by the API yet; run apply again to retry.", data.Tag.ValueString(), data.TenancyUnit.ValueString())
 }
 
 func (r *UtilsProviderConfig) getTenantProject(ctx context.Context, tenancyUnitID, tag string) (*TenantResource, error) {
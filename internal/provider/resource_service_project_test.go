@@ -0,0 +1,1960 @@
+package provider
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/servicemanagement/apiv1/servicemanagementpb"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+	"google.golang.org/api/cloudresourcemanager/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/serviceconsumermanagement/v1"
+	"google.golang.org/grpc/codes"
+)
+
+func TestAccResourceServiceProject(t *testing.T) {
+	project := testAccProducerProject(t)
+	consumer := testAccConsumerProject(t)
+	folder, billingAccount, owner := testAccTenantProjectConfig(t)
+	serviceName := fmt.Sprintf("tf-test-%s.endpoints.%s.cloud.goog", acctest.RandString(8), project)
+
+	config := fmt.Sprintf(`
+		resource "utils_service" "test" {
+			service_name = %q
+			producer_project_id = %q
+		}
+
+		resource "utils_service_tenancy_unit" "test" {
+			service_name = utils_service.test.service_name
+			consumer = "projects/%s"
+			deletion_protection = false
+		}
+
+		resource "utils_service_project" "test" {
+			tenancy_unit = utils_service_tenancy_unit.test.id
+			tag = "test"
+			project_config = {
+				folder = %q
+				tenant_project_policy = {
+					policy_bindings = [{
+						role = "roles/owner"
+						members = [%q]
+					}]
+				}
+				billing_config = {
+					billing_account = %q
+				}
+				service_account_config = {
+					account_id = "tenant"
+					tenant_project_roles = ["roles/editor"]
+				}
+			}
+		}`, serviceName, project, consumer, folder, owner, billingAccount)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCreateConfig(config),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("utils_service_project.test", tfjsonpath.New("status"), knownvalue.StringExact("ACTIVE")),
+				},
+			},
+			{
+				ResourceName: "utils_service_project.test",
+				ImportState:  true,
+				ImportStateIdFunc: func(s *terraform.State) (string, error) {
+					attrs := s.RootModule().Resources["utils_service_project.test"].Primary.Attributes
+					return attrs["tenancy_unit"] + "|" + attrs["tag"], nil
+				},
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"project_config"},
+			},
+			{
+				ResourceName:  "utils_service_project.test",
+				ImportState:   true,
+				ImportStateId: "test",
+				ExpectError:   regexp.MustCompile("must be in the format"),
+			},
+		},
+	})
+}
+
+// testProjectConfigModel returns a valid project configuration.
+func testProjectConfigModel() ServiceProjectConfigModel {
+	policyBindingType := types.ObjectType{AttrTypes: PolicyBinding{}.AttributeTypes()}
+	return ServiceProjectConfigModel{
+		Folder: types.StringValue("folders/123"),
+		TenantProjectPolicy: types.ObjectValueMust(ServiceProjectConfigTenantProjectPolicyModel{}.AttributeTypes(), map[string]attr.Value{
+			"policy_bindings": types.SetValueMust(policyBindingType, []attr.Value{
+				types.ObjectValueMust(PolicyBinding{}.AttributeTypes(), map[string]attr.Value{
+					"role":    types.StringValue("roles/owner"),
+					"members": types.SetValueMust(types.StringType, []attr.Value{types.StringValue("group:owners@example.com")}),
+				}),
+			}),
+		}),
+		Labels:   types.MapValueMust(types.StringType, map[string]attr.Value{"env": types.StringValue("test")}),
+		Services: types.SetValueMust(types.StringType, []attr.Value{types.StringValue("compute.googleapis.com")}),
+		BillingConfig: types.ObjectValueMust(ServiceProjectConfigBillingConfigModel{}.AttributeTypes(), map[string]attr.Value{
+			"billing_account": types.StringValue("billingAccounts/012345-567890-ABCDEF"),
+		}),
+		ServiceAccountConfig: types.ObjectValueMust(ServiceProjectConfigServiceAccountConfigModel{}.AttributeTypes(), map[string]attr.Value{
+			"account_id":           types.StringValue("tenant"),
+			"tenant_project_roles": types.SetValueMust(types.StringType, []attr.Value{types.StringValue("roles/editor")}),
+		}),
+	}
+}
+
+func TestServiceProjectConfigModelToProjectConfig(t *testing.T) {
+	ctx := context.Background()
+
+	var diags diag.Diagnostics
+	projectConfig := testProjectConfigModel().toProjectConfig(ctx, &diags)
+	if diags.HasError() {
+		t.Fatalf("got diagnostics %v, want none", diags)
+	}
+	want := &serviceconsumermanagement.TenantProjectConfig{
+		Folder: "folders/123",
+		TenantProjectPolicy: &serviceconsumermanagement.TenantProjectPolicy{
+			PolicyBindings: []*serviceconsumermanagement.PolicyBinding{
+				{Role: "roles/owner", Members: []string{"group:owners@example.com"}},
+			},
+		},
+		Labels:        map[string]string{"env": "test"},
+		Services:      []string{"compute.googleapis.com"},
+		BillingConfig: &serviceconsumermanagement.BillingConfig{BillingAccount: "billingAccounts/012345-567890-ABCDEF"},
+		ServiceAccountConfig: &serviceconsumermanagement.ServiceAccountConfig{
+			AccountId:          "tenant",
+			TenantProjectRoles: []string{"roles/editor"},
+		},
+	}
+	if !reflect.DeepEqual(projectConfig, want) {
+		t.Errorf("got project config %+v, want %+v", projectConfig, want)
+	}
+
+	tests := map[string]func(*ServiceProjectConfigModel){
+		"labels": func(m *ServiceProjectConfigModel) {
+			m.Labels = types.MapValueMust(types.BoolType, map[string]attr.Value{"env": types.BoolValue(true)})
+		},
+		"policy_bindings": func(m *ServiceProjectConfigModel) {
+			m.TenantProjectPolicy = types.ObjectValueMust(map[string]attr.Type{
+				"policy_bindings": types.ListType{ElemType: types.StringType},
+			}, map[string]attr.Value{
+				"policy_bindings": types.ListValueMust(types.StringType, []attr.Value{types.StringValue("roles/owner")}),
+			})
+		},
+	}
+	for name, invalidate := range tests {
+		t.Run(name, func(t *testing.T) {
+			model := testProjectConfigModel()
+			invalidate(&model)
+
+			var diags diag.Diagnostics
+			if projectConfig := model.toProjectConfig(ctx, &diags); projectConfig != nil {
+				t.Errorf("got project config %+v, want nil", projectConfig)
+			}
+			if !diags.HasError() {
+				t.Errorf("got diagnostics %v, want an error", diags)
+			}
+		})
+	}
+}
+
+func TestServiceProjectConfigModelValidatePolicyBindings(t *testing.T) {
+	ctx := context.Background()
+	policyBindingType := types.ObjectType{AttrTypes: PolicyBinding{}.AttributeTypes()}
+	bindingsPath := path.Root("project_config").AtName("tenant_project_policy").AtName("policy_bindings")
+
+	withBindings := func(bindings ...attr.Value) ServiceProjectConfigModel {
+		model := testProjectConfigModel()
+		model.TenantProjectPolicy = types.ObjectValueMust(ServiceProjectConfigTenantProjectPolicyModel{}.AttributeTypes(), map[string]attr.Value{
+			"policy_bindings": types.SetValueMust(policyBindingType, bindings),
+		})
+		return model
+	}
+	binding := func(role attr.Value, members ...attr.Value) attr.Value {
+		return types.ObjectValueMust(PolicyBinding{}.AttributeTypes(), map[string]attr.Value{
+			"role":    role,
+			"members": types.SetValueMust(types.StringType, members),
+		})
+	}
+	owner := binding(types.StringValue("roles/owner"), types.StringValue("group:owners@example.com"))
+	invalidRole := binding(types.StringValue("owner"), types.StringValue("user:a@example.com"))
+	invalidMember := binding(types.StringValue("roles/owner"), types.StringValue("user:a@example.com"), types.StringValue("a@example.com"))
+
+	for _, tt := range []struct {
+		name      string
+		model     ServiceProjectConfigModel
+		wantPaths []path.Path
+	}{
+		{name: "valid", model: testProjectConfigModel()},
+		{
+			name: "custom role",
+			model: withBindings(owner,
+				binding(types.StringValue("projects/p1/roles/custom"), types.StringValue("serviceAccount:sa@p1.iam.gserviceaccount.com"), types.StringValue("domain:example.com")),
+			),
+		},
+		{name: "no policy", model: func() ServiceProjectConfigModel {
+			model := testProjectConfigModel()
+			model.TenantProjectPolicy = types.ObjectUnknown(ServiceProjectConfigTenantProjectPolicyModel{}.AttributeTypes())
+			return model
+		}()},
+		{name: "unknown owner member", model: withBindings(binding(types.StringValue("roles/owner"), types.StringUnknown()))},
+		{name: "unknown binding", model: withBindings(types.ObjectUnknown(PolicyBinding{}.AttributeTypes()))},
+		{
+			name:      "no owner",
+			model:     withBindings(binding(types.StringValue("roles/editor"), types.StringValue("group:owners@example.com"))),
+			wantPaths: []path.Path{bindingsPath},
+		},
+		{
+			name:      "service account owner",
+			model:     withBindings(binding(types.StringValue("roles/owner"), types.StringValue("serviceAccount:sa@p1.iam.gserviceaccount.com"))),
+			wantPaths: []path.Path{bindingsPath},
+		},
+		{
+			name:      "invalid role",
+			model:     withBindings(owner, invalidRole),
+			wantPaths: []path.Path{bindingsPath.AtSetValue(invalidRole).AtName("role")},
+		},
+		{
+			name:      "invalid member",
+			model:     withBindings(invalidMember),
+			wantPaths: []path.Path{bindingsPath.AtSetValue(invalidMember).AtName("members").AtSetValue(types.StringValue("a@example.com"))},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := tt.model.validatePolicyBindings(ctx)
+			var gotPaths []path.Path
+			for _, d := range diags {
+				if d, ok := d.(diag.DiagnosticWithPath); ok {
+					gotPaths = append(gotPaths, d.Path())
+				}
+			}
+			if len(diags) != len(tt.wantPaths) || !reflect.DeepEqual(gotPaths, tt.wantPaths) {
+				t.Errorf("got diagnostics %v, want errors at %v", diags, tt.wantPaths)
+			}
+		})
+	}
+}
+
+// testProjectConfigValue returns model as a terraform value.
+func testProjectConfigValue(t *testing.T, model ServiceProjectConfigModel) tftypes.Value {
+	t.Helper()
+	ctx := context.Background()
+
+	object, diags := types.ObjectValueFrom(ctx, ServiceProjectConfigModel{}.AttributeTypes(), model)
+	if diags.HasError() {
+		t.Fatalf("got diagnostics %v, want none", diags)
+	}
+	value, err := object.ToTerraformValue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestResourceServiceProjectNotVisible(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+	ctx := context.Background()
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+	defer func(delay time.Duration) { consistencyRetryBaseDelay = delay }(consistencyRetryBaseDelay)
+	consistencyRetryBaseDelay = time.Millisecond
+	defer func(timeout time.Duration) { tenantProjectVisibleTimeout = timeout }(tenantProjectVisibleTimeout)
+	tenantProjectVisibleTimeout = 50 * time.Millisecond
+
+	tenant := newFakeTenantServer()
+	tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{Name: tenancyUnit}
+	server, schemas := newFakeTenancyProviderServer(t, tenant)
+	typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+	newConfig := func(tag string, model ServiceProjectConfigModel) *tfprotov6.DynamicValue {
+		return testDynamicValue(t, typ, map[string]tftypes.Value{
+			"tenancy_unit":   tftypes.NewValue(tftypes.String, tenancyUnit),
+			"tag":            tftypes.NewValue(tftypes.String, tag),
+			"project_config": testProjectConfigValue(t, model),
+		})
+	}
+	apply := func(priorState, config *tfprotov6.DynamicValue) *tfprotov6.ApplyResourceChangeResponse {
+		t.Helper()
+		if priorState == nil {
+			priorState = testNullDynamicValue(t, typ)
+		}
+		planResp := testPlanResource(t, server, "utils_service_project", typ, priorState, nil, config)
+		applyResp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+			TypeName:     "utils_service_project",
+			PriorState:   priorState,
+			PlannedState: planResp.PlannedState,
+			Config:       config,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return applyResp
+	}
+	wantNotFound := func(diags []*tfprotov6.Diagnostic, tag string) {
+		t.Helper()
+		if len(diags) != 1 || diags[0].Severity != tfprotov6.DiagnosticSeverityError || !strings.Contains(diags[0].Detail, tenancyUnit) || !strings.Contains(diags[0].Detail, fmt.Sprintf("%q", tag)) {
+			t.Errorf("got diagnostics %v, want a not found error naming the tenancy unit and tag %q", diags, tag)
+		}
+	}
+
+	// A project which is listed after a few attempts is created.
+	tenant.hiddenListCalls = 2
+	model := testProjectConfigModel()
+	config := newConfig("prod", model)
+	applyResp := apply(nil, config)
+	requireNoErrors(t, applyResp.Diagnostics)
+	if id := testStateAttributes(t, typ, applyResp.NewState)["id"]; !id.Equal(tftypes.NewValue(tftypes.String, "projects/1001")) {
+		t.Errorf("got id %v, want projects/1001", id)
+	}
+	if email := testStateAttributes(t, typ, applyResp.NewState)["service_account_email"]; !email.Equal(tftypes.NewValue(tftypes.String, "tenant@tenant-1001.iam.gserviceaccount.com")) {
+		t.Errorf("got service_account_email %v, want tenant@tenant-1001.iam.gserviceaccount.com", email)
+	}
+	state := applyResp.NewState
+
+	// An update whose project is not listed keeps the known project.
+	tenant.hiddenListCalls = math.MaxInt
+	model.Folder = types.StringValue("folders/456")
+	applyResp = apply(state, newConfig("prod", model))
+	wantNotFound(applyResp.Diagnostics, "prod")
+	attrs := testStateAttributes(t, typ, applyResp.NewState)
+	if !attrs["id"].Equal(tftypes.NewValue(tftypes.String, "projects/1001")) {
+		t.Errorf("got id %v, want projects/1001", attrs["id"])
+	}
+	if folder := tenant.projectConfigs["prod"].Folder; folder != "folders/456" {
+		t.Errorf("got folder %q, want folders/456", folder)
+	}
+
+	// A created project which is not listed is kept in state.
+	applyResp = apply(nil, newConfig("staging", testProjectConfigModel()))
+	wantNotFound(applyResp.Diagnostics, "staging")
+	attrs = testStateAttributes(t, typ, applyResp.NewState)
+	if !attrs["tag"].Equal(tftypes.NewValue(tftypes.String, "staging")) || !attrs["id"].IsNull() {
+		t.Errorf("got state %v, want tag staging without an id", attrs)
+	}
+}
+
+func TestResourceServiceProjectOperationError(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+	ctx := context.Background()
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+
+	tenant := newFakeTenantServer()
+	tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{Name: tenancyUnit}
+	server, schemas := newFakeTenancyProviderServer(t, tenant)
+	typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+	newConfig := func(tag string, model ServiceProjectConfigModel) *tfprotov6.DynamicValue {
+		return testDynamicValue(t, typ, map[string]tftypes.Value{
+			"tenancy_unit":   tftypes.NewValue(tftypes.String, tenancyUnit),
+			"tag":            tftypes.NewValue(tftypes.String, tag),
+			"project_config": testProjectConfigValue(t, model),
+		})
+	}
+	apply := func(priorState, plannedState, config *tfprotov6.DynamicValue) *tfprotov6.ApplyResourceChangeResponse {
+		t.Helper()
+		applyResp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+			TypeName:     "utils_service_project",
+			PriorState:   priorState,
+			PlannedState: plannedState,
+			Config:       config,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return applyResp
+	}
+	wantOperationError := func(diags []*tfprotov6.Diagnostic, summary, operation string) {
+		t.Helper()
+		if len(diags) != 1 || diags[0].Summary != summary {
+			t.Fatalf("got diagnostics %v, want %q", diags, summary)
+		}
+		for _, want := range []string{operation, "PermissionDenied", "fake failure", "QuotaFailure"} {
+			if !strings.Contains(diags[0].Detail, want) {
+				t.Errorf("got detail %q, want it to contain %q", diags[0].Detail, want)
+			}
+		}
+	}
+
+	model := testProjectConfigModel()
+	state := testApplyResource(t, server, "utils_service_project", typ, nil, newConfig("prod", model))
+	attrs := testStateAttributes(t, typ, state)
+	if !attrs["resource"].Equal(tftypes.NewValue(tftypes.String, "projects/1001")) || !attrs["last_operation"].Equal(tftypes.NewValue(tftypes.String, "operations/add-project-1001")) {
+		t.Errorf("got resource %v and last_operation %v, want projects/1001 and operations/add-project-1001", attrs["resource"], attrs["last_operation"])
+	}
+
+	tenant.operationError = &serviceconsumermanagement.Status{
+		Code:    int64(codes.PermissionDenied),
+		Message: "fake failure",
+		Details: []googleapi.RawMessage{googleapi.RawMessage(`{"@type":"type.googleapis.com/google.rpc.QuotaFailure"}`)},
+	}
+
+	// A failed update is reported.
+	model.Folder = types.StringValue("folders/456")
+	config := newConfig("prod", model)
+	planResp := testPlanResource(t, server, "utils_service_project", typ, state, nil, config)
+	applyResp := apply(state, planResp.PlannedState, config)
+	wantOperationError(applyResp.Diagnostics, "Error updating project", "operations/apply-project-config-prod")
+	if op := testStateAttributes(t, typ, applyResp.NewState)["last_operation"]; !op.Equal(tftypes.NewValue(tftypes.String, "operations/apply-project-config-prod")) {
+		t.Errorf("got last_operation %v, want operations/apply-project-config-prod", op)
+	}
+
+	// A failed removal is reported, rather than removing the project from
+	// state.
+	applyResp = apply(state, testNullDynamicValue(t, typ), testNullDynamicValue(t, typ))
+	wantOperationError(applyResp.Diagnostics, "Error removing project", "operations/remove-1")
+	if op := testStateAttributes(t, typ, applyResp.NewState)["last_operation"]; !op.Equal(tftypes.NewValue(tftypes.String, "operations/remove-1")) {
+		t.Errorf("got last_operation %v, want operations/remove-1", op)
+	}
+
+	// A failed creation is reported, keeping the operation in state without
+	// a project.
+	config = newConfig("staging", testProjectConfigModel())
+	planResp = testPlanResource(t, server, "utils_service_project", typ, nil, nil, config)
+	applyResp = apply(testNullDynamicValue(t, typ), planResp.PlannedState, config)
+	wantOperationError(applyResp.Diagnostics, "Error adding project", "operations/add-project-1002")
+	attrs = testStateAttributes(t, typ, applyResp.NewState)
+	if !attrs["last_operation"].Equal(tftypes.NewValue(tftypes.String, "operations/add-project-1002")) || !attrs["id"].IsNull() || !attrs["resource"].IsNull() {
+		t.Errorf("got last_operation %v, id %v and resource %v, want operations/add-project-1002 without a project", attrs["last_operation"], attrs["id"], attrs["resource"])
+	}
+}
+
+func TestResourceServiceProjectFailed(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+	ctx := context.Background()
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+
+	tenant := newFakeTenantServer()
+	tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{Name: tenancyUnit}
+	tenant.projectStatus = "FAILED"
+	server, schemas := newFakeTenancyProviderServer(t, tenant)
+	typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+	create := func(tag string, deleteOnFailure bool) *tfprotov6.ApplyResourceChangeResponse {
+		t.Helper()
+		config := testDynamicValue(t, typ, map[string]tftypes.Value{
+			"tenancy_unit":      tftypes.NewValue(tftypes.String, tenancyUnit),
+			"tag":               tftypes.NewValue(tftypes.String, tag),
+			"project_config":    testProjectConfigValue(t, testProjectConfigModel()),
+			"delete_on_failure": tftypes.NewValue(tftypes.Bool, deleteOnFailure),
+		})
+		planResp := testPlanResource(t, server, "utils_service_project", typ, nil, nil, config)
+		applyResp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+			TypeName:     "utils_service_project",
+			PriorState:   testNullDynamicValue(t, typ),
+			PlannedState: planResp.PlannedState,
+			Config:       config,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(applyResp.Diagnostics) != 1 || applyResp.Diagnostics[0].Summary != "Tenant project creation failed" {
+			t.Fatalf("got diagnostics %v, want a creation failure", applyResp.Diagnostics)
+		}
+		return applyResp
+	}
+
+	// By default, the failed project is kept in state.
+	applyResp := create("prod", false)
+	if detail := applyResp.Diagnostics[0].Detail; !strings.Contains(detail, "operations/add-project-1001") {
+		t.Errorf("got detail %q, want it to name the operation", detail)
+	}
+	attrs := testStateAttributes(t, typ, applyResp.NewState)
+	if !attrs["status"].Equal(tftypes.NewValue(tftypes.String, "FAILED")) {
+		t.Errorf("got status %v, want FAILED", attrs["status"])
+	}
+	if len(tenant.deletedTags) != 0 {
+		t.Errorf("got deleted tags %v, want none", tenant.deletedTags)
+	}
+
+	// Reading it warns about the failure.
+	readResp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+		TypeName:     "utils_service_project",
+		CurrentState: applyResp.NewState,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(readResp.Diagnostics) != 1 || readResp.Diagnostics[0].Severity != tfprotov6.DiagnosticSeverityWarning {
+		t.Errorf("got diagnostics %v, want a warning", readResp.Diagnostics)
+	}
+
+	// With delete_on_failure, it is deleted and its tag removed.
+	applyResp = create("staging", true)
+	if value, err := applyResp.NewState.Unmarshal(typ); err != nil || !value.IsNull() {
+		t.Errorf("got state %v, %v, want null", value, err)
+	}
+	if !slices.Equal(tenant.deletedTags, []string{"staging"}) || !slices.Equal(tenant.removedTags, []string{"staging"}) {
+		t.Errorf("got deleted tags %v and removed tags %v, want staging", tenant.deletedTags, tenant.removedTags)
+	}
+}
+
+func TestResourceServiceProjectReadProjectConfig(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+	ctx := context.Background()
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+
+	tenant := newFakeTenantServer()
+	tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{Name: tenancyUnit}
+	resourceManager := newFakeResourceManager()
+	serviceUsage := newFakeServiceUsage()
+	providerConfig := newFakeProviderConfig(t, newFakeServiceManager())
+	providerConfig.TenantClient = newFakeTenantClient(t, tenant)
+	providerConfig.ResourceManagerClient = newFakeResourceManagerClient(t, resourceManager)
+	providerConfig.ServiceUsageClient = newFakeServiceUsageClient(t, serviceUsage)
+	server, schemas := newFakeProviderServer(t, providerConfig)
+	typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+	config := testDynamicValue(t, typ, map[string]tftypes.Value{
+		"tenancy_unit":   tftypes.NewValue(tftypes.String, tenancyUnit),
+		"tag":            tftypes.NewValue(tftypes.String, "prod"),
+		"project_config": testProjectConfigValue(t, testProjectConfigModel()),
+	})
+	resourceManager.projects["projects/1001"] = &cloudresourcemanager.Project{
+		Name:      "projects/1001",
+		ProjectId: "tenant-1001",
+		Parent:    "folders/123",
+		Labels:    map[string]string{"env": "test"},
+	}
+	state := testApplyResource(t, server, "utils_service_project", typ, nil, config)
+	serviceUsage.enabledServices["projects/1001/services/compute.googleapis.com"] = true
+
+	read := func(state *tfprotov6.DynamicValue) (*tfprotov6.DynamicValue, []*tfprotov6.Diagnostic) {
+		t.Helper()
+		resp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+			TypeName:     "utils_service_project",
+			CurrentState: state,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.NewState, resp.Diagnostics
+	}
+	planChanged := func(state *tfprotov6.DynamicValue) bool {
+		t.Helper()
+		planned := testPlanResource(t, server, "utils_service_project", typ, state, nil, config).PlannedState
+		return !testStateAttributes(t, typ, planned)["project_config"].Equal(testStateAttributes(t, typ, state)["project_config"])
+	}
+
+	// The project matches its config.
+	newState, diags := read(state)
+	if len(diags) != 0 {
+		t.Fatalf("got diagnostics %v, want none", diags)
+	}
+	if !testStateAttributes(t, typ, newState)["project_config"].Equal(testStateAttributes(t, typ, state)["project_config"]) {
+		t.Errorf("got project_config %v, want it unchanged", testStateAttributes(t, typ, newState)["project_config"])
+	}
+	if planChanged(newState) {
+		t.Error("got changes planned, want none")
+	}
+
+	// Changes made outside of Terraform are read back and planned.
+	resourceManager.projects["projects/1001"].Parent = "folders/456"
+	resourceManager.projects["projects/1001"].Labels = map[string]string{"env": "prod"}
+	serviceUsage.enabledServices["projects/1001/services/compute.googleapis.com"] = false
+	newState, diags = read(state)
+	requireNoErrors(t, diags)
+	model := testProjectConfigModel()
+	model.Folder = types.StringValue("folders/456")
+	model.Labels = types.MapValueMust(types.StringType, map[string]attr.Value{"env": types.StringValue("prod")})
+	model.Services = types.SetValueMust(types.StringType, []attr.Value{})
+	if got, want := testStateAttributes(t, typ, newState)["project_config"], testProjectConfigValue(t, model); !got.Equal(want) {
+		t.Errorf("got project_config %v, want %v", got, want)
+	}
+	if !planChanged(newState) {
+		t.Error("got no changes planned, want the config to be applied again")
+	}
+
+	// If the project cannot be read, the config and the service account
+	// email are kept with warnings.
+	delete(resourceManager.projects, "projects/1001")
+	newState, diags = read(state)
+	if len(diags) != 2 || diags[0].Severity != tfprotov6.DiagnosticSeverityWarning || diags[1].Severity != tfprotov6.DiagnosticSeverityWarning {
+		t.Errorf("got diagnostics %v, want two warnings", diags)
+	}
+	if email := testStateAttributes(t, typ, newState)["service_account_email"]; !email.Equal(tftypes.NewValue(tftypes.String, "tenant@tenant-1001.iam.gserviceaccount.com")) {
+		t.Errorf("got service_account_email %v, want it unchanged", email)
+	}
+	if !testStateAttributes(t, typ, newState)["project_config"].Equal(testStateAttributes(t, typ, state)["project_config"]) {
+		t.Errorf("got project_config %v, want it unchanged", testStateAttributes(t, typ, newState)["project_config"])
+	}
+}
+
+func TestResourceServiceProjectDeletedExternally(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+	ctx := context.Background()
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+
+	tests := map[string]func(tenant *fakeTenantServer){
+		"removed": func(tenant *fakeTenantServer) {
+			tenant.tenancyUnits[tenancyUnit].TenantResources = nil
+		},
+		"deleted": func(tenant *fakeTenantServer) {
+			tenant.tenancyUnits[tenancyUnit].TenantResources[0].Status = "DELETED"
+		},
+		"tenancy unit deleted": func(tenant *fakeTenantServer) {
+			delete(tenant.tenancyUnits, tenancyUnit)
+		},
+	}
+	for name, deleteProject := range tests {
+		t.Run(name, func(t *testing.T) {
+			tenant := newFakeTenantServer()
+			tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{Name: tenancyUnit}
+			server, schemas := newFakeTenancyProviderServer(t, tenant)
+			typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+			config := testDynamicValue(t, typ, map[string]tftypes.Value{
+				"tenancy_unit":   tftypes.NewValue(tftypes.String, tenancyUnit),
+				"tag":            tftypes.NewValue(tftypes.String, "prod"),
+				"project_config": testProjectConfigValue(t, testProjectConfigModel()),
+			})
+			state := testApplyResource(t, server, "utils_service_project", typ, nil, config)
+			deleteProject(tenant)
+
+			resp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+				TypeName:     "utils_service_project",
+				CurrentState: state,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			requireNoErrors(t, resp.Diagnostics)
+			if value, err := resp.NewState.Unmarshal(typ); err != nil || !value.IsNull() {
+				t.Errorf("got state %v, %v, want it removed", value, err)
+			}
+		})
+	}
+}
+
+func TestResourceServiceProjectImportState(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+	ctx := context.Background()
+
+	tenant := newFakeTenantServer()
+	tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{
+		Name: tenancyUnit,
+		TenantResources: []*serviceconsumermanagement.TenantResource{
+			{Tag: "prod", Resource: "projects/1001", Status: "ACTIVE"},
+			{Tag: "old", Resource: "projects/1002", Status: "DELETED"},
+		},
+	}
+	server, schemas := newFakeTenancyProviderServer(t, tenant)
+	typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+	importState := func(id string) *tfprotov6.ImportResourceStateResponse {
+		t.Helper()
+		resp, err := server.ImportResourceState(ctx, &tfprotov6.ImportResourceStateRequest{
+			TypeName: "utils_service_project",
+			ID:       id,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := importState(tenancyUnit + "|prod")
+	requireNoErrors(t, resp.Diagnostics)
+	if len(resp.Diagnostics) != 1 || !strings.Contains(resp.Diagnostics[0].Detail, "project_config") {
+		t.Errorf("got diagnostics %v, want a project_config warning", resp.Diagnostics)
+	}
+	if len(resp.ImportedResources) != 1 {
+		t.Fatalf("got %d imported resources, want 1", len(resp.ImportedResources))
+	}
+	attrs := testStateAttributes(t, typ, resp.ImportedResources[0].State)
+	for name, want := range map[string]tftypes.Value{
+		"tenancy_unit":           tftypes.NewValue(tftypes.String, tenancyUnit),
+		"tag":                    tftypes.NewValue(tftypes.String, "prod"),
+		"id":                     tftypes.NewValue(tftypes.String, "projects/1001"),
+		"status":                 tftypes.NewValue(tftypes.String, "ACTIVE"),
+		"delete_on_failure":      tftypes.NewValue(tftypes.Bool, false),
+		"deletion_policy":        tftypes.NewValue(tftypes.String, "REMOVE"),
+		"folder_change_behavior": tftypes.NewValue(tftypes.String, "MOVE"),
+	} {
+		if !attrs[name].Equal(want) {
+			t.Errorf("got %s %v, want %v", name, attrs[name], want)
+		}
+	}
+
+	for id, want := range map[string]string{
+		"prod":                    "must be in the format",
+		tenancyUnit:               "must be in the format",
+		tenancyUnit + "|":         "invalid tag",
+		tenancyUnit + "|prod/eu":  "invalid tag",
+		"projects/123/unit1|prod": "must be in the format",
+		tenancyUnit + "|staging":  "no tenant project",
+		tenancyUnit + "|old":      "no tenant project",
+	} {
+		resp := importState(id)
+		if len(resp.Diagnostics) != 1 || resp.Diagnostics[0].Severity != tfprotov6.DiagnosticSeverityError || !strings.Contains(resp.Diagnostics[0].Detail, want) {
+			t.Errorf("import %q: got diagnostics %v, want an error containing %q", id, resp.Diagnostics, want)
+		}
+	}
+}
+
+func TestTenantResourceServiceAccountEmail(t *testing.T) {
+	project := TenantResource{Tag: "prod", Resource: "projects/1001"}
+	for accountId, want := range map[string]string{
+		"prod":   "prod@tenant-project.iam.gserviceaccount.com",
+		"tenant": "tenant@tenant-project.iam.gserviceaccount.com",
+	} {
+		if got, err := project.ServiceAccountEmail(accountId, "tenant-project"); err != nil || got != want {
+			t.Errorf("account ID %q: got %q, %v, want %q", accountId, got, err, want)
+		}
+	}
+
+	for _, resource := range []string{
+		"",
+		"projects",
+		"projects/",
+		"folders/123",
+		"organizations/456",
+		"projects/1001/extra",
+	} {
+		t.Run(resource, func(t *testing.T) {
+			project := TenantResource{Tag: "prod", Resource: resource}
+			if got, err := project.ServiceAccountEmail("tenant", "tenant-project"); err == nil {
+				t.Errorf("got %q, want an error", got)
+			}
+		})
+	}
+}
+
+func TestResourceServiceProjectSetServiceAccountEmail(t *testing.T) {
+	ctx := context.Background()
+	projectConfig, diags := types.ObjectValueFrom(ctx, ServiceProjectConfigModel{}.AttributeTypes(), testProjectConfigModel())
+	if diags.HasError() {
+		t.Fatalf("got diagnostics %v, want none", diags)
+	}
+
+	resourceManager := newFakeResourceManager()
+	resourceManager.projectNumbers["tenant-project"] = "1001"
+	r := &ServiceProjectResource{}
+	r.ResourceManagerClient = newFakeResourceManagerClient(t, resourceManager)
+
+	for _, tt := range []struct {
+		name        string
+		project     TenantResource
+		prior       types.String
+		wantEmail   types.String
+		wantWarning bool
+	}{
+		{name: "active", project: TenantResource{Tag: "prod", Resource: "projects/1001", Status: "ACTIVE"}, wantEmail: types.StringValue("tenant@tenant-project.iam.gserviceaccount.com")},
+		{name: "failed without resource", project: TenantResource{Tag: "prod", Status: "FAILED"}, wantEmail: types.StringNull()},
+		{name: "failed", project: TenantResource{Tag: "prod", Resource: "projects/1001", Status: "FAILED"}, wantEmail: types.StringNull()},
+		{name: "not found", project: TenantResource{Tag: "prod", Resource: "projects/1002", Status: "ACTIVE"}, prior: types.StringValue("tenant@other-project.iam.gserviceaccount.com"), wantEmail: types.StringValue("tenant@other-project.iam.gserviceaccount.com"), wantWarning: true},
+		{name: "folder", project: TenantResource{Tag: "prod", Resource: "folders/123", Status: "ACTIVE"}, wantEmail: types.StringNull(), wantWarning: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data := ServiceProjectResourceModel{ProjectConfig: projectConfig, ServiceAccountEmail: tt.prior}
+			var diags diag.Diagnostics
+			r.setServiceAccountEmail(ctx, &data, &tt.project, &diags)
+			if diags.HasError() || (diags.WarningsCount() > 0) != tt.wantWarning {
+				t.Errorf("got diagnostics %v, want warning %v", diags, tt.wantWarning)
+			}
+			if !data.ServiceAccountEmail.Equal(tt.wantEmail) {
+				t.Errorf("got email %v, want %v", data.ServiceAccountEmail, tt.wantEmail)
+			}
+		})
+	}
+}
+
+func TestResourceServiceProjectDeletionPolicy(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+	ctx := context.Background()
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+
+	tests := []struct {
+		deletionPolicy  string
+		wantRemovedTags []string
+		wantDeletedTags []string
+		wantStatus      string
+	}{
+		{deletionPolicy: "", wantRemovedTags: []string{"prod"}},
+		{deletionPolicy: "REMOVE", wantRemovedTags: []string{"prod"}},
+		{deletionPolicy: "DELETE", wantDeletedTags: []string{"prod"}, wantStatus: "DELETED"},
+		{deletionPolicy: "ABANDON", wantStatus: "ACTIVE"},
+	}
+	for _, tt := range tests {
+		t.Run(cmp.Or(tt.deletionPolicy, "default"), func(t *testing.T) {
+			tenant := newFakeTenantServer()
+			tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{Name: tenancyUnit}
+			server, schemas := newFakeTenancyProviderServer(t, tenant)
+			typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+			values := map[string]tftypes.Value{
+				"tenancy_unit":   tftypes.NewValue(tftypes.String, tenancyUnit),
+				"tag":            tftypes.NewValue(tftypes.String, "prod"),
+				"project_config": testProjectConfigValue(t, testProjectConfigModel()),
+			}
+			if tt.deletionPolicy != "" {
+				values["deletion_policy"] = tftypes.NewValue(tftypes.String, tt.deletionPolicy)
+			}
+			state := testApplyResource(t, server, "utils_service_project", typ, nil, testDynamicValue(t, typ, values))
+
+			resp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+				TypeName:     "utils_service_project",
+				PriorState:   state,
+				PlannedState: testNullDynamicValue(t, typ),
+				Config:       testNullDynamicValue(t, typ),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			requireNoErrors(t, resp.Diagnostics)
+
+			if !slices.Equal(tenant.removedTags, tt.wantRemovedTags) {
+				t.Errorf("got removed tags %v, want %v", tenant.removedTags, tt.wantRemovedTags)
+			}
+			if !slices.Equal(tenant.deletedTags, tt.wantDeletedTags) {
+				t.Errorf("got deleted tags %v, want %v", tenant.deletedTags, tt.wantDeletedTags)
+			}
+			var status string
+			if resources := tenant.tenancyUnits[tenancyUnit].TenantResources; len(resources) > 0 {
+				status = resources[0].Status
+			}
+			if status != tt.wantStatus {
+				t.Errorf("got status %q, want %q", status, tt.wantStatus)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		server, schemas := newFakeTenancyProviderServer(t, newFakeTenantServer())
+		typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+		resp, err := server.ValidateResourceConfig(ctx, &tfprotov6.ValidateResourceConfigRequest{
+			TypeName: "utils_service_project",
+			Config: testDynamicValue(t, typ, map[string]tftypes.Value{
+				"tenancy_unit":    tftypes.NewValue(tftypes.String, tenancyUnit),
+				"tag":             tftypes.NewValue(tftypes.String, "prod"),
+				"project_config":  testProjectConfigValue(t, testProjectConfigModel()),
+				"deletion_policy": tftypes.NewValue(tftypes.String, "KEEP"),
+			}),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Diagnostics) == 0 {
+			t.Error("got no diagnostics, want a validation error")
+		}
+	})
+}
+
+func TestResourceServiceProjectDeleteMissing(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+	ctx := context.Background()
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+
+	for _, deletionPolicy := range []string{deletionPolicyRemove, deletionPolicyDelete} {
+		for name, remove := range map[string]func(*fakeTenantServer){
+			"missing tag": func(tenant *fakeTenantServer) {
+				tenant.tenancyUnits[tenancyUnit].TenantResources = nil
+			},
+			"missing tenancy unit": func(tenant *fakeTenantServer) {
+				delete(tenant.tenancyUnits, tenancyUnit)
+			},
+		} {
+			t.Run(deletionPolicy+" "+name, func(t *testing.T) {
+				tenant := newFakeTenantServer()
+				tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{Name: tenancyUnit}
+				server, schemas := newFakeTenancyProviderServer(t, tenant)
+				typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+				state := testApplyResource(t, server, "utils_service_project", typ, nil, testDynamicValue(t, typ, map[string]tftypes.Value{
+					"tenancy_unit":    tftypes.NewValue(tftypes.String, tenancyUnit),
+					"tag":             tftypes.NewValue(tftypes.String, "prod"),
+					"project_config":  testProjectConfigValue(t, testProjectConfigModel()),
+					"deletion_policy": tftypes.NewValue(tftypes.String, deletionPolicy),
+				}))
+				remove(tenant)
+
+				resp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+					TypeName:     "utils_service_project",
+					PriorState:   state,
+					PlannedState: testNullDynamicValue(t, typ),
+					Config:       testNullDynamicValue(t, typ),
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				requireNoErrors(t, resp.Diagnostics)
+				if value, err := resp.NewState.Unmarshal(typ); err != nil || !value.IsNull() {
+					t.Errorf("got state %v, %v, want null", value, err)
+				}
+				if len(tenant.removedTags) != 0 || len(tenant.deletedTags) != 0 {
+					t.Errorf("got removed tags %v and deleted tags %v, want none", tenant.removedTags, tenant.deletedTags)
+				}
+			})
+		}
+	}
+}
+
+func TestResourceServiceProjectUndeleteIfPendingDelete(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+	ctx := context.Background()
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+
+	for _, status := range []string{"PENDING_DELETE", "DELETED"} {
+		t.Run(status, func(t *testing.T) {
+			tenant := newFakeTenantServer()
+			tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{
+				Name: tenancyUnit,
+				TenantResources: []*serviceconsumermanagement.TenantResource{
+					{Tag: "prod", Resource: "projects/900", Status: status},
+				},
+			}
+			server, schemas := newFakeTenancyProviderServer(t, tenant)
+			typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+			newConfig := func(undelete bool) *tfprotov6.DynamicValue {
+				return testDynamicValue(t, typ, map[string]tftypes.Value{
+					"tenancy_unit":               tftypes.NewValue(tftypes.String, tenancyUnit),
+					"tag":                        tftypes.NewValue(tftypes.String, "prod"),
+					"project_config":             testProjectConfigValue(t, testProjectConfigModel()),
+					"undelete_if_pending_delete": tftypes.NewValue(tftypes.Bool, undelete),
+				})
+			}
+
+			// By default, the tag is taken.
+			config := newConfig(false)
+			planResp := testPlanResource(t, server, "utils_service_project", typ, nil, nil, config)
+			applyResp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+				TypeName:     "utils_service_project",
+				PriorState:   testNullDynamicValue(t, typ),
+				PlannedState: planResp.PlannedState,
+				Config:       config,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(applyResp.Diagnostics) != 1 || applyResp.Diagnostics[0].Summary != "Error adding project" {
+				t.Errorf("got diagnostics %v, want an error adding the project", applyResp.Diagnostics)
+			}
+			if len(tenant.undeletedTags) != 0 {
+				t.Errorf("got undeleted tags %v, want none", tenant.undeletedTags)
+			}
+
+			// Otherwise, the project is restored and configured.
+			state := testApplyResource(t, server, "utils_service_project", typ, nil, newConfig(true))
+			attrs := testStateAttributes(t, typ, state)
+			if !attrs["id"].Equal(tftypes.NewValue(tftypes.String, "projects/900")) || !attrs["status"].Equal(tftypes.NewValue(tftypes.String, "ACTIVE")) {
+				t.Errorf("got id %v and status %v, want projects/900 and ACTIVE", attrs["id"], attrs["status"])
+			}
+			if !slices.Equal(tenant.undeletedTags, []string{"prod"}) {
+				t.Errorf("got undeleted tags %v, want prod", tenant.undeletedTags)
+			}
+			if projectConfig := tenant.projectConfigs["prod"]; projectConfig == nil || projectConfig.Folder != "folders/123" {
+				t.Errorf("got project config %+v, want the planned config to be applied", projectConfig)
+			}
+		})
+	}
+
+	// An active project is not undeleted.
+	tenant := newFakeTenantServer()
+	tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{Name: tenancyUnit}
+	server, schemas := newFakeTenancyProviderServer(t, tenant)
+	typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+	testApplyResource(t, server, "utils_service_project", typ, nil, testDynamicValue(t, typ, map[string]tftypes.Value{
+		"tenancy_unit":               tftypes.NewValue(tftypes.String, tenancyUnit),
+		"tag":                        tftypes.NewValue(tftypes.String, "prod"),
+		"project_config":             testProjectConfigValue(t, testProjectConfigModel()),
+		"undelete_if_pending_delete": tftypes.NewValue(tftypes.Bool, true),
+	}))
+	if len(tenant.undeletedTags) != 0 {
+		t.Errorf("got undeleted tags %v, want none", tenant.undeletedTags)
+	}
+}
+
+func TestResourceServiceProjectAttachExisting(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+	ctx := context.Background()
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+
+	attachConfig := func(t *testing.T, typ tftypes.Type, attr string, folder bool) *tfprotov6.DynamicValue {
+		attachType := typ.(tftypes.Object).AttributeTypes["attach_existing"].(tftypes.Object)
+		attach := map[string]tftypes.Value{
+			"external_resource": tftypes.NewValue(tftypes.String, nil),
+			"reserved_resource": tftypes.NewValue(tftypes.String, nil),
+		}
+		attach[attr] = tftypes.NewValue(tftypes.String, map[string]string{
+			"external_resource": "projects/42",
+			"reserved_resource": "reserved1",
+		}[attr])
+		model := testProjectConfigModel()
+		if !folder {
+			model.Folder = types.StringNull()
+		}
+		return testDynamicValue(t, typ, map[string]tftypes.Value{
+			"tenancy_unit":    tftypes.NewValue(tftypes.String, tenancyUnit),
+			"tag":             tftypes.NewValue(tftypes.String, "prod"),
+			"project_config":  testProjectConfigValue(t, model),
+			"attach_existing": tftypes.NewValue(attachType, attach),
+		})
+	}
+
+	for _, tt := range []struct {
+		attr   string
+		wantID string
+	}{
+		{attr: "external_resource", wantID: "projects/42"},
+		{attr: "reserved_resource", wantID: "projects/77"},
+	} {
+		t.Run(tt.attr, func(t *testing.T) {
+			tenant := newFakeTenantServer()
+			tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{Name: tenancyUnit}
+			tenant.reservedProjects["reserved1"] = "projects/77"
+			server, schemas := newFakeTenancyProviderServer(t, tenant)
+			typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+			state := testApplyResource(t, server, "utils_service_project", typ, nil, attachConfig(t, typ, tt.attr, false))
+			attrs := testStateAttributes(t, typ, state)
+			if !attrs["id"].Equal(tftypes.NewValue(tftypes.String, tt.wantID)) || !attrs["status"].Equal(tftypes.NewValue(tftypes.String, "ACTIVE")) {
+				t.Errorf("got id %v and status %v, want %s and ACTIVE", attrs["id"], attrs["status"], tt.wantID)
+			}
+			if !slices.Equal(tenant.attachedTags, []string{"prod"}) {
+				t.Errorf("got attached tags %v, want prod", tenant.attachedTags)
+			}
+			if projectConfig := tenant.projectConfigs["prod"]; projectConfig == nil || projectConfig.Folder != "" || projectConfig.BillingConfig.BillingAccount != "billingAccounts/012345-567890-ABCDEF" {
+				t.Errorf("got project config %+v, want the planned config without a folder to be applied", projectConfig)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		server, schemas := newFakeTenancyProviderServer(t, newFakeTenantServer())
+		typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+		noFolder := testProjectConfigModel()
+		noFolder.Folder = types.StringNull()
+		for name, config := range map[string]*tfprotov6.DynamicValue{
+			"folder with attach": attachConfig(t, typ, "external_resource", true),
+			"neither folder nor attach": testDynamicValue(t, typ, map[string]tftypes.Value{
+				"tenancy_unit":   tftypes.NewValue(tftypes.String, tenancyUnit),
+				"tag":            tftypes.NewValue(tftypes.String, "prod"),
+				"project_config": testProjectConfigValue(t, noFolder),
+			}),
+			"neither resource": testDynamicValue(t, typ, map[string]tftypes.Value{
+				"tenancy_unit":   tftypes.NewValue(tftypes.String, tenancyUnit),
+				"tag":            tftypes.NewValue(tftypes.String, "prod"),
+				"project_config": testProjectConfigValue(t, noFolder),
+				"attach_existing": tftypes.NewValue(typ.(tftypes.Object).AttributeTypes["attach_existing"], map[string]tftypes.Value{
+					"external_resource": tftypes.NewValue(tftypes.String, nil),
+					"reserved_resource": tftypes.NewValue(tftypes.String, nil),
+				}),
+			}),
+		} {
+			resp, err := server.ValidateResourceConfig(ctx, &tfprotov6.ValidateResourceConfigRequest{
+				TypeName: "utils_service_project",
+				Config:   config,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Diagnostics) == 0 {
+				t.Errorf("%s: got no diagnostics, want a validation error", name)
+			}
+		}
+	})
+}
+
+func TestResourceServiceProjectValidateBillingAccount(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+	const billingAccount = "billingAccounts/012345-567890-ABCDEF"
+
+	for _, tt := range []struct {
+		name         string
+		exists       bool
+		open         bool
+		forbidden    bool
+		validate     bool
+		priorState   bool
+		wantSummary  string
+		wantGetCalls int
+	}{
+		{name: "open", exists: true, open: true, validate: true, wantGetCalls: 1},
+		{name: "closed", exists: true, validate: true, wantSummary: "Billing account is closed", wantGetCalls: 1},
+		{name: "missing", validate: true, wantSummary: "Billing account not found", wantGetCalls: 1},
+		{name: "forbidden", forbidden: true, validate: true, wantGetCalls: 1},
+		{name: "disabled"},
+		{name: "unchanged", exists: true, validate: true, priorState: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			billing := newFakeCloudBilling()
+			billing.forbidden = tt.forbidden
+			if tt.exists {
+				billing.accounts[billingAccount] = tt.open
+			}
+			providerConfig := newFakeProviderConfig(t, newFakeServiceManager())
+			providerConfig.TenantClient = newFakeTenantClient(t, newFakeTenantServer())
+			providerConfig.BillingClient = newFakeCloudBillingClient(t, billing)
+			server, schemas := newFakeProviderServer(t, providerConfig)
+			typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+			config := testDynamicValue(t, typ, map[string]tftypes.Value{
+				"tenancy_unit":             tftypes.NewValue(tftypes.String, tenancyUnit),
+				"tag":                      tftypes.NewValue(tftypes.String, "prod"),
+				"project_config":           testProjectConfigValue(t, testProjectConfigModel()),
+				"validate_billing_account": tftypes.NewValue(tftypes.Bool, tt.validate),
+			})
+			var priorState *tfprotov6.DynamicValue
+			if tt.priorState {
+				priorState = testDynamicValue(t, typ, map[string]tftypes.Value{
+					"id":                         tftypes.NewValue(tftypes.String, "projects/1001"),
+					"tenancy_unit":               tftypes.NewValue(tftypes.String, tenancyUnit),
+					"tag":                        tftypes.NewValue(tftypes.String, "prod"),
+					"project_config":             testProjectConfigValue(t, testProjectConfigModel()),
+					"validate_billing_account":   tftypes.NewValue(tftypes.Bool, true),
+					"delete_on_failure":          tftypes.NewValue(tftypes.Bool, false),
+					"deletion_policy":            tftypes.NewValue(tftypes.String, deletionPolicyRemove),
+					"undelete_if_pending_delete": tftypes.NewValue(tftypes.Bool, false),
+					"status":                     tftypes.NewValue(tftypes.String, "ACTIVE"),
+				})
+			}
+			resp, err := server.PlanResourceChange(context.Background(), &tfprotov6.PlanResourceChangeRequest{
+				TypeName:         "utils_service_project",
+				PriorState:       cmp.Or(priorState, testNullDynamicValue(t, typ)),
+				ProposedNewState: config,
+				Config:           config,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var summaries []string
+			for _, d := range resp.Diagnostics {
+				summaries = append(summaries, d.Summary)
+			}
+			if tt.wantSummary == "" && len(summaries) != 0 || tt.wantSummary != "" && !slices.Equal(summaries, []string{tt.wantSummary}) {
+				t.Errorf("got diagnostics %v, want %q", summaries, tt.wantSummary)
+			}
+			if billing.getCalls != tt.wantGetCalls {
+				t.Errorf("got %d calls to get the billing account, want %d", billing.getCalls, tt.wantGetCalls)
+			}
+		})
+	}
+
+	t.Run("invalid format", func(t *testing.T) {
+		server, schemas := newFakeTenancyProviderServer(t, newFakeTenantServer())
+		typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+		model := testProjectConfigModel()
+		model.BillingConfig = types.ObjectValueMust(ServiceProjectConfigBillingConfigModel{}.AttributeTypes(), map[string]attr.Value{
+			"billing_account": types.StringValue("012345-567890-ABCDEF"),
+		})
+		resp, err := server.ValidateResourceConfig(context.Background(), &tfprotov6.ValidateResourceConfigRequest{
+			TypeName: "utils_service_project",
+			Config: testDynamicValue(t, typ, map[string]tftypes.Value{
+				"tenancy_unit":   tftypes.NewValue(tftypes.String, tenancyUnit),
+				"tag":            tftypes.NewValue(tftypes.String, "prod"),
+				"project_config": testProjectConfigValue(t, model),
+			}),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Diagnostics) == 0 {
+			t.Error("got no diagnostics, want a validation error")
+		}
+	})
+}
+
+func TestResourceServiceProjectWaitForStatus(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+	ctx := context.Background()
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = time.Millisecond
+
+	for _, tt := range []struct {
+		name             string
+		waitForStatus    string
+		settledStatus    string
+		pendingListCalls int
+		createTimeout    string
+		wantStatus       string
+		wantSummary      string
+	}{
+		{name: "active", waitForStatus: "ACTIVE", settledStatus: "ACTIVE", pendingListCalls: 3, wantStatus: "ACTIVE"},
+		{name: "none", waitForStatus: "NONE", settledStatus: "ACTIVE", pendingListCalls: 3, wantStatus: "PENDING_CREATE"},
+		{name: "failed", waitForStatus: "ACTIVE", settledStatus: "FAILED", pendingListCalls: 3, wantStatus: "FAILED", wantSummary: "Tenant project creation failed"},
+		{name: "timeout", waitForStatus: "ACTIVE", settledStatus: "ACTIVE", pendingListCalls: math.MaxInt, createTimeout: "50ms", wantStatus: "PENDING_CREATE", wantSummary: "Error waiting for project"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant := newFakeTenantServer()
+			tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{Name: tenancyUnit}
+			tenant.projectStatus = "PENDING_CREATE"
+			tenant.settledStatus = tt.settledStatus
+			tenant.pendingListCalls = tt.pendingListCalls
+			server, schemas := newFakeTenancyProviderServer(t, tenant)
+			typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+			timeoutsType := typ.(tftypes.Object).AttributeTypes["timeouts"].(tftypes.Object)
+			timeouts := tftypes.NewValue(timeoutsType, nil)
+			if tt.createTimeout != "" {
+				timeouts = tftypes.NewValue(timeoutsType, map[string]tftypes.Value{
+					"create": tftypes.NewValue(tftypes.String, tt.createTimeout),
+					"update": tftypes.NewValue(tftypes.String, nil),
+				})
+			}
+			config := testDynamicValue(t, typ, map[string]tftypes.Value{
+				"tenancy_unit":    tftypes.NewValue(tftypes.String, tenancyUnit),
+				"tag":             tftypes.NewValue(tftypes.String, "prod"),
+				"project_config":  testProjectConfigValue(t, testProjectConfigModel()),
+				"wait_for_status": tftypes.NewValue(tftypes.String, tt.waitForStatus),
+				"timeouts":        timeouts,
+			})
+			planResp := testPlanResource(t, server, "utils_service_project", typ, nil, nil, config)
+			applyResp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+				TypeName:     "utils_service_project",
+				PriorState:   testNullDynamicValue(t, typ),
+				PlannedState: planResp.PlannedState,
+				Config:       config,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var summaries []string
+			for _, d := range applyResp.Diagnostics {
+				summaries = append(summaries, d.Summary)
+			}
+			if tt.wantSummary == "" && len(summaries) != 0 || tt.wantSummary != "" && !slices.Equal(summaries, []string{tt.wantSummary}) {
+				t.Errorf("got diagnostics %v, want %q", applyResp.Diagnostics, tt.wantSummary)
+			}
+			if status := testStateAttributes(t, typ, applyResp.NewState)["status"]; !status.Equal(tftypes.NewValue(tftypes.String, tt.wantStatus)) {
+				t.Errorf("got status %v, want %s", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestResourceServiceProjectWaitForCompletion(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+	ctx := context.Background()
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+
+	setup := func(t *testing.T) (*fakeTenantServer, tfprotov6.ProviderServer, tftypes.Type, *tfprotov6.ApplyResourceChangeResponse) {
+		tenant := newFakeTenantServer()
+		tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{Name: tenancyUnit}
+		resourceManager := newFakeResourceManager()
+		resourceManager.projects["projects/1001"] = &cloudresourcemanager.Project{Name: "projects/1001", ProjectId: "tenant-1001", Parent: "folders/123"}
+		providerConfig := newFakeProviderConfig(t, newFakeServiceManager())
+		providerConfig.TenantClient = newFakeTenantClient(t, tenant)
+		providerConfig.ResourceManagerClient = newFakeResourceManagerClient(t, resourceManager)
+		providerConfig.ServiceUsageClient = newFakeServiceUsageClient(t, newFakeServiceUsage())
+		server, schemas := newFakeProviderServer(t, providerConfig)
+		typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+		config := testDynamicValue(t, typ, map[string]tftypes.Value{
+			"tenancy_unit":        tftypes.NewValue(tftypes.String, tenancyUnit),
+			"tag":                 tftypes.NewValue(tftypes.String, "prod"),
+			"project_config":      testProjectConfigValue(t, testProjectConfigModel()),
+			"wait_for_completion": tftypes.NewValue(tftypes.Bool, false),
+		})
+		planResp := testPlanResource(t, server, "utils_service_project", typ, nil, nil, config)
+		applyResp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+			TypeName:     "utils_service_project",
+			PriorState:   testNullDynamicValue(t, typ),
+			PlannedState: planResp.PlannedState,
+			Config:       config,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		requireNoErrors(t, applyResp.Diagnostics)
+
+		// Create returns without waiting for the operation.
+		attrs := testStateAttributes(t, typ, applyResp.NewState)
+		if !attrs["status"].Equal(tftypes.NewValue(tftypes.String, "PENDING_CREATE")) || !attrs["id"].IsNull() {
+			t.Errorf("got status %v and id %v, want PENDING_CREATE without an id", attrs["status"], attrs["id"])
+		}
+		if len(tenant.operations) != 1 {
+			t.Errorf("got pending operations %v, want 1", tenant.operations)
+		}
+		return tenant, server, typ, applyResp
+	}
+
+	t.Run("read", func(t *testing.T) {
+		tenant, server, typ, applyResp := setup(t)
+
+		// The next refresh waits for the operation.
+		readResp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+			TypeName:     "utils_service_project",
+			CurrentState: applyResp.NewState,
+			Private:      applyResp.Private,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		requireNoErrors(t, readResp.Diagnostics)
+		if len(tenant.operations) != 0 {
+			t.Errorf("got pending operations %v, want none", tenant.operations)
+		}
+		attrs := testStateAttributes(t, typ, readResp.NewState)
+		if !attrs["status"].Equal(tftypes.NewValue(tftypes.String, "ACTIVE")) || !attrs["id"].Equal(tftypes.NewValue(tftypes.String, "projects/1001")) {
+			t.Errorf("got status %v and id %v, want ACTIVE projects/1001", attrs["status"], attrs["id"])
+		}
+
+		// The completed operation is not polled again, which would fail.
+		readResp, err = server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+			TypeName:     "utils_service_project",
+			CurrentState: readResp.NewState,
+			Private:      readResp.Private,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		requireNoErrors(t, readResp.Diagnostics)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		tenant, server, _, applyResp := setup(t)
+		tenant.operationError = &serviceconsumermanagement.Status{Code: int64(codes.ResourceExhausted), Message: "quota exceeded"}
+
+		readResp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+			TypeName:     "utils_service_project",
+			CurrentState: applyResp.NewState,
+			Private:      applyResp.Private,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		requireNoErrors(t, readResp.Diagnostics)
+		if !slices.ContainsFunc(readResp.Diagnostics, func(d *tfprotov6.Diagnostic) bool {
+			return d.Summary == "Tenant project operation failed" && strings.Contains(d.Detail, "quota exceeded")
+		}) {
+			t.Errorf("got diagnostics %v, want a failed operation warning", readResp.Diagnostics)
+		}
+	})
+
+	t.Run("update without refresh", func(t *testing.T) {
+		tenant, server, typ, applyResp := setup(t)
+
+		model := testProjectConfigModel()
+		model.Labels = types.MapValueMust(types.StringType, map[string]attr.Value{"env": types.StringValue("prod")})
+		config := testDynamicValue(t, typ, map[string]tftypes.Value{
+			"tenancy_unit":        tftypes.NewValue(tftypes.String, tenancyUnit),
+			"tag":                 tftypes.NewValue(tftypes.String, "prod"),
+			"project_config":      testProjectConfigValue(t, model),
+			"wait_for_completion": tftypes.NewValue(tftypes.Bool, false),
+		})
+		planResp := testPlanResource(t, server, "utils_service_project", typ, applyResp.NewState, applyResp.Private, config)
+		updateResp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+			TypeName:       "utils_service_project",
+			PriorState:     applyResp.NewState,
+			PlannedState:   planResp.PlannedState,
+			Config:         config,
+			PlannedPrivate: planResp.PlannedPrivate,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		requireNoErrors(t, updateResp.Diagnostics)
+		if len(tenant.operations) != 0 {
+			t.Errorf("got pending operations %v, want none", tenant.operations)
+		}
+		if status := testStateAttributes(t, typ, updateResp.NewState)["status"]; !status.Equal(tftypes.NewValue(tftypes.String, "ACTIVE")) {
+			t.Errorf("got status %v, want ACTIVE", status)
+		}
+	})
+}
+
+func TestResourceServiceProjectEffectivePolicy(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+
+	tenant := newFakeTenantServer()
+	tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{Name: tenancyUnit}
+	resourceManager := newFakeResourceManager()
+	resourceManager.projects["projects/1001"] = &cloudresourcemanager.Project{
+		Name:      "projects/1001",
+		ProjectId: "tenant-1001",
+		Parent:    "folders/123",
+		Labels:    map[string]string{"env": "test"},
+	}
+	serviceUsage := newFakeServiceUsage()
+	serviceUsage.enabledServices["projects/1001/services/compute.googleapis.com"] = true
+	providerConfig := newFakeProviderConfig(t, newFakeServiceManager())
+	providerConfig.TenantClient = newFakeTenantClient(t, tenant)
+	providerConfig.ResourceManagerClient = newFakeResourceManagerClient(t, resourceManager)
+	providerConfig.ServiceUsageClient = newFakeServiceUsageClient(t, serviceUsage)
+	server, schemas := newFakeProviderServer(t, providerConfig)
+	typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+	state := testApplyResource(t, server, "utils_service_project", typ, nil, testDynamicValue(t, typ, map[string]tftypes.Value{
+		"tenancy_unit":   tftypes.NewValue(tftypes.String, tenancyUnit),
+		"tag":            tftypes.NewValue(tftypes.String, "prod"),
+		"project_config": testProjectConfigValue(t, testProjectConfigModel()),
+	}))
+	if policy := testStateAttributes(t, typ, state)["effective_policy_json"]; !policy.IsNull() {
+		t.Errorf("got effective_policy_json %v after create, want null", policy)
+	}
+
+	resourceManager.iamPolicies["projects/1001"] = &cloudresourcemanager.Policy{
+		Etag: "BwX",
+		Bindings: []*cloudresourcemanager.Binding{
+			{Role: "roles/owner", Members: []string{"serviceAccount:scm@example.iam.gserviceaccount.com", "group:owners@example.com"}},
+			{Role: "roles/editor", Members: []string{"user:b@example.com", "user:a@example.com"}},
+		},
+	}
+	const want = `{"bindings":[{"role":"roles/editor","members":["user:a@example.com","user:b@example.com"]},{"role":"roles/owner","members":["group:owners@example.com","serviceAccount:scm@example.iam.gserviceaccount.com"]}]}`
+	for range 2 {
+		state = testReadResource(t, server, "utils_service_project", state)
+		if policy := testStateAttributes(t, typ, state)["effective_policy_json"]; !policy.Equal(tftypes.NewValue(tftypes.String, want)) {
+			t.Errorf("got effective_policy_json %v, want %s", policy, want)
+		}
+	}
+
+	// Without permission, the policy is null with a warning.
+	resourceManager.denyGetIamPolicy = true
+	resp, err := server.ReadResource(context.Background(), &tfprotov6.ReadResourceRequest{
+		TypeName:     "utils_service_project",
+		CurrentState: state,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Diagnostics) != 1 || resp.Diagnostics[0].Severity != tfprotov6.DiagnosticSeverityWarning {
+		t.Errorf("got diagnostics %v, want a warning", resp.Diagnostics)
+	}
+	if policy := testStateAttributes(t, typ, resp.NewState)["effective_policy_json"]; !policy.IsNull() {
+		t.Errorf("got effective_policy_json %v, want null", policy)
+	}
+}
+
+func TestResourceServiceProjectTagExists(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+
+	for _, tt := range []struct {
+		status      string
+		wantID      string
+		wantDeleted []string
+	}{
+		{status: "ACTIVE", wantID: "projects/900"},
+		{status: "FAILED", wantID: "projects/1001", wantDeleted: []string{"prod"}},
+	} {
+		t.Run(tt.status, func(t *testing.T) {
+			tenant := newFakeTenantServer()
+			tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{
+				Name: tenancyUnit,
+				TenantResources: []*serviceconsumermanagement.TenantResource{
+					{Tag: "prod", Resource: "projects/900", Status: tt.status},
+				},
+			}
+			server, schemas := newFakeTenancyProviderServer(t, tenant)
+			typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+			state := testApplyResource(t, server, "utils_service_project", typ, nil, testDynamicValue(t, typ, map[string]tftypes.Value{
+				"tenancy_unit":   tftypes.NewValue(tftypes.String, tenancyUnit),
+				"tag":            tftypes.NewValue(tftypes.String, "prod"),
+				"project_config": testProjectConfigValue(t, testProjectConfigModel()),
+			}))
+			attrs := testStateAttributes(t, typ, state)
+			if !attrs["id"].Equal(tftypes.NewValue(tftypes.String, tt.wantID)) || !attrs["status"].Equal(tftypes.NewValue(tftypes.String, "ACTIVE")) {
+				t.Errorf("got id %v and status %v, want %s and ACTIVE", attrs["id"], attrs["status"], tt.wantID)
+			}
+			if !slices.Equal(tenant.deletedTags, tt.wantDeleted) || !slices.Equal(tenant.removedTags, tt.wantDeleted) {
+				t.Errorf("got deleted tags %v and removed tags %v, want %v", tenant.deletedTags, tenant.removedTags, tt.wantDeleted)
+			}
+			if projectConfig := tenant.projectConfigs["prod"]; projectConfig == nil || projectConfig.Folder != "folders/123" {
+				t.Errorf("got project config %+v, want the planned config to be applied", projectConfig)
+			}
+		})
+	}
+}
+
+func TestResourceServiceProjectRetry(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+	ctx := context.Background()
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+	defer func(delay time.Duration) { tenantProjectRetryBaseDelay = delay }(tenantProjectRetryBaseDelay)
+	tenantProjectRetryBaseDelay = 0
+
+	exhausted := fakeTenantError{code: http.StatusTooManyRequests, status: "RESOURCE_EXHAUSTED"}
+	aborted := fakeTenantError{code: http.StatusConflict, status: "ABORTED"}
+	for _, tt := range []struct {
+		name        string
+		update      bool
+		maxAttempts int
+		failures    []fakeTenantError
+		wantCalls   int
+		wantError   string
+	}{
+		{name: "resource exhausted", failures: []fakeTenantError{exhausted, exhausted}, wantCalls: 3},
+		{name: "aborted", failures: []fakeTenantError{aborted, aborted}, wantCalls: 3},
+		{name: "update aborted", update: true, failures: []fakeTenantError{aborted, aborted}, wantCalls: 3},
+		{name: "invalid argument", failures: []fakeTenantError{{code: http.StatusBadRequest, status: "INVALID_ARGUMENT"}}, wantCalls: 1, wantError: "fake failure"},
+		{name: "permission denied", failures: []fakeTenantError{{code: http.StatusForbidden, status: "PERMISSION_DENIED"}}, wantCalls: 1, wantError: "fake failure"},
+		{
+			name:        "attempts exhausted",
+			maxAttempts: 2,
+			failures:    []fakeTenantError{exhausted, exhausted, exhausted},
+			wantCalls:   2,
+			wantError:   "failed after 2 attempts",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant := newFakeTenantServer()
+			tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{Name: tenancyUnit}
+			resourceManager := newFakeResourceManager()
+			resourceManager.tenantProjects = true
+			providerConfig := newFakeProviderConfig(t, newFakeServiceManager())
+			providerConfig.TenantClient = newFakeTenantClient(t, tenant)
+			providerConfig.ResourceManagerClient = newFakeResourceManagerClient(t, resourceManager)
+			providerConfig.TenantProjectRetryAttempts = tt.maxAttempts
+			server, schemas := newFakeProviderServer(t, providerConfig)
+			typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+			model := testProjectConfigModel()
+			newConfig := func(model ServiceProjectConfigModel) *tfprotov6.DynamicValue {
+				return testDynamicValue(t, typ, map[string]tftypes.Value{
+					"tenancy_unit":   tftypes.NewValue(tftypes.String, tenancyUnit),
+					"tag":            tftypes.NewValue(tftypes.String, "prod"),
+					"project_config": testProjectConfigValue(t, model),
+				})
+			}
+			priorState := testNullDynamicValue(t, typ)
+			if tt.update {
+				priorState = testApplyResource(t, server, "utils_service_project", typ, nil, newConfig(model))
+				tenant.mutationCalls = 0
+				model.Labels = types.MapValueMust(types.StringType, map[string]attr.Value{"env": types.StringValue("prod")})
+			}
+			tenant.mutationFailures = tt.failures
+
+			config := newConfig(model)
+			planResp := testPlanResource(t, server, "utils_service_project", typ, priorState, nil, config)
+			resp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+				TypeName:     "utils_service_project",
+				PriorState:   priorState,
+				PlannedState: planResp.PlannedState,
+				Config:       config,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantError == "" {
+				requireNoErrors(t, resp.Diagnostics)
+			} else if len(resp.Diagnostics) == 0 || !strings.Contains(resp.Diagnostics[0].Detail, tt.wantError) {
+				t.Errorf("got diagnostics %v, want error containing %q", resp.Diagnostics, tt.wantError)
+			}
+			if tenant.mutationCalls != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", tenant.mutationCalls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestResourceServiceProjectValidateFolder(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+	const serviceAgent = "serviceAccount:service-456@service-consumer-management.iam.gserviceaccount.com"
+
+	for _, tt := range []struct {
+		name         string
+		folderState  string
+		bindings     []*cloudresourcemanager.Binding
+		denyPolicy   bool
+		wantSeverity tfprotov6.DiagnosticSeverity
+		wantSummary  string
+	}{
+		{
+			name:        "granted",
+			folderState: "ACTIVE",
+			bindings:    []*cloudresourcemanager.Binding{{Role: "roles/resourcemanager.projectCreator", Members: []string{"user:a@example.com", serviceAgent}}},
+		},
+		{name: "missing", wantSeverity: tfprotov6.DiagnosticSeverityError, wantSummary: "Folder not found"},
+		{name: "deleted", folderState: "DELETE_REQUESTED", wantSeverity: tfprotov6.DiagnosticSeverityError, wantSummary: "Folder is not active"},
+		{
+			name:         "not granted",
+			folderState:  "ACTIVE",
+			bindings:     []*cloudresourcemanager.Binding{{Role: "roles/viewer", Members: []string{serviceAgent}}},
+			wantSeverity: tfprotov6.DiagnosticSeverityWarning,
+			wantSummary:  "Service agent may not be able to create projects",
+		},
+		{
+			name:         "policy denied",
+			folderState:  "ACTIVE",
+			denyPolicy:   true,
+			wantSeverity: tfprotov6.DiagnosticSeverityWarning,
+			wantSummary:  "Could not check folder permissions",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceManager := newFakeServiceManager()
+			serviceManager.services["test.endpoints.example.cloud.goog"] = &servicemanagementpb.ManagedService{
+				ServiceName:       "test.endpoints.example.cloud.goog",
+				ProducerProjectId: "producer",
+			}
+			resourceManager := newFakeResourceManager()
+			resourceManager.projectNumbers["producer"] = "456"
+			resourceManager.denyGetIamPolicy = tt.denyPolicy
+			if tt.folderState != "" {
+				resourceManager.folders["folders/123"] = &cloudresourcemanager.Folder{Name: "folders/123", State: tt.folderState}
+			}
+			resourceManager.iamPolicies["folders/123"] = &cloudresourcemanager.Policy{Bindings: tt.bindings}
+			providerConfig := newFakeProviderConfig(t, serviceManager)
+			providerConfig.TenantClient = newFakeTenantClient(t, newFakeTenantServer())
+			providerConfig.ResourceManagerClient = newFakeResourceManagerClient(t, resourceManager)
+			server, schemas := newFakeProviderServer(t, providerConfig)
+			typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+			config := testDynamicValue(t, typ, map[string]tftypes.Value{
+				"tenancy_unit":    tftypes.NewValue(tftypes.String, tenancyUnit),
+				"tag":             tftypes.NewValue(tftypes.String, "prod"),
+				"project_config":  testProjectConfigValue(t, testProjectConfigModel()),
+				"validate_folder": tftypes.NewValue(tftypes.Bool, true),
+			})
+			resp, err := server.PlanResourceChange(context.Background(), &tfprotov6.PlanResourceChangeRequest{
+				TypeName:         "utils_service_project",
+				PriorState:       testNullDynamicValue(t, typ),
+				ProposedNewState: config,
+				Config:           config,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantSummary == "" {
+				if len(resp.Diagnostics) != 0 {
+					t.Errorf("got diagnostics %v, want none", resp.Diagnostics)
+				}
+				return
+			}
+			if len(resp.Diagnostics) != 1 || resp.Diagnostics[0].Summary != tt.wantSummary || resp.Diagnostics[0].Severity != tt.wantSeverity {
+				t.Fatalf("got diagnostics %v, want %q", resp.Diagnostics, tt.wantSummary)
+			}
+			if want := tftypes.NewAttributePath().WithAttributeName("project_config").WithAttributeName("folder"); !resp.Diagnostics[0].Attribute.Equal(want) {
+				t.Errorf("got diagnostic for %v, want %v", resp.Diagnostics[0].Attribute, want)
+			}
+		})
+	}
+
+	t.Run("invalid format", func(t *testing.T) {
+		server, schemas := newFakeTenancyProviderServer(t, newFakeTenantServer())
+		typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+		model := testProjectConfigModel()
+		model.Folder = types.StringValue("123")
+		resp, err := server.ValidateResourceConfig(context.Background(), &tfprotov6.ValidateResourceConfigRequest{
+			TypeName: "utils_service_project",
+			Config: testDynamicValue(t, typ, map[string]tftypes.Value{
+				"tenancy_unit":   tftypes.NewValue(tftypes.String, tenancyUnit),
+				"tag":            tftypes.NewValue(tftypes.String, "prod"),
+				"project_config": testProjectConfigValue(t, model),
+			}),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Diagnostics) == 0 {
+			t.Error("got no diagnostics, want a validation error")
+		}
+	})
+}
+
+func TestResourceServiceProjectServicesOrder(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+
+	tenant := newFakeTenantServer()
+	tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{Name: tenancyUnit}
+	server, schemas := newFakeTenancyProviderServer(t, tenant)
+	typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+	newConfig := func(services ...string) *tfprotov6.DynamicValue {
+		model := testProjectConfigModel()
+		var elements []attr.Value
+		for _, service := range services {
+			elements = append(elements, types.StringValue(service))
+		}
+		model.Services = types.SetValueMust(types.StringType, elements)
+		return testDynamicValue(t, typ, map[string]tftypes.Value{
+			"tenancy_unit":   tftypes.NewValue(tftypes.String, tenancyUnit),
+			"tag":            tftypes.NewValue(tftypes.String, "prod"),
+			"project_config": testProjectConfigValue(t, model),
+		})
+	}
+	state := testApplyResource(t, server, "utils_service_project", typ, nil, newConfig("compute.googleapis.com", "storage.googleapis.com"))
+
+	planned := testPlanResource(t, server, "utils_service_project", typ, state, nil, newConfig("storage.googleapis.com", "compute.googleapis.com")).PlannedState
+	if got, want := testStateAttributes(t, typ, planned)["project_config"], testStateAttributes(t, typ, state)["project_config"]; !got.Equal(want) {
+		t.Errorf("got planned project_config %v, want it unchanged %v", got, want)
+	}
+}
+
+func TestResourceServiceProjectUpdateUnchangedConfig(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+
+	tenant := newFakeTenantServer()
+	tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{Name: tenancyUnit}
+	server, schemas := newFakeTenancyProviderServer(t, tenant)
+	typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+	newConfig := func(model ServiceProjectConfigModel, deleteOnFailure bool) *tfprotov6.DynamicValue {
+		return testDynamicValue(t, typ, map[string]tftypes.Value{
+			"tenancy_unit":      tftypes.NewValue(tftypes.String, tenancyUnit),
+			"tag":               tftypes.NewValue(tftypes.String, "prod"),
+			"project_config":    testProjectConfigValue(t, model),
+			"delete_on_failure": tftypes.NewValue(tftypes.Bool, deleteOnFailure),
+		})
+	}
+	model := testProjectConfigModel()
+	state := testApplyResource(t, server, "utils_service_project", typ, nil, newConfig(model, false))
+
+	// Changing another attribute does not apply the project config.
+	state = testApplyResource(t, server, "utils_service_project", typ, state, newConfig(model, true))
+	if len(tenant.appliedTags) != 0 {
+		t.Errorf("got applied tags %v, want none", tenant.appliedTags)
+	}
+	if deleteOnFailure := testStateAttributes(t, typ, state)["delete_on_failure"]; !deleteOnFailure.Equal(tftypes.NewValue(tftypes.Bool, true)) {
+		t.Errorf("got delete_on_failure %v, want true", deleteOnFailure)
+	}
+
+	// Changing the project config applies it.
+	model.Labels = types.MapValueMust(types.StringType, map[string]attr.Value{"env": types.StringValue("prod")})
+	testApplyResource(t, server, "utils_service_project", typ, state, newConfig(model, true))
+	if !slices.Equal(tenant.appliedTags, []string{"prod"}) {
+		t.Errorf("got applied tags %v, want [prod]", tenant.appliedTags)
+	}
+	if labels := tenant.projectConfigs["prod"].Labels; labels["env"] != "prod" {
+		t.Errorf("got labels %v, want env=prod", labels)
+	}
+}
+
+func TestResourceServiceProjectFolderChangeBehavior(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+
+	for _, tt := range []struct {
+		behavior    string
+		wantReplace bool
+	}{
+		{behavior: folderChangeBehaviorMove},
+		{behavior: folderChangeBehaviorRecreate, wantReplace: true},
+	} {
+		t.Run(tt.behavior, func(t *testing.T) {
+			tenant := newFakeTenantServer()
+			tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{Name: tenancyUnit}
+			server, schemas := newFakeTenancyProviderServer(t, tenant)
+			typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+			newConfig := func(model ServiceProjectConfigModel) *tfprotov6.DynamicValue {
+				return testDynamicValue(t, typ, map[string]tftypes.Value{
+					"tenancy_unit":           tftypes.NewValue(tftypes.String, tenancyUnit),
+					"tag":                    tftypes.NewValue(tftypes.String, "prod"),
+					"project_config":         testProjectConfigValue(t, model),
+					"folder_change_behavior": tftypes.NewValue(tftypes.String, tt.behavior),
+				})
+			}
+			model := testProjectConfigModel()
+			state := testApplyResource(t, server, "utils_service_project", typ, nil, newConfig(model))
+
+			// Other changes to the project config are applied in place.
+			model.Labels = types.MapValueMust(types.StringType, map[string]attr.Value{"env": types.StringValue("prod")})
+			if planResp := testPlanResource(t, server, "utils_service_project", typ, state, nil, newConfig(model)); len(planResp.RequiresReplace) != 0 {
+				t.Errorf("got replacement for %v, want none for a label change", planResp.RequiresReplace)
+			}
+
+			model.Folder = types.StringValue("folders/456")
+			planResp := testPlanResource(t, server, "utils_service_project", typ, state, nil, newConfig(model))
+			folderPath := tftypes.NewAttributePath().WithAttributeName("project_config").WithAttributeName("folder")
+			if gotReplace := slices.ContainsFunc(planResp.RequiresReplace, folderPath.Equal); gotReplace != tt.wantReplace {
+				t.Errorf("got replacement for %v, want replacement %t", planResp.RequiresReplace, tt.wantReplace)
+			}
+			if tt.wantReplace {
+				return
+			}
+
+			testApplyResource(t, server, "utils_service_project", typ, state, newConfig(model))
+			if !slices.Equal(tenant.appliedTags, []string{"prod"}) {
+				t.Errorf("got applied tags %v, want [prod]", tenant.appliedTags)
+			}
+			if folder := tenant.projectConfigs["prod"].Folder; folder != "folders/456" {
+				t.Errorf("got folder %q, want folders/456", folder)
+			}
+		})
+	}
+}
+
+func TestResourceServiceProjectPolicyOrder(t *testing.T) {
+	const tenancyUnit = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/unit1"
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+
+	policyBindingType := types.ObjectType{AttrTypes: PolicyBinding{}.AttributeTypes()}
+	binding := func(role string, members ...string) attr.Value {
+		var elements []attr.Value
+		for _, member := range members {
+			elements = append(elements, types.StringValue(member))
+		}
+		return types.ObjectValueMust(PolicyBinding{}.AttributeTypes(), map[string]attr.Value{
+			"role":    types.StringValue(role),
+			"members": types.SetValueMust(types.StringType, elements),
+		})
+	}
+
+	for _, tt := range []struct {
+		name            string
+		bindings, apply []attr.Value
+		roles, reorder  []string
+	}{
+		{
+			name:     "members",
+			bindings: []attr.Value{binding("roles/owner", "group:owners@example.com", "user:a@example.com")},
+			apply:    []attr.Value{binding("roles/owner", "user:a@example.com", "group:owners@example.com")},
+		},
+		{
+			name:     "bindings",
+			bindings: []attr.Value{binding("roles/owner", "group:owners@example.com"), binding("roles/viewer", "user:a@example.com")},
+			apply:    []attr.Value{binding("roles/viewer", "user:a@example.com"), binding("roles/owner", "group:owners@example.com")},
+		},
+		{
+			name:     "tenant project roles",
+			bindings: []attr.Value{binding("roles/owner", "group:owners@example.com")},
+			roles:    []string{"roles/editor", "roles/storage.admin"},
+			reorder:  []string{"roles/storage.admin", "roles/editor"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant := newFakeTenantServer()
+			tenant.tenancyUnits[tenancyUnit] = &serviceconsumermanagement.TenancyUnit{Name: tenancyUnit}
+			server, schemas := newFakeTenancyProviderServer(t, tenant)
+			typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+			newConfig := func(bindings []attr.Value, roles []string) *tfprotov6.DynamicValue {
+				model := testProjectConfigModel()
+				if bindings != nil {
+					model.TenantProjectPolicy = types.ObjectValueMust(ServiceProjectConfigTenantProjectPolicyModel{}.AttributeTypes(), map[string]attr.Value{
+						"policy_bindings": types.SetValueMust(policyBindingType, bindings),
+					})
+				}
+				if roles != nil {
+					var elements []attr.Value
+					for _, role := range roles {
+						elements = append(elements, types.StringValue(role))
+					}
+					model.ServiceAccountConfig = types.ObjectValueMust(ServiceProjectConfigServiceAccountConfigModel{}.AttributeTypes(), map[string]attr.Value{
+						"account_id":           types.StringValue("tenant"),
+						"tenant_project_roles": types.SetValueMust(types.StringType, elements),
+					})
+				}
+				return testDynamicValue(t, typ, map[string]tftypes.Value{
+					"tenancy_unit":   tftypes.NewValue(tftypes.String, tenancyUnit),
+					"tag":            tftypes.NewValue(tftypes.String, "prod"),
+					"project_config": testProjectConfigValue(t, model),
+				})
+			}
+			state := testApplyResource(t, server, "utils_service_project", typ, nil, newConfig(tt.bindings, tt.roles))
+
+			reordered := tt.apply
+			if reordered == nil {
+				reordered = tt.bindings
+			}
+			planned := testPlanResource(t, server, "utils_service_project", typ, state, nil, newConfig(reordered, tt.reorder)).PlannedState
+			if got, want := testStateAttributes(t, typ, planned)["project_config"], testStateAttributes(t, typ, state)["project_config"]; !got.Equal(want) {
+				t.Errorf("got planned project_config %v, want it unchanged %v", got, want)
+			}
+		})
+	}
+}
+
+func TestResourceServiceProjectUpgradeState(t *testing.T) {
+	server, schemas := newFakeProviderServer(t, &UtilsProviderConfig{})
+	typ := schemas.ResourceSchemas["utils_service_project"].ValueType()
+
+	for _, version := range []int64{0, 1} {
+		t.Run(fmt.Sprintf("version %d", version), func(t *testing.T) {
+			resp, err := server.UpgradeResourceState(context.Background(), &tfprotov6.UpgradeResourceStateRequest{
+				TypeName: "utils_service_project",
+				Version:  version,
+				RawState: &tfprotov6.RawState{JSON: []byte(`{"id":"projects/1001","tenancy_unit":"services/svc/projects/123/tenancyUnits/unit1","tag":"prod","project_config":{"folder":"folders/123","tenant_project_policy":{"policy_bindings":[{"role":"roles/owner","members":["group:owners@example.com","group:owners@example.com"]},{"role":"roles/owner","members":["group:owners@example.com"]}]},"labels":null,"services":["compute.googleapis.com","storage.googleapis.com","compute.googleapis.com"],"billing_config":{"billing_account":"billingAccounts/012345-567890-ABCDEF"},"service_account_config":{"account_id":"tenant","tenant_project_roles":["roles/editor","roles/editor"]}},"status":"ACTIVE"}`)},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			requireNoErrors(t, resp.Diagnostics)
+
+			var projectConfig map[string]tftypes.Value
+			if err := testStateAttributes(t, typ, resp.UpgradedState)["project_config"].As(&projectConfig); err != nil {
+				t.Fatal(err)
+			}
+			var services []tftypes.Value
+			if err := projectConfig["services"].As(&services); err != nil {
+				t.Fatal(err)
+			}
+			if len(services) != 2 {
+				t.Errorf("got services %v, want 2 unique services", services)
+			}
+			var policy, serviceAccountConfig map[string]tftypes.Value
+			var bindings, roles []tftypes.Value
+			if err := projectConfig["tenant_project_policy"].As(&policy); err != nil {
+				t.Fatal(err)
+			}
+			if err := policy["policy_bindings"].As(&bindings); err != nil {
+				t.Fatal(err)
+			}
+			if len(bindings) != 1 {
+				t.Errorf("got policy bindings %v, want 1 unique binding", bindings)
+			}
+			if err := projectConfig["service_account_config"].As(&serviceAccountConfig); err != nil {
+				t.Fatal(err)
+			}
+			if err := serviceAccountConfig["tenant_project_roles"].As(&roles); err != nil {
+				t.Fatal(err)
+			}
+			if len(roles) != 1 {
+				t.Errorf("got tenant project roles %v, want 1 unique role", roles)
+			}
+		})
+	}
+}
@@ -2,25 +2,42 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 
+	servicemanagement "cloud.google.com/go/servicemanagement/apiv1"
 	"cloud.google.com/go/servicemanagement/apiv1/servicemanagementpb"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/float64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/resourcevalidator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
-	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &ServiceRolloutResource{}
 var _ resource.ResourceWithImportState = &ServiceRolloutResource{}
+var _ resource.ResourceWithModifyPlan = &ServiceRolloutResource{}
+var _ resource.ResourceWithValidateConfig = &ServiceRolloutResource{}
+var _ resource.ResourceWithConfigValidators = &ServiceRolloutResource{}
 
 func NewServiceRolloutResource() resource.Resource {
 	return &ServiceRolloutResource{}
@@ -32,9 +49,77 @@ type ServiceRolloutResource struct {
 }
 
 type ServiceRolloutResourceModel struct {
-	Id            types.String `tfsdk:"id"`
-	ConfigId      types.String `tfsdk:"config_id"`
-	RolloutConfig types.Map    `tfsdk:"rollout_config"`
+	Id                   types.String   `tfsdk:"id"`
+	ConfigId             types.String   `tfsdk:"config_id"`
+	RolloutConfig        types.Map      `tfsdk:"rollout_config"`
+	TrafficPercent       types.Float64  `tfsdk:"traffic_percent"`
+	Strategy             types.String   `tfsdk:"strategy"`
+	ServiceName          types.String   `tfsdk:"service_name"`
+	Steps                types.List     `tfsdk:"steps"`
+	StepRolloutIds       types.List     `tfsdk:"step_rollout_ids"`
+	WaitForCompletion    types.Bool     `tfsdk:"wait_for_completion"`
+	WaitTimeout          types.String   `tfsdk:"wait_timeout"`
+	ConflictRetryTimeout types.String   `tfsdk:"conflict_retry_timeout"`
+	PollInterval         types.String   `tfsdk:"poll_interval"`
+	Timeouts             timeouts.Value `tfsdk:"timeouts"`
+	AlwaysCreate         types.Bool     `tfsdk:"always_create"`
+	PreviousRolloutId    types.String   `tfsdk:"previous_rollout_id"`
+	RollbackOnFailure    types.Bool     `tfsdk:"rollback_on_failure"`
+	Status               types.String   `tfsdk:"status"`
+	CreateTime           types.String   `tfsdk:"create_time"`
+	CreatedBy            types.String   `tfsdk:"created_by"`
+}
+
+// ServiceRolloutStepModel describes a step of a progressive rollout.
+type ServiceRolloutStepModel struct {
+	Percentages types.Map    `tfsdk:"percentages"`
+	Wait        types.String `tfsdk:"wait"`
+}
+
+func (ServiceRolloutStepModel) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"percentages": types.MapType{ElemType: PercentageType{}},
+		"wait":        types.StringType,
+	}
+}
+
+// Rollout strategies supported by the `strategy` attribute.
+const (
+	rolloutStrategyTrafficPercent = "traffic_percent"
+	rolloutStrategyDeleteService  = "delete_service"
+)
+
+// rolloutStepsPendingKey is the private state key which is set when a rollout
+// of `steps` failed partway. The state then only holds the steps which were
+// rolled out, and the next apply resumes with the remaining steps.
+const rolloutStepsPendingKey = "steps_pending"
+
+// defaultRolloutWaitTimeout is the default of the `wait_timeout` attribute.
+const defaultRolloutWaitTimeout = "30m"
+
+// defaultConflictRetryTimeout is the default of the `conflict_retry_timeout`
+// attribute.
+const defaultConflictRetryTimeout = "10m"
+
+// defaultRolloutPollInterval is the default of the `poll_interval` attribute.
+const defaultRolloutPollInterval = "5s"
+
+// defaultRolloutTimeout is the default create and update timeout, which
+// bounds the whole apply including conflict retries, waiting for the
+// operation, and the waits of `steps`. Each wait for a rollout's status is
+// further bounded by `wait_timeout`.
+const defaultRolloutTimeout = time.Hour
+
+// rolloutPollAfter waits before each poll of a rollout's status. It is
+// replaced in tests.
+var rolloutPollAfter = time.After
+
+// failedRolloutStatuses are the terminal statuses of rollouts which did not
+// succeed.
+var failedRolloutStatuses = []servicemanagementpb.Rollout_RolloutStatus{
+	servicemanagementpb.Rollout_FAILED,
+	servicemanagementpb.Rollout_CANCELLED,
+	servicemanagementpb.Rollout_FAILED_ROLLED_BACK,
 }
 
 func (r *ServiceRolloutResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -49,22 +134,156 @@ func (r *ServiceRolloutResource) Schema(ctx context.Context, req resource.Schema
 			"id": schema.StringAttribute{
 				MarkdownDescription: "The ID of the rollout.",
 				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
 			},
 			"config_id": schema.StringAttribute{
-				MarkdownDescription: "The ID of the config. Only one of `config_id` or `rollout_config` can be specified.",
+				MarkdownDescription: "The ID of the config. Only one of `config_id`, `rollout_config` or `steps` can be specified, and one is required unless `strategy` is `delete_service`.",
 				Optional:            true,
-				Validators: []validator.String{
-					stringvalidator.ExactlyOneOf(path.MatchRoot("config_id"), path.MatchRoot("rollout_config")),
+			},
+			"traffic_percent": schema.Float64Attribute{
+				MarkdownDescription: "The percentage of traffic to send to `config_id`, greater than 0 and at most 100. The rest is sent to the config serving the most traffic in the latest successful rollout of the service, which must exist. Defaults to sending all traffic to `config_id`.",
+				Optional:            true,
+				Validators: []validator.Float64{
+					float64validator.AlsoRequires(path.MatchRoot("config_id")),
+					percentageValidator{},
 				},
 			},
 			"rollout_config": schema.MapAttribute{
-				MarkdownDescription: "The rollout configuration by config ID. Only one of `config_id` or `rollout_config` can be specified.",
+				MarkdownDescription: "The rollout configuration by config ID, either `{serviceName}/{configId}` or, when `service_name` is set, the ID of a config of the service. Each percentage must be greater than 0 and at most 100. The API normalizes percentages, so differences of less than 0.01 are ignored. Only one of `config_id`, `rollout_config` or `steps` can be specified, and one is required unless `strategy` is `delete_service`.",
 				Optional:            true,
-				ElementType:         types.Float64Type,
+				ElementType:         PercentageType{},
 				Validators: []validator.Map{
-					mapvalidator.ExactlyOneOf(path.MatchRoot("config_id"), path.MatchRoot("rollout_config")),
+					mapvalidator.ValueFloat64sAre(percentageValidator{}),
+				},
+			},
+			"steps": schema.ListNestedAttribute{
+				MarkdownDescription: "The steps of a progressive rollout. A rollout is created for each step in order, waiting between steps, and the resource ID is that of the final rollout. If a step fails, the next apply resumes with that step. Only one of `config_id`, `rollout_config` or `steps` can be specified.",
+				Optional:            true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"percentages": schema.MapAttribute{
+							MarkdownDescription: "The traffic percentages of the step by config ID.",
+							Required:            true,
+							ElementType:         PercentageType{},
+							Validators: []validator.Map{
+								mapvalidator.ValueFloat64sAre(percentageValidator{}),
+							},
+						},
+						"wait": schema.StringAttribute{
+							MarkdownDescription: "How long to wait after the step is rolled out before starting the next step, for example `10m`. Ignored for the final step.",
+							Optional:            true,
+							Validators: []validator.String{
+								durationValidator{},
+							},
+						},
+					},
+				},
+			},
+			"wait_for_completion": schema.BoolAttribute{
+				MarkdownDescription: "Whether to wait for the rollout to complete. When `false`, the rollout is created with a `status` of `IN_PROGRESS` and its status is refreshed on later plans. Resources which depend on the new config being live, for example DNS records or clients, should not use rollouts which do not wait for completion. Cannot be `false` with `steps`. Defaults to `true`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+			},
+			"always_create": schema.BoolAttribute{
+				MarkdownDescription: "Whether to create a rollout even if the latest successful rollout of the service already has the desired traffic split. By default, that rollout is reused instead. Defaults to `false`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"previous_rollout_id": schema.StringAttribute{
+				MarkdownDescription: "The ID of the rollout which this rollout replaced, or null if the resource has not been updated.",
+				Computed:            true,
+			},
+			"rollback_on_failure": schema.BoolAttribute{
+				MarkdownDescription: "Whether to restore the traffic split of `previous_rollout_id` if an update's rollout fails. The apply still fails, with an error stating whether the rollback succeeded. Ignored when creating the resource and with `steps` or `wait_for_completion = false`. Defaults to `false`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"wait_timeout": schema.StringAttribute{
+				MarkdownDescription: "How long to wait for the rollout to report `SUCCESS` once its operation has completed, for example `1h`. It applies separately to each rollout of `steps`. The create and update `timeouts` bound the whole apply, so whichever ends first stops the wait. Defaults to `" + defaultRolloutWaitTimeout + "`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(defaultRolloutWaitTimeout),
+				Validators: []validator.String{
+					durationValidator{},
+				},
+			},
+			"conflict_retry_timeout": schema.StringAttribute{
+				MarkdownDescription: "How long to keep retrying the creation of the rollout while another rollout of the service is in progress, for example `30m`. Defaults to `" + defaultConflictRetryTimeout + "`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(defaultConflictRetryTimeout),
+				Validators: []validator.String{
+					durationValidator{},
+				},
+			},
+			"poll_interval": schema.StringAttribute{
+				MarkdownDescription: "How often to check the status of the rollout once its operation has completed, for example `30s`. Must be greater than zero. Defaults to `" + defaultRolloutPollInterval + "`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(defaultRolloutPollInterval),
+				Validators: []validator.String{
+					durationValidator{},
 				},
 			},
+			"step_rollout_ids": schema.ListAttribute{
+				MarkdownDescription: "The IDs of the rollouts created for each of `steps`, in order.",
+				Computed:            true,
+				ElementType:         types.StringType,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"strategy": schema.StringAttribute{
+				MarkdownDescription: "The rollout strategy, either `traffic_percent` to split traffic between configs or `delete_service` to roll out the deletion of the service before it is deleted. Defaults to `traffic_percent`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(rolloutStrategyTrafficPercent),
+				Validators: []validator.String{
+					stringvalidator.OneOf(rolloutStrategyTrafficPercent, rolloutStrategyDeleteService),
+				},
+			},
+			"service_name": schema.StringAttribute{
+				MarkdownDescription: "The name of the service of the rollout. When set, the keys of `rollout_config` and of the `percentages` of `steps` can be config IDs within the service instead of `{serviceName}/{configId}`. Required when `strategy` is `delete_service`. Defaults to the service of the configured config IDs. Changing the service replaces the resource, and the previous service keeps serving its latest rollout.",
+				Optional:            true,
+				Computed:            true,
+			},
+			"status": schema.StringAttribute{
+				MarkdownDescription: "The status of the rollout, for example `SUCCESS`, `FAILED` or `CANCELLED`.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"create_time": schema.StringAttribute{
+				MarkdownDescription: "The time the rollout was created, in RFC 3339 format.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"created_by": schema.StringAttribute{
+				MarkdownDescription: "The user who created the rollout.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create:            true,
+				Update:            true,
+				CreateDescription: "How long to wait for the rollout to be created and succeed, including all `steps`. Waiting for a rollout to succeed is also bounded by `wait_timeout`, and whichever ends first stops the wait. Defaults to `1h`.",
+				UpdateDescription: "How long to wait for a new rollout to be created and succeed, including all `steps`. Waiting for a rollout to succeed is also bounded by `wait_timeout`, and whichever ends first stops the wait. Defaults to `1h`.",
+			}),
 		},
 	}
 }
@@ -87,19 +306,202 @@ func (r *ServiceRolloutResource) Configure(ctx context.Context, req resource.Con
 	r.OperationsClient = config.OperationsClient
 }
 
+// ValidateConfig implements resource.ResourceWithValidateConfig.
+func (r *ServiceRolloutResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data ServiceRolloutResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() || data.Strategy.IsUnknown() {
+		return
+	}
+
+	// The Conflicting config validator ensures at most one of `config_id`,
+	// `rollout_config` and `steps` is set, so only check which attributes
+	// are required for the strategy.
+	if !data.PollInterval.IsNull() && !data.PollInterval.IsUnknown() {
+		if d, err := time.ParseDuration(data.PollInterval.ValueString()); err == nil && d <= 0 {
+			resp.Diagnostics.AddAttributeError(path.Root("poll_interval"), "Invalid duration", "`poll_interval` must be greater than zero.")
+		}
+	}
+
+	traffic := !data.ConfigId.IsNull() || !data.RolloutConfig.IsNull() || !data.Steps.IsNull()
+	switch data.Strategy.ValueString() {
+	case rolloutStrategyDeleteService:
+		if data.ServiceName.IsNull() || traffic {
+			resp.Diagnostics.AddAttributeError(path.Root("service_name"), "Invalid rollout configuration", "`service_name` is required, and `config_id`, `rollout_config` and `steps` cannot be specified, when `strategy` is `delete_service`.")
+		}
+	default:
+		if !traffic {
+			resp.Diagnostics.AddAttributeError(path.Root("config_id"), "Invalid rollout configuration", "One of `config_id`, `rollout_config` or `steps` is required unless `strategy` is `delete_service`.")
+		}
+		if !data.Steps.IsNull() && !data.WaitForCompletion.IsNull() && !data.WaitForCompletion.ValueBool() {
+			resp.Diagnostics.AddAttributeError(path.Root("wait_for_completion"), "Invalid rollout configuration", "`wait_for_completion` cannot be `false` with `steps`, since each step must complete before the next.")
+		}
+		resp.Diagnostics.Append(data.validateTrafficSplits()...)
+	}
+}
+
+// validateTrafficSplits checks that the traffic splits of data are not empty,
+// that their known config IDs are configs of `service_name`, and that config
+// IDs within the service are only used when it is set. Unknown values, for
+// example config IDs of configs created in the same apply, are skipped.
+func (data ServiceRolloutResourceModel) validateTrafficSplits() diag.Diagnostics {
+	var diags diag.Diagnostics
+	serviceName := data.ServiceName.ValueString()
+
+	if serviceName != "" && !data.ConfigId.IsNull() && !data.ConfigId.IsUnknown() {
+		if svc, _, err := parseConfigId(data.ConfigId.ValueString()); err == nil && svc != serviceName {
+			diags.AddAttributeError(path.Root("config_id"), "Invalid config ID", fmt.Sprintf("Config ID %q is a config of service %s, not `service_name` %s.", data.ConfigId.ValueString(), svc, serviceName))
+		}
+	}
+
+	validateKeys := func(p path.Path, percentages types.Map) {
+		if percentages.IsNull() || percentages.IsUnknown() {
+			return
+		}
+		if len(percentages.Elements()) == 0 {
+			diags.AddAttributeError(p, "Invalid rollout configuration", "The traffic split must contain at least one config.")
+			return
+		}
+		var firstKey, firstServiceName string
+		for _, key := range slices.Sorted(maps.Keys(percentages.Elements())) {
+			if data.ServiceName.IsUnknown() && !strings.Contains(key, "/") {
+				// The service of the config is not known yet.
+				continue
+			}
+			svcName, _, err := resolveConfigId(serviceName, key)
+			switch {
+			case err != nil:
+				diags.AddAttributeError(p.AtMapKey(key), "Invalid config ID", err.Error())
+			case firstKey == "":
+				firstKey, firstServiceName = key, svcName
+			case svcName != firstServiceName:
+				diags.AddAttributeError(p.AtMapKey(key), "Invalid config ID", mixedServicesError(firstKey, firstServiceName, key, svcName).Error())
+			}
+		}
+	}
+	validateKeys(path.Root("rollout_config"), data.RolloutConfig)
+	if !data.Steps.IsNull() && !data.Steps.IsUnknown() {
+		for i, step := range data.Steps.Elements() {
+			if step, ok := step.(types.Object); ok && !step.IsNull() && !step.IsUnknown() {
+				percentages, _ := step.Attributes()["percentages"].(types.Map)
+				validateKeys(path.Root("steps").AtListIndex(i).AtName("percentages"), percentages)
+			}
+		}
+	}
+	return diags
+}
+
+// ConfigValidators implements resource.ResourceWithConfigValidators.
+func (r *ServiceRolloutResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
+	// resourcevalidator.ExactlyOneOf cannot be used, since none of these
+	// attributes are allowed when `strategy` is `delete_service`. ValidateConfig
+	// requires one of them for other strategies instead.
+	return []resource.ConfigValidator{
+		resourcevalidator.Conflicting(path.MatchRoot("config_id"), path.MatchRoot("rollout_config"), path.MatchRoot("steps")),
+	}
+}
+
+// ModifyPlan implements resource.ResourceWithModifyPlan.
+func (r *ServiceRolloutResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to do on destroy.
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan ServiceRolloutResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if plan.ServiceName.IsUnknown() {
+		if serviceName, ok := plan.targetServiceName(); ok {
+			plan.ServiceName = types.StringValue(serviceName)
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("service_name"), plan.ServiceName)...)
+		}
+	}
+
+	if req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("previous_rollout_id"), types.StringNull())...)
+		return
+	}
+
+	var state ServiceRolloutResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// A new rollout is created whenever the traffic split changes, so its
+	// attributes are not known until apply. Otherwise, keep the attributes
+	// of the existing rollout, including null ones.
+	if !plan.rolloutChanged(ctx, state) {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("id"), state.Id)...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("status"), state.Status)...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("create_time"), state.CreateTime)...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("created_by"), state.CreatedBy)...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("step_rollout_ids"), state.StepRolloutIds)...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("previous_rollout_id"), state.PreviousRolloutId)...)
+		return
+	}
+	previousRolloutId := state.Id
+	plannedService, plannedOk := plan.targetServiceName()
+	currentService, currentOk := state.targetServiceName()
+	if plannedOk && currentOk && plannedService != currentService {
+		// Rollouts cannot be moved between services, and the previous service
+		// keeps serving its last rollout, so a new resource is created.
+		previousRolloutId = types.StringNull()
+		resp.RequiresReplace = append(resp.RequiresReplace, path.Root("service_name"))
+		resp.Diagnostics.AddAttributeWarning(
+			path.Root("service_name"),
+			"Service rollout will be replaced",
+			fmt.Sprintf("The rollout targets service %s instead of %s, so the resource will be replaced. Rollouts are never deleted, so %s keeps serving the traffic split of rollout %s.", plannedService, currentService, currentService, state.Id.ValueString()),
+		)
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("previous_rollout_id"), previousRolloutId)...)
+	for _, attr := range []string{"id", "status", "create_time", "created_by"} {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root(attr), types.StringUnknown())...)
+	}
+	stepRolloutIds := types.ListNull(types.StringType)
+	if !plan.Steps.IsNull() {
+		stepRolloutIds = types.ListUnknown(types.StringType)
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("step_rollout_ids"), stepRolloutIds)...)
+}
+
 // Create implements resource.Resource.
 func (r *ServiceRolloutResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
 	var data ServiceRolloutResourceModel
-	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	rolloutId := r.createRollout(ctx, data, resp.Diagnostics)
+	createTimeout, diags := data.Timeouts.Create(ctx, defaultRolloutTimeout)
+	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
-	data.Id = *rolloutId
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	if !data.Steps.IsNull() {
+		if r.createRolloutSteps(ctx, &data, nil, &resp.Diagnostics) {
+			resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+			if resp.Diagnostics.HasError() {
+				resp.Diagnostics.Append(resp.Private.SetKey(ctx, rolloutStepsPendingKey, []byte("true"))...)
+			}
+		}
+		return
+	}
+
+	rollout := r.createRollout(ctx, data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() || rollout == nil {
+		return
+	}
+	data.Id = newRolloutId(rollout.GetServiceName(), rollout.GetRolloutId())
+	data.ServiceName = types.StringValue(rollout.GetServiceName())
+	data.StepRolloutIds = types.ListNull(types.StringType)
+	data.setRollout(rollout)
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
@@ -127,72 +529,392 @@ func (r *ServiceRolloutResource) Read(ctx context.Context, req resource.ReadRequ
 		return
 	}
 
-	rollout, err := r.ServiceManagerClient.GetServiceRollout(ctx, &servicemanagementpb.GetServiceRolloutRequest{
-		ServiceName: serviceName,
-		RolloutId:   rolloutId,
+	rollout, err := retryTransient(ctx, func(ctx context.Context) (*servicemanagementpb.Rollout, error) {
+		return r.ServiceManagerClient.GetServiceRollout(ctx, &servicemanagementpb.GetServiceRolloutRequest{
+			ServiceName: serviceName,
+			RolloutId:   rolloutId,
+		})
 	})
 
 	if err != nil {
-		if status, ok := status.FromError(err); ok && status.Code() == codes.NotFound {
+		if isNotFound(err) {
+			tflog.Info(ctx, "Service rollout not found, removing from state", map[string]interface{}{
+				"service_name": serviceName,
+				"rollout_id":   rolloutId,
+			})
+			resp.State.RemoveResource(ctx)
 			return
 		}
 		resp.Diagnostics.AddError("Error reading service rollout", err.Error())
 		return
 	}
 
-	rawRolloutConfig := rollout.GetTrafficPercentStrategy().GetPercentages()
-	rolloutConfig, diags := types.MapValueFrom(ctx, types.Float64Type, rawRolloutConfig)
-	resp.Diagnostics.Append(diags...)
+	data.setRollout(rollout)
+	if slices.Contains(failedRolloutStatuses, rollout.GetStatus()) {
+		resp.Diagnostics.AddWarning(
+			"Service rollout did not succeed",
+			fmt.Sprintf("Rollout %s of service %s has status %s, so traffic may not be split as configured.", rolloutId, serviceName, rollout.GetStatus()),
+		)
+	}
 
+	resp.Diagnostics.Append(data.setTrafficSplit(ctx, serviceName, rollout)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	if data.ConfigId.IsNull() && data.RolloutConfig.IsNull() {
-		if len(rawRolloutConfig) == 1 {
-			var configId string
-			for key := range rawRolloutConfig {
-				configId = key
-			}
-			// Populate the config ID.
-			data.ConfigId = newConfigId(serviceName, configId)
-		} else {
-			// Populate the rollout config.
-			data.RolloutConfig = rolloutConfig
-		}
-	} else if data.ConfigId.IsNull() {
-		data.RolloutConfig = rolloutConfig
-	}
-
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 // Update implements resource.Resource.
 func (r *ServiceRolloutResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	var data ServiceRolloutResourceModel
+	var data, state ServiceRolloutResourceModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	completed, diags := state.completedSteps(ctx, data, req.Private)
+	resp.Diagnostics.Append(diags...)
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, rolloutStepsPendingKey, nil)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	rolloutId := r.createRollout(ctx, data, resp.Diagnostics)
+	// Changing only how the rollout is applied, for example
+	// wait_for_completion, does not create a new rollout.
+	if !data.rolloutChanged(ctx, state) {
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+	data.PreviousRolloutId = state.Id
+	if len(completed) > 0 {
+		// Resume the steps after the last rollout of the failed apply, which
+		// is still described by state.
+		data.PreviousRolloutId = state.PreviousRolloutId
+		data.Id = state.Id
+		data.Status = state.Status
+		data.CreateTime = state.CreateTime
+		data.CreatedBy = state.CreatedBy
+	}
+
+	updateTimeout, diags := data.Timeouts.Update(ctx, defaultRolloutTimeout)
+	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
-	data.Id = *rolloutId
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	if !data.Steps.IsNull() {
+		if r.createRolloutSteps(ctx, &data, completed, &resp.Diagnostics) {
+			resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+			if resp.Diagnostics.HasError() {
+				resp.Diagnostics.Append(resp.Private.SetKey(ctx, rolloutStepsPendingKey, []byte("true"))...)
+			}
+		}
+		return
+	}
+
+	rollout := r.createRollout(ctx, data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() || rollout == nil {
+		return
+	}
+	data.Id = newRolloutId(rollout.GetServiceName(), rollout.GetRolloutId())
+	data.ServiceName = types.StringValue(rollout.GetServiceName())
+	data.StepRolloutIds = types.ListNull(types.StringType)
+	data.setRollout(rollout)
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
+// completedSteps returns the IDs of the rollouts of the leading steps of plan
+// which were rolled out by an apply that failed partway, as recorded in state
+// and private, or nil if plan does not continue that apply.
+func (state ServiceRolloutResourceModel) completedSteps(ctx context.Context, plan ServiceRolloutResourceModel, private privateStateGetter) ([]string, diag.Diagnostics) {
+	pending, diags := private.GetKey(ctx, rolloutStepsPendingKey)
+	if len(pending) == 0 || diags.HasError() || plan.Steps.IsNull() || plan.Steps.IsUnknown() || state.Steps.IsNull() {
+		return nil, diags
+	}
+	planned, done := plan.Steps.Elements(), state.Steps.Elements()
+	if len(done) >= len(planned) || len(state.StepRolloutIds.Elements()) != len(done) {
+		return nil, diags
+	}
+	for i := range done {
+		if !done[i].Equal(planned[i]) {
+			return nil, diags
+		}
+	}
+
+	var rolloutIds []string
+	diags.Append(state.StepRolloutIds.ElementsAs(ctx, &rolloutIds, false)...)
+	return rolloutIds, diags
+}
+
 func (r *ServiceRolloutResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	serviceName, rolloutId, err := parseRolloutId(req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid ID", err.Error())
+		return
+	}
+
+	// Fetch the rollout now so that config_id or rollout_config is populated
+	// in the imported state.
+	rollout, err := retryTransient(ctx, func(ctx context.Context) (*servicemanagementpb.Rollout, error) {
+		return r.ServiceManagerClient.GetServiceRollout(ctx, &servicemanagementpb.GetServiceRolloutRequest{
+			ServiceName: serviceName,
+			RolloutId:   rolloutId,
+		})
+	})
+	if err != nil {
+		resp.Diagnostics.AddError("Error importing service rollout", err.Error())
+		return
+	}
+
+	data := ServiceRolloutResourceModel{
+		Id:             newRolloutId(serviceName, rolloutId),
+		ConfigId:       types.StringNull(),
+		RolloutConfig:  types.MapNull(PercentageType{}),
+		ServiceName:    types.StringNull(),
+		Steps:          types.ListNull(types.ObjectType{AttrTypes: ServiceRolloutStepModel{}.AttributeTypes()}),
+		StepRolloutIds: types.ListNull(types.StringType),
+		// Defaults are not applied to imported state.
+		WaitForCompletion:    types.BoolValue(true),
+		AlwaysCreate:         types.BoolValue(false),
+		RollbackOnFailure:    types.BoolValue(false),
+		WaitTimeout:          types.StringValue(defaultRolloutWaitTimeout),
+		ConflictRetryTimeout: types.StringValue(defaultConflictRetryTimeout),
+		PollInterval:         types.StringValue(defaultRolloutPollInterval),
+		Timeouts: timeouts.Value{
+			Object: types.ObjectNull(map[string]attr.Type{
+				"create": types.StringType,
+				"update": types.StringType,
+			}),
+		},
+	}
+	data.setRollout(rollout)
+	resp.Diagnostics.Append(data.setTrafficSplit(ctx, serviceName, rollout)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
-func (r *ServiceRolloutResource) createRollout(ctx context.Context, data ServiceRolloutResourceModel, diagnostics diag.Diagnostics) *basetypes.StringValue {
-	var serviceName string
+// setTrafficSplit populates the service, strategy and traffic split of data
+// from rollout. Whichever of `config_id` or `rollout_config` is already set is
+// kept, and left unchanged if it describes the same split, so that the state
+// matches the configuration. Otherwise, `config_id` is used for rollouts of a
+// single config.
+func (data *ServiceRolloutResourceModel) setTrafficSplit(ctx context.Context, serviceName string, rollout *servicemanagementpb.Rollout) diag.Diagnostics {
+	data.ServiceName = types.StringValue(serviceName)
+	if rollout.GetDeleteServiceStrategy() != nil {
+		data.Strategy = types.StringValue(rolloutStrategyDeleteService)
+		data.ConfigId = types.StringNull()
+		data.RolloutConfig = types.MapNull(PercentageType{})
+		return nil
+	}
+	data.Strategy = types.StringValue(rolloutStrategyTrafficPercent)
+	if !data.Steps.IsNull() {
+		// The traffic split is that of the final step.
+		return nil
+	}
+
 	percentages := make(map[string]float64)
+	for configId, percentage := range rollout.GetTrafficPercentStrategy().GetPercentages() {
+		percentages[serviceName+"/"+configId] = percentage
+	}
+	if !data.ConfigId.IsNull() && !data.TrafficPercent.IsNull() {
+		// Only the share of config_id is configured, so refresh that.
+		if svc, configId, err := parseConfigId(data.ConfigId.ValueString()); err == nil {
+			if percent := percentages[svc+"/"+configId]; !percentageEqual(percent, data.TrafficPercent.ValueFloat64()) {
+				data.TrafficPercent = types.Float64Value(percent)
+			}
+			return nil
+		}
+	}
+	if current, ok := data.trafficSplit(ctx); ok && percentagesEqual(current, percentages) {
+		return nil
+	}
+
+	if data.RolloutConfig.IsNull() && len(percentages) == 1 {
+		for configId, percentage := range percentages {
+			if percentage == 100 {
+				data.ConfigId = types.StringValue(configId)
+				data.RolloutConfig = types.MapNull(PercentageType{})
+				return nil
+			}
+		}
+	}
+	if data.hasBareConfigIds() {
+		// Keep the config IDs within the service used by the configuration.
+		percentages = rollout.GetTrafficPercentStrategy().GetPercentages()
+	}
+	rolloutConfig, diags := types.MapValueFrom(ctx, PercentageType{}, percentages)
+	data.ConfigId = types.StringNull()
+	data.RolloutConfig = rolloutConfig
+	return diags
+}
 
+// trafficSplit returns the traffic percentages described by `config_id` or
+// `rollout_config`, keyed by `{serviceName}/{configId}`. It reports false if
+// neither is set, either is unknown, or a config ID is invalid.
+func (data ServiceRolloutResourceModel) trafficSplit(ctx context.Context) (map[string]float64, bool) {
+	if data.ConfigId.IsUnknown() || data.RolloutConfig.IsUnknown() || data.TrafficPercent.IsUnknown() {
+		return nil, false
+	}
+	if !data.TrafficPercent.IsNull() && data.TrafficPercent.ValueFloat64() != 100 {
+		// The rest of the traffic depends on the rollout being replaced.
+		return nil, false
+	}
 	if !data.ConfigId.IsNull() {
+		serviceName, configId, err := parseConfigId(data.ConfigId.ValueString())
+		if err != nil {
+			return nil, false
+		}
+		return map[string]float64{serviceName + "/" + configId: 100}, true
+	}
+	if data.RolloutConfig.IsNull() {
+		return nil, false
+	}
+
+	rawPercentages := make(map[string]float64)
+	if diags := data.RolloutConfig.ElementsAs(ctx, &rawPercentages, false); diags.HasError() {
+		return nil, false
+	}
+	if data.ServiceName.IsUnknown() {
+		return nil, false
+	}
+	percentages := make(map[string]float64, len(rawPercentages))
+	for k, v := range rawPercentages {
+		serviceName, configId, err := resolveConfigId(data.ServiceName.ValueString(), k)
+		if err != nil {
+			return nil, false
+		}
+		percentages[serviceName+"/"+configId] = v
+	}
+	return percentages, true
+}
+
+// hasBareConfigIds reports whether the keys of `rollout_config` are config
+// IDs within `service_name` rather than `{serviceName}/{configId}`.
+func (data ServiceRolloutResourceModel) hasBareConfigIds() bool {
+	if data.RolloutConfig.IsNull() || data.RolloutConfig.IsUnknown() || len(data.RolloutConfig.Elements()) == 0 {
+		return false
+	}
+	for key := range data.RolloutConfig.Elements() {
+		if strings.Contains(key, "/") {
+			return false
+		}
+	}
+	return true
+}
+
+// targetServiceName returns the name of the service targeted by data, either
+// `service_name` or the service of its config IDs, and reports whether it is
+// known. For `steps`, this is the service of the final step.
+func (data ServiceRolloutResourceModel) targetServiceName() (string, bool) {
+	if !data.ServiceName.IsNull() && !data.ServiceName.IsUnknown() {
+		return data.ServiceName.ValueString(), true
+	}
+
+	var keys []string
+	switch {
+	case data.ConfigId.IsUnknown() || data.RolloutConfig.IsUnknown() || data.Steps.IsUnknown():
+		return "", false
+	case !data.ConfigId.IsNull():
+		keys = []string{data.ConfigId.ValueString()}
+	case !data.RolloutConfig.IsNull():
+		keys = slices.Collect(maps.Keys(data.RolloutConfig.Elements()))
+	case !data.Steps.IsNull():
+		steps := data.Steps.Elements()
+		if len(steps) == 0 {
+			return "", false
+		}
+		step, ok := steps[len(steps)-1].(types.Object)
+		if !ok || step.IsNull() || step.IsUnknown() {
+			return "", false
+		}
+		percentages, ok := step.Attributes()["percentages"].(types.Map)
+		if !ok || percentages.IsNull() || percentages.IsUnknown() {
+			return "", false
+		}
+		keys = slices.Collect(maps.Keys(percentages.Elements()))
+	}
+	for _, key := range keys {
+		if serviceName, _, err := parseConfigId(key); err == nil {
+			return serviceName, true
+		}
+	}
+	return "", false
+}
+
+// waitTimeout returns the parsed `wait_timeout`, or its default if unset.
+func (data ServiceRolloutResourceModel) waitTimeout() time.Duration {
+	return durationOrDefault(data.WaitTimeout, defaultRolloutWaitTimeout)
+}
+
+// conflictRetryTimeout returns the parsed `conflict_retry_timeout`, or its
+// default if unset.
+func (data ServiceRolloutResourceModel) conflictRetryTimeout() time.Duration {
+	return durationOrDefault(data.ConflictRetryTimeout, defaultConflictRetryTimeout)
+}
+
+// pollInterval returns the parsed `poll_interval`, or its default if unset.
+func (data ServiceRolloutResourceModel) pollInterval() time.Duration {
+	return durationOrDefault(data.PollInterval, defaultRolloutPollInterval)
+}
+
+// durationOrDefault parses the duration in value, or def if value is null or
+// unknown. Values are checked by durationValidator.
+func durationOrDefault(value types.String, def string) time.Duration {
+	if !value.IsNull() && !value.IsUnknown() {
+		def = value.ValueString()
+	}
+	d, _ := time.ParseDuration(def)
+	return d
+}
+
+// rolloutChanged reports whether data describes a different rollout than
+// state, so that a new rollout must be created. Switching between
+// `config_id` and `rollout_config` for the same traffic split, for example
+// after an import, does not create a rollout.
+func (data ServiceRolloutResourceModel) rolloutChanged(ctx context.Context, state ServiceRolloutResourceModel) bool {
+	if !data.Steps.Equal(state.Steps) || !data.Strategy.Equal(state.Strategy) || !data.ServiceName.Equal(state.ServiceName) || !data.TrafficPercent.Equal(state.TrafficPercent) {
+		return true
+	}
+	if data.ConfigId.Equal(state.ConfigId) && data.RolloutConfig.Equal(state.RolloutConfig) {
+		return false
+	}
+	planned, ok := data.trafficSplit(ctx)
+	if !ok {
+		return true
+	}
+	current, ok := state.trafficSplit(ctx)
+	return !ok || !percentagesEqual(planned, current)
+}
+
+// setRollout populates the status and audit attributes of data from rollout.
+func (data *ServiceRolloutResourceModel) setRollout(rollout *servicemanagementpb.Rollout) {
+	data.Status = types.StringValue(rollout.GetStatus().String())
+	data.CreateTime = types.StringNull()
+	if rollout.GetCreateTime() != nil {
+		data.CreateTime = types.StringValue(rollout.GetCreateTime().AsTime().Format(time.RFC3339))
+	}
+	data.CreatedBy = types.StringNull()
+	if rollout.GetCreatedBy() != "" {
+		data.CreatedBy = types.StringValue(rollout.GetCreatedBy())
+	}
+}
+
+// createRollout creates the rollout described by data and returns it, or adds
+// an error to diagnostics and returns nil.
+func (r *ServiceRolloutResource) createRollout(ctx context.Context, data ServiceRolloutResourceModel, diagnostics *diag.Diagnostics) *servicemanagementpb.Rollout {
+	var serviceName string
+	percentages := make(map[string]float64)
+
+	if data.Strategy.ValueString() == rolloutStrategyDeleteService {
+		serviceName = data.ServiceName.ValueString()
+	} else if !data.ConfigId.IsNull() {
 		svc, configId, err := parseConfigId(data.ConfigId.ValueString())
 		if err != nil {
 			diagnostics.AddError("Invalid config ID", err.Error())
@@ -200,6 +922,29 @@ func (r *ServiceRolloutResource) createRollout(ctx context.Context, data Service
 		}
 		serviceName = svc
 		percentages[configId] = 100
+
+		if percent := data.TrafficPercent.ValueFloat64(); !data.TrafficPercent.IsNull() && percent < 100 {
+			// Send the rest of the traffic to the config which is currently
+			// serving, other than config_id itself.
+			latest, err := r.getLatestSuccessfulRollout(ctx, serviceName)
+			if err != nil {
+				diagnostics.AddError("Error reading service rollouts", err.Error())
+				return nil
+			}
+			current := maps.Clone(latest.GetTrafficPercentStrategy().GetPercentages())
+			delete(current, configId)
+			currentConfigId := dominantConfigId(current)
+			if currentConfigId == "" {
+				diagnostics.AddAttributeError(
+					path.Root("traffic_percent"),
+					"Cannot determine the current config",
+					fmt.Sprintf("Service %s has no successful rollout of a config other than %s to send the remaining traffic to. Use `rollout_config` instead.", serviceName, configId),
+				)
+				return nil
+			}
+			percentages[configId] = percent
+			percentages[currentConfigId] = 100 - percent
+		}
 	} else {
 		rawPercentages := make(map[string]float64)
 		diags := data.RolloutConfig.ElementsAs(ctx, &rawPercentages, false)
@@ -207,47 +952,350 @@ func (r *ServiceRolloutResource) createRollout(ctx context.Context, data Service
 		if diagnostics.HasError() {
 			return nil
 		}
-		for k, v := range rawPercentages {
-			svcName, configId, err := parseConfigId(k)
-			if err != nil {
-				diagnostics.AddError("Invalid config ID", err.Error())
-				return nil
-			}
-			if serviceName == "" {
-				serviceName = svcName
-			} else if serviceName != svcName {
-				diagnostics.AddError("Invalid config ID", "All config IDs must be for the same service")
-				return nil
-			}
-			percentages[configId] = v
+		svc, configPercentages, err := parseRolloutPercentages(data.ServiceName.ValueString(), rawPercentages)
+		if err != nil {
+			diagnostics.AddError("Invalid config ID", err.Error())
+			return nil
+		}
+		serviceName = svc
+		percentages = configPercentages
+	}
+
+	// Reuse the latest rollout if traffic is already split as desired, for
+	// example after the same config was rolled out manually.
+	if data.Strategy.ValueString() != rolloutStrategyDeleteService && !data.AlwaysCreate.ValueBool() {
+		latest, err := r.getLatestSuccessfulRollout(ctx, serviceName)
+		if err != nil {
+			diagnostics.AddError("Error reading service rollouts", err.Error())
+			return nil
+		}
+		if latest != nil && percentagesEqual(latest.GetTrafficPercentStrategy().GetPercentages(), percentages) {
+			tflog.Info(ctx, "Reusing service rollout with the same traffic split", map[string]interface{}{
+				"service_name": serviceName,
+				"rollout_id":   latest.GetRolloutId(),
+			})
+			return latest
 		}
 	}
 
 	// Create the rollout.
 
-	rolloutOp, err := r.ServiceManagerClient.CreateServiceRollout(ctx, &servicemanagementpb.CreateServiceRolloutRequest{
+	rollout := &servicemanagementpb.Rollout{
+		ServiceName: serviceName,
+		Strategy: &servicemanagementpb.Rollout_TrafficPercentStrategy_{
+			TrafficPercentStrategy: &servicemanagementpb.Rollout_TrafficPercentStrategy{
+				Percentages: percentages,
+			},
+		},
+	}
+	if data.Strategy.ValueString() == rolloutStrategyDeleteService {
+		rollout.Strategy = &servicemanagementpb.Rollout_DeleteServiceStrategy_{
+			DeleteServiceStrategy: &servicemanagementpb.Rollout_DeleteServiceStrategy{},
+		}
+	}
+
+	rollout, err := r.submitRollout(ctx, rollout, data.WaitForCompletion.ValueBool(), data.conflictRetryTimeout())
+	if err == nil && data.WaitForCompletion.ValueBool() {
+		rollout, err = r.waitForRollout(ctx, rollout, data.waitTimeout(), data.pollInterval())
+	}
+	var failed *rolloutFailedError
+	if errors.As(err, &failed) && data.RollbackOnFailure.ValueBool() && !data.PreviousRolloutId.IsNull() {
+		rollback, rollbackErr := r.rollback(ctx, data)
+		if rollbackErr != nil {
+			diagnostics.AddError(
+				"Error creating service rollout",
+				fmt.Sprintf("%v. Rolling back to the traffic split of rollout %s also failed: %v", err, data.PreviousRolloutId.ValueString(), rollbackErr),
+			)
+			return nil
+		}
+		diagnostics.AddError(
+			"Service rollout rolled back",
+			fmt.Sprintf("%v. The traffic split of rollout %s was restored by rollout %s.", err, data.PreviousRolloutId.ValueString(), rollback.GetRolloutId()),
+		)
+		return nil
+	}
+	var timedOut *rolloutTimeoutError
+	if errors.As(err, &timedOut) {
+		diagnostics.AddError(
+			"Timed out waiting for service rollout",
+			fmt.Sprintf("%v. The rollout continues in the background, and its progress can be checked with ID %s. Applying again reuses the rollout once it has succeeded, unless `always_create` is set.", err, newRolloutId(timedOut.rollout.GetServiceName(), timedOut.rollout.GetRolloutId()).ValueString()),
+		)
+		return nil
+	}
+	if err != nil {
+		diagnostics.AddError("Error creating service rollout", err.Error())
+		return nil
+	}
+
+	return rollout
+}
+
+// rollback creates a rollout restoring the traffic split of the previous
+// rollout of data and waits for it. It is not retried if it fails.
+func (r *ServiceRolloutResource) rollback(ctx context.Context, data ServiceRolloutResourceModel) (*servicemanagementpb.Rollout, error) {
+	serviceName, rolloutId, err := parseRolloutId(data.PreviousRolloutId.ValueString())
+	if err != nil {
+		return nil, err
+	}
+	previous, err := retryTransient(ctx, func(ctx context.Context) (*servicemanagementpb.Rollout, error) {
+		return r.ServiceManagerClient.GetServiceRollout(ctx, &servicemanagementpb.GetServiceRolloutRequest{
+			ServiceName: serviceName,
+			RolloutId:   rolloutId,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	percentages := previous.GetTrafficPercentStrategy().GetPercentages()
+	if len(percentages) == 0 {
+		return nil, fmt.Errorf("rollout %s does not split traffic", rolloutId)
+	}
+
+	tflog.Warn(ctx, "Rolling back service rollout", map[string]interface{}{
+		"service_name": serviceName,
+		"rollout_id":   rolloutId,
+	})
+	rollout, err := r.submitRollout(ctx, &servicemanagementpb.Rollout{
 		ServiceName: serviceName,
-		Rollout: &servicemanagementpb.Rollout{
+		Strategy: &servicemanagementpb.Rollout_TrafficPercentStrategy_{
+			TrafficPercentStrategy: &servicemanagementpb.Rollout_TrafficPercentStrategy{
+				Percentages: percentages,
+			},
+		},
+	}, true, data.conflictRetryTimeout())
+	if err != nil {
+		return nil, err
+	}
+	return r.waitForRollout(ctx, rollout, data.waitTimeout(), data.pollInterval())
+}
+
+// createRolloutSteps creates a rollout for each of the steps of data in
+// order, waiting between steps, skipping the steps whose rollout IDs are in
+// completed. It stops at the first step which fails, in which case data
+// describes the last successful step, if any, and its `steps` only the steps
+// which were rolled out. It reports whether any step succeeded.
+func (r *ServiceRolloutResource) createRolloutSteps(ctx context.Context, data *ServiceRolloutResourceModel, completed []string, diagnostics *diag.Diagnostics) bool {
+	var steps []ServiceRolloutStepModel
+	diagnostics.Append(data.Steps.ElementsAs(ctx, &steps, false)...)
+	if diagnostics.HasError() {
+		return false
+	}
+
+	rolloutIds := slices.Clone(completed)
+	defer func() {
+		stepRolloutIds, diags := types.ListValueFrom(ctx, types.StringType, rolloutIds)
+		diagnostics.Append(diags...)
+		data.StepRolloutIds = stepRolloutIds
+		if len(rolloutIds) < len(steps) {
+			// Only record the steps which were rolled out, so that the next
+			// plan shows the remaining ones.
+			data.Steps, diags = types.ListValue(data.Steps.ElementType(ctx), data.Steps.Elements()[:len(rolloutIds)])
+			diagnostics.Append(diags...)
+		}
+	}()
+
+	for i, step := range steps {
+		if i < len(completed) {
+			continue
+		}
+		if i > 0 {
+			wait, _ := time.ParseDuration(steps[i-1].Wait.ValueString())
+			tflog.Info(ctx, "Waiting before next rollout step", map[string]interface{}{
+				"step": i + 1,
+				"wait": wait.String(),
+			})
+			select {
+			case <-ctx.Done():
+				diagnostics.AddError("Error creating service rollout", fmt.Sprintf("Step %d of %d was not started: %v", i+1, len(steps), ctx.Err()))
+				return len(rolloutIds) > 0
+			case <-time.After(wait):
+			}
+		}
+
+		rawPercentages := make(map[string]float64)
+		diagnostics.Append(step.Percentages.ElementsAs(ctx, &rawPercentages, false)...)
+		if diagnostics.HasError() {
+			return len(rolloutIds) > 0
+		}
+		serviceName, percentages, err := parseRolloutPercentages(data.ServiceName.ValueString(), rawPercentages)
+		if err != nil {
+			diagnostics.AddAttributeError(path.Root("steps").AtListIndex(i).AtName("percentages"), "Invalid config ID", err.Error())
+			return len(rolloutIds) > 0
+		}
+
+		rollout, err := r.submitRollout(ctx, &servicemanagementpb.Rollout{
 			ServiceName: serviceName,
 			Strategy: &servicemanagementpb.Rollout_TrafficPercentStrategy_{
 				TrafficPercentStrategy: &servicemanagementpb.Rollout_TrafficPercentStrategy{
 					Percentages: percentages,
 				},
 			},
-		},
-	})
+		}, true, data.conflictRetryTimeout())
+		if err == nil {
+			rollout, err = r.waitForRollout(ctx, rollout, data.waitTimeout(), data.pollInterval())
+		}
+		if err != nil {
+			diagnostics.AddError(
+				"Error creating service rollout",
+				fmt.Sprintf("Step %d of %d failed, so the remaining steps were not rolled out: %v", i+1, len(steps), err),
+			)
+			return len(rolloutIds) > 0
+		}
+
+		rolloutIds = append(rolloutIds, rollout.GetRolloutId())
+		data.Id = newRolloutId(rollout.GetServiceName(), rollout.GetRolloutId())
+		data.ServiceName = types.StringValue(rollout.GetServiceName())
+		data.setRollout(rollout)
+	}
+	return true
+}
 
+// submitRollout creates rollout, retrying for up to conflictTimeout while
+// another rollout of the service is in progress. If wait is true, it waits for
+// the operation to complete. Otherwise, it returns as soon as the ID of the
+// new rollout is known, with a status of IN_PROGRESS if the operation has not
+// completed.
+func (r *ServiceRolloutResource) submitRollout(ctx context.Context, rollout *servicemanagementpb.Rollout, wait bool, conflictTimeout time.Duration) (*servicemanagementpb.Rollout, error) {
+	rolloutOp, err := retryRolloutConflict(ctx, conflictTimeout, func(ctx context.Context) (*servicemanagement.CreateServiceRolloutOperation, error) {
+		return r.ServiceManagerClient.CreateServiceRollout(ctx, &servicemanagementpb.CreateServiceRolloutRequest{
+			ServiceName: rollout.ServiceName,
+			Rollout:     rollout,
+		})
+	})
 	if err != nil {
-		diagnostics.AddError("Error creating service rollout", err.Error())
-		return nil
+		return nil, err
+	}
+	if wait || rolloutOp.Done() {
+		rollout, err := rolloutOp.Wait(ctx)
+		if err != nil && ctx.Err() != nil {
+			if pending, metadataErr := pendingRollout(rolloutOp); metadataErr == nil {
+				return nil, &rolloutTimeoutError{rollout: pending, err: ctx.Err()}
+			}
+		}
+		return rollout, err
 	}
+	return pendingRollout(rolloutOp)
+}
 
-	rollout, err := rolloutOp.Wait(ctx)
+// pendingRollout returns the rollout created by the incomplete operation op,
+// with a status of IN_PROGRESS.
+func pendingRollout(op *servicemanagement.CreateServiceRolloutOperation) (*servicemanagementpb.Rollout, error) {
+	metadata, err := op.Metadata()
 	if err != nil {
-		diagnostics.AddError("Error creating service rollout", err.Error())
-		return nil
+		return nil, err
+	}
+	for _, name := range metadata.GetResourceNames() {
+		serviceName, rolloutId, ok := parseRolloutResourceName(name)
+		if ok {
+			return &servicemanagementpb.Rollout{
+				RolloutId:   rolloutId,
+				ServiceName: serviceName,
+				Status:      servicemanagementpb.Rollout_IN_PROGRESS,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("operation %s does not reference a rollout", op.Name())
+}
+
+// waitForRollout polls rollout every pollInterval until it reports SUCCESS,
+// returning an error if it fails or does not succeed within timeout or before
+// ctx is done. Rollouts can remain in progress, or fail, after their
+// operation completes.
+func (r *ServiceRolloutResource) waitForRollout(ctx context.Context, rollout *servicemanagementpb.Rollout, timeout, pollInterval time.Duration) (*servicemanagementpb.Rollout, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		switch status := rollout.GetStatus(); {
+		case status == servicemanagementpb.Rollout_SUCCESS:
+			return rollout, nil
+		case slices.Contains(failedRolloutStatuses, status):
+			return nil, &rolloutFailedError{rollout: rollout}
+		}
+
+		tflog.Debug(ctx, "Waiting for service rollout", map[string]interface{}{
+			"rollout_id": rollout.GetRolloutId(),
+			"status":     rollout.GetStatus().String(),
+		})
+		var next *servicemanagementpb.Rollout
+		var err error
+		select {
+		case <-ctx.Done():
+		case <-rolloutPollAfter(pollInterval):
+			next, err = retryTransient(ctx, func(ctx context.Context) (*servicemanagementpb.Rollout, error) {
+				return r.ServiceManagerClient.GetServiceRollout(ctx, &servicemanagementpb.GetServiceRolloutRequest{
+					ServiceName: rollout.GetServiceName(),
+					RolloutId:   rollout.GetRolloutId(),
+				})
+			})
+		}
+		if ctx.Err() != nil {
+			return nil, &rolloutTimeoutError{rollout: rollout, err: ctx.Err()}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading rollout %s: %w", rollout.GetRolloutId(), err)
+		}
+		rollout = next
+	}
+}
+
+// rolloutFailedError is returned when a rollout ends in one of
+// failedRolloutStatuses.
+type rolloutFailedError struct {
+	rollout *servicemanagementpb.Rollout
+}
+
+func (e *rolloutFailedError) Error() string {
+	var configIds []string
+	for configId := range e.rollout.GetTrafficPercentStrategy().GetPercentages() {
+		configIds = append(configIds, configId)
+	}
+	slices.Sort(configIds)
+	if len(configIds) == 0 {
+		return fmt.Sprintf("rollout %s has status %s", e.rollout.GetRolloutId(), e.rollout.GetStatus())
+	}
+	return fmt.Sprintf("rollout %s of configs %s has status %s", e.rollout.GetRolloutId(), strings.Join(configIds, ", "), e.rollout.GetStatus())
+}
+
+// rolloutTimeoutError is returned when a rollout does not succeed before the
+// timeout. The rollout has its last observed status.
+type rolloutTimeoutError struct {
+	rollout *servicemanagementpb.Rollout
+	err     error
+}
+
+func (e *rolloutTimeoutError) Error() string {
+	return fmt.Sprintf("rollout %s did not succeed, last status %s: %v", e.rollout.GetRolloutId(), e.rollout.GetStatus(), e.err)
+}
+
+func (e *rolloutTimeoutError) Unwrap() error {
+	return e.err
+}
+
+// parseRolloutPercentages splits the traffic percentages keyed by config ID
+// into the service name and the percentages keyed by the config's ID within
+// the service. Config IDs within the service are resolved against
+// defaultServiceName, if set.
+func parseRolloutPercentages(defaultServiceName string, rawPercentages map[string]float64) (string, map[string]float64, error) {
+	var serviceName, serviceKey string
+	percentages := make(map[string]float64, len(rawPercentages))
+	for _, k := range slices.Sorted(maps.Keys(rawPercentages)) {
+		svcName, configId, err := resolveConfigId(defaultServiceName, k)
+		if err != nil {
+			return "", nil, err
+		}
+		if serviceName == "" {
+			serviceName, serviceKey = svcName, k
+		} else if serviceName != svcName {
+			return "", nil, mixedServicesError(serviceKey, serviceName, k, svcName)
+		}
+		percentages[configId] = rawPercentages[k]
 	}
+	return serviceName, percentages, nil
+}
 
-	rolloutId := newRolloutId(serviceName, rollout.RolloutId)
-	return &rolloutId
+// mixedServicesError returns the error for a traffic split whose config IDs
+// key and otherKey are configs of different services.
+func mixedServicesError(key, serviceName, otherKey, otherServiceName string) error {
+	return fmt.Errorf("All config IDs must be for the same service, but %q is a config of service %s and %q is a config of service %s", key, serviceName, otherKey, otherServiceName)
 }
@@ -0,0 +1,1542 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/servicemanagement/apiv1/servicemanagementpb"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+)
+
+func TestAccResourceServiceRollout(t *testing.T) {
+	project := testAccProducerProject(t)
+	serviceName := fmt.Sprintf("tf-test-%s.endpoints.%s.cloud.goog", acctest.RandString(8), project)
+
+	configs := fmt.Sprintf(`
+		resource "utils_service" "test" {
+			service_name = %[1]q
+			producer_project_id = %[2]q
+		}
+
+		resource "utils_service_config" "test" {
+			for_each = toset(["v1", "v2"])
+
+			service_name = utils_service.test.service_name
+			config_yaml = <<-EOT
+				type: google.api.Service
+				config_version: 3
+				name: %[1]s
+				title: Terraform acceptance test ${each.key}
+			EOT
+			proto_descriptor = ""
+		}`, serviceName, project)
+	importStep := resource.TestStep{
+		ResourceName:      "utils_service_rollout.test",
+		ImportState:       true,
+		ImportStateVerify: true,
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCreateConfig(configs + `
+				resource "utils_service_rollout" "test" {
+					config_id = utils_service_config.test["v1"].id
+				}`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("utils_service_rollout.test", tfjsonpath.New("status"), knownvalue.StringExact("SUCCESS")),
+				},
+			},
+			importStep,
+			{
+				Config: testAccCreateConfig(configs + `
+				resource "utils_service_rollout" "test" {
+					rollout_config = {
+						(utils_service_config.test["v1"].id) = 90
+						(utils_service_config.test["v2"].id) = 10
+					}
+				}`),
+			},
+			importStep,
+		},
+	})
+}
+
+func TestAccResourceServiceRolloutReplace(t *testing.T) {
+	project := testAccProducerProject(t)
+	suffix := acctest.RandString(8)
+	serviceNames := []string{
+		fmt.Sprintf("tf-test-%s-a.endpoints.%s.cloud.goog", suffix, project),
+		fmt.Sprintf("tf-test-%s-b.endpoints.%s.cloud.goog", suffix, project),
+	}
+
+	configs := fmt.Sprintf(`
+		resource "utils_service" "test" {
+			for_each = toset([%[1]q, %[2]q])
+
+			service_name = each.key
+			producer_project_id = %[3]q
+		}
+
+		resource "utils_service_config" "test" {
+			for_each = utils_service.test
+
+			service_name = each.value.service_name
+			config_yaml = <<-EOT
+				type: google.api.Service
+				config_version: 3
+				name: ${each.value.service_name}
+				title: Terraform acceptance test
+			EOT
+			proto_descriptor = ""
+		}`, serviceNames[0], serviceNames[1], project)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCreateConfig(configs + fmt.Sprintf(`
+				resource "utils_service_rollout" "test" {
+					config_id = utils_service_config.test[%q].id
+				}`, serviceNames[0])),
+			},
+			{
+				Config: testAccCreateConfig(configs + fmt.Sprintf(`
+				resource "utils_service_rollout" "test" {
+					config_id = utils_service_config.test[%q].id
+				}`, serviceNames[1])),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PostApplyPreRefresh: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("utils_service_rollout.test", plancheck.ResourceActionReplace),
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestAccResourceServiceRolloutValidateConfig(t *testing.T) {
+	project := testAccProducerProject(t)
+	serviceName := fmt.Sprintf("tf-test-%s.endpoints.%s.cloud.goog", acctest.RandString(8), project)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCreateConfig(`
+				resource "utils_service_rollout" "test" {
+					rollout_config = {}
+				}`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("The traffic split must contain at least one config"),
+			},
+			{
+				// The config IDs are unknown until the configs are created.
+				Config: testAccCreateConfig(fmt.Sprintf(`
+				resource "utils_service" "test" {
+					service_name = %[1]q
+					producer_project_id = %[2]q
+				}
+
+				resource "utils_service_config" "test" {
+					service_name = utils_service.test.service_name
+					config_yaml = <<-EOT
+						type: google.api.Service
+						config_version: 3
+						name: %[1]s
+						title: Terraform acceptance test
+					EOT
+					proto_descriptor = ""
+				}
+
+				resource "utils_service_rollout" "test" {
+					rollout_config = {
+						(utils_service_config.test.id) = 100
+					}
+				}`, serviceName, project)),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+func TestResourceServiceRolloutRead(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	for _, tt := range []struct {
+		name        string
+		status      servicemanagementpb.Rollout_RolloutStatus
+		deleted     bool
+		wantRemoved bool
+		wantWarning bool
+	}{
+		{name: "success", status: servicemanagementpb.Rollout_SUCCESS},
+		{name: "in progress", status: servicemanagementpb.Rollout_IN_PROGRESS},
+		{name: "failed", status: servicemanagementpb.Rollout_FAILED, wantWarning: true},
+		{name: "cancelled", status: servicemanagementpb.Rollout_CANCELLED, wantWarning: true},
+		{name: "failed rolled back", status: servicemanagementpb.Rollout_FAILED_ROLLED_BACK, wantWarning: true},
+		{name: "deleted out of band", deleted: true, wantRemoved: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeServiceManager()
+			fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+			if !tt.deleted {
+				fake.rollouts[serviceName+"/rollout1"] = &servicemanagementpb.Rollout{
+					RolloutId:   "rollout1",
+					ServiceName: serviceName,
+					Status:      tt.status,
+					Strategy: &servicemanagementpb.Rollout_TrafficPercentStrategy_{
+						TrafficPercentStrategy: &servicemanagementpb.Rollout_TrafficPercentStrategy{
+							Percentages: map[string]float64{"config1": 100},
+						},
+					},
+				}
+			}
+			server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+			typ := schemas.ResourceSchemas["utils_service_rollout"].ValueType()
+
+			resp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+				TypeName: "utils_service_rollout",
+				CurrentState: testDynamicValue(t, typ, map[string]tftypes.Value{
+					"id":        tftypes.NewValue(tftypes.String, serviceName+"/rollout1"),
+					"config_id": tftypes.NewValue(tftypes.String, serviceName+"/config1"),
+					"status":    tftypes.NewValue(tftypes.String, "SUCCESS"),
+				}),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			requireNoErrors(t, resp.Diagnostics)
+			if gotWarning := len(resp.Diagnostics) > 0; gotWarning != tt.wantWarning {
+				t.Errorf("got diagnostics %v, want warning %v", resp.Diagnostics, tt.wantWarning)
+			}
+
+			newState, err := resp.NewState.Unmarshal(typ)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if removed := newState.IsNull(); removed != tt.wantRemoved {
+				t.Fatalf("got removed %v, want %v", removed, tt.wantRemoved)
+			}
+			if tt.wantRemoved {
+				return
+			}
+
+			got := testStateAttributes(t, typ, resp.NewState)["status"]
+			if want := tftypes.NewValue(tftypes.String, tt.status.String()); !got.Equal(want) {
+				t.Errorf("got status %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestResourceServiceRolloutCreate(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	fake := newFakeServiceManager()
+	fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+	server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+	typ := schemas.ResourceSchemas["utils_service_rollout"].ValueType()
+
+	state := testApplyResource(t, server, "utils_service_rollout", typ, nil, testDynamicValue(t, typ, map[string]tftypes.Value{
+		"config_id": tftypes.NewValue(tftypes.String, serviceName+"/config1"),
+	}))
+	attrs := testStateAttributes(t, typ, state)
+	if want := tftypes.NewValue(tftypes.String, serviceName+"/rollout1"); !attrs["id"].Equal(want) {
+		t.Errorf("got id %v, want %v", attrs["id"], want)
+	}
+	if want := tftypes.NewValue(tftypes.String, "SUCCESS"); !attrs["status"].Equal(want) {
+		t.Errorf("got status %v, want %v", attrs["status"], want)
+	}
+	if want := tftypes.NewValue(tftypes.String, fakeOperationStartTime.Format(time.RFC3339)); !attrs["create_time"].Equal(want) {
+		t.Errorf("got create_time %v, want %v", attrs["create_time"], want)
+	}
+	if want := tftypes.NewValue(tftypes.String, "user@example.com"); !attrs["created_by"].Equal(want) {
+		t.Errorf("got created_by %v, want %v", attrs["created_by"], want)
+	}
+	if want := tftypes.NewValue(tftypes.String, serviceName); !attrs["service_name"].Equal(want) {
+		t.Errorf("got service_name %v, want %v", attrs["service_name"], want)
+	}
+
+	// Unchanged rollouts keep their computed attributes.
+	config := testDynamicValue(t, typ, map[string]tftypes.Value{
+		"config_id": tftypes.NewValue(tftypes.String, serviceName+"/config1"),
+	})
+	planned := testStateAttributes(t, typ, testPlanResource(t, server, "utils_service_rollout", typ, state, nil, config).PlannedState)
+	for _, attr := range []string{"id", "status", "create_time", "created_by", "service_name"} {
+		if !planned[attr].Equal(attrs[attr]) {
+			t.Errorf("got planned %s %v, want %v", attr, planned[attr], attrs[attr])
+		}
+	}
+
+	// Changing the config creates a new rollout, whose attributes are unknown
+	// until apply.
+	config = testDynamicValue(t, typ, map[string]tftypes.Value{
+		"config_id": tftypes.NewValue(tftypes.String, serviceName+"/config2"),
+	})
+	planned = testStateAttributes(t, typ, testPlanResource(t, server, "utils_service_rollout", typ, state, nil, config).PlannedState)
+	for _, attr := range []string{"id", "status", "create_time", "created_by"} {
+		if planned[attr].IsKnown() {
+			t.Errorf("got planned %s %v, want unknown", attr, planned[attr])
+		}
+	}
+
+	state = testApplyResource(t, server, "utils_service_rollout", typ, state, config)
+	attrs = testStateAttributes(t, typ, state)
+	if want := tftypes.NewValue(tftypes.String, serviceName+"/rollout2"); !attrs["id"].Equal(want) {
+		t.Errorf("got id %v, want %v", attrs["id"], want)
+	}
+}
+
+func TestResourceServiceRolloutDeleteService(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	fake := newFakeServiceManager()
+	fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+	server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+	typ := schemas.ResourceSchemas["utils_service_rollout"].ValueType()
+
+	state := testApplyResource(t, server, "utils_service_rollout", typ, nil, testDynamicValue(t, typ, map[string]tftypes.Value{
+		"strategy":     tftypes.NewValue(tftypes.String, "delete_service"),
+		"service_name": tftypes.NewValue(tftypes.String, serviceName),
+	}))
+	if len(fake.createdRollouts) != 1 || fake.createdRollouts[0].GetRollout().GetDeleteServiceStrategy() == nil {
+		t.Fatalf("got rollouts %v, want one delete service rollout", fake.createdRollouts)
+	}
+
+	// Read reports the strategy back without a traffic split.
+	attrs := testStateAttributes(t, typ, testReadResource(t, server, "utils_service_rollout", testDynamicValue(t, typ, map[string]tftypes.Value{
+		"id": testStateAttributes(t, typ, state)["id"],
+	})))
+	want := map[string]tftypes.Value{
+		"id":             tftypes.NewValue(tftypes.String, serviceName+"/rollout1"),
+		"strategy":       tftypes.NewValue(tftypes.String, "delete_service"),
+		"service_name":   tftypes.NewValue(tftypes.String, serviceName),
+		"config_id":      tftypes.NewValue(tftypes.String, nil),
+		"rollout_config": tftypes.NewValue(tftypes.Map{ElementType: tftypes.Number}, nil),
+	}
+	for attr, want := range want {
+		if !attrs[attr].Equal(want) {
+			t.Errorf("got %s %v, want %v", attr, attrs[attr], want)
+		}
+	}
+
+	for _, tt := range []struct {
+		name   string
+		config map[string]tftypes.Value
+	}{
+		{
+			name: "config_id with delete_service",
+			config: map[string]tftypes.Value{
+				"strategy":     tftypes.NewValue(tftypes.String, "delete_service"),
+				"service_name": tftypes.NewValue(tftypes.String, serviceName),
+				"config_id":    tftypes.NewValue(tftypes.String, serviceName+"/config1"),
+			},
+		},
+		{
+			name: "missing service_name",
+			config: map[string]tftypes.Value{
+				"strategy": tftypes.NewValue(tftypes.String, "delete_service"),
+			},
+		},
+		{
+			name: "service_name of another service",
+			config: map[string]tftypes.Value{
+				"service_name": tftypes.NewValue(tftypes.String, "other.example.com"),
+				"config_id":    tftypes.NewValue(tftypes.String, serviceName+"/config1"),
+			},
+		},
+		{
+			name:   "missing config",
+			config: map[string]tftypes.Value{},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := server.ValidateResourceConfig(ctx, &tfprotov6.ValidateResourceConfigRequest{
+				TypeName: "utils_service_rollout",
+				Config:   testDynamicValue(t, typ, tt.config),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Diagnostics) != 1 || resp.Diagnostics[0].Severity != tfprotov6.DiagnosticSeverityError {
+				t.Errorf("got diagnostics %v, want one error", resp.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestResourceServiceRolloutSteps(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	stepType := tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+		"percentages": tftypes.Map{ElementType: tftypes.Number},
+		"wait":        tftypes.String,
+	}}
+	step := func(percentages map[string]float64, wait string) tftypes.Value {
+		values := make(map[string]tftypes.Value, len(percentages))
+		for configId, percentage := range percentages {
+			values[serviceName+"/"+configId] = tftypes.NewValue(tftypes.Number, percentage)
+		}
+		waitValue := tftypes.NewValue(tftypes.String, nil)
+		if wait != "" {
+			waitValue = tftypes.NewValue(tftypes.String, wait)
+		}
+		return tftypes.NewValue(stepType, map[string]tftypes.Value{
+			"percentages": tftypes.NewValue(tftypes.Map{ElementType: tftypes.Number}, values),
+			"wait":        waitValue,
+		})
+	}
+	config := map[string]tftypes.Value{
+		"steps": tftypes.NewValue(tftypes.List{ElementType: stepType}, []tftypes.Value{
+			step(map[string]float64{"config1": 95, "config2": 5}, "1ms"),
+			step(map[string]float64{"config1": 50, "config2": 50}, "1ms"),
+			step(map[string]float64{"config2": 100}, ""),
+		}),
+	}
+	stepRolloutIds := func(ids ...string) tftypes.Value {
+		values := make([]tftypes.Value, len(ids))
+		for i, id := range ids {
+			values[i] = tftypes.NewValue(tftypes.String, id)
+		}
+		return tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, values)
+	}
+
+	t.Run("success", func(t *testing.T) {
+		fake := newFakeServiceManager()
+		fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+		server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+		typ := schemas.ResourceSchemas["utils_service_rollout"].ValueType()
+
+		state := testApplyResource(t, server, "utils_service_rollout", typ, nil, testDynamicValue(t, typ, config))
+		if len(fake.createdRollouts) != 3 {
+			t.Fatalf("got %d rollouts, want 3", len(fake.createdRollouts))
+		}
+		if got := fake.createdRollouts[1].GetRollout().GetTrafficPercentStrategy().GetPercentages(); got["config2"] != 50 {
+			t.Errorf("got step 2 percentages %v, want 50%% config2", got)
+		}
+
+		attrs := testStateAttributes(t, typ, state)
+		if want := tftypes.NewValue(tftypes.String, serviceName+"/rollout3"); !attrs["id"].Equal(want) {
+			t.Errorf("got id %v, want %v", attrs["id"], want)
+		}
+		if want := stepRolloutIds("rollout1", "rollout2", "rollout3"); !attrs["step_rollout_ids"].Equal(want) {
+			t.Errorf("got step_rollout_ids %v, want %v", attrs["step_rollout_ids"], want)
+		}
+
+		// Refreshing keeps the steps rather than populating config_id.
+		attrs = testStateAttributes(t, typ, testReadResource(t, server, "utils_service_rollout", state))
+		if !attrs["config_id"].IsNull() || !attrs["rollout_config"].IsNull() {
+			t.Errorf("got config_id %v and rollout_config %v, want null", attrs["config_id"], attrs["rollout_config"])
+		}
+	})
+
+	t.Run("failed step", func(t *testing.T) {
+		ctx := context.Background()
+		fake := newFakeServiceManager()
+		fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+		fake.rolloutStatuses = []servicemanagementpb.Rollout_RolloutStatus{
+			servicemanagementpb.Rollout_SUCCESS,
+			servicemanagementpb.Rollout_FAILED,
+		}
+		server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+		typ := schemas.ResourceSchemas["utils_service_rollout"].ValueType()
+
+		configValue := testDynamicValue(t, typ, config)
+		planResp := testPlanResource(t, server, "utils_service_rollout", typ, nil, nil, configValue)
+		applyResp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+			TypeName:     "utils_service_rollout",
+			PriorState:   testNullDynamicValue(t, typ),
+			PlannedState: planResp.PlannedState,
+			Config:       configValue,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(applyResp.Diagnostics) != 1 || applyResp.Diagnostics[0].Severity != tfprotov6.DiagnosticSeverityError {
+			t.Fatalf("got diagnostics %v, want one error", applyResp.Diagnostics)
+		}
+		if len(fake.createdRollouts) != 2 {
+			t.Errorf("got %d rollouts, want 2", len(fake.createdRollouts))
+		}
+
+		// The last successful rollout is kept in state.
+		attrs := testStateAttributes(t, typ, applyResp.NewState)
+		if want := tftypes.NewValue(tftypes.String, serviceName+"/rollout1"); !attrs["id"].Equal(want) {
+			t.Errorf("got id %v, want %v", attrs["id"], want)
+		}
+		if want := stepRolloutIds("rollout1"); !attrs["step_rollout_ids"].Equal(want) {
+			t.Errorf("got step_rollout_ids %v, want %v", attrs["step_rollout_ids"], want)
+		}
+	})
+
+	t.Run("failed step on update", func(t *testing.T) {
+		ctx := context.Background()
+		fake := newFakeServiceManager()
+		fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+		server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+		typ := schemas.ResourceSchemas["utils_service_rollout"].ValueType()
+
+		priorState := testApplyResource(t, server, "utils_service_rollout", typ, nil, testDynamicValue(t, typ, map[string]tftypes.Value{
+			"config_id": tftypes.NewValue(tftypes.String, serviceName+"/config1"),
+		}))
+
+		// The second step fails, leaving traffic at the first step.
+		fake.rolloutStatuses = []servicemanagementpb.Rollout_RolloutStatus{
+			servicemanagementpb.Rollout_SUCCESS,
+			servicemanagementpb.Rollout_FAILED,
+		}
+		configValue := testDynamicValue(t, typ, config)
+		planResp := testPlanResource(t, server, "utils_service_rollout", typ, priorState, nil, configValue)
+		applyResp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+			TypeName:       "utils_service_rollout",
+			PriorState:     priorState,
+			PlannedState:   planResp.PlannedState,
+			PlannedPrivate: planResp.PlannedPrivate,
+			Config:         configValue,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(applyResp.Diagnostics) != 1 || applyResp.Diagnostics[0].Severity != tfprotov6.DiagnosticSeverityError {
+			t.Fatalf("got diagnostics %v, want one error", applyResp.Diagnostics)
+		}
+
+		// Only the first step is recorded, so the next plan is not empty.
+		attrs := testStateAttributes(t, typ, applyResp.NewState)
+		if want := tftypes.NewValue(tftypes.String, serviceName+"/rollout2"); !attrs["id"].Equal(want) {
+			t.Errorf("got id %v, want %v", attrs["id"], want)
+		}
+		if want := stepRolloutIds("rollout2"); !attrs["step_rollout_ids"].Equal(want) {
+			t.Errorf("got step_rollout_ids %v, want %v", attrs["step_rollout_ids"], want)
+		}
+		var steps []tftypes.Value
+		if err := attrs["steps"].As(&steps); err != nil {
+			t.Fatal(err)
+		}
+		if len(steps) != 1 {
+			t.Errorf("got %d steps, want 1", len(steps))
+		}
+
+		planResp = testPlanResource(t, server, "utils_service_rollout", typ, applyResp.NewState, applyResp.Private, configValue)
+		if planned := testStateAttributes(t, typ, planResp.PlannedState)["id"]; planned.IsKnown() {
+			t.Fatalf("got planned id %v, want unknown", planned)
+		}
+
+		// Applying again resumes with the failed step.
+		priorState = applyResp.NewState
+		applyResp, err = server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+			TypeName:       "utils_service_rollout",
+			PriorState:     priorState,
+			PlannedState:   planResp.PlannedState,
+			PlannedPrivate: planResp.PlannedPrivate,
+			Config:         configValue,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		requireNoErrors(t, applyResp.Diagnostics)
+		if len(fake.createdRollouts) != 5 {
+			t.Fatalf("got %d rollouts, want 5", len(fake.createdRollouts))
+		}
+		if got := fake.createdRollouts[3].GetRollout().GetTrafficPercentStrategy().GetPercentages(); got["config2"] != 50 {
+			t.Errorf("got resumed step percentages %v, want 50%% config2", got)
+		}
+
+		attrs = testStateAttributes(t, typ, applyResp.NewState)
+		if want := tftypes.NewValue(tftypes.String, serviceName+"/rollout5"); !attrs["id"].Equal(want) {
+			t.Errorf("got id %v, want %v", attrs["id"], want)
+		}
+		if want := stepRolloutIds("rollout2", "rollout4", "rollout5"); !attrs["step_rollout_ids"].Equal(want) {
+			t.Errorf("got step_rollout_ids %v, want %v", attrs["step_rollout_ids"], want)
+		}
+		if want := tftypes.NewValue(tftypes.String, serviceName+"/rollout1"); !attrs["previous_rollout_id"].Equal(want) {
+			t.Errorf("got previous_rollout_id %v, want %v", attrs["previous_rollout_id"], want)
+		}
+
+		// Once all steps are rolled out, the plan is empty.
+		planResp = testPlanResource(t, server, "utils_service_rollout", typ, applyResp.NewState, applyResp.Private, configValue)
+		if planned := testStateAttributes(t, typ, planResp.PlannedState)["id"]; !planned.Equal(attrs["id"]) {
+			t.Errorf("got planned id %v, want %v", planned, attrs["id"])
+		}
+	})
+}
+
+func TestResourceServiceRolloutAsync(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	fake := newFakeServiceManager()
+	fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+	fake.rolloutsPending = true
+	server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+	typ := schemas.ResourceSchemas["utils_service_rollout"].ValueType()
+
+	config := testDynamicValue(t, typ, map[string]tftypes.Value{
+		"config_id":           tftypes.NewValue(tftypes.String, serviceName+"/config1"),
+		"wait_for_completion": tftypes.NewValue(tftypes.Bool, false),
+	})
+	state := testApplyResource(t, server, "utils_service_rollout", typ, nil, config)
+	attrs := testStateAttributes(t, typ, state)
+	if want := tftypes.NewValue(tftypes.String, serviceName+"/rollout1"); !attrs["id"].Equal(want) {
+		t.Errorf("got id %v, want %v", attrs["id"], want)
+	}
+	if want := tftypes.NewValue(tftypes.String, "IN_PROGRESS"); !attrs["status"].Equal(want) {
+		t.Errorf("got status %v, want %v", attrs["status"], want)
+	}
+
+	// Refreshing after the rollout completes updates its status without
+	// changing the configuration.
+	fake.rollouts[serviceName+"/rollout1"].Status = servicemanagementpb.Rollout_SUCCESS
+	state = testReadResource(t, server, "utils_service_rollout", state)
+	refreshed := testStateAttributes(t, typ, state)
+	if want := tftypes.NewValue(tftypes.String, "SUCCESS"); !refreshed["status"].Equal(want) {
+		t.Errorf("got status %v, want %v", refreshed["status"], want)
+	}
+	for _, attr := range []string{"id", "config_id", "rollout_config", "wait_for_completion"} {
+		if !refreshed[attr].Equal(attrs[attr]) {
+			t.Errorf("got %s %v, want %v", attr, refreshed[attr], attrs[attr])
+		}
+	}
+
+	// The refreshed state matches the config, so no new rollout is planned.
+	planned := testStateAttributes(t, typ, testPlanResource(t, server, "utils_service_rollout", typ, state, nil, config).PlannedState)
+	for attr, want := range refreshed {
+		if !planned[attr].Equal(want) {
+			t.Errorf("got planned %s %v, want %v", attr, planned[attr], want)
+		}
+	}
+}
+
+func TestResourceServiceRolloutWaitForStatus(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	for _, tt := range []struct {
+		name      string
+		updates   []servicemanagementpb.Rollout_RolloutStatus
+		timeout   time.Duration
+		wantError string
+	}{
+		{
+			name:    "success",
+			updates: []servicemanagementpb.Rollout_RolloutStatus{servicemanagementpb.Rollout_IN_PROGRESS, servicemanagementpb.Rollout_SUCCESS},
+		},
+		{
+			name:      "failed",
+			updates:   []servicemanagementpb.Rollout_RolloutStatus{servicemanagementpb.Rollout_IN_PROGRESS, servicemanagementpb.Rollout_FAILED},
+			wantError: "rollout rollout1 of configs config1 has status FAILED",
+		},
+		{
+			name:      "timeout",
+			timeout:   10 * time.Millisecond,
+			wantError: "rollout rollout1 did not succeed, last status PENDING",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeServiceManager()
+			rollout := &servicemanagementpb.Rollout{
+				RolloutId:   "rollout1",
+				ServiceName: serviceName,
+				Status:      servicemanagementpb.Rollout_PENDING,
+				Strategy: &servicemanagementpb.Rollout_TrafficPercentStrategy_{
+					TrafficPercentStrategy: &servicemanagementpb.Rollout_TrafficPercentStrategy{
+						Percentages: map[string]float64{"config1": 100},
+					},
+				},
+			}
+			fake.rollouts[serviceName+"/rollout1"] = rollout
+			fake.rolloutStatusUpdates = tt.updates
+			r := &ServiceRolloutResource{UtilsProviderConfig: *newFakeProviderConfig(t, fake)}
+
+			timeout := tt.timeout
+			if timeout == 0 {
+				timeout = time.Minute
+			}
+			got, err := r.waitForRollout(ctx, rollout, timeout, time.Millisecond)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.GetStatus() != servicemanagementpb.Rollout_SUCCESS {
+					t.Errorf("got status %v, want SUCCESS", got.GetStatus())
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("got error %v, want error containing %q", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestResourceServiceRolloutValidateConfig(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, newFakeServiceManager()))
+	typ := schemas.ResourceSchemas["utils_service_rollout"].ValueType()
+	rolloutConfig := tftypes.NewValue(tftypes.Map{ElementType: tftypes.Number}, map[string]tftypes.Value{
+		serviceName + "/config1": tftypes.NewValue(tftypes.Number, 100),
+	})
+	bareRolloutConfig := tftypes.NewValue(tftypes.Map{ElementType: tftypes.Number}, map[string]tftypes.Value{
+		"config1": tftypes.NewValue(tftypes.Number, 50),
+		"config2": tftypes.NewValue(tftypes.Number, 50),
+	})
+	stepType := tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+		"percentages": tftypes.Map{ElementType: tftypes.Number},
+		"wait":        tftypes.String,
+	}}
+	steps := tftypes.NewValue(tftypes.List{ElementType: stepType}, []tftypes.Value{
+		tftypes.NewValue(stepType, map[string]tftypes.Value{
+			"percentages": rolloutConfig,
+			"wait":        tftypes.NewValue(tftypes.String, nil),
+		}),
+	})
+
+	for _, tt := range []struct {
+		name       string
+		config     map[string]tftypes.Value
+		wantDetail string
+		wantCount  int
+	}{
+		{
+			name:   "config_id",
+			config: map[string]tftypes.Value{"config_id": tftypes.NewValue(tftypes.String, serviceName+"/config1")},
+		},
+		{
+			name:   "rollout_config",
+			config: map[string]tftypes.Value{"rollout_config": rolloutConfig},
+		},
+		{
+			name: "rollout_config with service_name",
+			config: map[string]tftypes.Value{
+				"service_name":   tftypes.NewValue(tftypes.String, serviceName),
+				"rollout_config": bareRolloutConfig,
+			},
+		},
+		{
+			name:       "neither",
+			config:     map[string]tftypes.Value{},
+			wantDetail: "One of `config_id`, `rollout_config` or `steps` is required",
+		},
+		{
+			name: "both",
+			config: map[string]tftypes.Value{
+				"config_id":      tftypes.NewValue(tftypes.String, serviceName+"/config1"),
+				"rollout_config": rolloutConfig,
+			},
+			wantDetail: "These attributes cannot be configured together",
+		},
+		{
+			name: "all",
+			config: map[string]tftypes.Value{
+				"config_id":      tftypes.NewValue(tftypes.String, serviceName+"/config1"),
+				"rollout_config": rolloutConfig,
+				"steps":          steps,
+			},
+			wantDetail: "These attributes cannot be configured together",
+		},
+		{
+			name: "delete_service",
+			config: map[string]tftypes.Value{
+				"strategy":     tftypes.NewValue(tftypes.String, rolloutStrategyDeleteService),
+				"service_name": tftypes.NewValue(tftypes.String, serviceName),
+			},
+		},
+		{
+			name: "delete_service with config_id",
+			config: map[string]tftypes.Value{
+				"strategy":     tftypes.NewValue(tftypes.String, rolloutStrategyDeleteService),
+				"service_name": tftypes.NewValue(tftypes.String, serviceName),
+				"config_id":    tftypes.NewValue(tftypes.String, serviceName+"/config1"),
+			},
+			wantDetail: "`config_id`, `rollout_config` and `steps` cannot be specified, when `strategy` is `delete_service`",
+		},
+		{
+			name:       "empty rollout_config",
+			config:     map[string]tftypes.Value{"rollout_config": tftypes.NewValue(tftypes.Map{ElementType: tftypes.Number}, map[string]tftypes.Value{})},
+			wantDetail: "The traffic split must contain at least one config",
+		},
+		{
+			name:   "unknown rollout_config",
+			config: map[string]tftypes.Value{"rollout_config": tftypes.NewValue(tftypes.Map{ElementType: tftypes.Number}, tftypes.UnknownValue)},
+		},
+		{
+			name: "unknown percentages",
+			config: map[string]tftypes.Value{
+				"rollout_config": tftypes.NewValue(tftypes.Map{ElementType: tftypes.Number}, map[string]tftypes.Value{
+					serviceName + "/config1": tftypes.NewValue(tftypes.Number, tftypes.UnknownValue),
+				}),
+			},
+		},
+		{
+			name: "bare config IDs with unknown service_name",
+			config: map[string]tftypes.Value{
+				"service_name":   tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+				"rollout_config": bareRolloutConfig,
+			},
+		},
+		{
+			name: "invalid config ID with unknown service_name",
+			config: map[string]tftypes.Value{
+				"service_name": tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+				"rollout_config": tftypes.NewValue(tftypes.Map{ElementType: tftypes.Number}, map[string]tftypes.Value{
+					"a/b/c": tftypes.NewValue(tftypes.Number, 100),
+				}),
+			},
+			wantDetail: "ID must be in the format",
+		},
+		{
+			name: "configs of different services",
+			config: map[string]tftypes.Value{
+				"rollout_config": tftypes.NewValue(tftypes.Map{ElementType: tftypes.Number}, map[string]tftypes.Value{
+					serviceName + "/config1":    tftypes.NewValue(tftypes.Number, 50),
+					"other.example.com/config2": tftypes.NewValue(tftypes.Number, 50),
+				}),
+			},
+			wantDetail: `"other.example.com/config2" is a config of service other.example.com and "` + serviceName + `/config1" is a config of service ` + serviceName,
+		},
+		{
+			name:       "bare config IDs without service_name",
+			config:     map[string]tftypes.Value{"rollout_config": bareRolloutConfig},
+			wantDetail: "unless `service_name` is set",
+			wantCount:  2,
+		},
+		{
+			name: "invalid percentages",
+			config: map[string]tftypes.Value{
+				"rollout_config": tftypes.NewValue(tftypes.Map{ElementType: tftypes.Number}, map[string]tftypes.Value{
+					serviceName + "/config1": tftypes.NewValue(tftypes.Number, 0),
+					serviceName + "/config2": tftypes.NewValue(tftypes.Number, 150),
+				}),
+			},
+			wantDetail: "The percentage must be greater than 0 and at most 100",
+			wantCount:  2,
+		},
+		{
+			name: "config of another service",
+			config: map[string]tftypes.Value{
+				"service_name":   tftypes.NewValue(tftypes.String, "other.example.com"),
+				"rollout_config": rolloutConfig,
+			},
+			wantDetail: "is a config of service " + serviceName + ", not `service_name` other.example.com",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := server.ValidateResourceConfig(ctx, &tfprotov6.ValidateResourceConfigRequest{
+				TypeName: "utils_service_rollout",
+				Config:   testDynamicValue(t, typ, tt.config),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantDetail == "" {
+				requireNoErrors(t, resp.Diagnostics)
+				return
+			}
+			if tt.wantCount == 0 {
+				tt.wantCount = 1
+			}
+			if len(resp.Diagnostics) != tt.wantCount {
+				t.Fatalf("got diagnostics %v, want %d errors", resp.Diagnostics, tt.wantCount)
+			}
+			for _, d := range resp.Diagnostics {
+				if !strings.Contains(d.Detail, tt.wantDetail) {
+					t.Errorf("got detail %q, want it to contain %q", d.Detail, tt.wantDetail)
+				}
+			}
+		})
+	}
+}
+
+func TestResourceServiceRolloutReuse(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	for _, tt := range []struct {
+		name         string
+		percentages  map[string]float64
+		alwaysCreate bool
+		wantId       string
+	}{
+		{name: "same split", percentages: map[string]float64{"config1": 100}, wantId: serviceName + "/manual"},
+		{name: "different split", percentages: map[string]float64{"config1": 50, "config0": 50}, wantId: serviceName + "/rollout1"},
+		{name: "always create", percentages: map[string]float64{"config1": 100}, alwaysCreate: true, wantId: serviceName + "/rollout1"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeServiceManager()
+			fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+			fake.rollouts[serviceName+"/manual"] = &servicemanagementpb.Rollout{
+				RolloutId:   "manual",
+				ServiceName: serviceName,
+				Status:      servicemanagementpb.Rollout_SUCCESS,
+				Strategy: &servicemanagementpb.Rollout_TrafficPercentStrategy_{
+					TrafficPercentStrategy: &servicemanagementpb.Rollout_TrafficPercentStrategy{
+						Percentages: tt.percentages,
+					},
+				},
+			}
+			server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+			typ := schemas.ResourceSchemas["utils_service_rollout"].ValueType()
+
+			state := testApplyResource(t, server, "utils_service_rollout", typ, nil, testDynamicValue(t, typ, map[string]tftypes.Value{
+				"config_id":     tftypes.NewValue(tftypes.String, serviceName+"/config1"),
+				"always_create": tftypes.NewValue(tftypes.Bool, tt.alwaysCreate),
+			}))
+			attrs := testStateAttributes(t, typ, state)
+			if want := tftypes.NewValue(tftypes.String, tt.wantId); !attrs["id"].Equal(want) {
+				t.Errorf("got id %v, want %v", attrs["id"], want)
+			}
+			wantCreated := 1
+			if tt.wantId == serviceName+"/manual" {
+				wantCreated = 0
+			}
+			if len(fake.createdRollouts) != wantCreated {
+				t.Errorf("got %d rollouts created, want %d", len(fake.createdRollouts), wantCreated)
+			}
+		})
+	}
+}
+
+func TestResourceServiceRolloutImport(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	for _, tt := range []struct {
+		name        string
+		percentages map[string]float64
+		want        map[string]tftypes.Value
+	}{
+		{
+			name:        "single config",
+			percentages: map[string]float64{"config1": 100},
+			want: map[string]tftypes.Value{
+				"config_id":      tftypes.NewValue(tftypes.String, serviceName+"/config1"),
+				"rollout_config": tftypes.NewValue(tftypes.Map{ElementType: tftypes.Number}, nil),
+			},
+		},
+		{
+			name:        "multiple configs",
+			percentages: map[string]float64{"config1": 80, "config2": 20},
+			want: map[string]tftypes.Value{
+				"config_id": tftypes.NewValue(tftypes.String, nil),
+				"rollout_config": tftypes.NewValue(tftypes.Map{ElementType: tftypes.Number}, map[string]tftypes.Value{
+					serviceName + "/config1": tftypes.NewValue(tftypes.Number, 80),
+					serviceName + "/config2": tftypes.NewValue(tftypes.Number, 20),
+				}),
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeServiceManager()
+			fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+			fake.rollouts[serviceName+"/rollout1"] = &servicemanagementpb.Rollout{
+				RolloutId:   "rollout1",
+				ServiceName: serviceName,
+				Status:      servicemanagementpb.Rollout_SUCCESS,
+				Strategy: &servicemanagementpb.Rollout_TrafficPercentStrategy_{
+					TrafficPercentStrategy: &servicemanagementpb.Rollout_TrafficPercentStrategy{
+						Percentages: tt.percentages,
+					},
+				},
+			}
+			server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+			typ := schemas.ResourceSchemas["utils_service_rollout"].ValueType()
+
+			resp, err := server.ImportResourceState(ctx, &tfprotov6.ImportResourceStateRequest{
+				TypeName: "utils_service_rollout",
+				ID:       serviceName + "/rollout1",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			requireNoErrors(t, resp.Diagnostics)
+			if len(resp.ImportedResources) != 1 {
+				t.Fatalf("got %d imported resources, want 1", len(resp.ImportedResources))
+			}
+			state := resp.ImportedResources[0].State
+
+			attrs := testStateAttributes(t, typ, state)
+			for attr, want := range tt.want {
+				if !attrs[attr].Equal(want) {
+					t.Errorf("got %s %v, want %v", attr, attrs[attr], want)
+				}
+			}
+
+			// Refreshing keeps the imported attributes.
+			refreshed := testStateAttributes(t, typ, testReadResource(t, server, "utils_service_rollout", state))
+			for attr, want := range attrs {
+				if !refreshed[attr].Equal(want) {
+					t.Errorf("got refreshed %s %v, want %v", attr, refreshed[attr], want)
+				}
+			}
+
+			// Configuring the same split with the other attribute does not
+			// create a rollout.
+			config := map[string]tftypes.Value{"config_id": tftypes.NewValue(tftypes.String, nil)}
+			if len(tt.percentages) == 1 {
+				config["rollout_config"] = tftypes.NewValue(tftypes.Map{ElementType: tftypes.Number}, map[string]tftypes.Value{
+					serviceName + "/config1": tftypes.NewValue(tftypes.Number, 100),
+				})
+			} else {
+				config["rollout_config"] = tt.want["rollout_config"]
+			}
+			state = testApplyResource(t, server, "utils_service_rollout", typ, state, testDynamicValue(t, typ, config))
+			if len(fake.createdRollouts) != 0 {
+				t.Errorf("got %d rollouts created, want 0", len(fake.createdRollouts))
+			}
+			if got := testStateAttributes(t, typ, state)["rollout_config"]; !got.Equal(config["rollout_config"]) {
+				t.Errorf("got rollout_config %v, want %v", got, config["rollout_config"])
+			}
+		})
+	}
+}
+
+func TestResourceServiceRolloutRollback(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	for _, tt := range []struct {
+		name         string
+		statuses     []servicemanagementpb.Rollout_RolloutStatus
+		rollback     bool
+		wantRollouts int
+		wantError    string
+	}{
+		{
+			name:         "success",
+			statuses:     []servicemanagementpb.Rollout_RolloutStatus{servicemanagementpb.Rollout_SUCCESS, servicemanagementpb.Rollout_SUCCESS},
+			rollback:     true,
+			wantRollouts: 2,
+		},
+		{
+			name:         "failed without rollback",
+			statuses:     []servicemanagementpb.Rollout_RolloutStatus{servicemanagementpb.Rollout_SUCCESS, servicemanagementpb.Rollout_FAILED},
+			wantRollouts: 2,
+			wantError:    "Error creating service rollout",
+		},
+		{
+			name:         "failed with rollback",
+			statuses:     []servicemanagementpb.Rollout_RolloutStatus{servicemanagementpb.Rollout_SUCCESS, servicemanagementpb.Rollout_FAILED},
+			rollback:     true,
+			wantRollouts: 3,
+			wantError:    "Service rollout rolled back",
+		},
+		{
+			name:         "rollback failed",
+			statuses:     []servicemanagementpb.Rollout_RolloutStatus{servicemanagementpb.Rollout_SUCCESS, servicemanagementpb.Rollout_FAILED, servicemanagementpb.Rollout_FAILED},
+			rollback:     true,
+			wantRollouts: 3,
+			wantError:    "Error creating service rollout",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			fake := newFakeServiceManager()
+			fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+			fake.rolloutStatuses = tt.statuses
+			server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+			typ := schemas.ResourceSchemas["utils_service_rollout"].ValueType()
+
+			config := func(configId string) *tfprotov6.DynamicValue {
+				return testDynamicValue(t, typ, map[string]tftypes.Value{
+					"config_id":           tftypes.NewValue(tftypes.String, serviceName+"/"+configId),
+					"rollback_on_failure": tftypes.NewValue(tftypes.Bool, tt.rollback),
+				})
+			}
+			state := testApplyResource(t, server, "utils_service_rollout", typ, nil, config("config1"))
+			if got := testStateAttributes(t, typ, state)["previous_rollout_id"]; !got.IsNull() {
+				t.Errorf("got previous_rollout_id %v, want null", got)
+			}
+
+			// The previous rollout is known when planning an update.
+			planResp := testPlanResource(t, server, "utils_service_rollout", typ, state, nil, config("config2"))
+			want := tftypes.NewValue(tftypes.String, serviceName+"/rollout1")
+			if got := testStateAttributes(t, typ, planResp.PlannedState)["previous_rollout_id"]; !got.Equal(want) {
+				t.Errorf("got planned previous_rollout_id %v, want %v", got, want)
+			}
+			applyResp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+				TypeName:     "utils_service_rollout",
+				PriorState:   state,
+				PlannedState: planResp.PlannedState,
+				Config:       config("config2"),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantError == "" {
+				requireNoErrors(t, applyResp.Diagnostics)
+			} else if len(applyResp.Diagnostics) != 1 || applyResp.Diagnostics[0].Summary != tt.wantError {
+				t.Errorf("got diagnostics %v, want error %q", applyResp.Diagnostics, tt.wantError)
+			}
+
+			if len(fake.createdRollouts) != tt.wantRollouts {
+				t.Fatalf("got %d rollouts, want %d", len(fake.createdRollouts), tt.wantRollouts)
+			}
+			if tt.wantRollouts == 3 {
+				got := fake.createdRollouts[2].GetRollout().GetTrafficPercentStrategy().GetPercentages()
+				if len(got) != 1 || got["config1"] != 100 {
+					t.Errorf("got rollback percentages %v, want config1 at 100", got)
+				}
+			}
+		})
+	}
+}
+
+func TestResourceServiceRolloutConflictRetry(t *testing.T) {
+	delay := rolloutConflictRetryBaseDelay
+	t.Cleanup(func() { rolloutConflictRetryBaseDelay = delay })
+	rolloutConflictRetryBaseDelay = time.Millisecond
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	for _, tt := range []struct {
+		name      string
+		conflicts int
+		timeout   string
+		wantError bool
+	}{
+		{name: "retried", conflicts: 2},
+		{name: "timeout", conflicts: 1000, timeout: "20ms", wantError: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeServiceManager()
+			fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+			fake.rolloutConflicts = tt.conflicts
+			r := &ServiceRolloutResource{UtilsProviderConfig: *newFakeProviderConfig(t, fake)}
+
+			timeout := time.Minute
+			if tt.timeout != "" {
+				timeout, _ = time.ParseDuration(tt.timeout)
+			}
+			rollout, err := r.submitRollout(ctx, &servicemanagementpb.Rollout{
+				ServiceName: serviceName,
+				Strategy: &servicemanagementpb.Rollout_TrafficPercentStrategy_{
+					TrafficPercentStrategy: &servicemanagementpb.Rollout_TrafficPercentStrategy{
+						Percentages: map[string]float64{"config1": 100},
+					},
+				},
+			}, true, timeout)
+			if tt.wantError {
+				if !isRolloutConflictError(errors.Unwrap(err)) {
+					t.Errorf("got error %v, want rollout conflict", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rollout.GetRolloutId() != "rollout1" || fake.rolloutConflicts != 0 {
+				t.Errorf("got rollout %q with %d conflicts remaining, want rollout1 after all conflicts", rollout.GetRolloutId(), fake.rolloutConflicts)
+			}
+		})
+	}
+}
+
+func TestResourceServiceRolloutTrafficPercent(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	fake := newFakeServiceManager()
+	fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+	fake.rollouts[serviceName+"/manual"] = &servicemanagementpb.Rollout{
+		RolloutId:   "manual",
+		ServiceName: serviceName,
+		Status:      servicemanagementpb.Rollout_SUCCESS,
+		Strategy: &servicemanagementpb.Rollout_TrafficPercentStrategy_{
+			TrafficPercentStrategy: &servicemanagementpb.Rollout_TrafficPercentStrategy{
+				Percentages: map[string]float64{"config1": 100},
+			},
+		},
+	}
+	server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+	typ := schemas.ResourceSchemas["utils_service_rollout"].ValueType()
+
+	config := func(percent float64) *tfprotov6.DynamicValue {
+		return testDynamicValue(t, typ, map[string]tftypes.Value{
+			"config_id":       tftypes.NewValue(tftypes.String, serviceName+"/config2"),
+			"traffic_percent": tftypes.NewValue(tftypes.Number, percent),
+		})
+	}
+
+	state := testApplyResource(t, server, "utils_service_rollout", typ, nil, config(10))
+	if got := fake.createdRollouts[0].GetRollout().GetTrafficPercentStrategy().GetPercentages(); !maps.Equal(got, map[string]float64{"config1": 90, "config2": 10}) {
+		t.Errorf("got percentages %v, want 90%% config1 and 10%% config2", got)
+	}
+
+	// Refreshing keeps the canary attributes.
+	attrs := testStateAttributes(t, typ, testReadResource(t, server, "utils_service_rollout", state))
+	if want := tftypes.NewValue(tftypes.Number, 10); !attrs["traffic_percent"].Equal(want) {
+		t.Errorf("got traffic_percent %v, want %v", attrs["traffic_percent"], want)
+	}
+	if !attrs["rollout_config"].IsNull() {
+		t.Errorf("got rollout_config %v, want null", attrs["rollout_config"])
+	}
+
+	// Increasing the share keeps sending the rest to the previous config.
+	testApplyResource(t, server, "utils_service_rollout", typ, state, config(50))
+	if got := fake.createdRollouts[1].GetRollout().GetTrafficPercentStrategy().GetPercentages(); !maps.Equal(got, map[string]float64{"config1": 50, "config2": 50}) {
+		t.Errorf("got percentages %v, want 50%% config1 and 50%% config2", got)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		config map[string]tftypes.Value
+	}{
+		{
+			name: "zero",
+			config: map[string]tftypes.Value{
+				"config_id":       tftypes.NewValue(tftypes.String, serviceName+"/config2"),
+				"traffic_percent": tftypes.NewValue(tftypes.Number, 0),
+			},
+		},
+		{
+			name: "over 100",
+			config: map[string]tftypes.Value{
+				"config_id":       tftypes.NewValue(tftypes.String, serviceName+"/config2"),
+				"traffic_percent": tftypes.NewValue(tftypes.Number, 150),
+			},
+		},
+		{
+			name: "without config_id",
+			config: map[string]tftypes.Value{
+				"rollout_config": tftypes.NewValue(tftypes.Map{ElementType: tftypes.Number}, map[string]tftypes.Value{
+					serviceName + "/config2": tftypes.NewValue(tftypes.Number, 100),
+				}),
+				"traffic_percent": tftypes.NewValue(tftypes.Number, 10),
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := server.ValidateResourceConfig(ctx, &tfprotov6.ValidateResourceConfigRequest{
+				TypeName: "utils_service_rollout",
+				Config:   testDynamicValue(t, typ, tt.config),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Diagnostics) != 1 || resp.Diagnostics[0].Severity != tfprotov6.DiagnosticSeverityError {
+				t.Errorf("got diagnostics %v, want one error", resp.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestResourceServiceRolloutCreateErrors(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	for _, tt := range []struct {
+		name        string
+		config      map[string]tftypes.Value
+		wantSummary string
+	}{
+		{
+			name:        "invalid config ID",
+			config:      map[string]tftypes.Value{"config_id": tftypes.NewValue(tftypes.String, "config1")},
+			wantSummary: "Invalid config ID",
+		},
+		{
+			name:        "unknown service",
+			config:      map[string]tftypes.Value{"config_id": tftypes.NewValue(tftypes.String, "other.example.com/config1")},
+			wantSummary: "Error creating service rollout",
+		},
+		{
+			name: "no current config",
+			config: map[string]tftypes.Value{
+				"config_id":       tftypes.NewValue(tftypes.String, serviceName+"/config1"),
+				"traffic_percent": tftypes.NewValue(tftypes.Number, 10),
+			},
+			wantSummary: "Cannot determine the current config",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeServiceManager()
+			fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+			server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+			typ := schemas.ResourceSchemas["utils_service_rollout"].ValueType()
+
+			config := testDynamicValue(t, typ, tt.config)
+			planResp := testPlanResource(t, server, "utils_service_rollout", typ, nil, nil, config)
+			applyResp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+				TypeName:     "utils_service_rollout",
+				PriorState:   testNullDynamicValue(t, typ),
+				PlannedState: planResp.PlannedState,
+				Config:       config,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(applyResp.Diagnostics) != 1 || applyResp.Diagnostics[0].Summary != tt.wantSummary {
+				t.Errorf("got diagnostics %v, want error %q", applyResp.Diagnostics, tt.wantSummary)
+			}
+			if len(fake.createdRollouts) != 0 {
+				t.Errorf("got %d rollouts created, want 0", len(fake.createdRollouts))
+			}
+		})
+	}
+}
+
+func TestResourceServiceRolloutServiceName(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	fake := newFakeServiceManager()
+	fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+	server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+	typ := schemas.ResourceSchemas["utils_service_rollout"].ValueType()
+
+	rolloutConfig := tftypes.NewValue(tftypes.Map{ElementType: tftypes.Number}, map[string]tftypes.Value{
+		"config1": tftypes.NewValue(tftypes.Number, 50),
+		"config2": tftypes.NewValue(tftypes.Number, 50),
+	})
+	config := testDynamicValue(t, typ, map[string]tftypes.Value{
+		"service_name":   tftypes.NewValue(tftypes.String, serviceName),
+		"rollout_config": rolloutConfig,
+	})
+	state := testApplyResource(t, server, "utils_service_rollout", typ, nil, config)
+	if len(fake.createdRollouts) != 1 {
+		t.Fatalf("got %d rollouts created, want 1", len(fake.createdRollouts))
+	}
+	created := fake.createdRollouts[0]
+	if got, want := created.GetServiceName(), serviceName; got != want {
+		t.Errorf("got service %q, want %q", got, want)
+	}
+	if got, want := created.GetRollout().GetTrafficPercentStrategy().GetPercentages(), map[string]float64{"config1": 50, "config2": 50}; !maps.Equal(got, want) {
+		t.Errorf("got percentages %v, want %v", got, want)
+	}
+
+	// Refreshing keeps the config IDs within the service.
+	state = testReadResource(t, server, "utils_service_rollout", state)
+	attrs := testStateAttributes(t, typ, state)
+	if !attrs["rollout_config"].Equal(rolloutConfig) {
+		t.Errorf("got rollout_config %v, want %v", attrs["rollout_config"], rolloutConfig)
+	}
+
+	// Switching to the equivalent composite config IDs does not create a
+	// rollout.
+	config = testDynamicValue(t, typ, map[string]tftypes.Value{
+		"rollout_config": tftypes.NewValue(tftypes.Map{ElementType: tftypes.Number}, map[string]tftypes.Value{
+			serviceName + "/config1": tftypes.NewValue(tftypes.Number, 50),
+			serviceName + "/config2": tftypes.NewValue(tftypes.Number, 50),
+		}),
+	})
+	planned := testStateAttributes(t, typ, testPlanResource(t, server, "utils_service_rollout", typ, state, nil, config).PlannedState)
+	for _, attr := range []string{"id", "service_name"} {
+		if !planned[attr].Equal(attrs[attr]) {
+			t.Errorf("got planned %s %v, want %v", attr, planned[attr], attrs[attr])
+		}
+	}
+}
+
+func TestResourceServiceRolloutPercentagePrecision(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	fake := newFakeServiceManager()
+	fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+	server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+	typ := schemas.ResourceSchemas["utils_service_rollout"].ValueType()
+
+	// Split traffic evenly, as computed by `100 / 3` in HCL.
+	rolloutConfig := tftypes.NewValue(tftypes.Map{ElementType: tftypes.Number}, map[string]tftypes.Value{
+		serviceName + "/config1": tftypes.NewValue(tftypes.Number, 100.0/3),
+		serviceName + "/config2": tftypes.NewValue(tftypes.Number, 100.0/3),
+		serviceName + "/config3": tftypes.NewValue(tftypes.Number, 100.0/3),
+	})
+	config := testDynamicValue(t, typ, map[string]tftypes.Value{
+		"rollout_config": rolloutConfig,
+	})
+	state := testApplyResource(t, server, "utils_service_rollout", typ, nil, config)
+	if got, want := fake.rollouts[serviceName+"/rollout1"].GetTrafficPercentStrategy().GetPercentages()["config1"], 33.33; got != want {
+		t.Fatalf("got normalized percentage %v, want %v", got, want)
+	}
+
+	// The normalized percentages are not drift.
+	state = testReadResource(t, server, "utils_service_rollout", state)
+	attrs := testStateAttributes(t, typ, state)
+	if !attrs["rollout_config"].Equal(rolloutConfig) {
+		t.Errorf("got rollout_config %v, want %v", attrs["rollout_config"], rolloutConfig)
+	}
+	planned := testStateAttributes(t, typ, testPlanResource(t, server, "utils_service_rollout", typ, state, nil, config).PlannedState)
+	if !planned["id"].Equal(attrs["id"]) {
+		t.Errorf("got planned id %v, want %v", planned["id"], attrs["id"])
+	}
+
+	// Recreating the resource reuses the rollout with the normalized split.
+	testApplyResource(t, server, "utils_service_rollout", typ, nil, config)
+	if len(fake.createdRollouts) != 1 {
+		t.Errorf("got %d rollouts created, want 1", len(fake.createdRollouts))
+	}
+}
+
+func TestResourceServiceRolloutReplace(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+	const otherServiceName = "other.endpoints.example.cloud.goog"
+
+	fake := newFakeServiceManager()
+	fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+	fake.services[otherServiceName] = &servicemanagementpb.ManagedService{ServiceName: otherServiceName}
+	server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+	typ := schemas.ResourceSchemas["utils_service_rollout"].ValueType()
+
+	state := testApplyResource(t, server, "utils_service_rollout", typ, nil, testDynamicValue(t, typ, map[string]tftypes.Value{
+		"config_id": tftypes.NewValue(tftypes.String, serviceName+"/config1"),
+	}))
+
+	for _, tt := range []struct {
+		name        string
+		config      map[string]tftypes.Value
+		wantReplace bool
+	}{
+		{
+			name:   "same service",
+			config: map[string]tftypes.Value{"config_id": tftypes.NewValue(tftypes.String, serviceName+"/config2")},
+		},
+		{
+			name:        "config_id of another service",
+			config:      map[string]tftypes.Value{"config_id": tftypes.NewValue(tftypes.String, otherServiceName+"/config1")},
+			wantReplace: true,
+		},
+		{
+			name: "rollout_config of another service",
+			config: map[string]tftypes.Value{
+				"rollout_config": tftypes.NewValue(tftypes.Map{ElementType: tftypes.Number}, map[string]tftypes.Value{
+					otherServiceName + "/config1": tftypes.NewValue(tftypes.Number, 100),
+				}),
+			},
+			wantReplace: true,
+		},
+		{
+			name: "service_name of another service",
+			config: map[string]tftypes.Value{
+				"service_name": tftypes.NewValue(tftypes.String, otherServiceName),
+				"rollout_config": tftypes.NewValue(tftypes.Map{ElementType: tftypes.Number}, map[string]tftypes.Value{
+					"config1": tftypes.NewValue(tftypes.Number, 100),
+				}),
+			},
+			wantReplace: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			planResp := testPlanResource(t, server, "utils_service_rollout", typ, state, nil, testDynamicValue(t, typ, tt.config))
+			wantRequiresReplace := []*tftypes.AttributePath(nil)
+			if tt.wantReplace {
+				wantRequiresReplace = []*tftypes.AttributePath{tftypes.NewAttributePath().WithAttributeName("service_name")}
+			}
+			if !slices.EqualFunc(planResp.RequiresReplace, wantRequiresReplace, (*tftypes.AttributePath).Equal) {
+				t.Errorf("got requires replace %v, want %v", planResp.RequiresReplace, wantRequiresReplace)
+			}
+			if gotWarning := len(planResp.Diagnostics) > 0; gotWarning != tt.wantReplace {
+				t.Errorf("got diagnostics %v, want warning %v", planResp.Diagnostics, tt.wantReplace)
+			}
+
+			planned := testStateAttributes(t, typ, planResp.PlannedState)
+			wantPrevious := testStateAttributes(t, typ, state)["id"]
+			if tt.wantReplace {
+				wantPrevious = tftypes.NewValue(tftypes.String, nil)
+			}
+			if !planned["previous_rollout_id"].Equal(wantPrevious) {
+				t.Errorf("got planned previous_rollout_id %v, want %v", planned["previous_rollout_id"], wantPrevious)
+			}
+		})
+	}
+}
+
+func TestResourceServiceRolloutTimeouts(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	t.Run("poll interval", func(t *testing.T) {
+		var waits []time.Duration
+		rolloutPollAfter = func(d time.Duration) <-chan time.Time {
+			waits = append(waits, d)
+			return time.After(0)
+		}
+		t.Cleanup(func() { rolloutPollAfter = time.After })
+
+		fake := newFakeServiceManager()
+		fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+		fake.rolloutStatuses = []servicemanagementpb.Rollout_RolloutStatus{servicemanagementpb.Rollout_IN_PROGRESS}
+		fake.rolloutStatusUpdates = []servicemanagementpb.Rollout_RolloutStatus{servicemanagementpb.Rollout_IN_PROGRESS, servicemanagementpb.Rollout_SUCCESS}
+		server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+		typ := schemas.ResourceSchemas["utils_service_rollout"].ValueType()
+
+		state := testApplyResource(t, server, "utils_service_rollout", typ, nil, testDynamicValue(t, typ, map[string]tftypes.Value{
+			"config_id":     tftypes.NewValue(tftypes.String, serviceName+"/config1"),
+			"poll_interval": tftypes.NewValue(tftypes.String, "30s"),
+		}))
+		if got, want := testStateAttributes(t, typ, state)["status"], tftypes.NewValue(tftypes.String, "SUCCESS"); !got.Equal(want) {
+			t.Errorf("got status %v, want %v", got, want)
+		}
+		if want := []time.Duration{30 * time.Second, 30 * time.Second}; !slices.Equal(waits, want) {
+			t.Errorf("got waits %v, want %v", waits, want)
+		}
+	})
+
+	// Whichever of `wait_timeout` and the create timeout is shorter stops
+	// the wait.
+	for _, tt := range []struct {
+		name        string
+		waitTimeout string
+		create      string
+	}{
+		{name: "create timeout", waitTimeout: "30m", create: "50ms"},
+		{name: "wait timeout", waitTimeout: "50ms", create: "1h"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			fake := newFakeServiceManager()
+			fake.services[serviceName] = &servicemanagementpb.ManagedService{ServiceName: serviceName}
+			fake.rolloutStatuses = []servicemanagementpb.Rollout_RolloutStatus{servicemanagementpb.Rollout_IN_PROGRESS}
+			server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+			typ := schemas.ResourceSchemas["utils_service_rollout"].ValueType()
+
+			config := testDynamicValue(t, typ, map[string]tftypes.Value{
+				"config_id":     tftypes.NewValue(tftypes.String, serviceName+"/config1"),
+				"poll_interval": tftypes.NewValue(tftypes.String, "1ms"),
+				"wait_timeout":  tftypes.NewValue(tftypes.String, tt.waitTimeout),
+				"timeouts": tftypes.NewValue(tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+					"create": tftypes.String,
+					"update": tftypes.String,
+				}}, map[string]tftypes.Value{
+					"create": tftypes.NewValue(tftypes.String, tt.create),
+					"update": tftypes.NewValue(tftypes.String, nil),
+				}),
+			})
+			planResp := testPlanResource(t, server, "utils_service_rollout", typ, nil, nil, config)
+			applyResp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+				TypeName:     "utils_service_rollout",
+				PriorState:   testNullDynamicValue(t, typ),
+				PlannedState: planResp.PlannedState,
+				Config:       config,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(applyResp.Diagnostics) != 1 || applyResp.Diagnostics[0].Summary != "Timed out waiting for service rollout" {
+				t.Fatalf("got diagnostics %v, want a timeout error", applyResp.Diagnostics)
+			}
+			for _, want := range []string{"rollout rollout1 did not succeed, last status IN_PROGRESS", serviceName + "/rollout1"} {
+				if detail := applyResp.Diagnostics[0].Detail; !strings.Contains(detail, want) {
+					t.Errorf("got detail %q, want it to contain %q", detail, want)
+				}
+			}
+		})
+	}
+}
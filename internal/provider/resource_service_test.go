@@ -0,0 +1,489 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/servicemanagement/apiv1/servicemanagementpb"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+	"google.golang.org/api/serviceusage/v1"
+	"google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/apipb"
+)
+
+func TestAccResourceService(t *testing.T) {
+	project := testAccProducerProject(t)
+	serviceName := fmt.Sprintf("tf-test-%s.endpoints.%s.cloud.goog", acctest.RandString(8), project)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCreateConfig(fmt.Sprintf(`
+				resource "utils_service" "test" {
+					service_name = %q
+					producer_project_id = %q
+				}`, serviceName, project)),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("utils_service.test", tfjsonpath.New("service_name"), knownvalue.StringExact(serviceName)),
+					statecheck.ExpectKnownValue("utils_service.test", tfjsonpath.New("create_operation"), knownvalue.StringRegexp(regexp.MustCompile(`^operations/.+`))),
+					statecheck.ExpectKnownValue("utils_service.test", tfjsonpath.New("delete_operation"), knownvalue.Null()),
+				},
+			},
+		},
+	})
+}
+
+func TestResourceServiceProducerProjectDrift(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	for _, tt := range []struct {
+		name          string
+		actualProject string
+		wantReplace   bool
+	}{
+		{name: "no drift", actualProject: "configured-project", wantReplace: false},
+		{name: "drift", actualProject: "other-project", wantReplace: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeServiceManager()
+			fake.services[serviceName] = &servicemanagementpb.ManagedService{
+				ServiceName:       serviceName,
+				ProducerProjectId: tt.actualProject,
+			}
+			server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+			typ := schemas.ResourceSchemas["utils_service"].ValueType()
+
+			config := map[string]tftypes.Value{
+				"service_name":        tftypes.NewValue(tftypes.String, serviceName),
+				"producer_project_id": tftypes.NewValue(tftypes.String, "configured-project"),
+			}
+			readResp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+				TypeName:     "utils_service",
+				CurrentState: testDynamicValue(t, typ, config),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			requireNoErrors(t, readResp.Diagnostics)
+
+			var warned bool
+			for _, d := range readResp.Diagnostics {
+				warned = warned || d.Severity == tfprotov6.DiagnosticSeverityWarning
+			}
+			if warned != tt.wantReplace {
+				t.Errorf("got warning %v, want %v", warned, tt.wantReplace)
+			}
+
+			// The state records the project reported by the API.
+			state := testStateAttributes(t, typ, readResp.NewState)
+			var stateProject string
+			if err := state["producer_project_id"].As(&stateProject); err != nil {
+				t.Fatal(err)
+			}
+			if stateProject != tt.actualProject {
+				t.Errorf("got producer_project_id %q in state, want %q", stateProject, tt.actualProject)
+			}
+
+			// As Terraform does, propose the configured value for the
+			// attribute, keeping the rest of the state.
+			proposed := make(map[string]tftypes.Value, len(state))
+			for name, value := range state {
+				proposed[name] = value
+			}
+			proposed["producer_project_id"] = config["producer_project_id"]
+			planResp, err := server.PlanResourceChange(ctx, &tfprotov6.PlanResourceChangeRequest{
+				TypeName:         "utils_service",
+				PriorState:       readResp.NewState,
+				ProposedNewState: testDynamicValue(t, typ, proposed),
+				Config:           testDynamicValue(t, typ, config),
+				PriorPrivate:     readResp.Private,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			requireNoErrors(t, planResp.Diagnostics)
+
+			// Terraform ignores replacement of attributes which are
+			// unchanged, so the planned value must differ from the state.
+			var plannedProject string
+			if err := testStateAttributes(t, typ, planResp.PlannedState)["producer_project_id"].As(&plannedProject); err != nil {
+				t.Fatal(err)
+			}
+			if changed := plannedProject != stateProject; changed != tt.wantReplace {
+				t.Errorf("got planned producer_project_id %q from %q, want change %v", plannedProject, stateProject, tt.wantReplace)
+			}
+
+			replace := false
+			for _, p := range planResp.RequiresReplace {
+				replace = replace || p.Equal(tftypes.NewAttributePath().WithAttributeName("producer_project_id"))
+			}
+			if replace != tt.wantReplace {
+				t.Errorf("got replacement %v, want %v", replace, tt.wantReplace)
+			}
+		})
+	}
+}
+
+func TestEnableServiceOperation(t *testing.T) {
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+	const serviceName = "test.endpoints.example.cloud.goog"
+	const enabledName = "projects/producer/services/" + serviceName
+
+	t.Run("done", func(t *testing.T) {
+		fake := newFakeServiceUsage()
+		fake.operationPolls = 2
+		p := &UtilsProviderConfig{ServiceUsageClient: newFakeServiceUsageClient(t, fake)}
+
+		if err := p.enableService(context.Background(), "producer", serviceName); err != nil {
+			t.Fatal(err)
+		}
+		if !fake.enabledServices[enabledName] {
+			t.Errorf("service %s was not enabled", enabledName)
+		}
+		if got := fake.operations["operations/enable-0"]; got != 2 {
+			t.Errorf("got %d polls, want 2", got)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		fake := newFakeServiceUsage()
+		fake.operationPolls = 1
+		fake.operationError = &serviceusage.Status{Code: int64(codes.PermissionDenied), Message: "fake failure"}
+		p := &UtilsProviderConfig{ServiceUsageClient: newFakeServiceUsageClient(t, fake)}
+
+		err := p.enableService(context.Background(), "producer", serviceName)
+		if err == nil || !strings.Contains(err.Error(), "operations/enable-0 failed: fake failure") {
+			t.Errorf("got error %v, want the operation error", err)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		operationPollBaseDelay = time.Millisecond
+		fake := newFakeServiceUsage()
+		fake.operationPolls = -1
+		p := &UtilsProviderConfig{ServiceUsageClient: newFakeServiceUsageClient(t, fake)}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		err := p.disableService(ctx, "producer", serviceName)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got error %v, want %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		defer func(timeout time.Duration) { serviceUsageOperationTimeout = timeout }(serviceUsageOperationTimeout)
+		serviceUsageOperationTimeout = 20 * time.Millisecond
+		operationPollBaseDelay = time.Millisecond
+		fake := newFakeServiceUsage()
+		fake.operationPolls = -1
+		p := &UtilsProviderConfig{ServiceUsageClient: newFakeServiceUsageClient(t, fake)}
+
+		err := p.enableService(context.Background(), "producer", serviceName)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func TestResourceServiceEnableFailure(t *testing.T) {
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	fake := newFakeServiceManager()
+	serviceUsage := newFakeServiceUsage()
+	serviceUsage.operationError = &serviceusage.Status{Code: int64(codes.PermissionDenied), Message: "fake failure"}
+	providerConfig := newFakeProviderConfig(t, fake)
+	providerConfig.ServiceUsageClient = newFakeServiceUsageClient(t, serviceUsage)
+	server, schemas := newFakeProviderServer(t, providerConfig)
+	typ := schemas.ResourceSchemas["utils_service"].ValueType()
+
+	config := testDynamicValue(t, typ, map[string]tftypes.Value{
+		"service_name":               tftypes.NewValue(tftypes.String, serviceName),
+		"producer_project_id":        tftypes.NewValue(tftypes.String, "producer"),
+		"enable_on_producer_project": tftypes.NewValue(tftypes.Bool, true),
+	})
+	priorState := testNullDynamicValue(t, typ)
+	planResp := testPlanResource(t, server, "utils_service", typ, priorState, nil, config)
+	applyResp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:     "utils_service",
+		PriorState:   priorState,
+		PlannedState: planResp.PlannedState,
+		Config:       config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The created service is kept without failing the apply, which would
+	// taint it.
+	requireNoErrors(t, applyResp.Diagnostics)
+	if len(applyResp.Diagnostics) != 1 || !strings.Contains(applyResp.Diagnostics[0].Detail, "fake failure") {
+		t.Errorf("got diagnostics %v, want a warning with the enable error", applyResp.Diagnostics)
+	}
+	if _, ok := fake.services[serviceName]; !ok {
+		t.Fatalf("service %s was not created", serviceName)
+	}
+
+	// The service is refreshed as not enabled, so the next apply enables it.
+	readResp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+		TypeName:     "utils_service",
+		CurrentState: applyResp.NewState,
+		Private:      applyResp.Private,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireNoErrors(t, readResp.Diagnostics)
+	if got := testStateAttributes(t, typ, readResp.NewState)["enable_on_producer_project"]; !got.Equal(tftypes.NewValue(tftypes.Bool, false)) {
+		t.Errorf("got enable_on_producer_project %v, want false", got)
+	}
+
+	serviceUsage.operationError = nil
+	planResp = testPlanResource(t, server, "utils_service", typ, readResp.NewState, readResp.Private, config)
+	if len(planResp.RequiresReplace) != 0 {
+		t.Errorf("got requires replace %v, want none", planResp.RequiresReplace)
+	}
+	applyResp, err = server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:       "utils_service",
+		PriorState:     readResp.NewState,
+		PlannedState:   planResp.PlannedState,
+		PlannedPrivate: planResp.PlannedPrivate,
+		Config:         config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireNoErrors(t, applyResp.Diagnostics)
+	if !serviceUsage.enabledServices["projects/producer/services/"+serviceName] {
+		t.Error("service was not enabled on the producer project")
+	}
+
+	readResp, err = server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+		TypeName:     "utils_service",
+		CurrentState: applyResp.NewState,
+		Private:      applyResp.Private,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := testStateAttributes(t, typ, readResp.NewState)["enable_on_producer_project"]; !got.Equal(tftypes.NewValue(tftypes.Bool, true)) {
+		t.Errorf("got enable_on_producer_project %v, want true", got)
+	}
+}
+
+func TestResourceServiceValidateProducerProject(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	for _, tt := range []struct {
+		name        string
+		missing     bool
+		exists      bool
+		apiEnabled  bool
+		wantError   string
+		wantCreated bool
+	}{
+		{name: "missing project", missing: true, wantError: "Producer project not found"},
+		{name: "permission denied", wantCreated: true},
+		{name: "api disabled", exists: true, wantError: "Service Management API not enabled"},
+		{name: "valid", exists: true, apiEnabled: true, wantCreated: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeServiceManager()
+			resourceManager := newFakeResourceManager()
+			if tt.missing {
+				resourceManager.missingProjects["producer"] = true
+			}
+			if tt.exists {
+				resourceManager.projectNumbers["producer"] = "123"
+			}
+			serviceUsage := newFakeServiceUsage()
+			if tt.apiEnabled {
+				serviceUsage.enabledServices["projects/producer/services/"+serviceManagementAPI] = true
+			}
+			providerConfig := newFakeProviderConfig(t, fake)
+			providerConfig.ResourceManagerClient = newFakeResourceManagerClient(t, resourceManager)
+			providerConfig.ServiceUsageClient = newFakeServiceUsageClient(t, serviceUsage)
+			server, schemas := newFakeProviderServer(t, providerConfig)
+			typ := schemas.ResourceSchemas["utils_service"].ValueType()
+
+			config := testDynamicValue(t, typ, map[string]tftypes.Value{
+				"service_name":              tftypes.NewValue(tftypes.String, serviceName),
+				"producer_project_id":       tftypes.NewValue(tftypes.String, "producer"),
+				"validate_producer_project": tftypes.NewValue(tftypes.Bool, true),
+			})
+			priorState := testNullDynamicValue(t, typ)
+			planResp := testPlanResource(t, server, "utils_service", typ, priorState, nil, config)
+			applyResp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+				TypeName:     "utils_service",
+				PriorState:   priorState,
+				PlannedState: planResp.PlannedState,
+				Config:       config,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantError == "" {
+				requireNoErrors(t, applyResp.Diagnostics)
+			} else if len(applyResp.Diagnostics) != 1 || applyResp.Diagnostics[0].Summary != tt.wantError {
+				t.Errorf("got diagnostics %v, want error %q", applyResp.Diagnostics, tt.wantError)
+			}
+			if _, ok := fake.services[serviceName]; ok != tt.wantCreated {
+				t.Errorf("got service created %t, want %t", ok, tt.wantCreated)
+			}
+		})
+	}
+}
+
+func TestResourceServiceActiveConfig(t *testing.T) {
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	fake := newFakeServiceManager()
+	fake.services[serviceName] = &servicemanagementpb.ManagedService{
+		ServiceName:       serviceName,
+		ProducerProjectId: "producer",
+	}
+	for _, configId := range []string{"config-1", "config-2", "config-3"} {
+		fake.configs[serviceName+"/"+configId] = &serviceconfig.Service{
+			Name:      serviceName,
+			Id:        configId,
+			Endpoints: []*serviceconfig.Endpoint{{Name: configId + ".example.com"}},
+			Apis:      []*apipb.Api{{Name: "example." + strings.ReplaceAll(configId, "-", "") + ".Api"}},
+		}
+	}
+	// The latest successful rollout is active, and its config with the most
+	// traffic is used. The later failed rollout is ignored.
+	for _, rollout := range []struct {
+		id          string
+		status      servicemanagementpb.Rollout_RolloutStatus
+		percentages map[string]float64
+	}{
+		{id: "2024-01-01r0", status: servicemanagementpb.Rollout_SUCCESS, percentages: map[string]float64{"config-1": 100}},
+		{id: "2024-01-02r0", status: servicemanagementpb.Rollout_SUCCESS, percentages: map[string]float64{"config-1": 10, "config-2": 90}},
+		{id: "2024-01-03r0", status: servicemanagementpb.Rollout_FAILED, percentages: map[string]float64{"config-3": 100}},
+	} {
+		fake.rollouts[serviceName+"/"+rollout.id] = &servicemanagementpb.Rollout{
+			RolloutId:   rollout.id,
+			ServiceName: serviceName,
+			Status:      rollout.status,
+			Strategy: &servicemanagementpb.Rollout_TrafficPercentStrategy_{
+				TrafficPercentStrategy: &servicemanagementpb.Rollout_TrafficPercentStrategy{Percentages: rollout.percentages},
+			},
+		}
+	}
+	server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+	typ := schemas.ResourceSchemas["utils_service"].ValueType()
+
+	readResp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+		TypeName: "utils_service",
+		CurrentState: testDynamicValue(t, typ, map[string]tftypes.Value{
+			"service_name":        tftypes.NewValue(tftypes.String, serviceName),
+			"producer_project_id": tftypes.NewValue(tftypes.String, "producer"),
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireNoErrors(t, readResp.Diagnostics)
+
+	listType := tftypes.List{ElementType: tftypes.String}
+	attributes := testStateAttributes(t, typ, readResp.NewState)
+	for name, want := range map[string]tftypes.Value{
+		"endpoints": tftypes.NewValue(listType, []tftypes.Value{tftypes.NewValue(tftypes.String, "config-2.example.com")}),
+		"apis":      tftypes.NewValue(listType, []tftypes.Value{tftypes.NewValue(tftypes.String, "example.config2.Api")}),
+	} {
+		if got := attributes[name]; !got.Equal(want) {
+			t.Errorf("got %s %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestResourceServiceActiveConfigLookupFailure(t *testing.T) {
+	delay := readRetryBaseDelay
+	t.Cleanup(func() { readRetryBaseDelay = delay })
+	readRetryBaseDelay = 0
+	ctx := context.Background()
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	for _, tt := range []struct {
+		name          string
+		failures      []codes.Code
+		wantCalls     int
+		wantWarning   bool
+		wantEndpoints []string
+	}{
+		{name: "transient", failures: []codes.Code{codes.Unavailable}, wantCalls: 2, wantEndpoints: []string{}},
+		{name: "permission denied", failures: []codes.Code{codes.PermissionDenied}, wantCalls: 1, wantWarning: true, wantEndpoints: []string{"old.example.com"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeServiceManager()
+			fake.services[serviceName] = &servicemanagementpb.ManagedService{
+				ServiceName:       serviceName,
+				ProducerProjectId: "producer",
+			}
+			fake.listRolloutsFailures = tt.failures
+			server, schemas := newFakeProviderServer(t, newFakeProviderConfig(t, fake))
+			typ := schemas.ResourceSchemas["utils_service"].ValueType()
+
+			listType := tftypes.List{ElementType: tftypes.String}
+			readResp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+				TypeName: "utils_service",
+				CurrentState: testDynamicValue(t, typ, map[string]tftypes.Value{
+					"service_name":        tftypes.NewValue(tftypes.String, serviceName),
+					"producer_project_id": tftypes.NewValue(tftypes.String, "producer"),
+					"endpoints":           tftypes.NewValue(listType, []tftypes.Value{tftypes.NewValue(tftypes.String, "old.example.com")}),
+					"apis":                tftypes.NewValue(listType, []tftypes.Value{}),
+				}),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			requireNoErrors(t, readResp.Diagnostics)
+
+			var warned bool
+			for _, d := range readResp.Diagnostics {
+				warned = warned || d.Summary == "Could not retrieve active service config"
+			}
+			if warned != tt.wantWarning {
+				t.Errorf("got warning %v, want %v", warned, tt.wantWarning)
+			}
+			if fake.listRolloutsCalls != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", fake.listRolloutsCalls, tt.wantCalls)
+			}
+
+			var endpoints []tftypes.Value
+			if err := testStateAttributes(t, typ, readResp.NewState)["endpoints"].As(&endpoints); err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, len(endpoints))
+			for i, endpoint := range endpoints {
+				if err := endpoint.As(&got[i]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantEndpoints, ",") {
+				t.Errorf("got endpoints %v, want %v", got, tt.wantEndpoints)
+			}
+		})
+	}
+}
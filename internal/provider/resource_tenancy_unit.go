@@ -2,26 +2,56 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"google.golang.org/api/serviceconsumermanagement/v1"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &ServiceTenancyUnitResource{}
 var _ resource.ResourceWithImportState = &ServiceTenancyUnitResource{}
+var _ resource.ResourceWithUpgradeState = &ServiceTenancyUnitResource{}
+var _ resource.ResourceWithModifyPlan = &ServiceTenancyUnitResource{}
+
+// tenancyUnitCreatedKey is the private state key which holds the time a
+// tenancy unit was created by the provider, until tenancyUnitConsistencyWindow
+// has passed.
+const tenancyUnitCreatedKey = "created"
+
+// tenancyUnitConsumerKey is the private state key which holds the
+// `projects/{project_number}` form of the consumer in state, so that a
+// consumer given by project ID is not resolved again on every refresh.
+const tenancyUnitConsumerKey = "consumer"
+
+// tenancyUnitConsistencyWindow is how long after creating a tenancy unit Read
+// waits for it to be listed, rather than removing it from state.
+const tenancyUnitConsistencyWindow = 5 * time.Minute
+
+// tenancyUnitVisibleTimeout is how long Create waits for a new tenancy unit to
+// be listed.
+const tenancyUnitVisibleTimeout = time.Minute
 
 func NewServiceTenancyUnitResource() resource.Resource {
 	return &ServiceTenancyUnitResource{}
@@ -34,9 +64,31 @@ type ServiceTenancyUnitResource struct {
 
 // ServiceTenancyUnitModel describes the resource data model.
 type ServiceTenancyUnitModel struct {
-	ID          types.String `tfsdk:"id"`
-	ServiceName types.String `tfsdk:"service_name"`
-	Consumer    types.String `tfsdk:"consumer"`
+	ID                 types.String     `tfsdk:"id"`
+	TenancyUnitId      types.String     `tfsdk:"tenancy_unit_id"`
+	ServiceName        ServiceNameValue `tfsdk:"service_name"`
+	Consumer           types.String     `tfsdk:"consumer"`
+	AdoptExisting      types.Bool       `tfsdk:"adopt_existing"`
+	ForceDelete        types.Bool       `tfsdk:"force_delete"`
+	DeletionProtection types.Bool       `tfsdk:"deletion_protection"`
+	CreateTime         types.String     `tfsdk:"create_time"`
+	TenantResources    types.List       `tfsdk:"tenant_resources"`
+	TenantProjects     types.Map        `tfsdk:"tenant_projects"`
+}
+
+// TenantResourceModel describes a resource held by a tenancy unit.
+type TenantResourceModel struct {
+	Tag      types.String `tfsdk:"tag"`
+	Resource types.String `tfsdk:"resource"`
+	Status   types.String `tfsdk:"status"`
+}
+
+func (TenantResourceModel) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"tag":      types.StringType,
+		"resource": types.StringType,
+		"status":   types.StringType,
+	}
 }
 
 func (r *ServiceTenancyUnitResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -47,28 +99,104 @@ func (r *ServiceTenancyUnitResource) Schema(ctx context.Context, req resource.Sc
 	resp.Schema = schema.Schema{
 		// This description is used by the documentation generator and the language server.
 		MarkdownDescription: "A tenancy unit in a Service Manager service.",
+		Version:             1,
 
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
-				MarkdownDescription: "The ID of the tenancy unit.",
+				MarkdownDescription: "The name of the tenancy unit, `services/{service}/{consumer}/tenancyUnits/{tenancy_unit_id}`.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"tenancy_unit_id": schema.StringAttribute{
+				MarkdownDescription: "The ID of the tenancy unit, unique within the service. At most 40 letters, digits, `-`, `.`, `_` or `~`. Generated by the API if unset. Changing it replaces the tenancy unit.",
 				Optional:            true,
 				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplaceIfConfigured(),
+				},
+				Validators: []validator.String{
+					stringvalidator.LengthBetween(1, 40),
+					stringvalidator.RegexMatches(regexp.MustCompile(`^[A-Za-z0-9._~-]+$`), "Tenancy unit ID must only contain letters, digits, `-`, `.`, `_` or `~`"),
+				},
 			},
 			"service_name": schema.StringAttribute{
-				MarkdownDescription: "The name of the service.",
+				MarkdownDescription: "The name of the service, with or without the `services/` prefix. Changing it to another service replaces the tenancy unit.",
+				CustomType:          ServiceNameType{},
 				Required:            true,
 				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplaceIfConfigured(),
+					stringplanmodifier.RequiresReplaceIf(
+						func(ctx context.Context, req planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+							resp.RequiresReplace = normalizeServiceName(req.PlanValue.ValueString()) != normalizeServiceName(req.StateValue.ValueString())
+						},
+						"Changing the service replaces the tenancy unit.",
+						"Changing the service replaces the tenancy unit.",
+					),
 				},
 			},
 			"consumer": schema.StringAttribute{
-				MarkdownDescription: "The consumer's ID, for example `projects/{project_number}`.",
+				MarkdownDescription: "The consumer's ID, `projects/{project_number}` or `projects/{project_id}`. Project IDs are resolved to project numbers when applying, which requires the `resourcemanager.projects.get` permission. Changing it to another project replaces the tenancy unit.",
 				Required:            true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(consumerPattern, "Consumer must be `projects/{project_number}` or `projects/{project_id}`"),
+				},
+			},
+			"adopt_existing": schema.BoolAttribute{
+				MarkdownDescription: "Whether to adopt the tenancy unit given by `tenancy_unit_id` if it already exists, for example after an interrupted apply, instead of failing. The existing unit must belong to `service_name` and `consumer`. Ignored if `tenancy_unit_id` is not set. Defaults to `false`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"force_delete": schema.BoolAttribute{
+				MarkdownDescription: "Whether to remove the `ACTIVE` tenant projects of the tenancy unit when deleting it. Otherwise, deleting a tenancy unit which still holds tenant projects fails. `FAILED` tenant projects, which cannot be removed, are always deleted. Defaults to `false`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"deletion_protection": schema.BoolAttribute{
+				MarkdownDescription: "Whether Terraform is prevented from destroying the tenancy unit, which would orphan its tenant projects. Set it to `false` and apply before destroying the tenancy unit. Defaults to `true`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+			},
+			"create_time": schema.StringAttribute{
+				MarkdownDescription: "The time the tenancy unit was created.",
+				Computed:            true,
 				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplaceIfConfigured(),
+					stringplanmodifier.UseStateForUnknown(),
 				},
-				Validators: []validator.String{
-					stringvalidator.RegexMatches(regexp.MustCompile(`^projects/\d+$`), "Consumer must be `projects/{project_number}`"),
+			},
+			"tenant_resources": schema.ListNestedAttribute{
+				MarkdownDescription: "The resources held by the tenancy unit, ordered by tag.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"tag": schema.StringAttribute{
+							MarkdownDescription: "The tag of the resource, unique within the tenancy unit.",
+							Computed:            true,
+						},
+						"resource": schema.StringAttribute{
+							MarkdownDescription: "The resource, for example `projects/{project_number}`.",
+							Computed:            true,
+						},
+						"status": schema.StringAttribute{
+							MarkdownDescription: "The status of the resource, for example `ACTIVE`.",
+							Computed:            true,
+						},
+					},
+				},
+			},
+			"tenant_projects": schema.MapAttribute{
+				MarkdownDescription: "The `ACTIVE` tenant projects of the tenancy unit, as `projects/{project_number}`, keyed by tag.",
+				Computed:            true,
+				ElementType:         types.StringType,
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.UseStateForUnknown(),
 				},
 			},
 		},
@@ -95,6 +223,40 @@ func (r *ServiceTenancyUnitResource) Configure(ctx context.Context, req resource
 	r.ServiceManagerClient = clients.ServiceManagerClient
 	r.TenantClient = clients.TenantClient
 	r.OperationsClient = clients.OperationsClient
+	r.ResourceManagerClient = clients.ResourceManagerClient
+}
+
+func (r *ServiceTenancyUnitResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to do on create or destroy.
+	if req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan, state ServiceTenancyUnitModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if plan.Consumer.Equal(state.Consumer) {
+		return
+	}
+
+	// Switching between the project ID and number of the same project does
+	// not replace the tenancy unit.
+	if !plan.Consumer.IsUnknown() && !state.Consumer.IsNull() {
+		same, err := r.sameConsumer(ctx, req.Private, plan.Consumer.ValueString(), state.Consumer.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("consumer"), "Error resolving consumer", err.Error())
+			return
+		}
+		if same {
+			return
+		}
+	}
+	resp.RequiresReplace.Append(path.Root("consumer"))
 }
 
 func (r *ServiceTenancyUnitResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -107,21 +269,51 @@ func (r *ServiceTenancyUnitResource) Create(ctx context.Context, req resource.Cr
 		return
 	}
 
-	var id string
-	if !data.ID.IsUnknown() && !data.ID.IsNull() {
-		id = data.ID.ValueString()
+	consumer, err := r.resolveConsumer(ctx, data.Consumer.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("consumer"), "Error resolving consumer", err.Error())
+		return
 	}
 
-	parent := fmt.Sprintf("services/%s/%s", data.ServiceName.ValueString(), data.Consumer.ValueString())
+	parent := tenancyUnitParent(data.ServiceName.ValueString(), consumer)
 	tenancyUnit, err := r.TenantClient.Services.TenancyUnits.Create(parent, &serviceconsumermanagement.CreateTenancyUnitRequest{
-		TenancyUnitId: id,
+		TenancyUnitId: data.TenancyUnitId.ValueString(),
 	}).Context(ctx).Do()
+	if isGoogleAPIErrorCode(err, http.StatusConflict) && data.AdoptExisting.ValueBool() && data.TenancyUnitId.ValueString() != "" {
+		tenancyUnit, err = r.adoptTenancyUnit(ctx, parent, consumer, &data, &resp.Diagnostics)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
 	if err != nil {
 		resp.Diagnostics.AddError("Error creating tenancy unit", err.Error())
 		return
 	}
 
-	data.ID = types.StringValue(tenancyUnit.Name)
+	// Dependent resources look the unit up by listing the units of the
+	// consumer, so wait until it is listed.
+	visible, err := r.getCreatedTenancyUnit(ctx, tenancyUnit.Name, tenancyUnitVisibleTimeout)
+	if err != nil {
+		resp.Diagnostics.AddError("Error getting tenancy unit", err.Error())
+		return
+	}
+	if visible != nil {
+		tenancyUnit = visible
+	} else {
+		resp.Diagnostics.AddWarning(
+			"Tenancy unit not visible yet",
+			fmt.Sprintf("Tenancy unit %s was created, but is not listed by the API yet. Resources which depend on it may fail until it is.", tenancyUnit.Name),
+		)
+	}
+
+	resp.Diagnostics.Append(data.setTenancyUnit(ctx, tenancyUnit)...)
+	createdAt, _ := json.Marshal(time.Now())
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, tenancyUnitCreatedKey, createdAt)...)
+	resp.Diagnostics.Append(setResolvedConsumer(ctx, resp.Private, data.Consumer.ValueString(), consumer)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
 	// Write the updated model back to Terraform
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
@@ -137,25 +329,121 @@ func (r *ServiceTenancyUnitResource) Read(ctx context.Context, req resource.Read
 		return
 	}
 
-	tenancyUnit, err := r.getTenancyUnit(ctx, data.ID.ValueString())
+	createdAt, diags := req.Private.GetKey(ctx, tenancyUnitCreatedKey)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// A unit which was created recently may not be listed yet.
+	var created time.Time
+	if len(createdAt) > 0 {
+		_ = json.Unmarshal(createdAt, &created)
+	}
+	var tenancyUnit *serviceconsumermanagement.TenancyUnit
+	var err error
+	if remaining := tenancyUnitConsistencyWindow - time.Since(created); remaining > 0 {
+		tenancyUnit, err = r.getCreatedTenancyUnit(ctx, data.ID.ValueString(), min(remaining, tenancyUnitVisibleTimeout))
+	} else {
+		tenancyUnit, err = r.getTenancyUnit(ctx, data.ID.ValueString())
+		if len(createdAt) > 0 {
+			resp.Diagnostics.Append(resp.Private.SetKey(ctx, tenancyUnitCreatedKey, nil)...)
+		}
+	}
 	if err != nil {
 		resp.Diagnostics.AddError("Error getting tenancy unit", err.Error())
 		return
 	}
 
 	if tenancyUnit == nil {
+		tflog.Info(ctx, "Tenancy unit not found, removing from state", map[string]interface{}{
+			"id": data.ID.ValueString(),
+		})
+		resp.State.RemoveResource(ctx)
 		return
 	}
 
-	data.ID = types.StringValue(tenancyUnit.Name)
-	data.ServiceName = types.StringValue(tenancyUnit.Service)
-	data.Consumer = types.StringValue(tenancyUnit.Consumer)
+	// Keep the spelling of the configured service and consumer unless the
+	// unit now belongs to another one. Storing the actual service or consumer
+	// then makes the plan replace the unit, rather than re-point it.
+	if data.ServiceName.IsNull() {
+		data.ServiceName = NewServiceNameValue(tenancyUnit.Service)
+	} else if actual := tenancyUnit.Service; actual != data.ServiceName.ServiceName() {
+		resp.Diagnostics.AddAttributeWarning(
+			path.Root("service_name"),
+			"Tenancy unit service has changed",
+			fmt.Sprintf("Tenancy unit %s belongs to service %q, but %q is configured. The tenancy unit will be replaced.", tenancyUnit.Name, actual, data.ServiceName.ServiceName()),
+		)
+		data.ServiceName = NewServiceNameValue(actual)
+	}
+	if data.Consumer.IsNull() {
+		data.Consumer = types.StringValue(tenancyUnit.Consumer)
+	}
+	consumer, err := r.consumerNumber(ctx, req.Private, data.Consumer.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("consumer"), "Error resolving consumer", err.Error())
+		return
+	}
+	if actual := tenancyUnit.Consumer; actual != consumer {
+		resp.Diagnostics.AddAttributeWarning(
+			path.Root("consumer"),
+			"Tenancy unit consumer has changed",
+			fmt.Sprintf("Tenancy unit %s belongs to consumer %q, but %q is configured. The tenancy unit will be replaced.", tenancyUnit.Name, actual, data.Consumer.ValueString()),
+		)
+		data.Consumer = types.StringValue(actual)
+		consumer = actual
+	}
+	resp.Diagnostics.Append(setResolvedConsumer(ctx, resp.Private, data.Consumer.ValueString(), consumer)...)
+	resp.Diagnostics.Append(data.setTenancyUnit(ctx, tenancyUnit)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *ServiceTenancyUnitResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	panic("Updating a service is not supported")
+	var data, state ServiceTenancyUnitModel
+
+	// Read Terraform plan and prior state data into the models
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Every attribute of a tenancy unit other than `adopt_existing`,
+	// `force_delete` and `deletion_protection`, which only affect Create and
+	// Delete, requires replacement, except for switching between spellings of
+	// the service or the ID and number of the consumer project.
+	consumer, err := r.consumerNumber(ctx, req.Private, data.Consumer.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("consumer"), "Error resolving consumer", err.Error())
+		return
+	}
+	stateConsumer, err := r.consumerNumber(ctx, req.Private, state.Consumer.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("consumer"), "Error resolving consumer", err.Error())
+		return
+	}
+	if data.ServiceName.ServiceName() != state.ServiceName.ServiceName() || consumer != stateConsumer || !data.TenancyUnitId.Equal(state.TenancyUnitId) {
+		resp.Diagnostics.AddError(
+			"Error updating tenancy unit",
+			"Tenancy units cannot be updated in place. Please report this issue to the provider developers.",
+		)
+		return
+	}
+
+	state.ServiceName = data.ServiceName
+	state.Consumer = data.Consumer
+	state.AdoptExisting = data.AdoptExisting
+	state.ForceDelete = data.ForceDelete
+	state.DeletionProtection = data.DeletionProtection
+	resp.Diagnostics.Append(setResolvedConsumer(ctx, resp.Private, data.Consumer.ValueString(), consumer)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
 func (r *ServiceTenancyUnitResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
@@ -168,34 +456,408 @@ func (r *ServiceTenancyUnitResource) Delete(ctx context.Context, req resource.De
 		return
 	}
 
-	_, err := r.TenantClient.Services.TenancyUnits.Delete(data.ID.ValueString()).Context(ctx).Do()
+	id := data.ID.ValueString()
+	if data.DeletionProtection.ValueBool() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("deletion_protection"),
+			"Cannot destroy tenancy unit with deletion protection",
+			fmt.Sprintf("Tenancy unit %s has `deletion_protection` set to `true`. Set it to `false` and apply before destroying the tenancy unit.", id),
+		)
+		return
+	}
+
+	if data.ForceDelete.ValueBool() {
+		if err := r.removeTenantResources(ctx, id); err != nil {
+			resp.Diagnostics.AddError("Error removing tenant projects", err.Error())
+			return
+		}
+	}
+
+	err := r.deleteTenancyUnit(ctx, id)
+	if err != nil {
+		// Tenant projects which failed to be created cannot be removed, and
+		// must be deleted before the unit can be.
+		purged, purgeErr := r.purgeFailedTenantResources(ctx, id)
+		if purgeErr != nil {
+			resp.Diagnostics.AddError("Error deleting failed tenant projects", purgeErr.Error())
+			return
+		}
+		if len(purged) > 0 {
+			resp.Diagnostics.AddWarning(
+				"Deleted failed tenant projects",
+				fmt.Sprintf("Deleted the FAILED tenant projects with tags %s, which prevented tenancy unit %s from being deleted.", strings.Join(purged, ", "), id),
+			)
+			err = r.deleteTenancyUnit(ctx, id)
+		}
+	}
 	if err != nil {
-		resp.Diagnostics.AddError("Error deleting tenancy unit", err.Error())
+		detail := err.Error()
+		if tags := r.blockingTenantResourceTags(ctx, id); len(tags) > 0 {
+			detail = fmt.Sprintf("Tenancy unit %s still holds tenant resources with tags %s. Remove them first, or set `force_delete` to `true` and apply before destroying the tenancy unit.\n\n%s", id, strings.Join(tags, ", "), detail)
+		}
+		resp.Diagnostics.AddError("Error deleting tenancy unit", detail)
 		return
 	}
 }
 
 func (r *ServiceTenancyUnitResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	serviceName, consumer, unitId, err := parseTenancyUnitName(req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid tenancy unit ID", err.Error())
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("service_name"), serviceName)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("consumer"), consumer)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("tenancy_unit_id"), unitId)...)
+	// Defaults are not applied to imported state.
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("adopt_existing"), false)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("force_delete"), false)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("deletion_protection"), true)...)
+}
+
+// sameConsumer reports whether the consumers a and b, each given as
+// `projects/{project_number}` or `projects/{project_id}`, are the same
+// project.
+func (r *ServiceTenancyUnitResource) sameConsumer(ctx context.Context, private privateStateGetter, a, b string) (bool, error) {
+	if a == b {
+		return true, nil
+	}
+	a, err := r.consumerNumber(ctx, private, a)
+	if err != nil {
+		return false, err
+	}
+	b, err = r.consumerNumber(ctx, private, b)
+	if err != nil {
+		return false, err
+	}
+	return a == b, nil
+}
+
+// resolvedConsumer is the value of tenancyUnitConsumerKey.
+type resolvedConsumer struct {
+	Consumer string `json:"consumer"`
+	Number   string `json:"number"`
+}
+
+// privateStateGetter is implemented by the private state of requests.
+type privateStateGetter interface {
+	GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+}
+
+// privateStateSetter is implemented by the private state of responses.
+type privateStateSetter interface {
+	SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics
+}
+
+// consumerNumber returns the `projects/{project_number}` form of consumer,
+// which is only resolved if private does not hold it.
+func (r *ServiceTenancyUnitResource) consumerNumber(ctx context.Context, private privateStateGetter, consumer string) (string, error) {
+	if value, diags := private.GetKey(ctx, tenancyUnitConsumerKey); !diags.HasError() && len(value) > 0 {
+		var resolved resolvedConsumer
+		if err := json.Unmarshal(value, &resolved); err == nil && resolved.Consumer == consumer {
+			return resolved.Number, nil
+		}
+	}
+	return r.resolveConsumer(ctx, consumer)
+}
+
+// setResolvedConsumer records in private that consumer is the project
+// `number`, given as `projects/{project_number}`.
+func setResolvedConsumer(ctx context.Context, private privateStateSetter, consumer, number string) diag.Diagnostics {
+	value, _ := json.Marshal(resolvedConsumer{Consumer: consumer, Number: number})
+	return private.SetKey(ctx, tenancyUnitConsumerKey, value)
+}
+
+// deleteTenancyUnit deletes the tenancy unit named id and waits for the
+// deletion to complete.
+func (r *ServiceTenancyUnitResource) deleteTenancyUnit(ctx context.Context, id string) error {
+	op, err := r.TenantClient.Services.TenancyUnits.Delete(id).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	return r.waitTenantOperation(ctx, op)
+}
+
+// purgeFailedTenantResources deletes the `FAILED` tenant resources of the
+// tenancy unit named id, waiting for each deletion to complete, and returns
+// their sorted tags.
+func (r *ServiceTenancyUnitResource) purgeFailedTenantResources(ctx context.Context, id string) ([]string, error) {
+	tenancyUnit, err := r.getTenancyUnit(ctx, id)
+	if err != nil || tenancyUnit == nil {
+		return nil, err
+	}
+	var purged []string
+	for _, tenantResource := range tenancyUnit.TenantResources {
+		if tenantResource.Status != "FAILED" {
+			continue
+		}
+		tflog.Info(ctx, "Deleting failed tenant project", map[string]interface{}{
+			"tenancy_unit": id,
+			"tag":          tenantResource.Tag,
+			"resource":     tenantResource.Resource,
+		})
+		op, err := r.TenantClient.Services.TenancyUnits.DeleteProject(id, &serviceconsumermanagement.DeleteTenantProjectRequest{
+			Tag: tenantResource.Tag,
+		}).Context(ctx).Do()
+		if err == nil {
+			err = r.waitTenantOperation(ctx, op)
+		}
+		if err != nil {
+			return purged, fmt.Errorf("could not delete tenant project with tag %q: %w", tenantResource.Tag, err)
+		}
+		purged = append(purged, tenantResource.Tag)
+	}
+	slices.Sort(purged)
+	return purged, nil
+}
+
+// removeTenantResources removes the `ACTIVE` tenant resources of the tenancy
+// unit named id, waiting for each removal to complete. `FAILED` resources
+// cannot be removed and are purged by purgeFailedTenantResources instead.
+func (r *ServiceTenancyUnitResource) removeTenantResources(ctx context.Context, id string) error {
+	tenancyUnit, err := r.getTenancyUnit(ctx, id)
+	if err != nil || tenancyUnit == nil {
+		return err
+	}
+	for _, tenantResource := range tenancyUnit.TenantResources {
+		if tenantResource.Status != "ACTIVE" {
+			continue
+		}
+		tflog.Info(ctx, "Removing tenant project", map[string]interface{}{
+			"tenancy_unit": id,
+			"tag":          tenantResource.Tag,
+			"resource":     tenantResource.Resource,
+		})
+		op, err := r.TenantClient.Services.TenancyUnits.RemoveProject(id, &serviceconsumermanagement.RemoveTenantProjectRequest{
+			Tag: tenantResource.Tag,
+		}).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("could not remove tenant project with tag %q: %w", tenantResource.Tag, err)
+		}
+		if err := r.waitTenantOperation(ctx, op); err != nil {
+			return fmt.Errorf("could not remove tenant project with tag %q: %w", tenantResource.Tag, err)
+		}
+	}
+	return nil
+}
+
+// blockingTenantResourceTags returns the sorted tags of the tenant resources
+// which prevent the tenancy unit named id from being deleted. Errors are
+// ignored, since it is only used to explain a failed deletion.
+func (r *ServiceTenancyUnitResource) blockingTenantResourceTags(ctx context.Context, id string) []string {
+	tenancyUnit, err := r.getTenancyUnit(ctx, id)
+	if err != nil || tenancyUnit == nil {
+		return nil
+	}
+	var tags []string
+	for _, tenantResource := range tenancyUnit.TenantResources {
+		if tenantResource.Status != "DELETED" {
+			tags = append(tags, tenantResource.Tag)
+		}
+	}
+	slices.Sort(tags)
+	return tags
+}
+
+// adoptTenancyUnit returns the existing tenancy unit of parent given by
+// `tenancy_unit_id`, after Create reported that it already exists. It adds an
+// error to diags if the unit does not belong to the planned service and
+// consumer.
+func (r *ServiceTenancyUnitResource) adoptTenancyUnit(ctx context.Context, parent, consumer string, data *ServiceTenancyUnitModel, diags *diag.Diagnostics) (*serviceconsumermanagement.TenancyUnit, error) {
+	name := parent + "/tenancyUnits/" + data.TenancyUnitId.ValueString()
+	tflog.Debug(ctx, "Tenancy unit already exists, adopting it", map[string]interface{}{
+		"name": name,
+	})
+	tenancyUnit, err := r.getTenancyUnit(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if tenancyUnit == nil {
+		diags.AddAttributeError(
+			path.Root("tenancy_unit_id"),
+			"Error adopting tenancy unit",
+			fmt.Sprintf("Tenancy unit %q already exists, but not for consumer %s. Either the ID is used by another consumer of the service, or the consumer already has a tenancy unit with another ID.", data.TenancyUnitId.ValueString(), consumer),
+		)
+		return nil, nil
+	}
+	if tenancyUnit.Service != data.ServiceName.ServiceName() || tenancyUnit.Consumer != consumer {
+		diags.AddAttributeError(
+			path.Root("tenancy_unit_id"),
+			"Error adopting tenancy unit",
+			fmt.Sprintf("Tenancy unit %s belongs to service %s and consumer %s, not service %s and consumer %s.", tenancyUnit.Name, tenancyUnit.Service, tenancyUnit.Consumer, data.ServiceName.ServiceName(), consumer),
+		)
+		return nil, nil
+	}
+	return tenancyUnit, nil
+}
+
+// UpgradeState implements resource.ResourceWithUpgradeState.
+func (r *ServiceTenancyUnitResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		// Version 1 made `id` computed and moved the requested ID to
+		// `tenancy_unit_id`.
+		0: {
+			StateUpgrader: upgradeTenancyUnitIdState,
+		},
+	}
+}
+
+// upgradeTenancyUnitIdState derives `tenancy_unit_id` from the name stored in
+// `id` in raw state.
+func upgradeTenancyUnitIdState(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal(req.RawState.JSON, &state); err != nil {
+		resp.Diagnostics.AddError("Could not upgrade state", err.Error())
+		return
+	}
+	var name string
+	if err := json.Unmarshal(state["id"], &name); err != nil {
+		resp.Diagnostics.AddError("Could not upgrade state", fmt.Sprintf("Invalid tenancy unit ID: %v", err))
+		return
+	}
+	unitId, err := json.Marshal(tenancyUnitId(name))
+	if err != nil {
+		resp.Diagnostics.AddError("Could not upgrade state", err.Error())
+		return
+	}
+	state["tenancy_unit_id"] = unitId
+	upgraded, err := json.Marshal(state)
+	if err != nil {
+		resp.Diagnostics.AddError("Could not upgrade state", err.Error())
+		return
+	}
+	resp.DynamicValue = &tfprotov6.DynamicValue{JSON: upgraded}
+}
+
+// tenancyUnitNamePattern matches tenancy unit names,
+// `services/{service}/{collection}/{consumer_id}/tenancyUnits/{tenancy_unit_id}`.
+var tenancyUnitNamePattern = regexp.MustCompile(`^services/([^/]+)/([^/]+/[^/]+)/tenancyUnits/([^/]+)$`)
+
+// parseTenancyUnitName returns the service, consumer and ID of the tenancy
+// unit with the given name.
+func parseTenancyUnitName(name string) (string, string, string, error) {
+	match := tenancyUnitNamePattern.FindStringSubmatch(name)
+	if match == nil {
+		return "", "", "", fmt.Errorf("tenancy unit ID %q must be in the format `services/{service}/{collection}/{consumer_id}/tenancyUnits/{tenancy_unit_id}`", name)
+	}
+	return match[1], match[2], match[3], nil
+}
+
+// tenancyUnitParent returns the parent of the tenancy units of consumer, given
+// as `projects/{project_number}`, in the service, given with or without the
+// `services/` prefix.
+func tenancyUnitParent(serviceName, consumer string) string {
+	return fmt.Sprintf("services/%s/%s", normalizeServiceName(serviceName), consumer)
+}
+
+// tenancyUnitId returns the ID of the tenancy unit with the given name,
+// `services/{service}/{consumer}/tenancyUnits/{tenancy_unit_id}`.
+func tenancyUnitId(name string) string {
+	_, unitId, _ := strings.Cut(name, "/tenancyUnits/")
+	return unitId
+}
+
+// setTenancyUnit sets the computed attributes of data from tenancyUnit.
+func (data *ServiceTenancyUnitModel) setTenancyUnit(ctx context.Context, tenancyUnit *serviceconsumermanagement.TenancyUnit) diag.Diagnostics {
+	data.ID = types.StringValue(tenancyUnit.Name)
+	data.TenancyUnitId = types.StringValue(tenancyUnitId(tenancyUnit.Name))
+	data.CreateTime = types.StringNull()
+	if tenancyUnit.CreateTime != "" {
+		data.CreateTime = types.StringValue(tenancyUnit.CreateTime)
+	}
+
+	// Sort by tag so that the order returned by the API does not cause
+	// spurious diffs.
+	resources := make([]TenantResourceModel, 0, len(tenancyUnit.TenantResources))
+	projects := make(map[string]string)
+	for _, r := range tenancyUnit.TenantResources {
+		resources = append(resources, TenantResourceModel{
+			Tag:      types.StringValue(r.Tag),
+			Resource: types.StringValue(r.Resource),
+			Status:   types.StringValue(r.Status),
+		})
+		if r.Status == "ACTIVE" && strings.HasPrefix(r.Resource, "projects/") {
+			projects[r.Tag] = r.Resource
+		}
+	}
+	slices.SortFunc(resources, func(a, b TenantResourceModel) int {
+		return strings.Compare(a.Tag.ValueString(), b.Tag.ValueString())
+	})
+
+	var diags, d diag.Diagnostics
+	data.TenantResources, d = types.ListValueFrom(ctx, types.ObjectType{AttrTypes: TenantResourceModel{}.AttributeTypes()}, resources)
+	diags.Append(d...)
+	data.TenantProjects, d = types.MapValueFrom(ctx, types.StringType, projects)
+	diags.Append(d...)
+	return diags
 }
 
+// getTenancyUnit returns the tenancy unit named id, or nil if it does not
+// exist.
+//
+// The tenancy units of the parent are filtered by the ID of the unit, so that
+// the lookup returns at most one page. If the filter is rejected, all
+// tenancy units of the parent are scanned instead.
 func (p *UtilsProviderConfig) getTenancyUnit(ctx context.Context, id string) (*serviceconsumermanagement.TenancyUnit, error) {
-	parent := strings.Split(id, "/tenancyUnits/")[0]
-	tenancyUnits, err := p.TenantClient.Services.TenancyUnits.List(parent).Context(ctx).Do()
+	parent, _, _ := strings.Cut(id, "/tenancyUnits/")
+	tenancyUnit, err := p.findTenancyUnit(ctx, parent, id, fmt.Sprintf("name:%q", tenancyUnitId(id)))
+	if isGoogleAPIErrorCode(err, http.StatusBadRequest) {
+		tflog.Debug(ctx, "Tenancy unit filter rejected, listing all tenancy units", map[string]interface{}{
+			"parent": parent,
+			"error":  err.Error(),
+		})
+		tenancyUnit, err = p.findTenancyUnit(ctx, parent, id, "")
+	}
 	if err != nil {
-		if err, ok := status.FromError(err); ok && (err.Code() == codes.NotFound || strings.Contains(err.String(), "not found")) {
+		if isNotFound(err) {
 			return nil, nil
 		}
 		return nil, err
 	}
+	return tenancyUnit, nil
+}
 
-	var tenancyUnit *serviceconsumermanagement.TenancyUnit
-	for _, tu := range tenancyUnits.TenancyUnits {
-		if strings.EqualFold(tu.Name, id) {
-			tenancyUnit = tu
-			break
+// getCreatedTenancyUnit is like getTenancyUnit, but retries for up to timeout
+// while the tenancy unit, which has just been created, is not listed yet.
+func (p *UtilsProviderConfig) getCreatedTenancyUnit(ctx context.Context, id string, timeout time.Duration) (*serviceconsumermanagement.TenancyUnit, error) {
+	tenancyUnit, err := retryUntilVisible(ctx, timeout, func(ctx context.Context) (*serviceconsumermanagement.TenancyUnit, error) {
+		tenancyUnit, err := p.getTenancyUnit(ctx, id)
+		if err == nil && tenancyUnit == nil {
+			return nil, fmt.Errorf("tenancy unit %s: %w", id, errNotVisible)
 		}
+		return tenancyUnit, err
+	})
+	if errors.Is(err, errNotVisible) {
+		return nil, nil
+	}
+	return tenancyUnit, err
+}
+
+// errTenancyUnitFound stops paging once findTenancyUnit finds the unit.
+var errTenancyUnitFound = errors.New("tenancy unit found")
+
+// findTenancyUnit lists the tenancy units of parent matching filter, if set,
+// until it finds the one named id. It returns nil if there is none.
+func (p *UtilsProviderConfig) findTenancyUnit(ctx context.Context, parent, id, filter string) (*serviceconsumermanagement.TenancyUnit, error) {
+	call := p.TenantClient.Services.TenancyUnits.List(parent)
+	if filter != "" {
+		call = call.Filter(filter)
 	}
 
+	var tenancyUnit *serviceconsumermanagement.TenancyUnit
+	err := call.Pages(ctx, func(page *serviceconsumermanagement.ListTenancyUnitsResponse) error {
+		for _, tu := range page.TenancyUnits {
+			if strings.EqualFold(tu.Name, id) {
+				tenancyUnit = tu
+				return errTenancyUnitFound
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errTenancyUnitFound) {
+		return nil, err
+	}
 	return tenancyUnit, nil
 }
@@ -0,0 +1,1016 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+	"google.golang.org/api/serviceconsumermanagement/v1"
+)
+
+func TestAccResourceServiceTenancyUnit(t *testing.T) {
+	project := testAccProducerProject(t)
+	consumer := testAccConsumerProject(t)
+	serviceName := fmt.Sprintf("tf-test-%s.endpoints.%s.cloud.goog", acctest.RandString(8), project)
+
+	service := fmt.Sprintf(`
+		resource "utils_service" "test" {
+			service_name = %q
+			producer_project_id = %q
+		}`, serviceName, project)
+	tenancyUnit := func(deletionProtection string) string {
+		return fmt.Sprintf(`
+		resource "utils_service_tenancy_unit" "test" {
+			service_name = utils_service.test.service_name
+			consumer = "projects/%s"
+			%s
+		}`, consumer, deletionProtection)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCreateConfig(service + tenancyUnit("")),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("utils_service_tenancy_unit.test", tfjsonpath.New("deletion_protection"), knownvalue.Bool(true)),
+				},
+			},
+			{
+				ResourceName:      "utils_service_tenancy_unit.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				ResourceName:  "utils_service_tenancy_unit.test",
+				ImportState:   true,
+				ImportStateId: serviceName + "/projects/" + consumer,
+				ExpectError:   regexp.MustCompile("must be in the format"),
+			},
+			// Destroying the tenancy unit fails while it is protected.
+			{
+				Config:      testAccCreateConfig(service),
+				ExpectError: regexp.MustCompile("deletion_protection"),
+			},
+			{
+				Config: testAccCreateConfig(service + tenancyUnit("deletion_protection = false")),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("utils_service_tenancy_unit.test", tfjsonpath.New("deletion_protection"), knownvalue.Bool(false)),
+				},
+			},
+		},
+	})
+}
+
+// newFakeTenancyProviderServer returns a provider server whose tenant client
+// is backed by tenant.
+func newFakeTenancyProviderServer(t *testing.T, tenant *fakeTenantServer) (tfprotov6.ProviderServer, *tfprotov6.GetProviderSchemaResponse) {
+	t.Helper()
+
+	resourceManager := newFakeResourceManager()
+	resourceManager.tenantProjects = true
+
+	config := newFakeProviderConfig(t, newFakeServiceManager())
+	config.TenantClient = newFakeTenantClient(t, tenant)
+	config.ResourceManagerClient = newFakeResourceManagerClient(t, resourceManager)
+	return newFakeProviderServer(t, config)
+}
+
+func TestGetTenancyUnit(t *testing.T) {
+	const parent = "services/test.endpoints.example.cloud.goog/projects/123"
+
+	for _, tt := range []struct {
+		name          string
+		id            string
+		rejectFilter  bool
+		wantFound     bool
+		wantListCalls int
+	}{
+		{name: "filtered", id: parent + "/tenancyUnits/unit249", wantFound: true, wantListCalls: 1},
+		{name: "filter rejected", id: parent + "/tenancyUnits/unit249", rejectFilter: true, wantFound: true, wantListCalls: 4},
+		{name: "filter rejected first page", id: parent + "/tenancyUnits/unit000", rejectFilter: true, wantFound: true, wantListCalls: 2},
+		{name: "missing", id: parent + "/tenancyUnits/missing", wantListCalls: 1},
+		{name: "missing filter rejected", id: parent + "/tenancyUnits/missing", rejectFilter: true, wantListCalls: 4},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant := newFakeTenantServer()
+			tenant.rejectFilter = tt.rejectFilter
+			for i := range 250 {
+				name := fmt.Sprintf("%s/tenancyUnits/unit%03d", parent, i)
+				tenant.tenancyUnits[name] = &serviceconsumermanagement.TenancyUnit{Name: name}
+			}
+			config := &UtilsProviderConfig{TenantClient: newFakeTenantClient(t, tenant)}
+
+			tenancyUnit, err := config.getTenancyUnit(context.Background(), tt.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found := tenancyUnit != nil; found != tt.wantFound {
+				t.Fatalf("got tenancy unit %v, want found %v", tenancyUnit, tt.wantFound)
+			}
+			if tt.wantFound && tenancyUnit.Name != tt.id {
+				t.Errorf("got tenancy unit %q, want %q", tenancyUnit.Name, tt.id)
+			}
+			if tenant.listCalls != tt.wantListCalls {
+				t.Errorf("got %d list calls, want %d", tenant.listCalls, tt.wantListCalls)
+			}
+		})
+	}
+}
+
+func TestResourceServiceTenancyUnitDeletedExternally(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	tenant := newFakeTenantServer()
+	server, schemas := newFakeTenancyProviderServer(t, tenant)
+	typ := schemas.ResourceSchemas["utils_service_tenancy_unit"].ValueType()
+
+	config := testDynamicValue(t, typ, map[string]tftypes.Value{
+		"service_name": tftypes.NewValue(tftypes.String, serviceName),
+		"consumer":     tftypes.NewValue(tftypes.String, "projects/123"),
+	})
+	state := testApplyResource(t, server, "utils_service_tenancy_unit", typ, nil, config)
+	id := testStateAttributes(t, typ, state)["id"]
+	if want := tftypes.NewValue(tftypes.String, "services/"+serviceName+"/projects/123/tenancyUnits/unit1"); !id.Equal(want) {
+		t.Fatalf("got id %v, want %v", id, want)
+	}
+
+	// Deleting the unit outside Terraform removes it from state on refresh.
+	clear(tenant.tenancyUnits)
+	state = testReadResource(t, server, "utils_service_tenancy_unit", state)
+	value, err := state.Unmarshal(typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !value.IsNull() {
+		t.Fatalf("got state %v, want it removed", value)
+	}
+
+	// The next plan creates it again.
+	planned := testStateAttributes(t, typ, testPlanResource(t, server, "utils_service_tenancy_unit", typ, nil, nil, config).PlannedState)
+	if planned["id"].IsKnown() {
+		t.Errorf("got planned id %v, want unknown", planned["id"])
+	}
+	testApplyResource(t, server, "utils_service_tenancy_unit", typ, nil, config)
+	if len(tenant.tenancyUnits) != 1 {
+		t.Errorf("got %d tenancy units, want 1", len(tenant.tenancyUnits))
+	}
+}
+
+func TestResourceServiceTenancyUnitReplace(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	tenant := newFakeTenantServer()
+	server, schemas := newFakeTenancyProviderServer(t, tenant)
+	typ := schemas.ResourceSchemas["utils_service_tenancy_unit"].ValueType()
+
+	config := testDynamicValue(t, typ, map[string]tftypes.Value{
+		"service_name": tftypes.NewValue(tftypes.String, serviceName),
+		"consumer":     tftypes.NewValue(tftypes.String, "projects/123"),
+	})
+	state := testApplyResource(t, server, "utils_service_tenancy_unit", typ, nil, config)
+
+	planResp := testPlanResource(t, server, "utils_service_tenancy_unit", typ, state, nil, config)
+	if len(planResp.RequiresReplace) != 0 {
+		t.Errorf("got requires replace %v for an unchanged config, want none", planResp.RequiresReplace)
+	}
+	if planned := testStateAttributes(t, typ, planResp.PlannedState); !planned["id"].Equal(testStateAttributes(t, typ, state)["id"]) {
+		t.Errorf("got planned id %v, want the ID in state", planned["id"])
+	}
+
+	// `adopt_existing` is updated in place.
+	config = testDynamicValue(t, typ, map[string]tftypes.Value{
+		"service_name":   tftypes.NewValue(tftypes.String, serviceName),
+		"consumer":       tftypes.NewValue(tftypes.String, "projects/123"),
+		"adopt_existing": tftypes.NewValue(tftypes.Bool, true),
+	})
+	state = testApplyResource(t, server, "utils_service_tenancy_unit", typ, state, config)
+	if adoptExisting := testStateAttributes(t, typ, state)["adopt_existing"]; !adoptExisting.Equal(tftypes.NewValue(tftypes.Bool, true)) {
+		t.Errorf("got adopt_existing %v, want true", adoptExisting)
+	}
+
+	config = testDynamicValue(t, typ, map[string]tftypes.Value{
+		"service_name": tftypes.NewValue(tftypes.String, serviceName),
+		"consumer":     tftypes.NewValue(tftypes.String, "projects/456"),
+	})
+	planResp = testPlanResource(t, server, "utils_service_tenancy_unit", typ, state, nil, config)
+	wantRequiresReplace := []*tftypes.AttributePath{tftypes.NewAttributePath().WithAttributeName("consumer")}
+	if !slices.EqualFunc(planResp.RequiresReplace, wantRequiresReplace, (*tftypes.AttributePath).Equal) {
+		t.Errorf("got requires replace %v, want %v", planResp.RequiresReplace, wantRequiresReplace)
+	}
+
+	// Updating in place is reported as an error rather than crashing the
+	// provider.
+	applyResp, err := server.ApplyResourceChange(context.Background(), &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:     "utils_service_tenancy_unit",
+		PriorState:   state,
+		PlannedState: planResp.PlannedState,
+		Config:       config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applyResp.Diagnostics) != 1 || applyResp.Diagnostics[0].Summary != "Error updating tenancy unit" {
+		t.Errorf("got diagnostics %v, want an update error", applyResp.Diagnostics)
+	}
+}
+
+func TestResourceServiceTenancyUnitTenantResources(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	tenant := newFakeTenantServer()
+	server, schemas := newFakeTenancyProviderServer(t, tenant)
+	typ := schemas.ResourceSchemas["utils_service_tenancy_unit"].ValueType()
+
+	config := testDynamicValue(t, typ, map[string]tftypes.Value{
+		"service_name": tftypes.NewValue(tftypes.String, serviceName),
+		"consumer":     tftypes.NewValue(tftypes.String, "projects/123"),
+	})
+	state := testApplyResource(t, server, "utils_service_tenancy_unit", typ, nil, config)
+	attrs := testStateAttributes(t, typ, state)
+	if !attrs["create_time"].IsKnown() || attrs["create_time"].IsNull() {
+		t.Errorf("got create_time %v, want it set", attrs["create_time"])
+	}
+	var createTime string
+	if err := attrs["create_time"].As(&createTime); err != nil {
+		t.Fatal(err)
+	}
+
+	tenancyUnit := tenant.tenancyUnits["services/"+serviceName+"/projects/123/tenancyUnits/unit1"]
+	tenancyUnit.TenantResources = []*serviceconsumermanagement.TenantResource{
+		{Tag: "staging", Resource: "projects/2", Status: "PENDING_CREATE"},
+		{Tag: "prod", Resource: "projects/1", Status: "ACTIVE"},
+	}
+	attrs = testStateAttributes(t, typ, testReadResource(t, server, "utils_service_tenancy_unit", state))
+
+	if want := tftypes.NewValue(tftypes.String, createTime); !attrs["create_time"].Equal(want) {
+		t.Errorf("got create_time %v, want %v", attrs["create_time"], want)
+	}
+
+	elemType := tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+		"tag":      tftypes.String,
+		"resource": tftypes.String,
+		"status":   tftypes.String,
+	}}
+	tenantResource := func(tag, resource, status string) tftypes.Value {
+		return tftypes.NewValue(elemType, map[string]tftypes.Value{
+			"tag":      tftypes.NewValue(tftypes.String, tag),
+			"resource": tftypes.NewValue(tftypes.String, resource),
+			"status":   tftypes.NewValue(tftypes.String, status),
+		})
+	}
+	want := tftypes.NewValue(tftypes.List{ElementType: elemType}, []tftypes.Value{
+		tenantResource("prod", "projects/1", "ACTIVE"),
+		tenantResource("staging", "projects/2", "PENDING_CREATE"),
+	})
+	if !attrs["tenant_resources"].Equal(want) {
+		t.Errorf("got tenant_resources %v, want %v", attrs["tenant_resources"], want)
+	}
+
+	// Only the active projects are mapped.
+	want = tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, map[string]tftypes.Value{
+		"prod": tftypes.NewValue(tftypes.String, "projects/1"),
+	})
+	if !attrs["tenant_projects"].Equal(want) {
+		t.Errorf("got tenant_projects %v, want %v", attrs["tenant_projects"], want)
+	}
+}
+
+func TestTenantResourceModelAttributeTypes(t *testing.T) {
+	ctx := context.Background()
+
+	var resp fwresource.SchemaResponse
+	NewServiceTenancyUnitResource().Schema(ctx, fwresource.SchemaRequest{}, &resp)
+	attribute, ok := resp.Schema.Attributes["tenant_resources"].(schema.ListNestedAttribute)
+	if !ok {
+		t.Fatalf("got tenant_resources %T, want a list nested attribute", resp.Schema.Attributes["tenant_resources"])
+	}
+	want := attribute.NestedObject.Type().(types.ObjectType).AttrTypes
+	if got := (TenantResourceModel{}).AttributeTypes(); !maps.EqualFunc(got, want, attr.Type.Equal) {
+		t.Errorf("got attribute types %v, want %v", got, want)
+	}
+}
+
+func TestResourceServiceTenancyUnitId(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	tenant := newFakeTenantServer()
+	server, schemas := newFakeTenancyProviderServer(t, tenant)
+	typ := schemas.ResourceSchemas["utils_service_tenancy_unit"].ValueType()
+
+	config := testDynamicValue(t, typ, map[string]tftypes.Value{
+		"service_name":    tftypes.NewValue(tftypes.String, serviceName),
+		"consumer":        tftypes.NewValue(tftypes.String, "projects/123"),
+		"tenancy_unit_id": tftypes.NewValue(tftypes.String, "my-unit"),
+	})
+	state := testApplyResource(t, server, "utils_service_tenancy_unit", typ, nil, config)
+	attrs := testStateAttributes(t, typ, state)
+	if want := tftypes.NewValue(tftypes.String, "services/"+serviceName+"/projects/123/tenancyUnits/my-unit"); !attrs["id"].Equal(want) {
+		t.Errorf("got id %v, want %v", attrs["id"], want)
+	}
+	if want := tftypes.NewValue(tftypes.String, "my-unit"); !attrs["tenancy_unit_id"].Equal(want) {
+		t.Errorf("got tenancy_unit_id %v, want %v", attrs["tenancy_unit_id"], want)
+	}
+
+	// The next plan has no changes.
+	state = testReadResource(t, server, "utils_service_tenancy_unit", state)
+	planned, err := testPlanResource(t, server, "utils_service_tenancy_unit", typ, state, nil, config).PlannedState.Unmarshal(typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prior, err := state.Unmarshal(typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diffs, err := prior.Diff(planned); err != nil || len(diffs) > 0 {
+		t.Errorf("expected no changes, got %v (%v)", diffs, err)
+	}
+
+	for _, unitId := range []string{"", "has/slash", strings.Repeat("a", 41)} {
+		resp, err := server.ValidateResourceConfig(context.Background(), &tfprotov6.ValidateResourceConfigRequest{
+			TypeName: "utils_service_tenancy_unit",
+			Config: testDynamicValue(t, typ, map[string]tftypes.Value{
+				"service_name":    tftypes.NewValue(tftypes.String, serviceName),
+				"consumer":        tftypes.NewValue(tftypes.String, "projects/123"),
+				"tenancy_unit_id": tftypes.NewValue(tftypes.String, unitId),
+			}),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Diagnostics) == 0 {
+			t.Errorf("tenancy_unit_id %q: got no diagnostics, want a validation error", unitId)
+		}
+	}
+}
+
+func TestResourceServiceTenancyUnitUpgradeState(t *testing.T) {
+	const name = "services/test.endpoints.example.cloud.goog/projects/123/tenancyUnits/my-unit"
+
+	server, schemas := newFakeProviderServer(t, &UtilsProviderConfig{})
+	typ := schemas.ResourceSchemas["utils_service_tenancy_unit"].ValueType()
+
+	resp, err := server.UpgradeResourceState(context.Background(), &tfprotov6.UpgradeResourceStateRequest{
+		TypeName: "utils_service_tenancy_unit",
+		Version:  0,
+		RawState: &tfprotov6.RawState{JSON: []byte(`{"id":"` + name + `","service_name":"test.endpoints.example.cloud.goog","consumer":"projects/123"}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireNoErrors(t, resp.Diagnostics)
+
+	attrs := testStateAttributes(t, typ, resp.UpgradedState)
+	for attr, want := range map[string]string{
+		"id":              name,
+		"tenancy_unit_id": "my-unit",
+	} {
+		if want := tftypes.NewValue(tftypes.String, want); !attrs[attr].Equal(want) {
+			t.Errorf("got %s %v, want %v", attr, attrs[attr], want)
+		}
+	}
+}
+
+func TestResourceServiceTenancyUnitAdoptExisting(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+	const name = "services/" + serviceName + "/projects/123/tenancyUnits/my-unit"
+
+	for _, tt := range []struct {
+		name             string
+		adoptExisting    bool
+		tenancyUnitId    string
+		existingConsumer string
+		wantErr          string
+	}{
+		{
+			name:          "adopted",
+			adoptExisting: true,
+			tenancyUnitId: "my-unit",
+		},
+		{
+			name:          "adopt_existing unset",
+			tenancyUnitId: "my-unit",
+			wantErr:       "Error creating tenancy unit",
+		},
+		{
+			name:             "other consumer",
+			adoptExisting:    true,
+			tenancyUnitId:    "my-unit",
+			existingConsumer: "projects/456",
+			wantErr:          "Error adopting tenancy unit",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := &serviceconsumermanagement.TenancyUnit{
+				Name:     name,
+				Service:  serviceName,
+				Consumer: "projects/123",
+			}
+			if tt.existingConsumer != "" {
+				existing.Consumer = tt.existingConsumer
+			}
+			tenant := newFakeTenantServer()
+			tenant.tenancyUnits[name] = existing
+			server, schemas := newFakeTenancyProviderServer(t, tenant)
+			typ := schemas.ResourceSchemas["utils_service_tenancy_unit"].ValueType()
+
+			values := map[string]tftypes.Value{
+				"service_name":   tftypes.NewValue(tftypes.String, serviceName),
+				"consumer":       tftypes.NewValue(tftypes.String, "projects/123"),
+				"adopt_existing": tftypes.NewValue(tftypes.Bool, tt.adoptExisting),
+			}
+			if tt.tenancyUnitId != "" {
+				values["tenancy_unit_id"] = tftypes.NewValue(tftypes.String, tt.tenancyUnitId)
+			}
+			config := testDynamicValue(t, typ, values)
+			priorState := testNullDynamicValue(t, typ)
+			planResp := testPlanResource(t, server, "utils_service_tenancy_unit", typ, priorState, nil, config)
+			applyResp, err := server.ApplyResourceChange(context.Background(), &tfprotov6.ApplyResourceChangeRequest{
+				TypeName:     "utils_service_tenancy_unit",
+				PriorState:   priorState,
+				PlannedState: planResp.PlannedState,
+				Config:       config,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantErr != "" {
+				if len(applyResp.Diagnostics) != 1 || applyResp.Diagnostics[0].Summary != tt.wantErr {
+					t.Errorf("got diagnostics %v, want %q", applyResp.Diagnostics, tt.wantErr)
+				}
+				return
+			}
+			requireNoErrors(t, applyResp.Diagnostics)
+			if id := testStateAttributes(t, typ, applyResp.NewState)["id"]; !id.Equal(tftypes.NewValue(tftypes.String, name)) {
+				t.Errorf("got id %v, want %q", id, name)
+			}
+			if len(tenant.tenancyUnits) != 1 {
+				t.Errorf("got %d tenancy units, want the existing unit to be adopted", len(tenant.tenancyUnits))
+			}
+		})
+	}
+}
+
+func TestResourceServiceTenancyUnitForceDelete(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+
+	for _, forceDelete := range []bool{false, true} {
+		t.Run(fmt.Sprintf("force_delete=%v", forceDelete), func(t *testing.T) {
+			tenant := newFakeTenantServer()
+			server, schemas := newFakeTenancyProviderServer(t, tenant)
+			typ := schemas.ResourceSchemas["utils_service_tenancy_unit"].ValueType()
+
+			config := testDynamicValue(t, typ, map[string]tftypes.Value{
+				"service_name":        tftypes.NewValue(tftypes.String, serviceName),
+				"consumer":            tftypes.NewValue(tftypes.String, "projects/123"),
+				"force_delete":        tftypes.NewValue(tftypes.Bool, forceDelete),
+				"deletion_protection": tftypes.NewValue(tftypes.Bool, false),
+			})
+			state := testApplyResource(t, server, "utils_service_tenancy_unit", typ, nil, config)
+			name := "services/" + serviceName + "/projects/123/tenancyUnits/unit1"
+			tenant.tenancyUnits[name].TenantResources = []*serviceconsumermanagement.TenantResource{
+				{Tag: "staging", Resource: "projects/2", Status: "ACTIVE"},
+				{Tag: "prod", Resource: "projects/1", Status: "ACTIVE"},
+			}
+
+			resp, err := server.ApplyResourceChange(context.Background(), &tfprotov6.ApplyResourceChangeRequest{
+				TypeName:     "utils_service_tenancy_unit",
+				PriorState:   state,
+				PlannedState: testNullDynamicValue(t, typ),
+				Config:       testNullDynamicValue(t, typ),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !forceDelete {
+				if len(resp.Diagnostics) != 1 || !strings.Contains(resp.Diagnostics[0].Detail, "with tags prod, staging") {
+					t.Fatalf("got diagnostics %v, want an error listing the tags", resp.Diagnostics)
+				}
+				if _, ok := tenant.tenancyUnits[name]; !ok {
+					t.Error("tenancy unit was deleted")
+				}
+				return
+			}
+			requireNoErrors(t, resp.Diagnostics)
+			if want := []string{"staging", "prod"}; !slices.Equal(tenant.removedTags, want) {
+				t.Errorf("got removed tags %v, want %v", tenant.removedTags, want)
+			}
+			if _, ok := tenant.tenancyUnits[name]; ok {
+				t.Error("tenancy unit was not deleted")
+			}
+		})
+	}
+}
+
+func TestResourceServiceTenancyUnitEventualConsistency(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+	ctx := context.Background()
+
+	defer func(delay time.Duration) { consistencyRetryBaseDelay = delay }(consistencyRetryBaseDelay)
+	consistencyRetryBaseDelay = time.Millisecond
+
+	tenant := newFakeTenantServer()
+	server, schemas := newFakeTenancyProviderServer(t, tenant)
+	typ := schemas.ResourceSchemas["utils_service_tenancy_unit"].ValueType()
+
+	// Create waits until the new unit is listed.
+	tenant.hiddenListCalls = 2
+	config := testDynamicValue(t, typ, map[string]tftypes.Value{
+		"service_name": tftypes.NewValue(tftypes.String, serviceName),
+		"consumer":     tftypes.NewValue(tftypes.String, "projects/123"),
+	})
+	priorState := testNullDynamicValue(t, typ)
+	planResp := testPlanResource(t, server, "utils_service_tenancy_unit", typ, priorState, nil, config)
+	applyResp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:     "utils_service_tenancy_unit",
+		PriorState:   priorState,
+		PlannedState: planResp.PlannedState,
+		Config:       config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireNoErrors(t, applyResp.Diagnostics)
+	if len(applyResp.Diagnostics) != 0 {
+		t.Errorf("got diagnostics %v, want none", applyResp.Diagnostics)
+	}
+	if tenant.listCalls != 3 {
+		t.Errorf("got %d list calls, want 3", tenant.listCalls)
+	}
+
+	// Shortly after creation, Read retries rather than removing the unit
+	// from state.
+	tenant.hiddenListCalls = 2
+	readResp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+		TypeName:     "utils_service_tenancy_unit",
+		CurrentState: applyResp.NewState,
+		Private:      applyResp.Private,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireNoErrors(t, readResp.Diagnostics)
+	if state, err := readResp.NewState.Unmarshal(typ); err != nil || state.IsNull() {
+		t.Fatalf("got state %v (%v), want the tenancy unit", state, err)
+	}
+
+	// Without the private state, a unit which is not listed is gone.
+	tenant.hiddenListCalls = 1
+	state := testReadResource(t, server, "utils_service_tenancy_unit", applyResp.NewState)
+	if value, err := state.Unmarshal(typ); err != nil || !value.IsNull() {
+		t.Errorf("got state %v (%v), want it removed", value, err)
+	}
+}
+
+func TestResourceServiceTenancyUnitConsumerProjectId(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+	ctx := context.Background()
+
+	tenant := newFakeTenantServer()
+	resourceManager := newFakeResourceManager()
+	resourceManager.projectNumbers["my-project"] = "123"
+	providerConfig := newFakeProviderConfig(t, newFakeServiceManager())
+	providerConfig.TenantClient = newFakeTenantClient(t, tenant)
+	providerConfig.ResourceManagerClient = newFakeResourceManagerClient(t, resourceManager)
+	server, schemas := newFakeProviderServer(t, providerConfig)
+	typ := schemas.ResourceSchemas["utils_service_tenancy_unit"].ValueType()
+
+	newConfig := func(consumer string) *tfprotov6.DynamicValue {
+		return testDynamicValue(t, typ, map[string]tftypes.Value{
+			"service_name": tftypes.NewValue(tftypes.String, serviceName),
+			"consumer":     tftypes.NewValue(tftypes.String, consumer),
+		})
+	}
+	apply := func(state *tfprotov6.DynamicValue, private []byte, config *tfprotov6.DynamicValue) *tfprotov6.ApplyResourceChangeResponse {
+		t.Helper()
+		if state == nil {
+			state = testNullDynamicValue(t, typ)
+		}
+		planResp := testPlanResource(t, server, "utils_service_tenancy_unit", typ, state, private, config)
+		applyResp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+			TypeName:       "utils_service_tenancy_unit",
+			PriorState:     state,
+			PlannedState:   planResp.PlannedState,
+			PlannedPrivate: planResp.PlannedPrivate,
+			Config:         config,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		requireNoErrors(t, applyResp.Diagnostics)
+		return applyResp
+	}
+	read := func(state *tfprotov6.DynamicValue, private []byte) *tfprotov6.ReadResourceResponse {
+		t.Helper()
+		readResp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+			TypeName:     "utils_service_tenancy_unit",
+			CurrentState: state,
+			Private:      private,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(readResp.Diagnostics) != 0 {
+			t.Fatalf("got diagnostics %v, want none", readResp.Diagnostics)
+		}
+		return readResp
+	}
+
+	// The project ID is resolved to its number for the API.
+	config := newConfig("projects/my-project")
+	applyResp := apply(nil, nil, config)
+	name := "services/" + serviceName + "/projects/123/tenancyUnits/unit1"
+	if _, ok := tenant.tenancyUnits[name]; !ok {
+		t.Fatalf("got tenancy units %v, want %s", slices.Collect(maps.Keys(tenant.tenancyUnits)), name)
+	}
+
+	// The numeric consumer returned by the API does not cause drift, and the
+	// number recorded in private state is used rather than resolving the
+	// project again.
+	readResp := read(applyResp.NewState, applyResp.Private)
+	readResp = read(readResp.NewState, readResp.Private)
+	if consumer := testStateAttributes(t, typ, readResp.NewState)["consumer"]; !consumer.Equal(tftypes.NewValue(tftypes.String, "projects/my-project")) {
+		t.Errorf("got consumer %v, want the configured project ID", consumer)
+	}
+	if resourceManager.getCalls != 1 {
+		t.Errorf("got %d project lookups, want 1", resourceManager.getCalls)
+	}
+
+	// Without private state, for example after an upgrade, the project is
+	// resolved again.
+	readResp = read(readResp.NewState, nil)
+	if resourceManager.getCalls != 2 {
+		t.Errorf("got %d project lookups, want 2", resourceManager.getCalls)
+	}
+
+	// Switching to the project number updates the tenancy unit in place.
+	config = newConfig("projects/123")
+	planResp := testPlanResource(t, server, "utils_service_tenancy_unit", typ, readResp.NewState, readResp.Private, config)
+	if len(planResp.RequiresReplace) != 0 {
+		t.Errorf("got requires replace %v, want none", planResp.RequiresReplace)
+	}
+	applyResp = apply(readResp.NewState, readResp.Private, config)
+	if consumer := testStateAttributes(t, typ, applyResp.NewState)["consumer"]; !consumer.Equal(tftypes.NewValue(tftypes.String, "projects/123")) {
+		t.Errorf("got consumer %v, want projects/123", consumer)
+	}
+	if resourceManager.getCalls != 2 {
+		t.Errorf("got %d project lookups, want 2", resourceManager.getCalls)
+	}
+
+	// Another project replaces it.
+	resourceManager.projectNumbers["other-project"] = "456"
+	planResp = testPlanResource(t, server, "utils_service_tenancy_unit", typ, applyResp.NewState, applyResp.Private, newConfig("projects/other-project"))
+	wantRequiresReplace := []*tftypes.AttributePath{tftypes.NewAttributePath().WithAttributeName("consumer")}
+	if !slices.EqualFunc(planResp.RequiresReplace, wantRequiresReplace, (*tftypes.AttributePath).Equal) {
+		t.Errorf("got requires replace %v, want %v", planResp.RequiresReplace, wantRequiresReplace)
+	}
+
+	for _, consumer := range []string{"projects/My_Project", "projects/abc", "folders/123"} {
+		resp, err := server.ValidateResourceConfig(ctx, &tfprotov6.ValidateResourceConfigRequest{
+			TypeName: "utils_service_tenancy_unit",
+			Config:   newConfig(consumer),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Diagnostics) == 0 {
+			t.Errorf("consumer %q: got no diagnostics, want a validation error", consumer)
+		}
+	}
+}
+
+func TestResourceServiceTenancyUnitDeletionProtection(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+	ctx := context.Background()
+
+	tenant := newFakeTenantServer()
+	server, schemas := newFakeTenancyProviderServer(t, tenant)
+	typ := schemas.ResourceSchemas["utils_service_tenancy_unit"].ValueType()
+
+	state := testApplyResource(t, server, "utils_service_tenancy_unit", typ, nil, testDynamicValue(t, typ, map[string]tftypes.Value{
+		"service_name": tftypes.NewValue(tftypes.String, serviceName),
+		"consumer":     tftypes.NewValue(tftypes.String, "projects/123"),
+	}))
+	if deletionProtection := testStateAttributes(t, typ, state)["deletion_protection"]; !deletionProtection.Equal(tftypes.NewValue(tftypes.Bool, true)) {
+		t.Errorf("got deletion_protection %v, want true by default", deletionProtection)
+	}
+
+	destroy := func(state *tfprotov6.DynamicValue) []*tfprotov6.Diagnostic {
+		resp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+			TypeName:     "utils_service_tenancy_unit",
+			PriorState:   state,
+			PlannedState: testNullDynamicValue(t, typ),
+			Config:       testNullDynamicValue(t, typ),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Diagnostics
+	}
+
+	diags := destroy(state)
+	if len(diags) != 1 || diags[0].Summary != "Cannot destroy tenancy unit with deletion protection" {
+		t.Fatalf("got diagnostics %v, want a deletion protection error", diags)
+	}
+	if len(tenant.tenancyUnits) != 1 {
+		t.Fatal("tenancy unit was deleted")
+	}
+
+	// Disabling the protection is an in-place update, after which the unit
+	// can be destroyed.
+	state = testApplyResource(t, server, "utils_service_tenancy_unit", typ, state, testDynamicValue(t, typ, map[string]tftypes.Value{
+		"service_name":        tftypes.NewValue(tftypes.String, serviceName),
+		"consumer":            tftypes.NewValue(tftypes.String, "projects/123"),
+		"deletion_protection": tftypes.NewValue(tftypes.Bool, false),
+	}))
+	requireNoErrors(t, destroy(state))
+	if len(tenant.tenancyUnits) != 0 {
+		t.Error("tenancy unit was not deleted")
+	}
+}
+
+func TestTenancyUnitParent(t *testing.T) {
+	for _, serviceName := range []string{"svc.example.com", "services/svc.example.com"} {
+		if got, want := tenancyUnitParent(serviceName, "projects/123"), "services/svc.example.com/projects/123"; got != want {
+			t.Errorf("tenancyUnitParent(%q): got %q, want %q", serviceName, got, want)
+		}
+	}
+}
+
+func TestResourceServiceTenancyUnitPrefixedServiceName(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	tenant := newFakeTenantServer()
+	server, schemas := newFakeTenancyProviderServer(t, tenant)
+	typ := schemas.ResourceSchemas["utils_service_tenancy_unit"].ValueType()
+
+	newConfig := func(serviceName string) *tfprotov6.DynamicValue {
+		return testDynamicValue(t, typ, map[string]tftypes.Value{
+			"service_name": tftypes.NewValue(tftypes.String, serviceName),
+			"consumer":     tftypes.NewValue(tftypes.String, "projects/123"),
+		})
+	}
+
+	state := testApplyResource(t, server, "utils_service_tenancy_unit", typ, nil, newConfig("services/"+serviceName))
+	name := "services/" + serviceName + "/projects/123/tenancyUnits/unit1"
+	if _, ok := tenant.tenancyUnits[name]; !ok {
+		t.Fatalf("got tenancy units %v, want %s", slices.Collect(maps.Keys(tenant.tenancyUnits)), name)
+	}
+
+	// The service name returned by the API does not cause drift.
+	state = testReadResource(t, server, "utils_service_tenancy_unit", state)
+	if got := testStateAttributes(t, typ, state)["service_name"]; !got.Equal(tftypes.NewValue(tftypes.String, "services/"+serviceName)) {
+		t.Errorf("got service_name %v, want the configured value", got)
+	}
+
+	// Dropping the prefix does not replace the tenancy unit.
+	planResp := testPlanResource(t, server, "utils_service_tenancy_unit", typ, state, nil, newConfig(serviceName))
+	if len(planResp.RequiresReplace) != 0 {
+		t.Errorf("got requires replace %v, want none", planResp.RequiresReplace)
+	}
+	state = testApplyResource(t, server, "utils_service_tenancy_unit", typ, state, newConfig(serviceName))
+	if got := testStateAttributes(t, typ, state)["service_name"]; !got.Equal(tftypes.NewValue(tftypes.String, serviceName)) {
+		t.Errorf("got service_name %v, want %q", got, serviceName)
+	}
+
+	planResp = testPlanResource(t, server, "utils_service_tenancy_unit", typ, state, nil, newConfig("services/other.example.com"))
+	wantRequiresReplace := []*tftypes.AttributePath{tftypes.NewAttributePath().WithAttributeName("service_name")}
+	if !slices.EqualFunc(planResp.RequiresReplace, wantRequiresReplace, (*tftypes.AttributePath).Equal) {
+		t.Errorf("got requires replace %v, want %v", planResp.RequiresReplace, wantRequiresReplace)
+	}
+}
+
+func TestResourceServiceTenancyUnitPurgeFailedTenantResources(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = 0
+
+	for _, tt := range []struct {
+		name            string
+		tenantResources []*serviceconsumermanagement.TenantResource
+		wantDeleted     bool
+	}{
+		{
+			name: "failed",
+			tenantResources: []*serviceconsumermanagement.TenantResource{
+				{Tag: "staging", Status: "FAILED"},
+				{Tag: "dev", Status: "FAILED"},
+			},
+			wantDeleted: true,
+		},
+		{
+			name: "failed and active",
+			tenantResources: []*serviceconsumermanagement.TenantResource{
+				{Tag: "staging", Status: "FAILED"},
+				{Tag: "dev", Status: "FAILED"},
+				{Tag: "prod", Resource: "projects/1", Status: "ACTIVE"},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant := newFakeTenantServer()
+			server, schemas := newFakeTenancyProviderServer(t, tenant)
+			typ := schemas.ResourceSchemas["utils_service_tenancy_unit"].ValueType()
+
+			state := testApplyResource(t, server, "utils_service_tenancy_unit", typ, nil, testDynamicValue(t, typ, map[string]tftypes.Value{
+				"service_name":        tftypes.NewValue(tftypes.String, serviceName),
+				"consumer":            tftypes.NewValue(tftypes.String, "projects/123"),
+				"deletion_protection": tftypes.NewValue(tftypes.Bool, false),
+			}))
+			name := "services/" + serviceName + "/projects/123/tenancyUnits/unit1"
+			tenant.tenancyUnits[name].TenantResources = tt.tenantResources
+
+			resp, err := server.ApplyResourceChange(context.Background(), &tfprotov6.ApplyResourceChangeRequest{
+				TypeName:     "utils_service_tenancy_unit",
+				PriorState:   state,
+				PlannedState: testNullDynamicValue(t, typ),
+				Config:       testNullDynamicValue(t, typ),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := []string{"staging", "dev"}; !slices.Equal(tenant.deletedTags, want) {
+				t.Errorf("got deleted tags %v, want %v", tenant.deletedTags, want)
+			}
+			var warned bool
+			for _, d := range resp.Diagnostics {
+				warned = warned || d.Severity == tfprotov6.DiagnosticSeverityWarning && strings.Contains(d.Detail, "with tags dev, staging")
+			}
+			if !warned {
+				t.Errorf("got diagnostics %v, want a warning listing the purged tags", resp.Diagnostics)
+			}
+
+			if tt.wantDeleted {
+				requireNoErrors(t, resp.Diagnostics)
+				if _, ok := tenant.tenancyUnits[name]; ok {
+					t.Error("tenancy unit was not deleted")
+				}
+				return
+			}
+			var blocked bool
+			for _, d := range resp.Diagnostics {
+				blocked = blocked || d.Severity == tfprotov6.DiagnosticSeverityError && strings.Contains(d.Detail, "with tags prod.")
+			}
+			if !blocked {
+				t.Errorf("got diagnostics %v, want an error listing the active tag", resp.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestResourceServiceTenancyUnitImportState(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+	const name = "services/" + serviceName + "/projects/123/tenancyUnits/my-unit"
+	ctx := context.Background()
+
+	tenant := newFakeTenantServer()
+	tenant.tenancyUnits[name] = &serviceconsumermanagement.TenancyUnit{
+		Name:     name,
+		Service:  serviceName,
+		Consumer: "projects/123",
+	}
+	server, schemas := newFakeTenancyProviderServer(t, tenant)
+	typ := schemas.ResourceSchemas["utils_service_tenancy_unit"].ValueType()
+
+	resp, err := server.ImportResourceState(ctx, &tfprotov6.ImportResourceStateRequest{
+		TypeName: "utils_service_tenancy_unit",
+		ID:       name,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireNoErrors(t, resp.Diagnostics)
+	if len(resp.ImportedResources) != 1 {
+		t.Fatalf("got %d imported resources, want 1", len(resp.ImportedResources))
+	}
+	attrs := testStateAttributes(t, typ, resp.ImportedResources[0].State)
+	for attr, want := range map[string]string{
+		"id":              name,
+		"service_name":    serviceName,
+		"consumer":        "projects/123",
+		"tenancy_unit_id": "my-unit",
+	} {
+		if want := tftypes.NewValue(tftypes.String, want); !attrs[attr].Equal(want) {
+			t.Errorf("got %s %v, want %v", attr, attrs[attr], want)
+		}
+	}
+
+	for _, id := range []string{"my-unit", serviceName + "/projects/123/tenancyUnits/my-unit", "services/" + serviceName + "/projects/123"} {
+		resp, err := server.ImportResourceState(ctx, &tfprotov6.ImportResourceStateRequest{
+			TypeName: "utils_service_tenancy_unit",
+			ID:       id,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Diagnostics) != 1 || resp.Diagnostics[0].Summary != "Invalid tenancy unit ID" {
+			t.Errorf("import ID %q: got diagnostics %v, want an invalid ID error", id, resp.Diagnostics)
+		}
+	}
+}
+
+func TestResourceServiceTenancyUnitDrift(t *testing.T) {
+	const serviceName = "test.endpoints.example.cloud.goog"
+	ctx := context.Background()
+
+	tests := map[string]struct {
+		drift     func(*serviceconsumermanagement.TenancyUnit)
+		attribute string
+		actual    string
+	}{
+		"service_name": {
+			drift:     func(u *serviceconsumermanagement.TenancyUnit) { u.Service = "other.endpoints.example.cloud.goog" },
+			attribute: "service_name",
+			actual:    "other.endpoints.example.cloud.goog",
+		},
+		"consumer": {
+			drift:     func(u *serviceconsumermanagement.TenancyUnit) { u.Consumer = "projects/456" },
+			attribute: "consumer",
+			actual:    "projects/456",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tenant := newFakeTenantServer()
+			server, schemas := newFakeTenancyProviderServer(t, tenant)
+			typ := schemas.ResourceSchemas["utils_service_tenancy_unit"].ValueType()
+
+			config := testDynamicValue(t, typ, map[string]tftypes.Value{
+				"service_name": tftypes.NewValue(tftypes.String, serviceName),
+				"consumer":     tftypes.NewValue(tftypes.String, "projects/123"),
+			})
+			state := testApplyResource(t, server, "utils_service_tenancy_unit", typ, nil, config)
+
+			// Without drift, nothing is replaced.
+			readResp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+				TypeName:     "utils_service_tenancy_unit",
+				CurrentState: state,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(readResp.Diagnostics) != 0 {
+				t.Fatalf("got diagnostics %v, want none", readResp.Diagnostics)
+			}
+			planResp := testPlanResource(t, server, "utils_service_tenancy_unit", typ, readResp.NewState, readResp.Private, config)
+			if len(planResp.RequiresReplace) != 0 {
+				t.Errorf("got requires replace %v, want none", planResp.RequiresReplace)
+			}
+
+			tt.drift(tenant.tenancyUnits["services/"+serviceName+"/projects/123/tenancyUnits/unit1"])
+
+			// The actual value is stored, with a warning.
+			readResp, err = server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{
+				TypeName:     "utils_service_tenancy_unit",
+				CurrentState: readResp.NewState,
+				Private:      readResp.Private,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			requireNoErrors(t, readResp.Diagnostics)
+			if len(readResp.Diagnostics) != 1 || readResp.Diagnostics[0].Severity != tfprotov6.DiagnosticSeverityWarning {
+				t.Errorf("got diagnostics %v, want a drift warning", readResp.Diagnostics)
+			}
+			prior := testStateAttributes(t, typ, readResp.NewState)
+			if want := tftypes.NewValue(tftypes.String, tt.actual); !prior[tt.attribute].Equal(want) {
+				t.Errorf("got %s %v, want %v", tt.attribute, prior[tt.attribute], want)
+			}
+
+			// The drifted attribute changes back to the configured value,
+			// which forces replacement.
+			planResp = testPlanResource(t, server, "utils_service_tenancy_unit", typ, readResp.NewState, readResp.Private, config)
+			planned := testStateAttributes(t, typ, planResp.PlannedState)
+			if planned[tt.attribute].Equal(prior[tt.attribute]) {
+				t.Errorf("got planned %s %v, want it to differ from state", tt.attribute, planned[tt.attribute])
+			}
+			wantRequiresReplace := []*tftypes.AttributePath{tftypes.NewAttributePath().WithAttributeName(tt.attribute)}
+			if !slices.EqualFunc(planResp.RequiresReplace, wantRequiresReplace, (*tftypes.AttributePath).Equal) {
+				t.Errorf("got requires replace %v, want %v", planResp.RequiresReplace, wantRequiresReplace)
+			}
+		})
+	}
+}
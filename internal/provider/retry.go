@@ -0,0 +1,269 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// readRetryAttempts is the maximum number of attempts made by retryTransient.
+const readRetryAttempts = 3
+
+// readRetryBaseDelay is the delay before the first retry. Each subsequent
+// retry doubles the delay, and a random jitter of up to the delay is added.
+var readRetryBaseDelay = time.Second
+
+// retryTransient calls fn until it succeeds, returns a non-transient error, or
+// readRetryAttempts attempts have been made.
+//
+// Only errors which indicate a temporary server-side problem are retried;
+// errors such as NotFound or PermissionDenied are returned immediately.
+func retryTransient[T any](ctx context.Context, fn func(ctx context.Context) (T, error)) (T, error) {
+	result, _, err := retry(ctx, readRetryAttempts, readRetryBaseDelay, isTransientError, fn)
+	return result, err
+}
+
+// defaultSubmitRetryAttempts is the maximum number of attempts made by
+// retrySubmit unless configured otherwise.
+const defaultSubmitRetryAttempts = 5
+
+// submitRetryBaseDelay is the delay before the first retry of retrySubmit.
+var submitRetryBaseDelay = 2 * time.Second
+
+// retrySubmit calls fn until it succeeds, returns an error which is not
+// retryable for submissions, or maxAttempts attempts have been made. If the
+// attempts are exhausted, the error reports how many were made.
+//
+// Submissions are retried when quota is exhausted or another operation on the
+// service is in progress, which is common when many services are pushed in
+// parallel. Validation errors are returned immediately.
+func retrySubmit[T any](ctx context.Context, maxAttempts int, fn func(ctx context.Context) (T, error)) (T, error) {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultSubmitRetryAttempts
+	}
+	result, attempts, err := retry(ctx, maxAttempts, submitRetryBaseDelay, isRetryableSubmitError, fn)
+	if err != nil && attempts > 1 {
+		err = fmt.Errorf("failed after %d attempts: %w", attempts, err)
+	}
+	return result, err
+}
+
+// defaultTenantProjectRetryAttempts is the maximum number of attempts made by
+// retryTenantProjectMutation unless configured otherwise.
+const defaultTenantProjectRetryAttempts = 5
+
+// tenantProjectRetryBaseDelay is the delay before the first retry of
+// retryTenantProjectMutation.
+var tenantProjectRetryBaseDelay = 5 * time.Second
+
+// retryTenantProjectMutation calls fn until it succeeds, returns an error
+// which is not isRetryableTenantProjectError, or maxAttempts attempts have been
+// made. If the attempts are exhausted, the error reports how many were made.
+//
+// Adding many tenant projects in parallel exhausts the project creation quota,
+// and concurrent changes to the same tenancy unit are aborted.
+func retryTenantProjectMutation[T any](ctx context.Context, maxAttempts int, fn func(ctx context.Context) (T, error)) (T, error) {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultTenantProjectRetryAttempts
+	}
+	result, attempts, err := retry(ctx, maxAttempts, tenantProjectRetryBaseDelay, isRetryableTenantProjectError, fn)
+	if err != nil && attempts > 1 {
+		err = fmt.Errorf("failed after %d attempts: %w", attempts, err)
+	}
+	return result, err
+}
+
+// rolloutConflictRetryBaseDelay is the delay before the first retry of
+// retryRolloutConflict.
+var rolloutConflictRetryBaseDelay = 5 * time.Second
+
+// retryRolloutConflict calls fn until it succeeds, returns an error other than
+// isRolloutConflictError, or timeout has elapsed.
+//
+// Only one rollout of a service can be in progress at a time, so concurrent
+// applies, or a config which is rolled out automatically, can cause rollouts
+// to be rejected until the other rollout completes.
+func retryRolloutConflict[T any](ctx context.Context, timeout time.Duration, fn func(ctx context.Context) (T, error)) (T, error) {
+	deadline := time.Now().Add(timeout)
+	retryable := func(err error) bool {
+		if !isRolloutConflictError(err) || time.Now().After(deadline) {
+			return false
+		}
+		tflog.Info(ctx, "Another rollout of the service is in progress, retrying", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return true
+	}
+	result, attempts, err := retry(ctx, math.MaxInt, rolloutConflictRetryBaseDelay, retryable, fn)
+	if err != nil && attempts > 1 {
+		err = fmt.Errorf("failed after %d attempts: %w", attempts, err)
+	}
+	return result, err
+}
+
+// consistencyRetryBaseDelay is the delay before the first retry of
+// retryUntilVisible.
+var consistencyRetryBaseDelay = time.Second
+
+// errNotVisible is returned by the functions passed to retryUntilVisible while
+// a resource is not visible yet.
+var errNotVisible = errors.New("not visible yet")
+
+// retryUntilVisible calls fn until it succeeds, returns an error other than
+// errNotVisible, or timeout has elapsed.
+//
+// Resources which have just been created are not always returned by the
+// list APIs immediately.
+func retryUntilVisible[T any](ctx context.Context, timeout time.Duration, fn func(ctx context.Context) (T, error)) (T, error) {
+	deadline := time.Now().Add(timeout)
+	retryable := func(err error) bool {
+		if !errors.Is(err, errNotVisible) || time.Now().After(deadline) {
+			return false
+		}
+		tflog.Info(ctx, "Newly created resource is not visible yet, retrying", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return true
+	}
+	result, _, err := retry(ctx, math.MaxInt, consistencyRetryBaseDelay, retryable, fn)
+	return result, err
+}
+
+// operationPollBaseDelay is the delay before the first poll of pollOperation.
+// Each subsequent delay doubles, and a random jitter of up to the delay is
+// added.
+var operationPollBaseDelay = time.Second
+
+// operationPollMaxDelay caps the delay between polls of pollOperation,
+// including jitter.
+var operationPollMaxDelay = 30 * time.Second
+
+// pollOperation calls get until done reports that the operation it returns
+// has completed, and returns the completed operation. op is the operation as
+// initially returned by the API, which may already be done.
+//
+// Polls back off exponentially, since operations such as creating a project
+// can take many minutes. Polling stops with an error when ctx is cancelled or
+// timeout has elapsed.
+func pollOperation[T any](ctx context.Context, timeout time.Duration, op T, done func(T) bool, get func(ctx context.Context) (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for poll := 0; !done(op); poll++ {
+		select {
+		case <-ctx.Done():
+			return op, fmt.Errorf("operation did not complete: %w", ctx.Err())
+		case <-time.After(operationPollDelay(poll)):
+		}
+
+		next, err := get(ctx)
+		if err != nil {
+			return op, fmt.Errorf("could not get operation: %w", err)
+		}
+		op = next
+	}
+	return op, nil
+}
+
+// operationPollDelay returns the delay before the given poll of
+// pollOperation, counting from zero.
+func operationPollDelay(poll int) time.Duration {
+	delay := operationPollBaseDelay
+	for i := 0; i < poll && delay < operationPollMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay+rand.N(delay+1), operationPollMaxDelay)
+}
+
+// retry calls fn until it succeeds, returns an error for which retryable is
+// false, or maxAttempts attempts have been made, and returns the number of
+// attempts made.
+func retry[T any](ctx context.Context, maxAttempts int, baseDelay time.Duration, retryable func(error) bool, fn func(ctx context.Context) (T, error)) (T, int, error) {
+	delay := baseDelay
+	for attempt := 1; ; attempt++ {
+		result, err := fn(ctx)
+		if err == nil || attempt >= maxAttempts || !retryable(err) {
+			return result, attempt, err
+		}
+
+		wait := delay + rand.N(delay+1)
+		tflog.Debug(ctx, "Retrying transient error", map[string]interface{}{
+			"attempt": attempt,
+			"wait":    wait.String(),
+			"error":   err.Error(),
+		})
+
+		select {
+		case <-ctx.Done():
+			return result, attempt, err
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+// isTransientError reports whether err is a gRPC or REST error which may
+// succeed if retried.
+func isTransientError(err error) bool {
+	if isGoogleAPIErrorCode(err, http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusGatewayTimeout) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal:
+		return true
+	default:
+		return false
+	}
+}
+
+// isRolloutConflictError reports whether err indicates that a rollout was
+// rejected because another rollout of the service is in progress.
+func isRolloutConflictError(err error) bool {
+	return status.Code(err) == codes.FailedPrecondition && strings.Contains(strings.ToLower(status.Convert(err).Message()), "in progress")
+}
+
+// isRetryableSubmitError reports whether a failed config submission may
+// succeed if retried.
+//
+// gRPC reports messages exceeding the size limit as ResourceExhausted as well,
+// but those fail again on every attempt.
+func isRetryableSubmitError(err error) bool {
+	switch status.Code(err) {
+	case codes.ResourceExhausted:
+		return !strings.Contains(status.Convert(err).Message(), "larger than max")
+	case codes.Unavailable, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+// isRetryableTenantProjectError reports whether a failed change to a tenant
+// project may succeed if retried, which is the case when quota is exhausted or
+// the change was aborted by a concurrent one.
+//
+// REST errors report ABORTED with the same HTTP status as ALREADY_EXISTS, so
+// they are told apart by their status.
+func isRetryableTenantProjectError(err error) bool {
+	if isGoogleAPIErrorCode(err, http.StatusTooManyRequests) {
+		return true
+	}
+	if isGoogleAPIErrorCode(err, http.StatusConflict) {
+		return googleAPIErrorStatus(err) == "ABORTED"
+	}
+	switch status.Code(err) {
+	case codes.ResourceExhausted, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
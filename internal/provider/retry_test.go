@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/servicemanagement/apiv1/servicemanagementpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeServiceClient fails the first `failures` calls to GetService with `code`.
+type fakeServiceClient struct {
+	failures int
+	code     codes.Code
+	calls    int
+}
+
+func (c *fakeServiceClient) GetService(ctx context.Context, req *servicemanagementpb.GetServiceRequest) (*servicemanagementpb.ManagedService, error) {
+	c.calls++
+	if c.calls <= c.failures {
+		return nil, status.Error(c.code, "fake failure")
+	}
+	return &servicemanagementpb.ManagedService{ServiceName: req.ServiceName}, nil
+}
+
+func TestRetryTransient(t *testing.T) {
+	delay := readRetryBaseDelay
+	t.Cleanup(func() { readRetryBaseDelay = delay })
+	readRetryBaseDelay = 0
+
+	tests := []struct {
+		name      string
+		failures  int
+		code      codes.Code
+		wantCalls int
+		wantCode  codes.Code
+	}{
+		{name: "success", failures: 0, code: codes.Unavailable, wantCalls: 1, wantCode: codes.OK},
+		{name: "unavailable then success", failures: 2, code: codes.Unavailable, wantCalls: 3, wantCode: codes.OK},
+		{name: "deadline exceeded then success", failures: 1, code: codes.DeadlineExceeded, wantCalls: 2, wantCode: codes.OK},
+		{name: "internal then success", failures: 1, code: codes.Internal, wantCalls: 2, wantCode: codes.OK},
+		{name: "attempts exhausted", failures: 5, code: codes.Unavailable, wantCalls: readRetryAttempts, wantCode: codes.Unavailable},
+		{name: "not found", failures: 1, code: codes.NotFound, wantCalls: 1, wantCode: codes.NotFound},
+		{name: "permission denied", failures: 1, code: codes.PermissionDenied, wantCalls: 1, wantCode: codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeServiceClient{failures: tt.failures, code: tt.code}
+			service, err := retryTransient(context.Background(), func(ctx context.Context) (*servicemanagementpb.ManagedService, error) {
+				return client.GetService(ctx, &servicemanagementpb.GetServiceRequest{ServiceName: "test.example.com"})
+			})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("got code %v, want %v", got, tt.wantCode)
+			}
+			if client.calls != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", client.calls, tt.wantCalls)
+			}
+			if err == nil && service.GetServiceName() != "test.example.com" {
+				t.Errorf("got service %q, want %q", service.GetServiceName(), "test.example.com")
+			}
+		})
+	}
+}
+
+// fakeOperationClient returns an operation which is done after `polls` calls
+// to GetOperation.
+type fakeOperationClient struct {
+	polls int
+	calls int
+}
+
+func (c *fakeOperationClient) GetOperation(ctx context.Context) (bool, error) {
+	c.calls++
+	return c.calls >= c.polls, nil
+}
+
+func TestOperationPollDelay(t *testing.T) {
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	operationPollBaseDelay = time.Second
+
+	for poll, wantMin := range []time.Duration{
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		16 * time.Second,
+		operationPollMaxDelay,
+		operationPollMaxDelay,
+	} {
+		wantMax := min(2*wantMin, operationPollMaxDelay)
+		for range 100 {
+			if delay := operationPollDelay(poll); delay < wantMin || delay > wantMax {
+				t.Fatalf("poll %d: got delay %v, want between %v and %v", poll, delay, wantMin, wantMax)
+			}
+		}
+	}
+	if delay := operationPollDelay(1000); delay != operationPollMaxDelay {
+		t.Errorf("got delay %v, want %v", delay, operationPollMaxDelay)
+	}
+}
+
+func TestPollOperation(t *testing.T) {
+	defer func(delay time.Duration) { operationPollBaseDelay = delay }(operationPollBaseDelay)
+	done := func(done bool) bool { return done }
+
+	t.Run("done", func(t *testing.T) {
+		operationPollBaseDelay = 0
+		client := &fakeOperationClient{polls: 3}
+		got, err := pollOperation(context.Background(), time.Minute, false, done, client.GetOperation)
+		if err != nil || !got {
+			t.Errorf("got %v, %v, want done", got, err)
+		}
+		if client.calls != 3 {
+			t.Errorf("got %d calls, want 3", client.calls)
+		}
+	})
+
+	t.Run("already done", func(t *testing.T) {
+		client := &fakeOperationClient{}
+		if _, err := pollOperation(context.Background(), time.Minute, true, done, client.GetOperation); err != nil {
+			t.Fatal(err)
+		}
+		if client.calls != 0 {
+			t.Errorf("got %d calls, want none", client.calls)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		operationPollBaseDelay = time.Hour
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		client := &fakeOperationClient{polls: 1}
+		_, err := pollOperation(ctx, time.Hour, false, done, client.GetOperation)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got error %v, want %v", err, context.Canceled)
+		}
+		if client.calls != 0 {
+			t.Errorf("got %d calls, want none", client.calls)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		operationPollBaseDelay = time.Millisecond
+		client := &fakeOperationClient{polls: math.MaxInt}
+		_, err := pollOperation(context.Background(), 50*time.Millisecond, false, done, client.GetOperation)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		operationPollBaseDelay = 0
+		_, err := pollOperation(context.Background(), time.Minute, false, done, func(ctx context.Context) (bool, error) {
+			return false, status.Error(codes.PermissionDenied, "fake failure")
+		})
+		if status.Code(errors.Unwrap(err)) != codes.PermissionDenied {
+			t.Errorf("got error %v, want %v", err, codes.PermissionDenied)
+		}
+	})
+}
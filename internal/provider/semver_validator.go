@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"context"
+
+	constraints "github.com/Masterminds/semver/v3"
+	"github.com/coreos/go-semver/semver"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+)
+
+var _ validator.String = semverValidator{}
+var _ validator.String = versionConstraintValidator{}
+
+// semverValidator validates that a string is a full semantic version, for
+// example `3.5.0`.
+type semverValidator struct{}
+
+func (v semverValidator) Description(ctx context.Context) string {
+	return "value must be a semantic version, for example `3.5.0`"
+}
+
+func (v semverValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v semverValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	if _, err := semver.NewVersion(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid version", err.Error())
+	}
+}
+
+// versionConstraintValidator validates that a string is a semantic version
+// constraint, for example `>=3.4.0 <3.6.0 !=3.5.2`.
+type versionConstraintValidator struct{}
+
+func (v versionConstraintValidator) Description(ctx context.Context) string {
+	return "value must be a version constraint, for example `>=3.4.0 <3.6.0 !=3.5.2`"
+}
+
+func (v versionConstraintValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v versionConstraintValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	if _, err := constraints.NewConstraint(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid version constraint", err.Error())
+	}
+}
@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestSemverValidator(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     types.String
+		wantError bool
+	}{
+		{name: "release", value: types.StringValue("3.5.0")},
+		{name: "prerelease", value: types.StringValue("3.6.0-149.3.beta")},
+		{name: "unknown", value: types.StringUnknown()},
+		{name: "null", value: types.StringNull()},
+		{name: "missing patch", value: types.StringValue("3.5"), wantError: true},
+		{name: "prefixed", value: types.StringValue("v3.5.0"), wantError: true},
+		{name: "empty", value: types.StringValue(""), wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := validator.StringRequest{
+				Path:        path.Root("min_version"),
+				ConfigValue: tt.value,
+			}
+			var resp validator.StringResponse
+			semverValidator{}.ValidateString(context.Background(), req, &resp)
+
+			if got := resp.Diagnostics.HasError(); got != tt.wantError {
+				t.Errorf("got error %v, want %v: %v", got, tt.wantError, resp.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestVersionConstraintValidator(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     types.String
+		wantError bool
+	}{
+		{name: "single", value: types.StringValue(">=3.4.0")},
+		{name: "compound", value: types.StringValue(">=3.4.0 <3.6.0 !=3.5.2")},
+		{name: "comma separated", value: types.StringValue(">=3.4.0, <3.6.0")},
+		{name: "alternatives", value: types.StringValue("~3.4.0 || ^3.6")},
+		{name: "unknown", value: types.StringUnknown()},
+		{name: "null", value: types.StringNull()},
+		{name: "invalid operator", value: types.StringValue(">=3.4.0 <<3.6.0"), wantError: true},
+		{name: "invalid version", value: types.StringValue(">=three"), wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := validator.StringRequest{
+				Path:        path.Root("version_constraint"),
+				ConfigValue: tt.value,
+			}
+			var resp validator.StringResponse
+			versionConstraintValidator{}.ValidateString(context.Background(), req, &resp)
+
+			if got := resp.Diagnostics.HasError(); got != tt.wantError {
+				t.Errorf("got error %v, want %v: %v", got, tt.wantError, resp.Diagnostics)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// serviceConfigType is the required `type` of a service config.
+const serviceConfigType = "google.api.Service"
+
+var _ validator.String = serviceConfigYAMLValidator{}
+
+// serviceConfigYAMLValidator validates that a string is a YAML service config,
+// so that syntax errors are caught during plan rather than by the API.
+type serviceConfigYAMLValidator struct{}
+
+func (v serviceConfigYAMLValidator) Description(ctx context.Context) string {
+	return fmt.Sprintf("value must be a YAML mapping with `type: %s`", serviceConfigType)
+}
+
+func (v serviceConfigYAMLValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v serviceConfigYAMLValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	docs, err := parseYAMLDocuments(req.ConfigValue.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid service config YAML", err.Error())
+		return
+	}
+
+	for _, doc := range docs {
+		config, ok := doc.(map[string]any)
+		if !ok {
+			resp.Diagnostics.AddAttributeError(
+				req.Path,
+				"Invalid service config YAML",
+				fmt.Sprintf("The service config must be a YAML mapping, got %T.", doc),
+			)
+			continue
+		}
+
+		if configType, _ := config["type"].(string); configType != serviceConfigType {
+			resp.Diagnostics.AddAttributeError(
+				req.Path,
+				"Invalid service config type",
+				fmt.Sprintf("The service config must have `type: %s`, got %q.", serviceConfigType, fmt.Sprint(config["type"])),
+			)
+		}
+
+		name, ok := config["name"]
+		if !ok {
+			continue
+		}
+		var serviceName types.String
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("service_name"), &serviceName)...)
+		if serviceName.IsNull() || serviceName.IsUnknown() {
+			continue
+		}
+		if fmt.Sprint(name) != serviceName.ValueString() {
+			resp.Diagnostics.AddAttributeWarning(
+				req.Path,
+				"Service config name mismatch",
+				fmt.Sprintf("The service config name %q does not match `service_name` %q.", fmt.Sprint(name), serviceName.ValueString()),
+			)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestServiceConfigYAMLValidator(t *testing.T) {
+	const serviceName = "example.endpoints.my-project.cloud.goog"
+
+	tests := []struct {
+		name         string
+		value        types.String
+		serviceName  tftypes.Value
+		wantError    bool
+		errorSummary string
+		wantWarning  bool
+	}{
+		{
+			name:  "valid",
+			value: types.StringValue("type: google.api.Service\nname: " + serviceName + "\n"),
+		},
+		{
+			name:  "name omitted",
+			value: types.StringValue("type: google.api.Service\ntitle: Example API\n"),
+		},
+		{
+			name:  "unknown",
+			value: types.StringUnknown(),
+		},
+		{
+			name:  "null",
+			value: types.StringNull(),
+		},
+		{
+			name:         "invalid YAML",
+			value:        types.StringValue("type: google.api.Service\nname: " + serviceName + "\n  title: Example API\n"),
+			wantError:    true,
+			errorSummary: "Invalid service config YAML",
+		},
+		{
+			name:         "not a mapping",
+			value:        types.StringValue("- type: google.api.Service\n"),
+			wantError:    true,
+			errorSummary: "Invalid service config YAML",
+		},
+		{
+			name:         "wrong type",
+			value:        types.StringValue("type: google.api.Other\nname: " + serviceName + "\n"),
+			wantError:    true,
+			errorSummary: "Invalid service config type",
+		},
+		{
+			name:         "missing type",
+			value:        types.StringValue("name: " + serviceName + "\n"),
+			wantError:    true,
+			errorSummary: "Invalid service config type",
+		},
+		{
+			name:        "mismatched name",
+			value:       types.StringValue("type: google.api.Service\nname: other.endpoints.my-project.cloud.goog\n"),
+			wantWarning: true,
+		},
+		{
+			name:        "unknown service name",
+			value:       types.StringValue("type: google.api.Service\nname: other.endpoints.my-project.cloud.goog\n"),
+			serviceName: tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceNameValue := tt.serviceName
+			if serviceNameValue.Type() == nil {
+				serviceNameValue = tftypes.NewValue(tftypes.String, serviceName)
+			}
+
+			config := tfsdk.Config{
+				Schema: schema.Schema{
+					Attributes: map[string]schema.Attribute{
+						"service_name": schema.StringAttribute{Required: true},
+						"config_yaml":  schema.StringAttribute{Optional: true},
+					},
+				},
+				Raw: tftypes.NewValue(tftypes.Object{
+					AttributeTypes: map[string]tftypes.Type{
+						"service_name": tftypes.String,
+						"config_yaml":  tftypes.String,
+					},
+				}, map[string]tftypes.Value{
+					"service_name": serviceNameValue,
+					"config_yaml":  tftypes.NewValue(tftypes.String, nil),
+				}),
+			}
+
+			req := validator.StringRequest{
+				Path:        path.Root("config_yaml"),
+				ConfigValue: tt.value,
+				Config:      config,
+			}
+			var resp validator.StringResponse
+			serviceConfigYAMLValidator{}.ValidateString(context.Background(), req, &resp)
+
+			if got := resp.Diagnostics.HasError(); got != tt.wantError {
+				t.Fatalf("got error %v, want %v: %v", got, tt.wantError, resp.Diagnostics)
+			}
+			if tt.wantError && resp.Diagnostics.Errors()[0].Summary() != tt.errorSummary {
+				t.Errorf("got error %q, want %q", resp.Diagnostics.Errors()[0].Summary(), tt.errorSummary)
+			}
+			if got := resp.Diagnostics.WarningsCount() > 0; got != tt.wantWarning {
+				t.Errorf("got warning %v, want %v: %v", got, tt.wantWarning, resp.Diagnostics)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var _ basetypes.StringTypable = ServiceNameType{}
+var _ basetypes.StringValuableWithSemanticEquals = ServiceNameValue{}
+
+// ServiceNameType is a string type for service names which may be given with
+// the `services/` prefix used by gcloud and the API's resource names. Values
+// are semantically equal if they name the same service.
+type ServiceNameType struct {
+	basetypes.StringType
+}
+
+func (t ServiceNameType) Equal(o attr.Type) bool {
+	other, ok := o.(ServiceNameType)
+	if !ok {
+		return false
+	}
+	return t.StringType.Equal(other.StringType)
+}
+
+func (t ServiceNameType) String() string {
+	return "ServiceNameType"
+}
+
+func (t ServiceNameType) ValueFromString(ctx context.Context, in basetypes.StringValue) (basetypes.StringValuable, diag.Diagnostics) {
+	return ServiceNameValue{StringValue: in}, nil
+}
+
+func (t ServiceNameType) ValueFromTerraform(ctx context.Context, in tftypes.Value) (attr.Value, error) {
+	attrValue, err := t.StringType.ValueFromTerraform(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	stringValue, ok := attrValue.(basetypes.StringValue)
+	if !ok {
+		return nil, fmt.Errorf("unexpected value type of %T", attrValue)
+	}
+
+	return ServiceNameValue{StringValue: stringValue}, nil
+}
+
+func (t ServiceNameType) ValueType(ctx context.Context) attr.Value {
+	return ServiceNameValue{}
+}
+
+// ServiceNameValue is a value of ServiceNameType.
+type ServiceNameValue struct {
+	basetypes.StringValue
+}
+
+// NewServiceNameValue returns a known ServiceNameValue.
+func NewServiceNameValue(value string) ServiceNameValue {
+	return ServiceNameValue{StringValue: basetypes.NewStringValue(value)}
+}
+
+func (v ServiceNameValue) Equal(o attr.Value) bool {
+	other, ok := o.(ServiceNameValue)
+	if !ok {
+		return false
+	}
+	return v.StringValue.Equal(other.StringValue)
+}
+
+func (v ServiceNameValue) Type(ctx context.Context) attr.Type {
+	return ServiceNameType{}
+}
+
+// ServiceName returns the name of the service without the `services/`
+// prefix.
+func (v ServiceNameValue) ServiceName() string {
+	return normalizeServiceName(v.ValueString())
+}
+
+// StringSemanticEquals reports whether both values name the same service.
+func (v ServiceNameValue) StringSemanticEquals(ctx context.Context, newValuable basetypes.StringValuable) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	newValue, ok := newValuable.(ServiceNameValue)
+	if !ok {
+		diags.AddError(
+			"Semantic Equality Check Error",
+			fmt.Sprintf("Expected value type %T but got value type %T. Please report this to the provider developers.", v, newValuable),
+		)
+		return false, diags
+	}
+
+	return v.ServiceName() == newValue.ServiceName(), nil
+}
+
+// normalizeServiceName strips the `services/` prefix from serviceName.
+func normalizeServiceName(serviceName string) string {
+	return strings.TrimPrefix(serviceName, "services/")
+}
@@ -0,0 +1,32 @@
+package provider
+
+import (
+	"context"
+	"testing"
+)
+
+func TestServiceNameSemanticEquals(t *testing.T) {
+	tests := []struct {
+		name     string
+		oldValue string
+		newValue string
+		want     bool
+	}{
+		{name: "identical", oldValue: "svc.example.com", newValue: "svc.example.com", want: true},
+		{name: "prefixed", oldValue: "services/svc.example.com", newValue: "svc.example.com", want: true},
+		{name: "both prefixed", oldValue: "services/svc.example.com", newValue: "services/svc.example.com", want: true},
+		{name: "different", oldValue: "services/svc.example.com", newValue: "other.example.com", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, diags := NewServiceNameValue(tt.oldValue).StringSemanticEquals(context.Background(), NewServiceNameValue(tt.newValue))
+			if diags.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
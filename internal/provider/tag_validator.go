@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+)
+
+var _ validator.String = tenantTagValidator{}
+
+// maxTenantTagLength is the maximum length of tenant resource tags.
+const maxTenantTagLength = 128
+
+// validateTenantTag checks that tag is a valid tag of a tenant resource:
+// non-empty, shorter than maxTenantTagLength characters, and without
+// slashes.
+func validateTenantTag(tag string) error {
+	switch {
+	case tag == "":
+		return errors.New("the tag must not be empty")
+	case len(tag) > maxTenantTagLength:
+		return fmt.Errorf("the tag must be at most %d characters long, got %d", maxTenantTagLength, len(tag))
+	case strings.Contains(tag, "/"):
+		return fmt.Errorf("the tag must not contain slashes, got %q", tag)
+	}
+	return nil
+}
+
+// tenantTagValidator validates that a string is a valid tag of a tenant
+// resource.
+type tenantTagValidator struct{}
+
+func (v tenantTagValidator) Description(ctx context.Context) string {
+	return fmt.Sprintf("value must be a non-empty tag of at most %d characters, without slashes", maxTenantTagLength)
+}
+
+func (v tenantTagValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v tenantTagValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	if err := validateTenantTag(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid tag", err.Error()+".")
+	}
+}
@@ -0,0 +1,43 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestTenantTagValidator(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     types.String
+		wantError bool
+	}{
+		{name: "simple", value: types.StringValue("prod")},
+		{name: "punctuation", value: types.StringValue("prod-eu_1.a")},
+		{name: "max length", value: types.StringValue(strings.Repeat("a", maxTenantTagLength))},
+		{name: "unknown", value: types.StringUnknown()},
+		{name: "null", value: types.StringNull()},
+		{name: "empty", value: types.StringValue(""), wantError: true},
+		{name: "too long", value: types.StringValue(strings.Repeat("a", maxTenantTagLength+1)), wantError: true},
+		{name: "slash", value: types.StringValue("prod/eu"), wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := validator.StringRequest{
+				Path:        path.Root("tag"),
+				ConfigValue: tt.value,
+			}
+			var resp validator.StringResponse
+			tenantTagValidator{}.ValidateString(context.Background(), req, &resp)
+
+			if got := resp.Diagnostics.HasError(); got != tt.wantError {
+				t.Errorf("got error %v, want %v: %v", got, tt.wantError, resp.Diagnostics)
+			}
+		})
+	}
+}
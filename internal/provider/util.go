@@ -1,18 +1,58 @@
 package provider
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/serviceconsumermanagement/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// serviceManagementAPI is the name of the Service Management API service.
+const serviceManagementAPI = "servicemanagement.googleapis.com"
+
+// parseConfigId parses a config ID in either the `{serviceName}/{configId}`
+// format used in state or the `services/{serviceName}/configs/{configId}`
+// resource name format used by gcloud and the API.
 func parseConfigId(id string) (string, string, error) {
 	parts := strings.Split(id, "/")
-	if len(parts) != 2 {
-		return "", "", errors.New("ID must be in the format `{serviceName}/{configId}`")
+	switch {
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		return parts[0], parts[1], nil
+	case len(parts) == 4 && parts[0] == "services" && parts[2] == "configs" && parts[1] != "" && parts[3] != "":
+		return parts[1], parts[3], nil
+	default:
+		return "", "", errors.New("ID must be in the format `{serviceName}/{configId}` or `services/{serviceName}/configs/{configId}`")
 	}
-	return parts[0], parts[1], nil
+}
+
+// resolveConfigId parses a config ID in the traffic split of a rollout. It is
+// either a config ID within serviceName, if set, or in a format accepted by
+// parseConfigId for the same service.
+func resolveConfigId(serviceName, id string) (string, string, error) {
+	if !strings.Contains(id, "/") {
+		if serviceName == "" || id == "" {
+			return "", "", fmt.Errorf("Config ID %q must be in the format `{serviceName}/{configId}` unless `service_name` is set", id)
+		}
+		return serviceName, id, nil
+	}
+	svcName, configId, err := parseConfigId(id)
+	if err != nil {
+		return "", "", err
+	}
+	if serviceName != "" && svcName != serviceName {
+		return "", "", fmt.Errorf("Config ID %q is a config of service %s, not `service_name` %s", id, svcName, serviceName)
+	}
+	return svcName, configId, nil
 }
 
 func newConfigId(serviceName, configId string) types.String {
@@ -27,6 +67,103 @@ func parseRolloutId(id string) (string, string, error) {
 	return parts[0], parts[1], nil
 }
 
+// parseRolloutResourceName parses a rollout resource name in the
+// `services/{serviceName}/rollouts/{rolloutId}` format used by the API.
+func parseRolloutResourceName(name string) (string, string, bool) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 4 || parts[0] != "services" || parts[2] != "rollouts" || parts[1] == "" || parts[3] == "" {
+		return "", "", false
+	}
+	return parts[1], parts[3], true
+}
+
 func newRolloutId(serviceName, rolloutId string) types.String {
 	return types.StringValue(serviceName + "/" + rolloutId)
 }
+
+// isGoogleAPIErrorCode reports whether err is a REST API error with one of
+// the given HTTP status codes.
+func isGoogleAPIErrorCode(err error, codes ...int) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return slices.Contains(codes, apiErr.Code)
+}
+
+// googleAPIErrorStatus returns the canonical status of a REST API error, such
+// as `ABORTED`, or "" if err is not one or has no status.
+func googleAPIErrorStatus(err error) string {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return ""
+	}
+	var body struct {
+		Error struct {
+			Status string `json:"status"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(apiErr.Body), &body); err != nil {
+		return ""
+	}
+	return body.Error.Status
+}
+
+// isNotFound reports whether err indicates that a resource does not exist.
+//
+// Service Management reports deleted services as PermissionDenied with a
+// "not found" message, so those are treated as not found as well.
+func isNotFound(err error) bool {
+	if isGoogleAPIErrorCode(err, http.StatusNotFound) {
+		return true
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	switch s.Code() {
+	case codes.NotFound:
+		return true
+	case codes.PermissionDenied:
+		return strings.Contains(strings.ToLower(s.Message()), "not found")
+	default:
+		return false
+	}
+}
+
+// tenantOperationTimeout is how long to wait for a Service Consumer
+// Management operation to complete.
+var tenantOperationTimeout = 30 * time.Minute
+
+// pollTenantOperation waits for a Service Consumer Management operation to
+// complete and returns the completed operation.
+func (p *UtilsProviderConfig) pollTenantOperation(ctx context.Context, op *serviceconsumermanagement.Operation) (*serviceconsumermanagement.Operation, error) {
+	done := func(op *serviceconsumermanagement.Operation) bool { return op.Done }
+	return pollOperation(ctx, tenantOperationTimeout, op, done, func(ctx context.Context) (*serviceconsumermanagement.Operation, error) {
+		return p.TenantClient.Operations.Get(op.Name).Context(ctx).Do()
+	})
+}
+
+// waitTenantOperation waits for a Service Consumer Management operation to
+// complete and returns its error, if any.
+func (p *UtilsProviderConfig) waitTenantOperation(ctx context.Context, op *serviceconsumermanagement.Operation) error {
+	op, err := p.pollTenantOperation(ctx, op)
+	if err != nil {
+		return err
+	}
+	return tenantOperationError(op)
+}
+
+// tenantOperationError returns the error of a completed Service Consumer
+// Management operation, including its code and details, or nil if it
+// succeeded.
+func tenantOperationError(op *serviceconsumermanagement.Operation) error {
+	if op.Error == nil {
+		return nil
+	}
+	msg := fmt.Sprintf("operation %s failed with %s: %s", op.Name, codes.Code(op.Error.Code), op.Error.Message)
+	for _, detail := range op.Error.Details {
+		msg += "\n" + string(detail)
+	}
+	return errors.New(msg)
+}
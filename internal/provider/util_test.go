@@ -0,0 +1,96 @@
+package provider
+
+import "testing"
+
+func TestParseConfigId(t *testing.T) {
+	for _, tt := range []struct {
+		id              string
+		wantServiceName string
+		wantConfigId    string
+		wantErr         bool
+	}{
+		{id: "my-api.example.com/2024-09-01r3", wantServiceName: "my-api.example.com", wantConfigId: "2024-09-01r3"},
+		{id: "services/my-api.example.com/configs/2024-09-01r3", wantServiceName: "my-api.example.com", wantConfigId: "2024-09-01r3"},
+		{id: "my-api.example.com", wantErr: true},
+		{id: "my-api.example.com/", wantErr: true},
+		{id: "services/my-api.example.com/rollouts/2024-09-01r3", wantErr: true},
+		{id: "services/my-api.example.com/configs/", wantErr: true},
+		{id: "a/b/c", wantErr: true},
+	} {
+		t.Run(tt.id, func(t *testing.T) {
+			serviceName, configId, err := parseConfigId(tt.id)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %q, %q", serviceName, configId)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if serviceName != tt.wantServiceName || configId != tt.wantConfigId {
+				t.Errorf("got %q, %q, want %q, %q", serviceName, configId, tt.wantServiceName, tt.wantConfigId)
+			}
+		})
+	}
+}
+
+func TestResolveConfigId(t *testing.T) {
+	for _, tt := range []struct {
+		serviceName     string
+		id              string
+		wantServiceName string
+		wantConfigId    string
+		wantErr         bool
+	}{
+		{id: "my-api.example.com/2024-09-01r3", wantServiceName: "my-api.example.com", wantConfigId: "2024-09-01r3"},
+		{id: "services/my-api.example.com/configs/2024-09-01r3", wantServiceName: "my-api.example.com", wantConfigId: "2024-09-01r3"},
+		{serviceName: "my-api.example.com", id: "2024-09-01r3", wantServiceName: "my-api.example.com", wantConfigId: "2024-09-01r3"},
+		{serviceName: "my-api.example.com", id: "my-api.example.com/2024-09-01r3", wantServiceName: "my-api.example.com", wantConfigId: "2024-09-01r3"},
+		{serviceName: "my-api.example.com", id: "services/my-api.example.com/configs/2024-09-01r3", wantServiceName: "my-api.example.com", wantConfigId: "2024-09-01r3"},
+		{id: "2024-09-01r3", wantErr: true},
+		{serviceName: "my-api.example.com", id: "", wantErr: true},
+		{serviceName: "my-api.example.com", id: "other.example.com/2024-09-01r3", wantErr: true},
+		{serviceName: "my-api.example.com", id: "a/b/c", wantErr: true},
+	} {
+		t.Run(tt.serviceName+":"+tt.id, func(t *testing.T) {
+			serviceName, configId, err := resolveConfigId(tt.serviceName, tt.id)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %q, %q", serviceName, configId)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if serviceName != tt.wantServiceName || configId != tt.wantConfigId {
+				t.Errorf("got %q, %q, want %q, %q", serviceName, configId, tt.wantServiceName, tt.wantConfigId)
+			}
+		})
+	}
+}
+
+func TestParseRolloutResourceName(t *testing.T) {
+	for _, tt := range []struct {
+		name            string
+		wantServiceName string
+		wantRolloutId   string
+		wantOk          bool
+	}{
+		{name: "services/my-api.example.com/rollouts/2024-09-01r3", wantServiceName: "my-api.example.com", wantRolloutId: "2024-09-01r3", wantOk: true},
+		{name: "services/my-api.example.com/configs/2024-09-01r3"},
+		{name: "services/my-api.example.com/rollouts/"},
+		{name: "my-api.example.com/2024-09-01r3"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceName, rolloutId, ok := parseRolloutResourceName(tt.name)
+			if ok != tt.wantOk {
+				t.Fatalf("got ok %v, want %v", ok, tt.wantOk)
+			}
+			if serviceName != tt.wantServiceName || rolloutId != tt.wantRolloutId {
+				t.Errorf("got %q, %q, want %q, %q", serviceName, rolloutId, tt.wantServiceName, tt.wantRolloutId)
+			}
+		})
+	}
+}
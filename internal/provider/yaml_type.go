@@ -0,0 +1,160 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"gopkg.in/yaml.v3"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var _ basetypes.StringTypable = YAMLStringType{}
+var _ basetypes.StringValuableWithSemanticEquals = YAMLStringValue{}
+
+// YAMLStringType is a string type for YAML documents. Values which parse to
+// the same YAML structure are semantically equal, so differences in key
+// ordering, quoting and whitespace do not produce diffs.
+type YAMLStringType struct {
+	basetypes.StringType
+}
+
+func (t YAMLStringType) Equal(o attr.Type) bool {
+	other, ok := o.(YAMLStringType)
+	if !ok {
+		return false
+	}
+	return t.StringType.Equal(other.StringType)
+}
+
+func (t YAMLStringType) String() string {
+	return "YAMLStringType"
+}
+
+func (t YAMLStringType) ValueFromString(ctx context.Context, in basetypes.StringValue) (basetypes.StringValuable, diag.Diagnostics) {
+	return YAMLStringValue{StringValue: in}, nil
+}
+
+func (t YAMLStringType) ValueFromTerraform(ctx context.Context, in tftypes.Value) (attr.Value, error) {
+	attrValue, err := t.StringType.ValueFromTerraform(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	stringValue, ok := attrValue.(basetypes.StringValue)
+	if !ok {
+		return nil, fmt.Errorf("unexpected value type of %T", attrValue)
+	}
+
+	return YAMLStringValue{StringValue: stringValue}, nil
+}
+
+func (t YAMLStringType) ValueType(ctx context.Context) attr.Value {
+	return YAMLStringValue{}
+}
+
+// YAMLStringValue is a value of YAMLStringType.
+type YAMLStringValue struct {
+	basetypes.StringValue
+}
+
+// NewYAMLStringValue returns a known YAMLStringValue.
+func NewYAMLStringValue(value string) YAMLStringValue {
+	return YAMLStringValue{StringValue: basetypes.NewStringValue(value)}
+}
+
+func (v YAMLStringValue) Equal(o attr.Value) bool {
+	other, ok := o.(YAMLStringValue)
+	if !ok {
+		return false
+	}
+	return v.StringValue.Equal(other.StringValue)
+}
+
+func (v YAMLStringValue) Type(ctx context.Context) attr.Type {
+	return YAMLStringType{}
+}
+
+// StringSemanticEquals reports whether both values parse to the same YAML
+// documents.
+func (v YAMLStringValue) StringSemanticEquals(ctx context.Context, newValuable basetypes.StringValuable) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	newValue, ok := newValuable.(YAMLStringValue)
+	if !ok {
+		diags.AddError(
+			"Semantic Equality Check Error",
+			fmt.Sprintf("Expected value type %T but got value type %T. Please report this to the provider developers.", v, newValuable),
+		)
+		return false, diags
+	}
+
+	oldDocs, err := parseYAMLDocuments(v.ValueString())
+	if err != nil {
+		return false, nil
+	}
+	newDocs, err := parseYAMLDocuments(newValue.ValueString())
+	if err != nil {
+		return false, nil
+	}
+
+	return reflect.DeepEqual(oldDocs, newDocs), nil
+}
+
+// parseYAMLDocuments parses each document in a YAML stream, resolving aliases
+// and normalizing numbers so that, for example, `1` and `1.0` are equal.
+// Empty documents are skipped.
+func parseYAMLDocuments(s string) ([]any, error) {
+	decoder := yaml.NewDecoder(bytes.NewBufferString(s))
+
+	var docs []any
+	for {
+		var doc any
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if doc != nil {
+			docs = append(docs, normalizeYAML(doc))
+		}
+	}
+}
+
+func normalizeYAML(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			v[key] = normalizeYAML(value)
+		}
+		return v
+	case map[any]any:
+		normalized := make(map[string]any, len(v))
+		for key, value := range v {
+			normalized[fmt.Sprint(key)] = normalizeYAML(value)
+		}
+		return normalized
+	case []any:
+		for i, value := range v {
+			v[i] = normalizeYAML(value)
+		}
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	default:
+		return v
+	}
+}
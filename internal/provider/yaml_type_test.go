@@ -0,0 +1,148 @@
+package provider
+
+import (
+	"context"
+	"testing"
+)
+
+func TestYAMLStringSemanticEquals(t *testing.T) {
+	tests := []struct {
+		name     string
+		oldValue string
+		newValue string
+		want     bool
+	}{
+		{
+			name: "identical",
+			oldValue: `type: google.api.Service
+config_version: 3
+name: example.endpoints.my-project.cloud.goog
+`,
+			newValue: `type: google.api.Service
+config_version: 3
+name: example.endpoints.my-project.cloud.goog
+`,
+			want: true,
+		},
+		{
+			name: "key ordering",
+			oldValue: `type: google.api.Service
+config_version: 3
+name: example.endpoints.my-project.cloud.goog
+title: Example API
+`,
+			newValue: `name: example.endpoints.my-project.cloud.goog
+title: Example API
+config_version: 3
+type: google.api.Service
+`,
+			want: true,
+		},
+		{
+			name: "quoting and trailing whitespace",
+			oldValue: `type: google.api.Service
+name: example.endpoints.my-project.cloud.goog
+apis:
+- name: example.v1.ExampleService
+`,
+			newValue: `type: "google.api.Service"
+name: 'example.endpoints.my-project.cloud.goog'
+apis:
+  - name: "example.v1.ExampleService"
+
+
+`,
+			want: true,
+		},
+		{
+			name: "flow style",
+			oldValue: `usage:
+  rules:
+  - selector: "*"
+    allow_unregistered_calls: true
+`,
+			newValue: `usage: {rules: [{selector: "*", allow_unregistered_calls: true}]}`,
+			want:     true,
+		},
+		{
+			name:     "numbers",
+			oldValue: `config_version: 3`,
+			newValue: `config_version: 3.0`,
+			want:     true,
+		},
+		{
+			name: "anchors and aliases",
+			oldValue: `http:
+  rules:
+  - selector: example.v1.ExampleService.Get
+    get: /v1/example
+  - selector: example.v1.ExampleService.List
+    get: /v1/example
+`,
+			newValue: `http:
+  rules:
+  - selector: example.v1.ExampleService.Get
+    get: &path /v1/example
+  - selector: example.v1.ExampleService.List
+    get: *path
+`,
+			want: true,
+		},
+		{
+			name: "multiple documents",
+			oldValue: `name: example.endpoints.my-project.cloud.goog
+---
+title: Example API
+`,
+			newValue: `---
+name: example.endpoints.my-project.cloud.goog
+---
+title: Example API
+---
+`,
+			want: true,
+		},
+		{
+			name:     "different values",
+			oldValue: `title: Example API`,
+			newValue: `title: Other API`,
+			want:     false,
+		},
+		{
+			name: "different list order",
+			oldValue: `apis:
+- name: a.v1.A
+- name: b.v1.B
+`,
+			newValue: `apis:
+- name: b.v1.B
+- name: a.v1.A
+`,
+			want: false,
+		},
+		{
+			name:     "different document split",
+			oldValue: "name: example\ntitle: Example API\n",
+			newValue: "name: example\n---\ntitle: Example API\n",
+			want:     false,
+		},
+		{
+			name:     "invalid",
+			oldValue: `title: Example API`,
+			newValue: `title: [`,
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, diags := NewYAMLStringValue(tt.oldValue).StringSemanticEquals(context.Background(), NewYAMLStringValue(tt.newValue))
+			if diags.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			if got != tt.want {
+				t.Errorf("StringSemanticEquals() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}